@@ -8,6 +8,7 @@ import (
 	"github.com/filecoin-project/go-leb128"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -62,6 +63,10 @@ const (
 	Parameters
 	// IntSet is a set of uint64
 	IntSet
+	// VoucherLane is the lane of a payment channel voucher
+	VoucherLane
+	// Multiaddrs is a slice of multiaddrs
+	Multiaddrs
 )
 
 func (t Type) String() string {
@@ -108,6 +113,10 @@ func (t Type) String() string {
 		return "[]interface{}"
 	case IntSet:
 		return "types.IntSet"
+	case VoucherLane:
+		return "*types.VoucherLane"
+	case Multiaddrs:
+		return "[]ma.Multiaddr"
 	default:
 		return "<unknown type>"
 	}
@@ -163,6 +172,10 @@ func (av *Value) String() string {
 		return fmt.Sprint(av.Val.([]interface{}))
 	case IntSet:
 		return av.Val.(types.IntSet).String()
+	case VoucherLane:
+		return fmt.Sprint(av.Val.(*types.VoucherLane))
+	case Multiaddrs:
+		return fmt.Sprint(av.Val.([]ma.Multiaddr))
 	default:
 		return "<unknown type>"
 	}
@@ -320,6 +333,24 @@ func (av *Value) Serialize() ([]byte, error) {
 			return nil, &typeError{types.IntSet{}, av.Val}
 		}
 		return cbor.DumpObject(is)
+	case VoucherLane:
+		l, ok := av.Val.(*types.VoucherLane)
+		if !ok {
+			return nil, &typeError{&types.VoucherLane{}, av.Val}
+		}
+
+		return cbor.DumpObject(l)
+	case Multiaddrs:
+		addrs, ok := av.Val.([]ma.Multiaddr)
+		if !ok {
+			return nil, &typeError{[]ma.Multiaddr{}, av.Val}
+		}
+
+		raw := make([][]byte, len(addrs))
+		for i, addr := range addrs {
+			raw[i] = addr.Bytes()
+		}
+		return cbor.DumpObject(raw)
 	default:
 		return nil, fmt.Errorf("unrecognized Type: %d", av.Type)
 	}
@@ -375,6 +406,10 @@ func ToValues(i []interface{}) ([]*Value, error) {
 			out = append(out, &Value{Type: Parameters, Val: v})
 		case types.IntSet:
 			out = append(out, &Value{Type: IntSet, Val: v})
+		case *types.VoucherLane:
+			out = append(out, &Value{Type: VoucherLane, Val: v})
+		case []ma.Multiaddr:
+			out = append(out, &Value{Type: Multiaddrs, Val: v})
 		default:
 			return nil, fmt.Errorf("unsupported type: %T", v)
 		}
@@ -536,6 +571,32 @@ func Deserialize(data []byte, t Type) (*Value, error) {
 			Type: t,
 			Val:  is,
 		}, nil
+	case VoucherLane:
+		var lane *types.VoucherLane
+		if err := cbor.DecodeInto(data, &lane); err != nil {
+			return nil, err
+		}
+		return &Value{
+			Type: t,
+			Val:  lane,
+		}, nil
+	case Multiaddrs:
+		var raw [][]byte
+		if err := cbor.DecodeInto(data, &raw); err != nil {
+			return nil, err
+		}
+		addrs := make([]ma.Multiaddr, len(raw))
+		for i, b := range raw {
+			addr, err := ma.NewMultiaddrBytes(b)
+			if err != nil {
+				return nil, err
+			}
+			addrs[i] = addr
+		}
+		return &Value{
+			Type: t,
+			Val:  addrs,
+		}, nil
 	case Invalid:
 		return nil, ErrInvalidType
 	default:
@@ -564,6 +625,8 @@ var typeTable = map[Type]reflect.Type{
 	Predicate:      reflect.TypeOf(&types.Predicate{}),
 	Parameters:     reflect.TypeOf([]interface{}{}),
 	IntSet:         reflect.TypeOf(types.IntSet{}),
+	VoucherLane:    reflect.TypeOf(&types.VoucherLane{}),
+	Multiaddrs:     reflect.TypeOf([]ma.Multiaddr{}),
 }
 
 // TypeMatches returns whether or not 'val' is the go type expected for the given ABI type
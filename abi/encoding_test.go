@@ -7,6 +7,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/types"
+	// registers the p2p-circuit protocol used by relay addresses
+	_ "github.com/libp2p/go-libp2p-circuit"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,6 +37,15 @@ func TestBasicEncodingRoundTrip(t *testing.T) {
 			Method: "someMethod",
 			Params: []interface{}{uint64(3), []byte("proof")},
 		}},
+		"voucher lane": {&types.VoucherLane{
+			ID:     2,
+			Nonce:  5,
+			Merges: []types.Merge{{Lane: 1, Nonce: 3}},
+		}},
+		"multiaddrs": {[]ma.Multiaddr{
+			mustMultiaddr("/ip4/127.0.0.1/tcp/6000"),
+			mustMultiaddr("/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit"),
+		}},
 	}
 
 	for tname, tcase := range cases {
@@ -58,6 +70,14 @@ func TestBasicEncodingRoundTrip(t *testing.T) {
 	}
 }
 
+func mustMultiaddr(s string) ma.Multiaddr {
+	addr, err := ma.NewMultiaddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
 type fooTestStruct struct {
 	Bar string
 	Baz uint64
@@ -0,0 +1,55 @@
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// jsonValue is the JSON representation of a Value. Type holds the name of the
+// go type of the value, as returned by Type.String.
+type jsonValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON encodes the value as an object holding its type name and its go
+// value encoded as JSON.
+func (av *Value) MarshalJSON() ([]byte, error) {
+	val, err := json.Marshal(av.Val)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue{Type: av.Type.String(), Value: val})
+}
+
+// UnmarshalJSON decodes a value encoded with MarshalJSON.
+func (av *Value) UnmarshalJSON(data []byte) error {
+	var jv jsonValue
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+
+	t, ok := typeFromString(jv.Type)
+	if !ok {
+		return fmt.Errorf("unrecognized type: %s", jv.Type)
+	}
+
+	ptr := reflect.New(typeTable[t])
+	if err := json.Unmarshal(jv.Value, ptr.Interface()); err != nil {
+		return err
+	}
+
+	av.Type = t
+	av.Val = ptr.Elem().Interface()
+	return nil
+}
+
+func typeFromString(s string) (Type, bool) {
+	for t := range typeTable {
+		if t.String() == s {
+			return t, true
+		}
+	}
+	return Invalid, false
+}
@@ -0,0 +1,55 @@
+package abi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestValueJSONRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	addrGetter := address.NewForTestGetter()
+
+	cases := map[string]*Value{
+		"address":      {Type: Address, Val: addrGetter()},
+		"attofil":      {Type: AttoFIL, Val: types.NewAttoFILFromFIL(17)},
+		"bytes amount": {Type: BytesAmount, Val: types.NewBytesAmount(1024)},
+		"block height": {Type: BlockHeight, Val: types.NewBlockHeight(42)},
+		"integer":      {Type: Integer, Val: big.NewInt(579)},
+		"bytes":        {Type: Bytes, Val: []byte("foo")},
+		"string":       {Type: String, Val: "flugzeug"},
+		"sector id":    {Type: SectorID, Val: uint64(1234)},
+		"boolean":      {Type: Boolean, Val: true},
+	}
+
+	for tname, val := range cases {
+		t.Run(tname, func(t *testing.T) {
+			data, err := json.Marshal(val)
+			require.NoError(t, err)
+
+			var out Value
+			require.NoError(t, json.Unmarshal(data, &out))
+			assert.Equal(t, val.Type, out.Type)
+			assert.Equal(t, val.String(), out.String())
+		})
+	}
+
+	t.Run("names the type", func(t *testing.T) {
+		data, err := json.Marshal(&Value{Type: String, Val: "flugzeug"})
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"string","value":"flugzeug"}`, string(data))
+	})
+
+	t.Run("rejects unknown types", func(t *testing.T) {
+		var out Value
+		assert.Error(t, json.Unmarshal([]byte(`{"type":"nope","value":1}`), &out))
+	})
+}
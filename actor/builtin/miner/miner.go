@@ -7,6 +7,7 @@ import (
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/libp2p/go-libp2p-peer"
+	multiaddr "github.com/multiformats/go-multiaddr"
 	xerrors "github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
@@ -128,6 +129,10 @@ type State struct {
 	// PeerID references the libp2p identity that the miner is operating.
 	PeerID peer.ID
 
+	// PeerAddrs are the binary multiaddrs the miner publishes as reachable
+	// for its peer, e.g. the relay addresses of a miner behind a NAT.
+	PeerAddrs [][]byte
+
 	// ActiveCollateral is the amount of collateral currently committed to live
 	// storage.
 	ActiveCollateral types.AttoFIL
@@ -250,12 +255,20 @@ var minerExports = exec.Exports{
 		Params: []abi.Type{abi.PeerID},
 		Return: []abi.Type{},
 	},
+	"getPeerAddrs": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.Multiaddrs},
+	},
+	"updatePeerAddrs": &exec.FunctionSignature{
+		Params: []abi.Type{abi.Multiaddrs},
+		Return: []abi.Type{},
+	},
 	"getPower": &exec.FunctionSignature{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.BytesAmount},
 	},
 	"submitPoSt": &exec.FunctionSignature{
-		Params: []abi.Type{abi.PoStProofs, abi.IntSet},
+		Params: []abi.Type{abi.PoStProofs, abi.IntSet, abi.IntSet},
 		Return: []abi.Type{},
 	},
 	"changeWorker": &exec.FunctionSignature{
@@ -538,7 +551,7 @@ func (ma *Actor) CommitSector(ctx exec.VMContext, sectorID uint64, commD, commR,
 			req.SectorID = sectorbuilder.SectorIDToBytes(sectorID)
 			req.SectorSize = state.SectorSize
 
-			res, err := (&proofs.RustVerifier{}).VerifySeal(req)
+			res, err := ctx.Verifier().VerifySeal(req)
 			if err != nil {
 				return nil, errors.RevertErrorWrap(err, "failed to verify seal proof")
 			}
@@ -729,6 +742,64 @@ func (ma *Actor) UpdatePeerID(ctx exec.VMContext, pid peer.ID) (uint8, error) {
 	return 0, nil
 }
 
+// GetPeerAddrs returns the addresses the peer of this miner can be reached at.
+func (ma *Actor) GetPeerAddrs(ctx exec.VMContext) ([]multiaddr.Multiaddr, uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+
+	chunk, err := ctx.ReadStorage()
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	if err := actor.UnmarshalStorage(chunk, &state); err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	addrs := make([]multiaddr.Multiaddr, len(state.PeerAddrs))
+	for i, b := range state.PeerAddrs {
+		addr, err := multiaddr.NewMultiaddrBytes(b)
+		if err != nil {
+			err = errors.FaultErrorWrap(err, "invalid peer address in miner state")
+			return nil, errors.CodeError(err), err
+		}
+		addrs[i] = addr
+	}
+
+	return addrs, 0, nil
+}
+
+// UpdatePeerAddrs is used to update the addresses the peer of this miner can
+// be reached at.
+func (ma *Actor) UpdatePeerAddrs(ctx exec.VMContext, addrs []multiaddr.Multiaddr) (uint8, error) {
+	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var storage State
+	_, err := actor.WithState(ctx, &storage, func() (interface{}, error) {
+		// verify that the caller is authorized to perform update
+		if ctx.Message().From != storage.Worker {
+			return nil, Errors[ErrCallerUnauthorized]
+		}
+
+		storage.PeerAddrs = make([][]byte, len(addrs))
+		for i, addr := range addrs {
+			storage.PeerAddrs[i] = addr.Bytes()
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
 // GetPower returns the amount of proven sectors for this miner.
 func (ma *Actor) GetPower(ctx exec.VMContext) (*types.BytesAmount, uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
@@ -752,8 +823,11 @@ func (ma *Actor) GetPower(ctx exec.VMContext) (*types.BytesAmount, uint8, error)
 }
 
 // SubmitPoSt is used to submit a coalesced PoST to the chain to convince the chain
-// that you have been actually storing the files you claim to be.
-func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProofs []types.PoStProof, done types.IntSet) (uint8, error) {
+// that you have been actually storing the files you claim to be. Faults are
+// the sectors that could not be proven this period; they are excluded from the
+// proof and from the miner's power until they are proven again. Done are the sectors the miner is finished with, which
+// are removed from its sector set.
+func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProofs []types.PoStProof, faults types.IntSet, done types.IntSet) (uint8, error) {
 	if err := ctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
@@ -824,12 +898,12 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProofs []types.PoStProof, do
 			req := proofs.VerifyPoStRequest{
 				ChallengeSeed: seed,
 				SortedCommRs:  sortedCommRs,
-				Faults:        []uint64{},
+				Faults:        faults.Values(),
 				Proofs:        poStProofs,
 				SectorSize:    state.SectorSize,
 			}
 
-			res, err := (&proofs.RustVerifier{}).VerifyPoST(req)
+			res, err := ctx.Verifier().VerifyPoST(req)
 			if err != nil {
 				return nil, errors.RevertErrorWrap(err, "failed to verify PoSt")
 			}
@@ -843,10 +917,10 @@ func (ma *Actor) SubmitPoSt(ctx exec.VMContext, poStProofs []types.PoStProof, do
 		state.LastPoSt = chainHeight
 
 		// Update miner power to the amount of data actually proved
-		// during the last proving period.
+		// during the last proving period, which excludes the faulted sectors.
 		oldPower := state.Power
-		// TODO subtract total faulted size from ProvingSet size #2889
-		newPower := types.NewBytesAmount(uint64(state.ProvingSet.Size())).Mul(state.SectorSize)
+		provenSet := state.ProvingSet.Difference(faults)
+		newPower := types.NewBytesAmount(uint64(provenSet.Size())).Mul(state.SectorSize)
 		state.Power = newPower
 		delta := newPower.Sub(oldPower)
 		_, ret, err := ctx.Send(address.StorageMarketAddress, "updateStorage", types.ZeroAttoFIL, []interface{}{delta})
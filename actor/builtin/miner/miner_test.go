@@ -6,7 +6,10 @@ import (
 	"math/big"
 	"testing"
 
+	// registers the p2p-circuit protocol used by relay addresses
+	_ "github.com/libp2p/go-libp2p-circuit"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -281,6 +284,69 @@ func TestPeerIdGetterAndSetter(t *testing.T) {
 	})
 }
 
+func TestPeerAddrsGetterAndSetter(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("successfully retrieves and updates peer addresses", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		st, vms := th.RequireCreateStorages(ctx, t)
+
+		minerAddr := th.CreateTestMiner(t, st, vms, address.TestAddress, th.RequireRandomPeerID(t))
+
+		// a new miner publishes no addresses
+		result := callQueryMethodSuccess("getPeerAddrs", ctx, t, st, vms, address.TestAddress, minerAddr)
+		addrs, err := abi.Deserialize(result[0], abi.Multiaddrs)
+		require.NoError(t, err)
+		assert.Empty(t, addrs.Val)
+
+		relayAddr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+		require.NoError(t, err)
+
+		updatePeerAddrsMsg := types.NewMessage(
+			address.TestAddress,
+			minerAddr,
+			th.RequireGetNonce(t, st, address.TestAddress),
+			types.NewAttoFILFromFIL(0),
+			"updatePeerAddrs",
+			actor.MustConvertParams([]ma.Multiaddr{relayAddr}))
+
+		applyMsgResult, err := th.ApplyTestMessage(st, vms, updatePeerAddrsMsg, types.NewBlockHeight(0))
+		require.NoError(t, err)
+		require.NoError(t, applyMsgResult.ExecutionError)
+		require.Equal(t, uint8(0), applyMsgResult.Receipt.ExitCode)
+
+		result = callQueryMethodSuccess("getPeerAddrs", ctx, t, st, vms, address.TestAddress, minerAddr)
+		addrs, err = abi.Deserialize(result[0], abi.Multiaddrs)
+		require.NoError(t, err)
+		assert.Equal(t, []ma.Multiaddr{relayAddr}, addrs.Val)
+	})
+
+	t.Run("authorization failure while updating peer addresses", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		st, vms := th.RequireCreateStorages(ctx, t)
+
+		minerAddr := th.CreateTestMiner(t, st, vms, address.TestAddress, th.RequireRandomPeerID(t))
+
+		// TestAddress2 isn't the miner's worker address
+		updatePeerAddrsMsg := types.NewMessage(
+			address.TestAddress2,
+			minerAddr,
+			th.RequireGetNonce(t, st, address.TestAddress2),
+			types.NewAttoFILFromFIL(0),
+			"updatePeerAddrs",
+			actor.MustConvertParams([]ma.Multiaddr{}))
+
+		applyMsgResult, err := th.ApplyTestMessage(st, vms, updatePeerAddrsMsg, types.NewBlockHeight(0))
+		require.NoError(t, err)
+		require.Equal(t, Errors[ErrCallerUnauthorized], applyMsgResult.ExecutionError)
+		require.NotEqual(t, uint8(0), applyMsgResult.Receipt.ExitCode)
+	})
+}
+
 func TestMinerGetPower(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -487,8 +553,12 @@ func (mal *minerActorLiason) requireCommit(blockHeight, sectorID uint64) {
 }
 
 func (mal *minerActorLiason) requirePoSt(blockHeight uint64, done types.IntSet) {
+	mal.requirePoStWithFaults(blockHeight, types.EmptyIntSet(), done)
+}
+
+func (mal *minerActorLiason) requirePoStWithFaults(blockHeight uint64, faults, done types.IntSet) {
 	mal.requireHeightNotPast(blockHeight)
-	res, err := th.CreateAndApplyTestMessage(mal.t, mal.st, mal.vms, mal.minerAddr, 0, blockHeight, "submitPoSt", mal.ancestors, []types.PoStProof{th.MakeRandomPoStProofForTest()}, done)
+	res, err := th.CreateAndApplyTestMessage(mal.t, mal.st, mal.vms, mal.minerAddr, 0, blockHeight, "submitPoSt", mal.ancestors, []types.PoStProof{th.MakeRandomPoStProofForTest()}, faults, done)
 	assert.NoError(mal.t, err)
 	assert.NoError(mal.t, res.ExecutionError)
 	assert.Equal(mal.t, uint8(0), res.Receipt.ExitCode)
@@ -527,7 +597,7 @@ func (mal *minerActorLiason) requireTotalStorage(queryHeight uint64) *types.Byte
 
 func (mal *minerActorLiason) assertPoStFail(blockHeight uint64, done types.IntSet, exitCode uint8) {
 	mal.requireHeightNotPast(blockHeight)
-	res, err := th.CreateAndApplyTestMessage(mal.t, mal.st, mal.vms, mal.minerAddr, 0, blockHeight, "submitPoSt", mal.ancestors, []types.PoStProof{th.MakeRandomPoStProofForTest()}, done)
+	res, err := th.CreateAndApplyTestMessage(mal.t, mal.st, mal.vms, mal.minerAddr, 0, blockHeight, "submitPoSt", mal.ancestors, []types.PoStProof{th.MakeRandomPoStProofForTest()}, types.EmptyIntSet(), done)
 	assert.NoError(mal.t, err)
 	assert.Error(mal.t, res.ExecutionError)
 	assert.Equal(mal.t, exitCode, res.Receipt.ExitCode)
@@ -640,7 +710,37 @@ func TestMinerSubmitPoStPowerUpdates(t *testing.T) {
 		mal.requirePoSt(fourthProvingPeriodStart+1, done)
 		power = mal.requirePower(fourthProvingPeriodStart+2)
 		assert.Equal(t, types.NewBytesAmount(2).Mul(types.OneKiBSectorSize), power)
-	})			
+	})
+
+	t.Run("power excludes faulted sectors", func(t *testing.T) {
+		mal := setupMinerActorLiason(t)
+
+		// Period 1 commit and prove
+		mal.requireCommit(firstCommitBlockHeight, uint64(1))
+		mal.requireCommit(firstCommitBlockHeight+1, uint64(2))
+		mal.requireCommit(firstCommitBlockHeight+1, uint64(3))
+		done := types.EmptyIntSet()
+		mal.requirePoSt(firstCommitBlockHeight+5, done)
+		// the miner proved sector 1, any other storage belongs to other miners
+		otherStorage := mal.requireTotalStorage(firstCommitBlockHeight+6).Sub(types.OneKiBSectorSize)
+
+		// Period 2 prove over 3 sectors with one fault
+		mal.requirePoStWithFaults(secondProvingPeriodStart+5, types.NewIntSet(2), done)
+		power := mal.requirePower(secondProvingPeriodStart+6)
+		assert.Equal(t, types.NewBytesAmount(2).Mul(types.OneKiBSectorSize), power)
+		assert.True(t, power.Equal(mal.requireTotalStorage(secondProvingPeriodStart+6).Sub(otherStorage)))
+
+		// Period 3 declare every sector faulted
+		mal.requirePoStWithFaults(thirdProvingPeriodStart+5, types.NewIntSet(1, 2, 3), done)
+		power = mal.requirePower(thirdProvingPeriodStart+6)
+		assert.Equal(t, types.NewBytesAmount(0), power)
+		assert.True(t, power.Equal(mal.requireTotalStorage(thirdProvingPeriodStart+6).Sub(otherStorage)))
+
+		// Period 4 the recovered sectors count again
+		mal.requirePoSt(fourthProvingPeriodStart+5, done)
+		power = mal.requirePower(fourthProvingPeriodStart+6)
+		assert.Equal(t, types.NewBytesAmount(3).Mul(types.OneKiBSectorSize), power)
+	})
 }
 
 func TestMinerSubmitPoStProvingSet(t *testing.T) {
@@ -724,6 +824,19 @@ func TestMinerSubmitPoStProvingSet(t *testing.T) {
 		assert.Equal(t, types.NewIntSet(1,2).Values(), mSt.ProvingSet.Values())
 	})
 
+	t.Run("faulted sectors stay in proving set", func(t *testing.T) {
+		mal := setupMinerActorLiason(t)
+
+		mal.requireCommit(firstCommitBlockHeight, uint64(1))
+		mal.requireCommit(firstCommitBlockHeight+1, uint64(2))
+
+		// a fault in one period does not remove the sector
+		mal.requirePoStWithFaults(firstCommitBlockHeight+5, types.NewIntSet(2), types.EmptyIntSet())
+		mSt := mal.requireReadState()
+		assert.Equal(t, types.NewIntSet(1, 2).Values(), mSt.ProvingSet.Values())
+		assert.Equal(t, types.EmptyIntSet().Values(), mSt.NextDoneSet.Values())
+	})
+
 }
 
 func TestMinerSubmitPoStNextDoneSet(t *testing.T) {
@@ -838,7 +951,7 @@ func TestMinerSubmitPoSt(t *testing.T) {
 
 	t.Run("on-time PoSt succeeds", func(t *testing.T) {
 		// submit post
-		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, firstCommitBlockHeight+5, "submitPoSt", ancestors, []types.PoStProof{proof}, doneDefault)
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, firstCommitBlockHeight+5, "submitPoSt", ancestors, []types.PoStProof{proof}, types.EmptyIntSet(), doneDefault)
 		assert.NoError(t, err)
 		assert.NoError(t, res.ExecutionError)
 		assert.Equal(t, uint8(0), res.Receipt.ExitCode)
@@ -852,19 +965,19 @@ func TestMinerSubmitPoSt(t *testing.T) {
 
 	t.Run("after generation attack grace period rejected", func(t *testing.T) {
 		// Rejected one block late
-		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, lastPossibleSubmission+1, "submitPoSt", ancestors, []types.PoStProof{proof}, doneDefault)
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, lastPossibleSubmission+1, "submitPoSt", ancestors, []types.PoStProof{proof}, types.EmptyIntSet(), doneDefault)
 		assert.NoError(t, err)
 		assert.Error(t, res.ExecutionError)
 	})
 
 	t.Run("late submission charged fee", func(t *testing.T) {
 		// Rejected on the deadline with message value not carrying sufficient fees
-		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, lastPossibleSubmission, "submitPoSt", ancestors, []types.PoStProof{proof}, doneDefault)
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, lastPossibleSubmission, "submitPoSt", ancestors, []types.PoStProof{proof}, types.EmptyIntSet(), doneDefault)
 		assert.NoError(t, err)
 		assert.Error(t, res.ExecutionError)
 
 		// Accepted on the deadline with a fee
-		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 1, lastPossibleSubmission, "submitPoSt", ancestors, []types.PoStProof{proof}, doneDefault)
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 1, lastPossibleSubmission, "submitPoSt", ancestors, []types.PoStProof{proof}, types.EmptyIntSet(), doneDefault)
 		assert.NoError(t, err)
 		assert.NoError(t, res.ExecutionError)
 		assert.Equal(t, uint8(0), res.Receipt.ExitCode)
@@ -930,7 +1043,7 @@ func TestVerifyPIP(t *testing.T) {
 		done := types.EmptyIntSet()
 		proof := th.MakeRandomPoStProofForTest()
 		blockheightOfPoSt := uint64(8)
-		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, blockheightOfPoSt, "submitPoSt", ancestors, []types.PoStProof{proof}, done)
+		res, err = th.CreateAndApplyTestMessage(t, st, vms, minerAddr, 0, blockheightOfPoSt, "submitPoSt", ancestors, []types.PoStProof{proof}, types.EmptyIntSet(), done)
 		assert.NoError(t, err)
 		assert.NoError(t, res.ExecutionError)
 		assert.Equal(t, uint8(0), res.Receipt.ExitCode)
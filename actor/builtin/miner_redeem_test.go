@@ -67,7 +67,7 @@ func TestVerifyPieceInclusionInRedeem(t *testing.T) {
 	}
 
 	makeAndSignVoucher := func(condition *types.Predicate) []byte {
-		sig, err := paymentbroker.SignVoucher(channelID, amt, defaultValidAt, payer, condition, mockSigner)
+		sig, err := paymentbroker.SignVoucher(channelID, amt, defaultValidAt, payer, condition, nil, mockSigner)
 		require.NoError(t, err)
 		signature := ([]byte)(sig)
 
@@ -76,7 +76,8 @@ func TestVerifyPieceInclusionInRedeem(t *testing.T) {
 
 	makeRedeemMsg := func(condition *types.Predicate, sectorID uint64, pip []byte, signature []byte) *types.Message {
 		suppliedParams := []interface{}{sectorID, pip}
-		pdata := core.MustConvertParams(payer, channelID, amt, types.NewBlockHeight(0), condition, signature, suppliedParams)
+		var lane *types.VoucherLane
+		pdata := core.MustConvertParams(payer, channelID, amt, types.NewBlockHeight(0), condition, lane, signature, suppliedParams)
 		return types.NewMessage(target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "redeem", pdata)
 	}
 
@@ -0,0 +1,33 @@
+package paymentbroker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestPaymentChannelLaneState(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("seeds lane 0 of channels redeemed before lanes", func(t *testing.T) {
+		channel := &PaymentChannel{
+			Amount:         types.NewAttoFILFromFIL(1000),
+			AmountRedeemed: types.NewAttoFILFromFIL(100),
+		}
+		assert.True(t, types.NewAttoFILFromFIL(100).Equal(channel.LaneRedeemed(0)))
+
+		// the amount already redeemed is not paid again on lane 0
+		assert.True(t, types.NewAttoFILFromFIL(100).Equal(channel.laneState(0).Redeemed))
+		assert.True(t, channel.laneState(1).Redeemed.IsZero())
+		require.Len(t, channel.Lanes, 2)
+
+		// channels that have lanes are not seeded again
+		channel.Lanes[0].Redeemed = types.ZeroAttoFIL
+		assert.True(t, channel.laneState(0).Redeemed.IsZero())
+		assert.True(t, channel.LaneRedeemed(0).IsZero())
+	})
+}
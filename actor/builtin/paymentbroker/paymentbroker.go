@@ -2,6 +2,7 @@ package paymentbroker
 
 import (
 	"context"
+	"encoding/binary"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
@@ -42,6 +43,10 @@ const (
 	ErrConditionInvalid = 44
 	//ErrInvalidCancel indicates that the condition attached to a voucher did execute successfully and therefore can't be cancelled
 	ErrInvalidCancel = 45
+	// ErrStaleNonce indicates a voucher with a nonce lower than that of its lane.
+	ErrStaleNonce = 46
+	// ErrInvalidMerge indicates a voucher that merges its own lane, a lane twice or a lane at a stale nonce.
+	ErrInvalidMerge = 47
 )
 
 // CancelDelayBlockTime is the number of rounds given to the target to respond after the channel
@@ -63,10 +68,13 @@ var Errors = map[uint8]error{
 	ErrExpired:                  errors.NewCodedRevertError(ErrExpired, "block height has exceeded channel's end of life"),
 	ErrAlreadyWithdrawn:         errors.NewCodedRevertError(ErrAlreadyWithdrawn, "update amount has already been redeemed"),
 	ErrInvalidSignature:         errors.NewCodedRevertErrorf(ErrInvalidSignature, "signature failed to validate"),
+	ErrStaleNonce:               errors.NewCodedRevertError(ErrStaleNonce, "voucher nonce is lower than the nonce of its lane"),
+	ErrInvalidMerge:             errors.NewCodedRevertError(ErrInvalidMerge, "voucher merges lanes it may not merge"),
 }
 
 func init() {
 	cbor.RegisterCborType(PaymentChannel{})
+	cbor.RegisterCborType(LaneState{})
 }
 
 // PaymentChannel records the intent to pay funds to a target account.
@@ -96,6 +104,58 @@ type PaymentChannel struct {
 	// payment channel yet. This is necessary because AmountRedeemed can still be
 	// zero in the event of a zero-value voucher
 	Redeemed bool `json:"redeemed"`
+
+	// Lanes are the states of the lanes vouchers have been redeemed on, in
+	// order of lane id. AmountRedeemed is the sum of their redeemed amounts.
+	Lanes []*LaneState `json:"lanes"`
+}
+
+// LaneState records the vouchers redeemed on a lane of a payment channel.
+type LaneState struct {
+	// ID identifies the lane within the channel.
+	ID uint64 `json:"id"`
+
+	// Redeemed is the amount transferred to the target through the lane.
+	Redeemed types.AttoFIL `json:"redeemed"`
+
+	// Nonce is the lowest nonce a voucher redeemed on the lane may have.
+	Nonce uint64 `json:"nonce"`
+}
+
+// laneState returns the state of lane id, adding it to the channel if no
+// voucher has been redeemed on the lane yet.
+func (pc *PaymentChannel) laneState(id uint64) *LaneState {
+	if len(pc.Lanes) == 0 && pc.AmountRedeemed.IsPositive() {
+		// Vouchers redeemed before channels had lanes were all redeemed on
+		// lane 0, so it starts from the amount redeemed from the channel.
+		pc.Lanes = []*LaneState{{ID: 0, Redeemed: pc.AmountRedeemed}}
+	}
+
+	i := 0
+	for ; i < len(pc.Lanes) && pc.Lanes[i].ID <= id; i++ {
+		if pc.Lanes[i].ID == id {
+			return pc.Lanes[i]
+		}
+	}
+
+	lane := &LaneState{ID: id, Redeemed: types.ZeroAttoFIL}
+	pc.Lanes = append(pc.Lanes, nil)
+	copy(pc.Lanes[i+1:], pc.Lanes[i:])
+	pc.Lanes[i] = lane
+	return lane
+}
+
+// LaneRedeemed returns the amount redeemed on lane id of the channel.
+func (pc *PaymentChannel) LaneRedeemed(id uint64) types.AttoFIL {
+	if len(pc.Lanes) == 0 && id == 0 {
+		return pc.AmountRedeemed
+	}
+	for _, lane := range pc.Lanes {
+		if lane.ID == id {
+			return lane.Redeemed
+		}
+	}
+	return types.ZeroAttoFIL
 }
 
 // Actor provides a mechanism for off chain payments.
@@ -123,12 +183,16 @@ func (pb *Actor) Exports() exec.Exports {
 var _ exec.ExecutableActor = (*Actor)(nil)
 
 var paymentBrokerExports = exec.Exports{
+	"addFunds": &exec.FunctionSignature{
+		Params: []abi.Type{abi.ChannelID},
+		Return: nil,
+	},
 	"cancel": &exec.FunctionSignature{
 		Params: []abi.Type{abi.ChannelID},
 		Return: nil,
 	},
 	"close": &exec.FunctionSignature{
-		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate, abi.Bytes, abi.Parameters},
+		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate, abi.VoucherLane, abi.Bytes, abi.Parameters},
 		Return: nil,
 	},
 	"createChannel": &exec.FunctionSignature{
@@ -148,11 +212,11 @@ var paymentBrokerExports = exec.Exports{
 		Return: nil,
 	},
 	"redeem": &exec.FunctionSignature{
-		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate, abi.Bytes, abi.Parameters},
+		Params: []abi.Type{abi.Address, abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate, abi.VoucherLane, abi.Bytes, abi.Parameters},
 		Return: nil,
 	},
 	"voucher": &exec.FunctionSignature{
-		Params: []abi.Type{abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate},
+		Params: []abi.Type{abi.ChannelID, abi.AttoFIL, abi.BlockHeight, abi.Predicate, abi.VoucherLane},
 		Return: []abi.Type{abi.Bytes},
 	},
 }
@@ -223,25 +287,32 @@ func (pb *Actor) CreateChannel(vmctx exec.VMContext, target address.Address, eol
 // target Redeem(200)          -> Payer: 1000, Target: 200, Channel: 800
 // target Close(500)           -> Payer: 1500, Target: 500, Channel: 0
 //
+// Amounts are cumulative within the voucher's lane. A voucher that merges
+// other lanes authorizes the total of its own lane and the merged lanes.
+//
 // If a condition is provided in the voucher:
 // - The parameters provided in the condition will be combined with redeemerConditionParams
 // - A message will be sent to the the condition.To address using the condition.Method with the combined params
 // - If the message returns an error the condition is considered to be false and the redeem will fail
 func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt types.AttoFIL,
-	validAt *types.BlockHeight, condition *types.Predicate, sig []byte, redeemerConditionParams []interface{}) (uint8, error) {
+	validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane, sig []byte, redeemerConditionParams []interface{}) (uint8, error) {
 
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	if !VerifyVoucherSignature(payer, chid, amt, validAt, condition, sig) {
+	valid, err := verifyVoucherSignature(vmctx, payer, chid, amt, validAt, condition, lane, sig)
+	if err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	if !valid {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
 
 	ctx := context.Background()
 	storage := vmctx.Storage()
 
-	err := withPayerChannels(ctx, storage, payer, func(byChannelID exec.Lookup) error {
+	err = withPayerChannels(ctx, storage, payer, func(byChannelID exec.Lookup) error {
 		chInt, err := byChannelID.Find(ctx, chid.KeyString())
 		if err != nil {
 			if err == hamt.ErrNotFound {
@@ -256,7 +327,7 @@ func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types
 		}
 
 		// validate the amount can be sent to the target and send payment to that address.
-		err = validateAndUpdateChannel(vmctx, vmctx.Message().From, channel, amt, validAt, condition, redeemerConditionParams)
+		err = validateAndUpdateChannel(vmctx, vmctx.Message().From, channel, amt, validAt, condition, lane, redeemerConditionParams)
 		if err != nil {
 			return err
 		}
@@ -290,20 +361,24 @@ func (pb *Actor) Redeem(vmctx exec.VMContext, payer address.Address, chid *types
 // - A message will be sent to the the condition.To address using the condition.Method with the combined params
 // - If the message returns an error the condition is considered to be false and the redeem will fail
 func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt types.AttoFIL,
-	validAt *types.BlockHeight, condition *types.Predicate, sig []byte, redeemerConditionParams []interface{}) (uint8, error) {
+	validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane, sig []byte, redeemerConditionParams []interface{}) (uint8, error) {
 
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
 
-	if !VerifyVoucherSignature(payer, chid, amt, validAt, condition, sig) {
+	valid, err := verifyVoucherSignature(vmctx, payer, chid, amt, validAt, condition, lane, sig)
+	if err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	if !valid {
 		return errors.CodeError(Errors[ErrInvalidSignature]), Errors[ErrInvalidSignature]
 	}
 
 	ctx := context.Background()
 	storage := vmctx.Storage()
 
-	err := withPayerChannels(ctx, storage, payer, func(byChannelID exec.Lookup) error {
+	err = withPayerChannels(ctx, storage, payer, func(byChannelID exec.Lookup) error {
 		chInt, err := byChannelID.Find(ctx, chid.KeyString())
 		if err != nil {
 			if err == hamt.ErrNotFound {
@@ -318,7 +393,7 @@ func (pb *Actor) Close(vmctx exec.VMContext, payer address.Address, chid *types.
 		}
 
 		// validate the amount can be sent to the target and send payment to that address.
-		err = validateAndUpdateChannel(vmctx, vmctx.Message().From, channel, amt, validAt, condition, redeemerConditionParams)
+		err = validateAndUpdateChannel(vmctx, vmctx.Message().From, channel, amt, validAt, condition, lane, redeemerConditionParams)
 		if err != nil {
 			return err
 		}
@@ -394,6 +469,53 @@ func (pb *Actor) Extend(vmctx exec.VMContext, chid *types.ChannelID, eol *types.
 	return 0, nil
 }
 
+// AddFunds adds the value attached to the invocation to the funds of a
+// payment channel owned by the caller without changing its eol. Funds can
+// not be added to a channel that has expired.
+func (pb *Actor) AddFunds(vmctx exec.VMContext, chid *types.ChannelID) (uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	ctx := context.Background()
+	storage := vmctx.Storage()
+	payerAddress := vmctx.Message().From
+
+	err := withPayerChannels(ctx, storage, payerAddress, func(byChannelID exec.Lookup) error {
+		chInt, err := byChannelID.Find(ctx, chid.KeyString())
+		if err != nil {
+			if err == hamt.ErrNotFound {
+				return Errors[ErrUnknownChannel]
+			}
+			return errors.FaultErrorWrapf(err, "Could not retrieve payment channel with ID: %s", chid)
+		}
+
+		channel, ok := chInt.(*PaymentChannel)
+		if !ok {
+			return errors.NewFaultError("Expected PaymentChannel from channels lookup")
+		}
+
+		if vmctx.BlockHeight().GreaterEqual(channel.Eol) {
+			return Errors[ErrExpired]
+		}
+
+		// increment the value
+		channel.Amount = channel.Amount.Add(vmctx.Message().Value)
+
+		return byChannelID.Set(ctx, chid.KeyString(), channel)
+	})
+
+	if err != nil {
+		// ensure error is properly wrapped
+		if !errors.IsFault(err) && !errors.ShouldRevert(err) {
+			return 1, errors.FaultErrorWrap(err, "Error adding funds to channel")
+		}
+		return errors.CodeError(err), err
+	}
+
+	return 0, nil
+}
+
 // Cancel can be used to end an off chain payment early. It lowers the EOL of
 // the payment channel to 1 blocktime from now and allows a caller to reclaim
 // their payments. In the time before the channel is closed, a target can
@@ -517,7 +639,9 @@ func (pb *Actor) Reclaim(vmctx exec.VMContext, chid *types.ChannelID) (uint8, er
 // If a condition is provided, attempts to redeem or close with the voucher will
 // first send a message based on the condition and require a successful response
 // for funds to be transferred.
-func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) ([]byte, uint8, error) {
+// If a lane is provided the voucher pays through that lane of the channel,
+// otherwise through lane 0.
+func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane) ([]byte, uint8, error) {
 	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
 		return []byte{}, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
 	}
@@ -557,6 +681,9 @@ func (pb *Actor) Voucher(vmctx exec.VMContext, chid *types.ChannelID, amount typ
 			ValidAt:   *validAt,
 			Condition: condition,
 		}
+		if lane != nil {
+			voucher.Lane = *lane
+		}
 
 		return nil
 	})
@@ -621,7 +748,7 @@ func (pb *Actor) Ls(vmctx exec.VMContext, payer address.Address) ([]byte, uint8,
 	return channelsBytes, 0, nil
 }
 
-func validateAndUpdateChannel(ctx exec.VMContext, target address.Address, channel *PaymentChannel, amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, redeemerSuppliedParams []interface{}) error {
+func validateAndUpdateChannel(ctx exec.VMContext, target address.Address, channel *PaymentChannel, amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane, redeemerSuppliedParams []interface{}) error {
 	cacheCondition(channel, condition, redeemerSuppliedParams)
 
 	if err := checkCondition(ctx, channel); err != nil {
@@ -640,23 +767,57 @@ func validateAndUpdateChannel(ctx exec.VMContext, target address.Address, channe
 		return Errors[ErrExpired]
 	}
 
-	if amt.GreaterThan(channel.Amount) {
-		return Errors[ErrInsufficientChannelFunds]
+	if lane == nil {
+		lane = &types.VoucherLane{}
+	}
+
+	laneState := channel.laneState(lane.ID)
+	if lane.Nonce < laneState.Nonce {
+		return Errors[ErrStaleNonce]
 	}
 
-	if amt.LessEqual(channel.AmountRedeemed) {
+	// the voucher amount covers what was redeemed on its lane and the merged lanes
+	covered := laneState.Redeemed
+	merged := make([]*LaneState, len(lane.Merges))
+	for i, merge := range lane.Merges {
+		mergedState := channel.laneState(merge.Lane)
+		if merge.Lane == lane.ID || merge.Nonce < mergedState.Nonce {
+			return Errors[ErrInvalidMerge]
+		}
+		for _, other := range merged[:i] {
+			if other == mergedState {
+				return Errors[ErrInvalidMerge]
+			}
+		}
+		covered = covered.Add(mergedState.Redeemed)
+		merged[i] = mergedState
+	}
+
+	if amt.LessEqual(covered) {
 		return Errors[ErrAlreadyWithdrawn]
 	}
 
+	updateAmount := amt.Sub(covered)
+	if channel.AmountRedeemed.Add(updateAmount).GreaterThan(channel.Amount) {
+		return Errors[ErrInsufficientChannelFunds]
+	}
+
 	// transfer funds to sender
-	updateAmount := amt.Sub(channel.AmountRedeemed)
 	_, _, err := ctx.Send(ctx.Message().From, "", updateAmount, nil)
 	if err != nil {
 		return err
 	}
 
-	// update amount redeemed from this channel
-	channel.AmountRedeemed = amt
+	// update amounts redeemed from this channel. Merged lanes start over at a
+	// nonce past the merged one, since their amounts now count towards the
+	// voucher's lane.
+	channel.AmountRedeemed = channel.AmountRedeemed.Add(updateAmount)
+	laneState.Redeemed = amt
+	laneState.Nonce = lane.Nonce
+	for i, merge := range lane.Merges {
+		merged[i].Redeemed = types.ZeroAttoFIL
+		merged[i].Nonce = merge.Nonce + 1
+	}
 
 	return nil
 }
@@ -688,9 +849,10 @@ const separator = 0x0
 
 // SignVoucher creates the signature for the given combination of
 // channel, amount, validAt (earliest block height for redeem) and from address.
-// It does so by signing the following bytes: (channelID | 0x0 | amount | 0x0 | validAt)
-func SignVoucher(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, addr address.Address, condition *types.Predicate, signer types.Signer) (types.Signature, error) {
-	data, err := createVoucherSignatureData(channelID, amount, validAt, condition)
+// It does so by signing the following bytes: (channelID | 0x0 | amount | 0x0 | validAt),
+// with the condition before validAt and the lane after it if they are given.
+func SignVoucher(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, addr address.Address, condition *types.Predicate, lane *types.VoucherLane, signer types.Signer) (types.Signature, error) {
+	data, err := createVoucherSignatureData(channelID, amount, validAt, condition, lane)
 	if err != nil {
 		return nil, err
 	}
@@ -698,8 +860,8 @@ func SignVoucher(channelID *types.ChannelID, amount types.AttoFIL, validAt *type
 }
 
 // VerifyVoucherSignature returns whether the voucher's signature is valid
-func VerifyVoucherSignature(payer address.Address, chid *types.ChannelID, amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, sig []byte) bool {
-	data, err := createVoucherSignatureData(chid, amt, validAt, condition)
+func VerifyVoucherSignature(payer address.Address, chid *types.ChannelID, amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane, sig []byte) bool {
+	data, err := createVoucherSignatureData(chid, amt, validAt, condition, lane)
 	// the only error is failure to encode the values
 	if err != nil {
 		return false
@@ -707,7 +869,18 @@ func VerifyVoucherSignature(payer address.Address, chid *types.ChannelID, amt ty
 	return types.IsValidSignature(data, payer, sig)
 }
 
-func createVoucherSignatureData(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) ([]byte, error) {
+// verifyVoucherSignature is VerifyVoucherSignature for use inside the VM,
+// where the verification is charged to the message.
+func verifyVoucherSignature(vmctx exec.VMContext, payer address.Address, chid *types.ChannelID, amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane, sig []byte) (bool, error) {
+	data, err := createVoucherSignatureData(chid, amt, validAt, condition, lane)
+	// the only error is failure to encode the values
+	if err != nil {
+		return false, nil
+	}
+	return vmctx.VerifySignature(data, payer, sig)
+}
+
+func createVoucherSignatureData(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate, lane *types.VoucherLane) ([]byte, error) {
 	data := append(channelID.Bytes(), separator)
 	data = append(data, amount.Bytes()...)
 	data = append(data, separator)
@@ -720,7 +893,25 @@ func createVoucherSignatureData(channelID *types.ChannelID, amount types.AttoFIL
 		}
 		data = append(data, encodedParams...)
 	}
-	return append(data, validAt.Bytes()...), nil
+	data = append(data, validAt.Bytes()...)
+
+	// lane 0 is signed as it was before channels had lanes
+	if !lane.IsZero() {
+		data = append(data, separator)
+		data = appendUint64(data, lane.ID)
+		data = appendUint64(data, lane.Nonce)
+		for _, merge := range lane.Merges {
+			data = appendUint64(data, merge.Lane)
+			data = appendUint64(data, merge.Nonce)
+		}
+	}
+	return data, nil
+}
+
+func appendUint64(data []byte, n uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return append(data, buf[:]...)
 }
 
 func withPayerChannels(ctx context.Context, storage exec.Storage, payer address.Address, f func(exec.Lookup) error) error {
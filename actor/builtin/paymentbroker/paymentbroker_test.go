@@ -606,7 +606,8 @@ func TestPaymentBrokerCloseInvalidSig(t *testing.T) {
 	signature[1] = 1
 
 	var condition *types.Predicate
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, condition, signature, []interface{}{})
+	var nilLane *types.VoucherLane
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, condition, nilLane, signature, []interface{}{})
 	msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "close", pdata)
 	res, err := sys.ApplyMessage(msg, 0)
 	require.EqualError(t, res.ExecutionError, Errors[ErrInvalidSignature].Error())
@@ -690,7 +691,8 @@ func TestPaymentBrokerRedeemInvalidSig(t *testing.T) {
 	signature[1] = 1
 
 	var condition *types.Predicate
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, condition, signature, []interface{}{})
+	var nilLane *types.VoucherLane
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, sys.defaultValidAt, condition, nilLane, signature, []interface{}{})
 	msg := types.NewMessage(sys.target, address.PaymentBrokerAddress, 0, types.NewAttoFILFromFIL(0), "redeem", pdata)
 	res, err := sys.ApplyMessage(msg, 0)
 	require.EqualError(t, res.ExecutionError, Errors[ErrInvalidSignature].Error())
@@ -805,6 +807,55 @@ func TestPaymentBrokerExtendRefusesToShortenTheEol(t *testing.T) {
 	assert.Contains(t, result.ExecutionError.Error(), "payment channel eol may not be decreased")
 }
 
+func TestPaymentBrokerAddFunds(t *testing.T) {
+	tf.UnitTest(t)
+
+	sys := setup(t)
+
+	pdata := core.MustConvertParams(sys.channelID)
+	msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.NewAttoFILFromFIL(1000), "addFunds", pdata)
+
+	result, err := sys.ApplyMessage(msg, 9)
+	require.NoError(t, err)
+	require.NoError(t, result.ExecutionError)
+	assert.Equal(t, uint8(0), result.Receipt.ExitCode)
+
+	paymentBroker := state.MustGetActor(sys.st, address.PaymentBrokerAddress)
+	assert.Equal(t, types.NewAttoFILFromFIL(2000), paymentBroker.Balance)
+
+	channel := sys.retrieveChannel(paymentBroker)
+	assert.Equal(t, types.NewAttoFILFromFIL(2000), channel.Amount)
+	assert.Equal(t, types.NewBlockHeight(20000), channel.Eol)
+}
+
+func TestPaymentBrokerAddFundsFailsWithNonExistentChannel(t *testing.T) {
+	tf.UnitTest(t)
+
+	sys := setup(t)
+
+	pdata := core.MustConvertParams(types.NewChannelID(383))
+	msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.NewAttoFILFromFIL(1000), "addFunds", pdata)
+
+	result, err := sys.ApplyMessage(msg, 9)
+	require.NoError(t, err)
+	require.EqualError(t, result.ExecutionError, "payment channel is unknown")
+	assert.NotEqual(t, uint8(0), result.Receipt.ExitCode)
+}
+
+func TestPaymentBrokerAddFundsRefusesExpiredChannel(t *testing.T) {
+	tf.UnitTest(t)
+
+	sys := setup(t)
+
+	pdata := core.MustConvertParams(sys.channelID)
+	msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.NewAttoFILFromFIL(1000), "addFunds", pdata)
+
+	result, err := sys.ApplyMessage(msg, 20000)
+	require.NoError(t, err)
+	assert.NotEqual(t, uint8(0), result.Receipt.ExitCode)
+	assert.Contains(t, result.ExecutionError.Error(), "block height has exceeded channel's end of life")
+}
+
 func TestPaymentBrokerCancel(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -989,13 +1040,14 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 	tf.UnitTest(t)
 
 	var nilCondition *types.Predicate
+	var nilLane *types.VoucherLane
 
 	t.Run("Returns valid voucher", func(t *testing.T) {
 		sys := setup(t)
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(100)
-		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, nilCondition)
+		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, nilCondition, nilLane)
 		msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.ZeroAttoFIL, "voucher", pdata)
 		res, err := sys.ApplyMessage(msg, 9)
 		assert.NoError(t, err)
@@ -1020,7 +1072,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(100)
-		_, exitCode, err := sys.CallQueryMethod("voucher", 9, notChannelID, voucherAmount, sys.defaultValidAt, nilCondition)
+		_, exitCode, err := sys.CallQueryMethod("voucher", 9, notChannelID, voucherAmount, sys.defaultValidAt, nilCondition, nilLane)
 		assert.NotEqual(t, uint8(0), exitCode)
 		assert.Contains(t, fmt.Sprintf("%v", err), "unknown")
 	})
@@ -1030,7 +1082,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(2000)
-		args := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, nilCondition)
+		args := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, nilCondition, nilLane)
 
 		msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.ZeroAttoFIL, "voucher", args)
 		res, err := sys.ApplyMessage(msg, 9)
@@ -1050,7 +1102,7 @@ func TestNewPaymentBrokerVoucher(t *testing.T) {
 
 		// create voucher
 		voucherAmount := types.NewAttoFILFromFIL(100)
-		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, condition)
+		pdata := core.MustConvertParams(sys.channelID, voucherAmount, sys.defaultValidAt, condition, nilLane)
 		msg := types.NewMessage(sys.payer, address.PaymentBrokerAddress, 1, types.ZeroAttoFIL, "voucher", pdata)
 		res, err := sys.ApplyMessage(msg, 9)
 		assert.NoError(t, err)
@@ -1079,27 +1131,138 @@ func TestSignVoucher(t *testing.T) {
 		Params: []interface{}{"encoded params"},
 	}
 	var nilCondition *types.Predicate
+	var nilLane *types.VoucherLane
 
 	t.Run("validates signatures with empty condition", func(t *testing.T) {
 		require := require.New(t)
 		assert := assert.New(t)
 
-		sig, err := SignVoucher(channelId, value, blockHeight, payer, nilCondition, mockSigner)
+		sig, err := SignVoucher(channelId, value, blockHeight, payer, nilCondition, nilLane, mockSigner)
 		require.NoError(err)
 
-		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, sig))
-		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, condition, sig))
+		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, nilLane, sig))
+		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, condition, nilLane, sig))
 	})
 
 	t.Run("validates signatures with condition", func(t *testing.T) {
 		require := require.New(t)
 		assert := assert.New(t)
 
-		sig, err := SignVoucher(channelId, value, blockHeight, payer, condition, mockSigner)
+		sig, err := SignVoucher(channelId, value, blockHeight, payer, condition, nilLane, mockSigner)
+		require.NoError(err)
+
+		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, condition, nilLane, sig))
+		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, nilLane, sig))
+	})
+
+	t.Run("validates signatures with lane", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		lane := &types.VoucherLane{ID: 2, Nonce: 1, Merges: []types.Merge{{Lane: 1, Nonce: 4}}}
+		sig, err := SignVoucher(channelId, value, blockHeight, payer, nilCondition, lane, mockSigner)
+		require.NoError(err)
+
+		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, lane, sig))
+		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, nilLane, sig))
+		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, &types.VoucherLane{ID: 2, Nonce: 1}, sig))
+	})
+
+	t.Run("signs lane 0 like vouchers without lanes", func(t *testing.T) {
+		require := require.New(t)
+		assert := assert.New(t)
+
+		sig, err := SignVoucher(channelId, value, blockHeight, payer, nilCondition, nilLane, mockSigner)
 		require.NoError(err)
 
-		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, condition, sig))
-		assert.False(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, sig))
+		assert.True(VerifyVoucherSignature(payer, channelId, value, blockHeight, nilCondition, &types.VoucherLane{}, sig))
+	})
+}
+
+func TestPaymentBrokerLanes(t *testing.T) {
+	tf.UnitTest(t)
+
+	redeemOnLane := func(sys *system, amt uint64, nonce uint64, lane *types.VoucherLane) *consensus.ApplicationResult {
+		result, err := sys.applyLaneSignatureMessage(sys.target, amt, sys.defaultValidAt, nonce, "redeem", 0, nil, lane)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("redeems lanes independently", func(t *testing.T) {
+		sys := setup(t)
+
+		result := redeemOnLane(&sys, 100, 0, &types.VoucherLane{ID: 1})
+		require.NoError(t, result.ExecutionError)
+		result = redeemOnLane(&sys, 100, 1, &types.VoucherLane{ID: 2})
+		require.NoError(t, result.ExecutionError)
+
+		payee := state.MustGetActor(sys.st, sys.target)
+		assert.Equal(t, types.NewAttoFILFromFIL(200), payee.Balance)
+
+		channel := sys.retrieveChannel(state.MustGetActor(sys.st, address.PaymentBrokerAddress))
+		assert.True(t, types.NewAttoFILFromFIL(200).Equal(channel.AmountRedeemed))
+		require.Len(t, channel.Lanes, 2)
+		assert.Equal(t, uint64(1), channel.Lanes[0].ID)
+		assert.True(t, types.NewAttoFILFromFIL(100).Equal(channel.Lanes[0].Redeemed))
+		assert.Equal(t, uint64(2), channel.Lanes[1].ID)
+		assert.True(t, types.NewAttoFILFromFIL(100).Equal(channel.Lanes[1].Redeemed))
+
+		// amounts remain cumulative within a lane
+		result = redeemOnLane(&sys, 100, 2, &types.VoucherLane{ID: 1})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrAlreadyWithdrawn].Error())
+	})
+
+	t.Run("rejects vouchers with stale nonces", func(t *testing.T) {
+		sys := setup(t)
+
+		result := redeemOnLane(&sys, 100, 0, &types.VoucherLane{ID: 1, Nonce: 2})
+		require.NoError(t, result.ExecutionError)
+
+		result = redeemOnLane(&sys, 150, 1, &types.VoucherLane{ID: 1, Nonce: 1})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrStaleNonce].Error())
+	})
+
+	t.Run("settles merged lanes together", func(t *testing.T) {
+		sys := setup(t)
+
+		require.NoError(t, redeemOnLane(&sys, 100, 0, &types.VoucherLane{ID: 1}).ExecutionError)
+		require.NoError(t, redeemOnLane(&sys, 100, 1, &types.VoucherLane{ID: 2}).ExecutionError)
+
+		// 300 covers the 100 redeemed on each lane, so 100 more is transferred
+		merge := &types.VoucherLane{ID: 1, Nonce: 1, Merges: []types.Merge{{Lane: 2, Nonce: 0}}}
+		require.NoError(t, redeemOnLane(&sys, 300, 2, merge).ExecutionError)
+
+		payee := state.MustGetActor(sys.st, sys.target)
+		assert.Equal(t, types.NewAttoFILFromFIL(300), payee.Balance)
+
+		channel := sys.retrieveChannel(state.MustGetActor(sys.st, address.PaymentBrokerAddress))
+		assert.True(t, types.NewAttoFILFromFIL(300).Equal(channel.AmountRedeemed))
+		assert.True(t, types.NewAttoFILFromFIL(300).Equal(channel.Lanes[0].Redeemed))
+		assert.True(t, channel.Lanes[1].Redeemed.IsZero())
+		assert.Equal(t, uint64(1), channel.Lanes[1].Nonce)
+
+		// the merged vouchers of lane 2 can no longer be redeemed
+		result := redeemOnLane(&sys, 150, 3, &types.VoucherLane{ID: 2})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrStaleNonce].Error())
+	})
+
+	t.Run("rejects invalid merges", func(t *testing.T) {
+		sys := setup(t)
+
+		result := redeemOnLane(&sys, 100, 0, &types.VoucherLane{ID: 1, Merges: []types.Merge{{Lane: 1}}})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrInvalidMerge].Error())
+
+		result = redeemOnLane(&sys, 100, 1, &types.VoucherLane{ID: 1, Merges: []types.Merge{{Lane: 2}, {Lane: 2}}})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrInvalidMerge].Error())
+	})
+
+	t.Run("limits lanes to the channel funds", func(t *testing.T) {
+		sys := setup(t)
+
+		require.NoError(t, redeemOnLane(&sys, 600, 0, &types.VoucherLane{ID: 1}).ExecutionError)
+
+		result := redeemOnLane(&sys, 600, 1, &types.VoucherLane{ID: 2})
+		assert.EqualError(t, result.ExecutionError, Errors[ErrInsufficientChannelFunds].Error())
 	})
 }
 
@@ -1187,7 +1350,7 @@ func setup(t *testing.T) system {
 }
 
 func (sys *system) Signature(amt types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) ([]byte, error) {
-	sig, err := SignVoucher(sys.channelID, amt, validAt, sys.payer, condition, mockSigner)
+	sig, err := SignVoucher(sys.channelID, amt, validAt, sys.payer, condition, nil, mockSigner)
 	if err != nil {
 		return nil, err
 	}
@@ -1253,11 +1416,18 @@ func (sys *system) retrieveChannel(paymentBroker *actor.Actor) *PaymentChannel {
 func (sys *system) applySignatureMessage(target address.Address, amtInt uint64, validAt *types.BlockHeight, nonce uint64, method string, height uint64, condition *types.Predicate, suppliedParams ...interface{}) (*consensus.ApplicationResult, error) {
 	sys.t.Helper()
 
+	return sys.applyLaneSignatureMessage(target, amtInt, validAt, nonce, method, height, condition, nil, suppliedParams...)
+}
+
+// applyLaneSignatureMessage is applySignatureMessage for a voucher paying through the given lane.
+func (sys *system) applyLaneSignatureMessage(target address.Address, amtInt uint64, validAt *types.BlockHeight, nonce uint64, method string, height uint64, condition *types.Predicate, lane *types.VoucherLane, suppliedParams ...interface{}) (*consensus.ApplicationResult, error) {
+	sys.t.Helper()
+
 	amt := types.NewAttoFILFromFIL(amtInt)
-	signature, err := sys.Signature(amt, validAt, condition)
+	signature, err := SignVoucher(sys.channelID, amt, validAt, sys.payer, condition, lane, mockSigner)
 	require.NoError(sys.t, err)
 
-	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, validAt, condition, signature, suppliedParams)
+	pdata := core.MustConvertParams(sys.payer, sys.channelID, amt, validAt, condition, lane, []byte(signature), suppliedParams)
 	msg := types.NewMessage(target, address.PaymentBrokerAddress, nonce, types.NewAttoFILFromFIL(0), method, pdata)
 
 	return sys.ApplyMessage(msg, height)
@@ -36,10 +36,14 @@ func UnmarshalStorage(raw []byte, to interface{}) error {
 // })
 //
 // Note that if 'f' returns an error, modifications to the storage are not
-// saved.
+// saved. Failing to read or write the storage is a fault, unless the failure
+// should revert the message, e.g. because it ran out of gas for the access.
 func WithState(ctx exec.VMContext, st interface{}, f func() (interface{}, error)) (interface{}, error) {
 	chunk, err := ctx.ReadStorage()
 	if err != nil {
+		if vmerrors.ShouldRevert(err) {
+			return nil, err
+		}
 		return nil, vmerrors.FaultErrorWrap(err, "Could not read actor storage")
 	}
 
@@ -53,6 +57,9 @@ func WithState(ctx exec.VMContext, st interface{}, f func() (interface{}, error)
 	}
 
 	if err := ctx.WriteStorage(st); err != nil {
+		if vmerrors.ShouldRevert(err) {
+			return nil, err
+		}
 		return nil, vmerrors.FaultErrorWrap(err, "Could not write actor storage")
 	}
 
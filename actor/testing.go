@@ -74,6 +74,10 @@ var FakeActorExports = exec.Exports{
 		Params: nil,
 		Return: nil,
 	},
+	"readStorageAsFault": &exec.FunctionSignature{
+		Params: nil,
+		Return: nil,
+	},
 }
 
 // InitializeState stores this actors
@@ -145,6 +149,16 @@ func (ma *FakeActor) GoodCall(ctx exec.VMContext) (uint8, error) {
 	return 0, nil
 }
 
+// ReadStorageAsFault reads fakeActor's storage and, like builtin actors that
+// fail to read a lookup, reports failing to do so as a fault.
+func (ma *FakeActor) ReadStorageAsFault(ctx exec.VMContext) (uint8, error) {
+	storage := ctx.Storage()
+	if _, err := storage.Get(storage.Head()); err != nil {
+		return 1, errors.FaultErrorWrap(err, "could not read storage")
+	}
+	return 0, nil
+}
+
 // NonZeroExitCode returns a nonzero exit code but no error.
 func (ma *FakeActor) NonZeroExitCode(ctx exec.VMContext) (uint8, error) {
 	return 42, nil
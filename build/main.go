@@ -152,6 +152,7 @@ func build() {
 	generateGenesis()
 	buildMigrations()
 	buildPrereleaseTool()
+	buildRemoteSigner()
 }
 
 func forcebuild() {
@@ -162,6 +163,7 @@ func forcebuild() {
 	generateGenesis()
 	buildMigrations()
 	buildPrereleaseTool()
+	buildRemoteSigner()
 }
 
 func forceBuildFC() {
@@ -284,6 +286,12 @@ func buildPrereleaseTool() {
 	runCmd(cmd([]string{"go", "build", "-o", "./tools/prerelease-tool/prerelease-tool", "./tools/prerelease-tool/"}...))
 }
 
+func buildRemoteSigner() {
+	log.Println("Building remote signer...")
+
+	runCmd(cmd([]string{"go", "build", "-o", "./tools/remote-signer/remote-signer", "./tools/remote-signer/"}...))
+}
+
 func install() {
 	log.Println("Installing...")
 
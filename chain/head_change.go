@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// HeadChangeType is the kind of a head change.
+type HeadChangeType string
+
+const (
+	// HeadChangeCurrent reports the head at the time changes started being
+	// watched.
+	HeadChangeCurrent = HeadChangeType("current")
+	// HeadChangeApply reports a tipset that joined the chain.
+	HeadChangeApply = HeadChangeType("apply")
+	// HeadChangeRevert reports a tipset that left the chain in a reorg.
+	HeadChangeRevert = HeadChangeType("revert")
+)
+
+// HeadChange is a tipset joining or leaving the chain as its head changes.
+type HeadChange struct {
+	Type   HeadChangeType
+	TipSet types.TipSet
+}
+
+// HeadChanges returns the changes taking the chain from oldHead to newHead:
+// the tipsets of the old chain back to the common ancestor of the heads are
+// reverted by decreasing height, then the tipsets of the new chain are applied
+// by increasing height.
+func HeadChanges(ctx context.Context, store TipSetProvider, oldHead, newHead types.TipSet) ([]HeadChange, error) {
+	commonAncestor, err := FindCommonAncestor(IterAncestors(ctx, store, oldHead), IterAncestors(ctx, store, newHead))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find common ancestor")
+	}
+	commonHeight, err := commonAncestor.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	// the common ancestor itself stays in the chain
+	reverted, err := CollectTipSetsOfHeightAtLeast(ctx, IterAncestors(ctx, store, oldHead), types.NewBlockHeight(commonHeight+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect reverted tipsets")
+	}
+	applied, err := CollectTipSetsOfHeightAtLeast(ctx, IterAncestors(ctx, store, newHead), types.NewBlockHeight(commonHeight+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect applied tipsets")
+	}
+
+	changes := make([]HeadChange, 0, len(reverted)+len(applied))
+	for _, ts := range reverted {
+		changes = append(changes, HeadChange{Type: HeadChangeRevert, TipSet: ts})
+	}
+	for i := len(applied) - 1; i >= 0; i-- {
+		changes = append(changes, HeadChange{Type: HeadChangeApply, TipSet: applied[i]})
+	}
+	return changes, nil
+}
@@ -0,0 +1,79 @@
+package chain_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func requireHeadChangeHeights(t *testing.T, changes []chain.HeadChange, typ chain.HeadChangeType) []uint64 {
+	var heights []uint64
+	for _, c := range changes {
+		if c.Type != typ {
+			continue
+		}
+		h, err := c.TipSet.Height()
+		require.NoError(t, err)
+		heights = append(heights, h)
+	}
+	return heights
+}
+
+func TestHeadChangesFork(t *testing.T) {
+	tf.UnitTest(t)
+	dstP := initDSTParams()
+	ctx, blockSource, chainStore := setupGetAncestorTests(t, dstP)
+	// main chain has 3 blocks past CA, fork has 2
+	old, new, common := getForkOldNewCommon(ctx, t, chainStore, blockSource, dstP, 2, 3, 2)
+	commonHeight, err := common.Height()
+	require.NoError(t, err)
+
+	changes, err := chain.HeadChanges(ctx, chainStore, old, new)
+	require.NoError(t, err)
+	require.Len(t, changes, 5)
+
+	// reverts come first, from the old head down
+	assert.Equal(t, []uint64{commonHeight + 2, commonHeight + 1}, requireHeadChangeHeights(t, changes[:2], chain.HeadChangeRevert))
+	assert.True(t, changes[0].TipSet.Equals(old))
+
+	// then applies, up to the new head
+	assert.Equal(t, []uint64{commonHeight + 1, commonHeight + 2, commonHeight + 3}, requireHeadChangeHeights(t, changes[2:], chain.HeadChangeApply))
+	assert.True(t, changes[4].TipSet.Equals(new))
+}
+
+func TestHeadChangesExtension(t *testing.T) {
+	tf.UnitTest(t)
+	dstP := initDSTParams()
+	ctx, blockSource, chainStore := setupGetAncestorTests(t, dstP)
+	// old head is a direct ancestor of new head
+	old, new, _ := getForkOldNewCommon(ctx, t, chainStore, blockSource, dstP, 2, 3, 0)
+
+	changes, err := chain.HeadChanges(ctx, chainStore, old, new)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Empty(t, requireHeadChangeHeights(t, changes, chain.HeadChangeRevert))
+	assert.True(t, changes[2].TipSet.Equals(new))
+
+	changes, err = chain.HeadChanges(ctx, chainStore, new, new)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestHeadChangesSubset(t *testing.T) {
+	tf.UnitTest(t)
+	dstP := initDSTParams()
+	ctx, blockSource, chainStore := setupGetAncestorTests(t, dstP)
+	old, new, _ := getSubsetOldNewCommon(ctx, t, chainStore, blockSource, dstP, 2)
+
+	changes, err := chain.HeadChanges(ctx, chainStore, old, new)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, chain.HeadChangeRevert, changes[0].Type)
+	assert.True(t, changes[0].TipSet.Equals(old))
+	assert.Equal(t, chain.HeadChangeApply, changes[1].Type)
+	assert.True(t, changes[1].TipSet.Equals(new))
+}
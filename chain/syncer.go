@@ -42,10 +42,18 @@ var (
 	ErrUnexpectedStoreState = errors.New("the chain store is in an unexpected state")
 )
 
-var syncOneTimer *metrics.Float64Timer
+var (
+	syncOneTimer       *metrics.Float64Timer
+	fetchTimer         *metrics.Float64Timer
+	tipSetsValidatedCt *metrics.Int64Counter
+	headHeightGa       *metrics.Int64Gauge
+)
 
 func init() {
 	syncOneTimer = metrics.NewTimerMs("syncer/sync_one", "Duration of single tipset validation in milliseconds")
+	fetchTimer = metrics.NewTimerMs("syncer/fetch_blocks", "Duration of fetching the blocks of a tipset in milliseconds")
+	tipSetsValidatedCt = metrics.NewInt64Counter("syncer/tipsets_validated", "The number of tipsets validated and added to the store")
+	headHeightGa = metrics.NewInt64Gauge("chain/head_height", "The height of the heaviest tipset")
 }
 
 var logSyncer = logging.Logger("chain.syncer")
@@ -71,6 +79,17 @@ const (
 	CaughtUp
 )
 
+func (sm SyncMode) String() string {
+	switch sm {
+	case Syncing:
+		return "syncing"
+	case CaughtUp:
+		return "caught up"
+	default:
+		return "unknown"
+	}
+}
+
 type syncerChainReader interface {
 	BlockHeight() (uint64, error)
 	GetBlock(context.Context, cid.Cid) (*types.Block, error)
@@ -144,6 +163,11 @@ func NewSyncer(cst *hamt.CborIpldStore, c consensus.Protocol, s syncerChainReade
 	}
 }
 
+// SyncMode returns the mode the syncer is in.
+func (syncer *Syncer) SyncMode() SyncMode {
+	return syncer.syncMode
+}
+
 // getBlksMaybeFromNet resolves cids of blocks.  It gets blocks through the
 // fetcher.  The fetcher wraps a bitswap session which wraps a bitswap exchange,
 // and the bitswap exchange wraps the node's shared blockstore.  So if blocks
@@ -155,6 +179,9 @@ func (syncer *Syncer) getBlksMaybeFromNet(ctx context.Context, blkCids []cid.Cid
 	ctx, cancel := context.WithTimeout(ctx, blkWaitTime)
 	defer cancel()
 
+	stopwatch := fetchTimer.Start(ctx)
+	defer stopwatch.Stop(ctx)
+
 	return syncer.fetcher.GetBlocks(ctx, blkCids)
 }
 
@@ -293,6 +320,7 @@ func (syncer *Syncer) syncOne(ctx context.Context, parent, next types.TipSet) er
 	if err != nil {
 		return err
 	}
+	tipSetsValidatedCt.Inc(ctx, 1)
 	logSyncer.Debugf("Successfully updated store with %s", next.String())
 
 	// TipSet is validated and added to store, now check if it is the heaviest.
@@ -326,6 +354,7 @@ func (syncer *Syncer) syncOne(ctx context.Context, parent, next types.TipSet) er
 		if err = syncer.chainStore.SetHead(ctx, next); err != nil {
 			return err
 		}
+		headHeightGa.Set(ctx, int64(h))
 		// Gather the entire new chain for reorg comparison and logging.
 		syncer.logReorg(ctx, headTipSet, next)
 	}
@@ -491,6 +520,69 @@ func (syncer *Syncer) HandleNewTipset(ctx context.Context, tipsetCids types.Sort
 	return nil
 }
 
+// Weight returns the consensus weight of ts, whose parent state must be in
+// the store.
+func (syncer *Syncer) Weight(ctx context.Context, ts types.TipSet) (uint64, error) {
+	parentCids, err := ts.Parents()
+	if err != nil {
+		return 0, err
+	}
+	var parentSt state.Tree
+	if parentCids.Len() != 0 { // ts is not genesis
+		parentSt, err = syncer.tipSetState(ctx, parentCids)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return syncer.consensus.Weight(ctx, ts, parentSt)
+}
+
+// SyncTargets provides the heads of the chains peers report.
+type SyncTargets interface {
+	// SyncTargets returns the heads of the chains peers report to be
+	// heavier than minWeight, most promising first.
+	SyncTargets(minWeight uint64) []types.SortedCidSet
+	// SyncFailed records that syncing to head failed, so that head is
+	// tried after the other targets.
+	SyncFailed(head types.SortedCidSet)
+}
+
+// maxSyncTargetAttempts is the number of sync targets SyncBestTarget tries
+// before giving up.
+const maxSyncTargetAttempts = 3
+
+// SyncBestTarget syncs to the most promising of the chains peers report to be
+// heavier than the chain of the store. When syncing to a chain fails, the
+// chain is demoted among the targets and the next most promising one is
+// tried, up to maxSyncTargetAttempts chains. Demoted chains are tried again
+// once the other targets have failed too.
+func (syncer *Syncer) SyncBestTarget(ctx context.Context, targets SyncTargets) error {
+	head, err := syncer.chainStore.GetTipSet(syncer.chainStore.GetHead())
+	if err != nil {
+		return errors.Wrap(err, "failed to get head")
+	}
+	headWeight, err := syncer.Weight(ctx, head)
+	if err != nil {
+		return errors.Wrap(err, "failed to weigh head")
+	}
+
+	candidates := targets.SyncTargets(headWeight)
+	if len(candidates) > maxSyncTargetAttempts {
+		candidates = candidates[:maxSyncTargetAttempts]
+	}
+	for _, target := range candidates {
+		if err = syncer.HandleNewTipset(ctx, target); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		logSyncer.Infof("failed to sync to %s: %s", target, err)
+		targets.SyncFailed(target)
+	}
+	return err
+}
+
 func (syncer *Syncer) exceedsFinalityLimit(chain []types.TipSet) bool {
 	if len(chain) == 0 {
 		return false
@@ -415,6 +415,37 @@ func TestSyncOneTipSet(t *testing.T) {
 	assertHead(t, chainStore, dstP.link1)
 }
 
+// fakeSyncTargets reports fixed sync targets and records failed ones.
+type fakeSyncTargets struct {
+	targets []types.SortedCidSet
+	failed  []types.SortedCidSet
+}
+
+func (f *fakeSyncTargets) SyncTargets(minWeight uint64) []types.SortedCidSet {
+	return f.targets
+}
+
+func (f *fakeSyncTargets) SyncFailed(head types.SortedCidSet) {
+	f.failed = append(f.failed, head)
+}
+
+// Syncer moves on from a target it fails to sync to and reports the failure.
+func TestSyncBestTargetReportsFailedTargets(t *testing.T) {
+	tf.UnitTest(t)
+	dstP := initDSTParams()
+
+	syncer, chainStore, _, blockSource := initSyncTestDefault(t, dstP)
+	ctx := context.Background()
+
+	missing := types.NewSortedCidSet(types.SomeCid())
+	good := requirePutBlocks(t, blockSource, dstP.link1blk1, dstP.link1blk2)
+	targets := &fakeSyncTargets{targets: []types.SortedCidSet{missing, good}}
+
+	require.NoError(t, syncer.SyncBestTarget(ctx, targets))
+	assert.Equal(t, []types.SortedCidSet{missing}, targets.failed)
+	assertHead(t, chainStore, dstP.link1)
+}
+
 // Syncer syncs one tipset, block by block.
 func TestSyncTipSetBlockByBlock(t *testing.T) {
 	tf.UnitTest(t)
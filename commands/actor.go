@@ -51,8 +51,17 @@ var actorCmd = &cmds.Command{
 }
 
 var actorLsCmd = &cmds.Command{
+	Options: []cmdkit.Option{
+		tipSetOption,
+		heightOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		results, err := GetPorcelainAPI(env).ActorLs(req.Context)
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		results, err := GetPorcelainAPI(env).ActorLsAt(req.Context, tsKey)
 		if err != nil {
 			return err
 		}
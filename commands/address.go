@@ -23,6 +23,7 @@ var walletCmd = &cmds.Command{
 		"balance": balanceCmd,
 		"import":  walletImportCmd,
 		"export":  walletExportCmd,
+		"restore": walletRestoreCmd,
 	},
 }
 
@@ -136,13 +137,22 @@ var balanceCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("address", true, false, "Address to get balance for"),
 	},
+	Options: []cmdkit.Option{
+		tipSetOption,
+		heightOption,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addr, err := address.NewFromString(req.Arguments[0])
 		if err != nil {
 			return err
 		}
 
-		balance, err := GetPorcelainAPI(env).WalletBalance(req.Context, addr)
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		balance, err := GetPorcelainAPI(env).WalletBalanceAt(req.Context, tsKey, addr)
 		if err != nil {
 			return err
 		}
@@ -254,3 +264,43 @@ var walletExportCmd = &cmds.Command{
 		}),
 	},
 }
+
+var walletRestoreCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Restore a wallet from its mnemonic",
+		ShortDescription: `
+Restores the addresses derived from a wallet mnemonic, as generated by
+go-filecoin init, that have actors in the state tree. New addresses are then
+derived from the mnemonic after the restored ones. Addresses already in the
+wallet are kept.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("mnemonic", true, false, "Mnemonic of the wallet to restore").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addrs, err := GetPorcelainAPI(env).WalletRestore(req.Context, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		var alr AddressLsResult
+		for _, addr := range addrs {
+			alr.Addresses = append(alr.Addresses, addr.String())
+		}
+
+		return re.Emit(&alr)
+	},
+	Type: &AddressLsResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, addrs *AddressLsResult) error {
+			for _, addr := range addrs.Addresses {
+				_, err := fmt.Fprintln(w, addr)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
@@ -54,6 +54,39 @@ func TestWalletBalance(t *testing.T) {
 	assert.Equal(t, "0", balance.ReadStdoutTrimNewlines())
 }
 
+func TestWalletBalanceAt(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+	addr := d.CreateAddress()
+
+	msgCid := d.RunSuccess("message", "send",
+		"--from", d.GetDefaultAddress(),
+		"--gas-price", "1",
+		"--gas-limit", "300",
+		"--value", "10",
+		addr,
+	).ReadStdoutTrimNewlines()
+	d.RunSuccess("mining", "once")
+	d.RunSuccess("message", "wait", msgCid)
+
+	t.Log("[success] balance at the head")
+	balance := d.RunSuccess("wallet", "balance", addr)
+	assert.Equal(t, "10", balance.ReadStdoutTrimNewlines())
+
+	t.Log("[success] balance at genesis")
+	balance = d.RunSuccess("wallet", "balance", "--height", "0", addr)
+	assert.Equal(t, "0", balance.ReadStdoutTrimNewlines())
+
+	t.Log("[failure] height above the head")
+	d.RunFail("is above the chain head", "wallet", "balance", "--height", "1000", addr)
+
+	t.Log("[failure] both tipset and height")
+	d.RunFail("only one of tipset and height may be given",
+		"wallet", "balance", "--height", "0", "--tipset", "foo", addr)
+}
+
 func TestAddrLookupAndUpdate(t *testing.T) {
 	tf.IntegrationTest(t)
 
@@ -2,6 +2,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strconv"
@@ -10,7 +11,12 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/plumbing/replay"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -19,8 +25,10 @@ var chainCmd = &cmds.Command{
 		Tagline: "Inspect the filecoin blockchain",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"head": chainHeadCmd,
-		"ls":   chainLsCmd,
+		"head":   chainHeadCmd,
+		"ls":     chainLsCmd,
+		"notify": chainNotifyCmd,
+		"replay": chainReplayCmd,
 	},
 }
 
@@ -56,8 +64,11 @@ var chainLsCmd = &cmds.Command{
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption("long", "l", "List blocks in long format, including CID, Miner, StateRoot, block height and message count respectively"),
+		cmdkit.BoolOption("decode", "List the messages of each block with their params and return values decoded"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		decode, _ := req.Options["decode"].(bool)
+
 		iter, err := GetPorcelainAPI(env).ChainLs(req.Context)
 		if err != nil {
 			return err
@@ -69,15 +80,28 @@ var chainLsCmd = &cmds.Command{
 			if !iter.Value().Defined() {
 				panic("tipsets from this iterator should have at least one member")
 			}
-			if err := re.Emit(iter.Value().ToSlice()); err != nil {
+
+			var blocks []ChainLsBlock
+			tsKey := iter.Value().ToSortedCidSet()
+			for _, blk := range iter.Value().ToSlice() {
+				lsBlk := ChainLsBlock{Block: *blk}
+				if decode {
+					lsBlk.DecodedMessages, err = decodeBlockMessages(req.Context, GetPorcelainAPI(env), tsKey, blk)
+					if err != nil {
+						return err
+					}
+				}
+				blocks = append(blocks, lsBlk)
+			}
+			if err := re.Emit(blocks); err != nil {
 				return err
 			}
 		}
 		return nil
 	},
-	Type: []types.Block{},
+	Type: []ChainLsBlock{},
 	Encoders: cmds.EncoderMap{
-		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *[]types.Block) error {
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *[]ChainLsBlock) error {
 			showAll, _ := req.Options["long"].(bool)
 			blocks := *res
 
@@ -98,6 +122,15 @@ var chainLsCmd = &cmds.Command{
 					output.WriteString(block.Cid().String())
 				}
 
+				for _, msg := range block.DecodedMessages {
+					output.WriteString("\n\t")
+					output.WriteString(msg.Cid.String())
+					if msg.Decoded != nil {
+						output.WriteString("\t")
+						output.WriteString(formatDecodedMessage(msg.Decoded))
+					}
+				}
+
 				_, err := fmt.Fprintln(w, output.String())
 				if err != nil {
 					return err
@@ -108,3 +141,182 @@ var chainLsCmd = &cmds.Command{
 		}),
 	},
 }
+
+// ChainLsBlock is a block listed by chain ls. DecodedMessages is only set
+// when decoding was requested.
+type ChainLsBlock struct {
+	types.Block
+	DecodedMessages []ChainLsMessage `json:"decodedMessages,omitempty"`
+}
+
+// ChainLsMessage is a message of a listed block with its params and return
+// values decoded. Decoded is nil for messages that invoke no method.
+type ChainLsMessage struct {
+	Cid     cid.Cid                   `json:"cid"`
+	Decoded *porcelain.DecodedMessage `json:"decoded"`
+}
+
+func decodeBlockMessages(ctx context.Context, api *porcelain.API, tsKey types.SortedCidSet, blk *types.Block) ([]ChainLsMessage, error) {
+	msgs := make([]ChainLsMessage, len(blk.Messages))
+	for i, msg := range blk.Messages {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt *types.MessageReceipt
+		if i < len(blk.MessageReceipts) {
+			receipt = blk.MessageReceipts[i]
+		}
+		decoded, err := api.MessageDecode(ctx, &msg.Message, receipt, tsKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode message %s", c)
+		}
+		msgs[i] = ChainLsMessage{Cid: c, Decoded: decoded}
+	}
+	return msgs, nil
+}
+
+// ChainNotifyEvent is a tipset joining or leaving the chain, or the head at
+// the time chain notify started.
+type ChainNotifyEvent struct {
+	Type   chain.HeadChangeType `json:"type"`
+	Height uint64               `json:"height"`
+	Cids   []cid.Cid            `json:"cids"`
+}
+
+var chainNotifyCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stream the changes of the chain head",
+		ShortDescription: `Emits an event for each tipset joining or leaving the chain, until
+interrupted. The first event is the current head, with type "current". When
+the head changes, the tipsets of the old chain back to the common ancestor of
+the old and new heads are emitted with type "revert", newest first, followed by
+the tipsets of the new chain with type "apply", oldest first.`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		results, err := GetPorcelainAPI(env).ChainNotify(req.Context)
+		if err != nil {
+			return err
+		}
+
+		for res := range results {
+			if res.Error != nil {
+				return res.Error
+			}
+			for _, change := range res.Changes {
+				height, err := change.TipSet.Height()
+				if err != nil {
+					return err
+				}
+				err = re.Emit(&ChainNotifyEvent{
+					Type:   change.Type,
+					Height: height,
+					Cids:   change.TipSet.ToSortedCidSet().ToSlice(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Type: ChainNotifyEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ev *ChainNotifyEvent) error {
+			cids := make([]string, len(ev.Cids))
+			for i, c := range ev.Cids {
+				cids[i] = c.String()
+			}
+			_, err := fmt.Fprintf(w, "%s\t%d\t%s\n", ev.Type, ev.Height, strings.Join(cids, ","))
+			return err
+		}),
+	},
+}
+
+var chainReplayCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Re-execute a tipset and compare the result with the stored state",
+		ShortDescription: `Loads the parent state of the tipset made of the given block CIDs, re-runs
+the state transition and compares each message receipt and the resulting state
+root with what is stored in the chain. When the state roots differ, the actors
+whose state differs are listed.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cids", true, true, "CIDs of the blocks of the tipset to replay"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		tsKey, err := tipSetKeyFromArgs(req.Arguments)
+		if err != nil {
+			return err
+		}
+
+		report, err := GetPorcelainAPI(env).ChainReplay(req.Context, tsKey)
+		if err != nil {
+			return err
+		}
+		return re.Emit(report)
+	},
+	Type: replay.Report{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, report *replay.Report) error {
+			sw := NewSilentWriter(w)
+
+			sw.Printf("TipSet:          %s\n", report.TipSet.String())
+			sw.Printf("Height:          %d\n", report.Height)
+			sw.Printf("Parent state:    %s\n", report.ParentStateRoot)
+			sw.Printf("Stored state:    %s\n", report.StoredStateRoot)
+			sw.Printf("Computed state:  %s\n", report.ComputedStateRoot)
+			if report.TransitionError != "" {
+				sw.Printf("Transition error: %s\n", report.TransitionError)
+			}
+
+			for _, rc := range report.Receipts {
+				if rc.Match {
+					continue
+				}
+				sw.Printf("receipt mismatch: block %s message %s\n", rc.Block, rc.Message)
+				sw.Printf("\tstored:   %s\n", formatReceipt(rc.Stored))
+				sw.Printf("\tcomputed: %s\n", formatReceipt(rc.Computed))
+			}
+
+			for _, c := range report.ActorDiffs {
+				sw.Printf("actor %s %s\n", c.Address, c.Kind)
+				sw.Printf("\tstored:   %s\n", formatActor(c.Before))
+				sw.Printf("\tcomputed: %s\n", formatActor(c.After))
+			}
+
+			if report.StateRootMatches() && report.ReceiptsMatch() {
+				sw.Println("OK: replayed state and receipts match the stored chain")
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+// tipSetKeyFromArgs parses block CID arguments into a tipset key.
+func tipSetKeyFromArgs(args []string) (types.SortedCidSet, error) {
+	var tsKey types.SortedCidSet
+	for _, arg := range args {
+		c, err := cid.Decode(arg)
+		if err != nil {
+			return types.SortedCidSet{}, err
+		}
+		tsKey.Add(c)
+	}
+	return tsKey, nil
+}
+
+func formatReceipt(r *types.MessageReceipt) string {
+	if r == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("exit code %d, gas %s, %d return values", r.ExitCode, r.GasAttoFIL, len(r.Return))
+}
+
+func formatActor(a *actor.Actor) string {
+	if a == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("code %s, head %s, nonce %d, balance %s", a.Code, a.Head, a.Nonce, a.Balance)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/fixtures"
+	"github.com/filecoin-project/go-filecoin/plumbing/replay"
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -128,3 +129,34 @@ func TestChainLs(t *testing.T) {
 		assert.Contains(t, chainLsResult, `"nonce":"0"`)
 	})
 }
+
+func TestChainReplay(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	t.Run("replaying a mined tipset matches the stored state", func(t *testing.T) {
+		daemon := makeTestDaemonWithMinerAndStart(t)
+		defer daemon.ShutdownSuccess()
+
+		newBlockCid := daemon.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines()
+
+		result := daemon.RunSuccess("chain", "replay", newBlockCid, "--enc", "json").ReadStdoutTrimNewlines()
+		var report replay.Report
+		require.NoError(t, json.Unmarshal([]byte(result), &report))
+
+		assert.Empty(t, report.TransitionError)
+		assert.True(t, report.StateRootMatches())
+		assert.True(t, report.ReceiptsMatch())
+		assert.Empty(t, report.ActorDiffs)
+
+		text := daemon.RunSuccess("chain", "replay", newBlockCid).ReadStdoutTrimNewlines()
+		assert.Contains(t, text, "OK: replayed state and receipts match the stored chain")
+	})
+
+	t.Run("replaying genesis fails", func(t *testing.T) {
+		daemon := th.NewDaemon(t).Start()
+		defer daemon.ShutdownSuccess()
+
+		genesisCid := daemon.RunSuccess("chain", "ls").ReadStdoutTrimNewlines()
+		daemon.RunFail("cannot replay the genesis tipset", "chain", "replay", genesisCid)
+	})
+}
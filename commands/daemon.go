@@ -20,6 +20,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/health"
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/repo"
@@ -137,6 +138,7 @@ func runAPIAndWait(ctx context.Context, nd *node.Node, config *config.Config, re
 		// TODO: should this be the passed in context?  Issue 2641
 		blockMiningAPI: nd.BlockMiningAPI,
 		ctx:            context.Background(),
+		healthChecker:  nd.Health,
 		inspectorAPI:   NewInspectorAPI(nd.Repo),
 		porcelainAPI:   nd.PorcelainAPI,
 		retrievalAPI:   nd.RetrievalAPI,
@@ -168,6 +170,8 @@ func runAPIAndWait(ctx context.Context, nd *node.Node, config *config.Config, re
 
 	handler := http.NewServeMux()
 	handler.Handle("/debug/pprof/", http.DefaultServeMux)
+	handler.HandleFunc(health.HealthPath, nd.Health.HandleHealth)
+	handler.HandleFunc(health.ReadyPath, nd.Health.HandleReady)
 	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cfg))
 
 	apiserv := http.Server{
@@ -37,6 +37,8 @@ type DealsListResult struct {
 	PieceCid    cid.Cid         `json:"pieceCid"`
 	ProposalCid cid.Cid         `json:"proposalCid"`
 	State       string          `json:"state"`
+	Redeemed    types.AttoFIL   `json:"redeemed"`
+	Missed      types.AttoFIL   `json:"missed"`
 }
 
 var dealsListCmd = &cmds.Command{
@@ -76,6 +78,8 @@ deals, active deals, finished deals and cancelled deals.
 				PieceCid:    deal.Deal.Proposal.PieceRef,
 				ProposalCid: deal.Deal.Response.ProposalCid,
 				State:       deal.Deal.Response.State.String(),
+				Redeemed:    deal.Deal.Redeemed,
+				Missed:      deal.Deal.Missed,
 			}
 			if err = re.Emit(out); err != nil {
 				return err
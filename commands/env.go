@@ -5,6 +5,7 @@ import (
 
 	"github.com/ipfs/go-ipfs-cmds"
 
+	"github.com/filecoin-project/go-filecoin/health"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/protocol/block"
 	"github.com/filecoin-project/go-filecoin/protocol/retrieval"
@@ -15,6 +16,7 @@ import (
 type Env struct {
 	blockMiningAPI *block.MiningAPI
 	ctx            context.Context
+	healthChecker  *health.Checker
 	porcelainAPI   *porcelain.API
 	retrievalAPI   *retrieval.API
 	storageAPI     *storage.API
@@ -57,3 +59,9 @@ func GetInspectorAPI(env cmds.Environment) *Inspector {
 	ce := env.(*Env)
 	return ce.inspectorAPI
 }
+
+// GetHealthChecker returns the node's readiness checker from the given environment.
+func GetHealthChecker(env cmds.Environment) *health.Checker {
+	ce := env.(*Env)
+	return ce.healthChecker
+}
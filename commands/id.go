@@ -20,6 +20,9 @@ type IDDetails struct {
 	AgentVersion    string
 	ProtocolVersion string
 	PublicKey       []byte // raw bytes
+	// Reachability is whether the node can be dialed from the public
+	// internet: public, private (only through relays) or unknown.
+	Reachability string
 }
 
 var idCmd = &cmds.Command{
@@ -35,8 +38,9 @@ var idCmd = &cmds.Command{
 		hostID := GetPorcelainAPI(env).NetworkGetPeerID()
 
 		details := IDDetails{
-			Addresses: make([]ma.Multiaddr, len(addrs)),
-			ID:        hostID,
+			Addresses:    make([]ma.Multiaddr, len(addrs)),
+			ID:           hostID,
+			Reachability: string(GetPorcelainAPI(env).NetworkReachability()),
 		}
 
 		for i, addr := range addrs {
@@ -82,6 +86,7 @@ func idFormatSubstitute(format string, val *IDDetails) string {
 	output = strings.Replace(output, "<pver>", val.ProtocolVersion, -1)
 	output = strings.Replace(output, "<pubkey>", base64.StdEncoding.EncodeToString(val.PublicKey), -1)
 	output = strings.Replace(output, "<addrs>", strings.Join(addrStrings, "\n"), -1)
+	output = strings.Replace(output, "<reachability>", val.Reachability, -1)
 	output = strings.Replace(output, "\\n", "\n", -1)
 	output = strings.Replace(output, "\\t", "\t", -1)
 	return output
@@ -112,6 +117,9 @@ func (idd IDDetails) MarshalJSON() ([]byte, error) {
 		// This is what the built-in JSON encoder does to []byte too.
 		v["PublicKey"] = base64.StdEncoding.EncodeToString(idd.PublicKey)
 	}
+	if idd.Reachability != "" {
+		v["Reachability"] = idd.Reachability
+	}
 	return json.Marshal(v)
 }
 
@@ -153,6 +161,9 @@ func (idd *IDDetails) UnmarshalJSON(data []byte) error {
 	if err := decode(v, "PublicKey", &idd.PublicKey); err != nil {
 		return err
 	}
+	if err := decode(v, "Reachability", &idd.Reachability); err != nil {
+		return err
+	}
 	return nil
 }
 
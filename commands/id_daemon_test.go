@@ -22,7 +22,7 @@ func TestId(t *testing.T) {
 	idContent := id.ReadStdout()
 	assert.Containsf(t, idContent, d.SwarmAddr(), "default addr")
 	assert.Contains(t, idContent, "ID")
-
+	assert.Contains(t, idContent, "Reachability")
 }
 
 func TestIdFormat(t *testing.T) {
@@ -41,6 +41,18 @@ func TestIdFormat(t *testing.T) {
 	assert.NotContains(t, idContent, "ID")
 }
 
+func TestIdReachability(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	// without peers running the autoNAT service, reachability can't be
+	// determined
+	reachability := d.RunSuccess("id", "--format=<reachability>").ReadStdout()
+	assert.Equal(t, "unknown", reachability)
+}
+
 func TestPersistId(t *testing.T) {
 	tf.IntegrationTest(t)
 
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/ipfs/go-car"
 	"github.com/ipfs/go-hamt-ipld"
@@ -24,6 +25,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
 var initCmd = &cmds.Command{
@@ -36,6 +38,7 @@ var initCmd = &cmds.Command{
 		cmdkit.StringOption(WithMiner, "when set, creates a custom genesis block with a pre generated miner account, requires running the daemon using dev mode (--dev)"),
 		cmdkit.StringOption(OptionSectorDir, "path of directory into which staged and sealed sectors will be written"),
 		cmdkit.StringOption(DefaultAddress, "when set, sets the daemons's default address to the provided address"),
+		cmdkit.StringOption(MnemonicFile, "path of file containing the mnemonic to derive wallet addresses from, e.g. /dev/stdin, a new one is generated and printed when not set"),
 		cmdkit.UintOption(AutoSealIntervalSeconds, "when set to a number > 0, configures the daemon to check for and seal any staged sectors on an interval.").WithDefault(uint(120)),
 		cmdkit.BoolOption(DevnetTest, "when set, populates config bootstrap addrs with the dns multiaddrs of the test devnet and other test devnet specific bootstrap parameters."),
 		cmdkit.BoolOption(DevnetNightly, "when set, populates config bootstrap addrs with the dns multiaddrs of the nightly devnet and other nightly devnet specific bootstrap parameters"),
@@ -47,6 +50,13 @@ var initCmd = &cmds.Command{
 			return err
 		}
 
+		// Check the mnemonic before anything is written to the repo.
+		mnemonicFile, _ := req.Options[MnemonicFile].(string)
+		mnemonic, err := loadMnemonic(mnemonicFile)
+		if err != nil {
+			return err
+		}
+
 		repoDir, _ := req.Options[OptionRepoDir].(string)
 		repoDir, err = paths.GetRepoPath(repoDir)
 		if err != nil {
@@ -73,12 +83,24 @@ var initCmd = &cmds.Command{
 			return err
 		}
 
+		if mnemonic == "" {
+			mnemonic, err = wallet.NewMnemonic()
+			if err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("generated wallet mnemonic, write it down to restore your wallet with go-filecoin wallet restore:\n%s\n", mnemonic)
+			if err := re.Emit(msg); err != nil {
+				return err
+			}
+		}
+
 		autoSealIntervalSeconds, _ := req.Options[AutoSealIntervalSeconds].(uint)
 		peerKeyFile, _ := req.Options[PeerKeyFile].(string)
 		initopts, err := getNodeInitOpts(autoSealIntervalSeconds, peerKeyFile)
 		if err != nil {
 			return err
 		}
+		initopts = append(initopts, node.MnemonicOpt(mnemonic))
 
 		return node.Init(req.Context, rep, genesisFile, initopts...)
 	},
@@ -220,3 +242,20 @@ func getNodeInitOpts(autoSealIntervalSeconds uint, peerKeyFile string) ([]node.I
 
 	return initOpts, nil
 }
+
+// loadMnemonic reads the wallet mnemonic from mnemonicFile and checks that
+// it is valid. It returns an empty mnemonic when no file is given.
+func loadMnemonic(mnemonicFile string) (string, error) {
+	if mnemonicFile == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(mnemonicFile)
+	if err != nil {
+		return "", err
+	}
+	mnemonic := strings.Join(strings.Fields(string(data)), " ")
+	if _, err := wallet.SeedFromMnemonic(mnemonic); err != nil {
+		return "", err
+	}
+	return mnemonic, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/ipfs/go-ipfs-cmdkit"
@@ -66,6 +67,9 @@ const (
 	// DefaultAddress when set, sets the daemons's default address to the provided address
 	DefaultAddress = "default-address"
 
+	// MnemonicFile is the path of file containing the mnemonic the wallet derives its addresses from
+	MnemonicFile = "mnemonicfile"
+
 	// GenesisFile is the path of file containing archive of genesis block DAG data
 	GenesisFile = "genesisfile"
 
@@ -102,12 +106,14 @@ STORE AND RETRIEVE DATA
 MINE
   go-filecoin miner                  - Manage a single miner actor
   go-filecoin mining                 - Manage all mining operations for a node
+  go-filecoin sectors                - Inspect and manage the sectors of this node's storage miner
 
 VIEW DATA STRUCTURES
   go-filecoin chain                  - Inspect the filecoin blockchain
   go-filecoin dag                    - Interact with IPLD DAG objects
   go-filecoin deals                  - Manage deals made by or with this node
   go-filecoin show                   - Get human-readable representations of filecoin objects
+  go-filecoin state                  - Inspect and compare the state of the chain
 
 NETWORK COMMANDS
   go-filecoin bitswap                - Explore libp2p bitswap
@@ -130,6 +136,7 @@ TOOL COMMANDS
   go-filecoin inspect                - Show info about the go-filecoin node
   go-filecoin log                    - Interact with the daemon event log output
   go-filecoin protocol               - Show protocol parameter details
+  go-filecoin status                 - Show whether the node is ready
   go-filecoin version                - Show go-filecoin version information
 `,
 	},
@@ -179,8 +186,11 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"ping":             pingCmd,
 	"protocol":         protocolCmd,
 	"retrieval-client": retrievalClientCmd,
+	"sectors":          sectorsCmd,
 	"show":             showCmd,
+	"state":            stateCmd,
 	"stats":            statsCmd,
+	"status":           statusCmd,
 	"swarm":            swarmCmd,
 	"wallet":           walletCmd,
 }
@@ -331,6 +341,9 @@ var priceOption = cmdkit.StringOption("gas-price", "Price (FIL e.g. 0.00013) to
 var limitOption = cmdkit.Uint64Option("gas-limit", "Maximum number of GasUnits this message is allowed to consume")
 var previewOption = cmdkit.BoolOption("preview", "Preview the Gas cost of this command without actually executing it")
 
+var tipSetOption = cmdkit.StringOption("tipset", "Comma separated block CIDs of the tipset whose state to read, defaults to the chain head")
+var heightOption = cmdkit.Uint64Option("height", "Chain height whose state to read, defaults to the chain head")
+
 func parseGasOptions(req *cmds.Request) (types.AttoFIL, types.GasUnits, bool, error) {
 	priceOption := req.Options["gas-price"]
 	if priceOption == nil {
@@ -357,3 +370,29 @@ func parseGasOptions(req *cmds.Request) (types.AttoFIL, types.GasUnits, bool, er
 
 	return price, types.NewGasUnits(gasLimitInt), preview, nil
 }
+
+// stateTipSetKey returns the key of the tipset selected with the tipset or
+// height option, or the key of the chain head when neither is given.
+func stateTipSetKey(req *cmds.Request, env cmds.Environment) (types.SortedCidSet, error) {
+	tipSet, hasTipSet := req.Options["tipset"].(string)
+	height, hasHeight := req.Options["height"].(uint64)
+
+	switch {
+	case hasTipSet && hasHeight:
+		return types.SortedCidSet{}, errors.New("only one of tipset and height may be given")
+	case hasTipSet:
+		return tipSetKeyFromArgs(strings.Split(tipSet, ","))
+	case hasHeight:
+		ts, err := GetPorcelainAPI(env).ChainTipSetAtHeight(req.Context, height)
+		if err != nil {
+			return types.SortedCidSet{}, err
+		}
+		return ts.ToSortedCidSet(), nil
+	default:
+		head, err := GetPorcelainAPI(env).ChainHead()
+		if err != nil {
+			return types.SortedCidSet{}, err
+		}
+		return head.ToSortedCidSet(), nil
+	}
+}
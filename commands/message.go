@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-files"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
@@ -19,6 +21,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -27,8 +30,13 @@ var msgCmd = &cmds.Command{
 		Tagline: "Send and monitor messages",
 	},
 	Subcommands: map[string]*cmds.Command{
+		"create": msgCreateCmd,
+		"query":  msgQueryCmd,
 		"send":   msgSendCmd,
+		"show":   msgShowCmd,
+		"sign":   msgSignCmd,
 		"status": msgStatusCmd,
+		"submit": msgSubmitCmd,
 		"wait":   msgWaitCmd,
 	},
 }
@@ -135,6 +143,397 @@ var msgSendCmd = &cmds.Command{
 	},
 }
 
+var msgCreateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create an unsigned message",
+		ShortDescription: `
+Creates an unsigned message and prints it as JSON, without signing or sending
+it. Unless --nonce is given the nonce is the next one expected from the sender,
+taking messages sent but not yet mined into account. 'message submit' only
+accepts messages with that nonce, so --nonce is for creating several messages
+before submitting any of them. The message can be signed with 'message sign'
+on a node holding the sender's key, e.g. an offline node, and the signed
+message sent with 'message submit'.
+
+The params of the method are given as a JSON array of values with their ABI
+types, in the form printed by 'message show --decode --enc=json', e.g.
+'[{"type":"address.Address","value":"fcq..."}]'. They are checked against the
+signature of the method when the target actor exists.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
+		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
+		cmdkit.StringArg("params", false, false, "JSON array of the ABI typed params of the method"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("value", "Value to send with message in FIL"),
+		cmdkit.StringOption("from", "Address to send message from"),
+		cmdkit.Uint64Option("nonce", "Nonce of the message, defaults to the sender's next nonce"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		rawVal := req.Options["value"]
+		if rawVal == nil {
+			rawVal = "0"
+		}
+		val, ok := types.NewAttoFILFromFILString(rawVal.(string))
+		if !ok {
+			return errors.New("mal-formed value")
+		}
+
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		method := ""
+		if len(req.Arguments) > 1 {
+			method = req.Arguments[1]
+		}
+
+		var params []byte
+		if len(req.Arguments) > 2 {
+			params, err = encodeMessageParams(req.Context, GetPorcelainAPI(env), target, method, req.Arguments[2])
+			if err != nil {
+				return err
+			}
+		}
+
+		nonce, ok := req.Options["nonce"].(uint64)
+		if !ok {
+			nonce, err = GetPorcelainAPI(env).MessageNextNonce(req.Context, fromAddr)
+			if err != nil {
+				return err
+			}
+		}
+
+		msg := types.NewMeteredMessage(*types.NewMessage(fromAddr, target, nonce, val, method, params), gasPrice, gasLimit)
+		return re.Emit(msg)
+	},
+	Type: types.MeteredMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, msg *types.MeteredMessage) error {
+			marshaled, err := appendJSON(msg, nil)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(marshaled)
+			return err
+		}),
+	},
+}
+
+// encodeMessageParams encodes params, a JSON array of ABI typed values, as
+// the params of a message calling method on target. The values are checked
+// against the signature of the method, unless the target has no code yet.
+func encodeMessageParams(ctx context.Context, api *porcelain.API, target address.Address, method, params string) ([]byte, error) {
+	if method == "" {
+		return nil, errors.New("params require a method")
+	}
+
+	var vals []*abi.Value
+	if err := json.Unmarshal([]byte(params), &vals); err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+	for i, val := range vals {
+		if val == nil {
+			return nil, errors.Errorf("param %d is null", i)
+		}
+	}
+
+	sig, err := api.ActorGetSignature(ctx, target, method)
+	switch errors.Cause(err) {
+	case nil:
+		if len(vals) != len(sig.Params) {
+			return nil, errors.Errorf("method %s takes %d params, got %d", method, len(sig.Params), len(vals))
+		}
+		for i, t := range sig.Params {
+			if vals[i].Type != t {
+				return nil, errors.Errorf("param %d of method %s must be %s, got %s", i, method, t, vals[i].Type)
+			}
+		}
+	case cst.ErrNoActor, cst.ErrNoActorImpl:
+		// the actor does not exist yet, so its signature can't be checked
+	default:
+		return nil, err
+	}
+
+	return abi.EncodeValues(vals)
+}
+
+var msgSignCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Sign a message created with 'message create'",
+		ShortDescription: `
+Signs an unsigned message with the key of its sender from this node's wallet
+and prints the signed message as JSON. The node does not need to be connected
+to the network, so the key can be kept on an offline node.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the unsigned message JSON").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var msg types.MeteredMessage
+		if err := decodeJSONFileArg(req, &msg); err != nil {
+			return errors.Wrap(err, "invalid message")
+		}
+
+		signed, err := types.NewSignedMessage(msg.Message, GetPorcelainAPI(env), msg.GasPrice, msg.GasLimit)
+		if err != nil {
+			return errors.Wrap(err, "failed to sign message")
+		}
+		return re.Emit(signed)
+	},
+	Type: types.SignedMessage{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, msg *types.SignedMessage) error {
+			marshaled, err := appendJSON(msg, nil)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(marshaled)
+			return err
+		}),
+	},
+}
+
+var msgSubmitCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Send a message signed with 'message sign'",
+		ShortDescription: `
+Validates a signed message against the latest state, adds it to the message
+pool and broadcasts it to the network. Prints the cid of the message.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("message", true, false, "File containing the signed message JSON").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var signed types.SignedMessage
+		if err := decodeJSONFileArg(req, &signed); err != nil {
+			return errors.Wrap(err, "invalid signed message")
+		}
+		if len(signed.Signature) == 0 {
+			return types.ErrMessageUnsigned
+		}
+
+		c, err := GetPorcelainAPI(env).MessageSendSigned(req.Context, &signed)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MessageSendResult{
+			Cid:     c,
+			GasUsed: types.NewGasUnits(0),
+			Preview: false,
+		})
+	},
+	Type: &MessageSendResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MessageSendResult) error {
+			return PrintString(w, res.Cid)
+		}),
+	},
+}
+
+// decodeJSONFileArg decodes the JSON in the request's file argument into v.
+func decodeJSONFileArg(req *cmds.Request, v interface{}) error {
+	iter := req.Files.Entries()
+	if !iter.Next() {
+		return fmt.Errorf("no file given: %s", iter.Err())
+	}
+
+	fi, ok := iter.Node().(files.File)
+	if !ok {
+		return fmt.Errorf("given file was not a files.File")
+	}
+
+	return json.NewDecoder(fi).Decode(v)
+}
+
+// MessageQueryResult is the result of a message query call. Decoded holds
+// the return values rendered as strings when the method signature is known.
+type MessageQueryResult struct {
+	Return  [][]byte
+	Decoded []string
+}
+
+var msgQueryCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Call a method on an actor without sending a message",
+		ShortDescription: `Run a parameterless method on an actor against the state of a tipset and print
+its return values. Use --tipset or --height to query an earlier point of the chain.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("target", true, false, "Address of the actor to query"),
+		cmdkit.StringArg("method", true, false, "The method to invoke on the target actor"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send the query from"),
+		tipSetOption,
+		heightOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		method := req.Arguments[1]
+
+		fromAddr, err := optionalAddr(req.Options["from"])
+		if err != nil {
+			return err
+		}
+
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		ret, err := GetPorcelainAPI(env).MessageQueryAt(req.Context, tsKey, fromAddr, target, method)
+		if err != nil {
+			return err
+		}
+
+		result := MessageQueryResult{Return: ret}
+		sig, err := GetPorcelainAPI(env).ActorGetSignature(req.Context, target, method)
+		if err != nil && err != cst.ErrNoMethod && err != cst.ErrNoActorImpl {
+			return errors.Wrap(err, "couldn't get signature for method")
+		}
+		if sig != nil && len(sig.Return) == len(ret) {
+			for i, t := range sig.Return {
+				val, err := abi.Deserialize(ret[i], t)
+				if err != nil {
+					return errors.Wrap(err, "unable to deserialize return value")
+				}
+				result.Decoded = append(result.Decoded, val.String())
+			}
+		}
+
+		return re.Emit(&result)
+	},
+	Type: &MessageQueryResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MessageQueryResult) error {
+			sw := NewSilentWriter(w)
+			if res.Decoded != nil {
+				for _, v := range res.Decoded {
+					sw.Println(v)
+				}
+				return sw.Error()
+			}
+			for _, r := range res.Return {
+				sw.Printf("%x\n", r)
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+// MessageShowResult is the result of a message show call. Receipt is nil for
+// messages that are not on chain yet and Decoded is only set when decoding
+// was requested and the message invokes a method.
+type MessageShowResult struct {
+	Message *types.SignedMessage
+	Receipt *types.MessageReceipt
+	Decoded *porcelain.DecodedMessage
+}
+
+var msgShowCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show a message on chain or in the message pool",
+		ShortDescription: `Prints a message and, once it is on chain, its receipt. With --decode, the
+params and return values are decoded using the signature of the invoked method.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "CID of the message to show"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("decode", "Decode the params and return values of the message"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		msgCid, err := cid.Parse(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid cid "+req.Arguments[0])
+		}
+
+		api := GetPorcelainAPI(env)
+		result := MessageShowResult{}
+
+		chainMsg, onChain, err := api.MessageFind(req.Context, msgCid)
+		if err != nil {
+			return err
+		}
+		var tsKey types.SortedCidSet
+		if onChain {
+			result.Message = chainMsg.Message
+			result.Receipt = chainMsg.Receipt
+			tsKey = chainMsg.TipSetKey
+		} else {
+			poolMsg, inPool := api.MessagePoolGet(msgCid)
+			if !inPool {
+				return fmt.Errorf("message %s not found", msgCid)
+			}
+			result.Message = poolMsg
+		}
+
+		if decode, _ := req.Options["decode"].(bool); decode {
+			result.Decoded, err = api.MessageDecode(req.Context, &result.Message.Message, result.Receipt, tsKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		return re.Emit(&result)
+	},
+	Type: &MessageShowResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MessageShowResult) error {
+			sw := NewSilentWriter(w)
+			sw.Println(res.Message.String())
+			if res.Receipt != nil {
+				sw.Printf("Receipt: exit code %d\n", res.Receipt.ExitCode)
+			}
+			if res.Decoded != nil {
+				sw.Println(formatDecodedMessage(res.Decoded))
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+// formatDecodedMessage renders a decoded message as a method call followed by
+// its return values, if any.
+func formatDecodedMessage(decoded *porcelain.DecodedMessage) string {
+	params := make([]string, len(decoded.Params))
+	for i, p := range decoded.Params {
+		params[i] = p.String()
+	}
+	out := fmt.Sprintf("%s(%s)", decoded.Method, strings.Join(params, ", "))
+
+	if len(decoded.Return) > 0 {
+		rets := make([]string, len(decoded.Return))
+		for i, r := range decoded.Return {
+			rets[i] = r.String()
+		}
+		out += " => " + strings.Join(rets, ", ")
+	}
+	return out
+}
+
 // WaitResult is the result of a message wait call.
 type WaitResult struct {
 	Message   *types.SignedMessage
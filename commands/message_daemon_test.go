@@ -187,3 +187,115 @@ func TestMessageStatus(t *testing.T) {
 		assert.NotContains(t, status, "On chain")
 	})
 }
+
+func TestMessageQuery(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	d.RunSuccess("mining", "once")
+
+	t.Log("[success] at the head")
+	head := d.RunSuccess("message", "query", address.StorageMarketAddress.String(), "getTotalStorage")
+	assert.NotEmpty(t, head.ReadStdoutTrimNewlines())
+
+	t.Log("[success] at genesis")
+	genesis := d.RunSuccess("message", "query", "--height", "0", address.StorageMarketAddress.String(), "getTotalStorage")
+	assert.NotEmpty(t, genesis.ReadStdoutTrimNewlines())
+}
+
+func TestMessageShow(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	pid := th.RequireRandomPeerID(t)
+	msgCid := th.RunSuccessFirstLine(d,
+		"miner", "update-peerid",
+		"--from", fixtures.TestAddresses[0],
+		"--gas-price", "1",
+		"--gas-limit", "300",
+		fixtures.TestMiners[0],
+		pid.Pretty(),
+	)
+
+	t.Log("[success] in the message pool")
+	pending := d.RunSuccess("message", "show", "--decode", msgCid).ReadStdout()
+	assert.Contains(t, pending, "updatePeerID("+pid.String()+")")
+	assert.NotContains(t, pending, "Receipt")
+
+	d.RunSuccess("mining", "once")
+
+	t.Log("[success] on chain")
+	mined := d.RunSuccess("message", "show", "--decode", msgCid).ReadStdout()
+	assert.Contains(t, mined, "Receipt: exit code 0")
+	assert.Contains(t, mined, "updatePeerID("+pid.String()+")")
+
+	t.Log("[success] without decoding")
+	plain := d.RunSuccess("message", "show", msgCid).ReadStdout()
+	assert.NotContains(t, plain, "updatePeerID(")
+
+	t.Log("[success] decoded in chain ls")
+	chain := d.RunSuccess("chain", "ls", "--decode").ReadStdout()
+	assert.Contains(t, chain, msgCid+"\tupdatePeerID("+pid.Pretty()+")")
+
+	t.Log("[failure] unknown message")
+	d.RunFail("not found", "message", "show", types.SomeCid().String())
+}
+
+func TestMessageOfflineSigning(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	// the signer holds the sender's key and is never connected to d
+	signer := th.NewDaemon(t, th.KeyFile(fixtures.KeyFilePaths()[2])).Start()
+	defer signer.ShutdownSuccess()
+
+	from := fixtures.TestAddresses[2]
+	create := func() string {
+		return d.RunSuccess("message", "create",
+			"--from", from,
+			"--gas-price", "1",
+			"--gas-limit", "300",
+			"--value", "10",
+			fixtures.TestAddresses[3],
+		).ReadStdout()
+	}
+
+	unsigned := create()
+	var msg types.MeteredMessage
+	require.NoError(t, json.Unmarshal([]byte(unsigned), &msg))
+	assert.Equal(t, from, msg.From.String())
+	assert.Equal(t, types.NewAttoFILFromFIL(10), msg.Value)
+
+	t.Log("[failure] the online node can't sign for the sender")
+	d.RunWithStdin(strings.NewReader(unsigned), "message", "sign").AssertFail("could not find address")
+
+	t.Log("[failure] unsigned messages are not submitted")
+	d.RunWithStdin(strings.NewReader(unsigned), "message", "submit").AssertFail("does not contain a signature")
+
+	signed := signer.RunWithStdin(strings.NewReader(unsigned), "message", "sign").AssertSuccess().ReadStdout()
+	var smsg types.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signed), &smsg))
+	assert.True(t, smsg.VerifySignature())
+
+	msgCid := d.RunWithStdin(strings.NewReader(signed), "message", "submit").AssertSuccess().ReadStdoutTrimNewlines()
+	expected, err := smsg.Cid()
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), msgCid)
+
+	// the next message created takes the queued one into account
+	var next types.MeteredMessage
+	require.NoError(t, json.Unmarshal([]byte(create()), &next))
+	assert.Equal(t, msg.Nonce+1, next.Nonce)
+
+	d.RunSuccess("mining", "once")
+	d.WaitForMessageRequireSuccess(expected)
+
+	t.Log("[failure] params must match the method's signature")
+	d.RunFail("params, got 0", "message", "create", "--from", from, address.StorageMarketAddress.String(), "createStorageMiner", "[]")
+}
@@ -11,10 +11,12 @@ import (
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -26,7 +28,9 @@ var minerCmd = &cmds.Command{
 		"create":        minerCreateCmd,
 		"owner":         minerOwnerCmd,
 		"power":         minerPowerCmd,
+		"proving":       minerProvingCmd,
 		"set-price":     minerSetPriceCmd,
+		"update-addrs":  minerUpdateAddrsCmd,
 		"update-peerid": minerUpdatePeerIDCmd,
 	},
 }
@@ -365,6 +369,113 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 	},
 }
 
+// MinerUpdateAddrsResult is the return type for miner update-addrs command
+type MinerUpdateAddrsResult struct {
+	Cid     cid.Cid
+	GasUsed types.GasUnits
+	Preview bool
+}
+
+var minerUpdateAddrsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Publish the addresses the peer of a miner can be reached at",
+		ShortDescription: `Issues a new message to the network to update the addresses published in the
+miner's peer info. Without <addrs>, the relay addresses of this node are
+published, so that clients can reach a miner behind a NAT through its relays.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "Miner address to update peer addresses for"),
+		cmdkit.StringArg("addrs", false, true, "Multiaddrs the peer of the miner can be reached at"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("from", "Address to send from"),
+		priceOption,
+		limitOption,
+		previewOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+
+		var addrs []ma.Multiaddr
+		for _, s := range req.Arguments[1:] {
+			addr, err := ma.NewMultiaddr(s)
+			if err != nil {
+				return errors.Wrapf(err, "invalid peer address %s", s)
+			}
+			addrs = append(addrs, addr)
+		}
+		if len(addrs) == 0 {
+			addrs = GetPorcelainAPI(env).NetworkRelayAddrs()
+			if len(addrs) == 0 {
+				return errors.New("node has no relay addresses to publish, pass the addresses to publish explicitly")
+			}
+		}
+
+		gasPrice, gasLimit, preview, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		if preview {
+			usedGas, err := GetPorcelainAPI(env).MessagePreview(
+				req.Context,
+				fromAddr,
+				minerAddr,
+				"updatePeerAddrs",
+				addrs,
+			)
+			if err != nil {
+				return err
+			}
+
+			return re.Emit(&MinerUpdateAddrsResult{
+				Cid:     cid.Cid{},
+				GasUsed: usedGas,
+				Preview: true,
+			})
+		}
+
+		c, err := GetPorcelainAPI(env).MessageSend(
+			req.Context,
+			fromAddr,
+			minerAddr,
+			types.ZeroAttoFIL,
+			gasPrice,
+			gasLimit,
+			"updatePeerAddrs",
+			addrs,
+		)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&MinerUpdateAddrsResult{
+			Cid:     c,
+			GasUsed: types.NewGasUnits(0),
+			Preview: false,
+		})
+	},
+	Type: &MinerUpdateAddrsResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *MinerUpdateAddrsResult) error {
+			if res.Preview {
+				output := strconv.FormatUint(uint64(res.GasUsed), 10)
+				_, err := w.Write([]byte(output))
+				return err
+			}
+			return PrintString(w, res.Cid)
+		}),
+	},
+}
+
 var minerOwnerCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Show the actor address of <miner>",
@@ -407,7 +518,12 @@ var minerPowerCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Get the power of a miner versus the total storage market power",
 		ShortDescription: `Check the current power of a given miner and total power of the storage market.
-Values will be output as a ratio where the first number is the miner power and second is the total market power.`,
+Values will be output as a ratio where the first number is the miner power and second is the total market power.
+Use --tipset or --height to check the power at an earlier point of the chain.`,
+	},
+	Options: []cmdkit.Option{
+		tipSetOption,
+		heightOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		minerAddr, err := optionalAddr(req.Arguments[0])
@@ -415,8 +531,14 @@ Values will be output as a ratio where the first number is the miner power and s
 			return err
 		}
 
-		bytes, err := GetPorcelainAPI(env).MessageQuery(
+		tsKey, err := stateTipSetKey(req, env)
+		if err != nil {
+			return err
+		}
+
+		bytes, err := GetPorcelainAPI(env).MessageQueryAt(
 			req.Context,
+			tsKey,
 			address.Undef,
 			minerAddr,
 			"getPower",
@@ -426,8 +548,9 @@ Values will be output as a ratio where the first number is the miner power and s
 		}
 		power := types.NewBytesAmountFromBytes(bytes[0])
 
-		bytes, err = GetPorcelainAPI(env).MessageQuery(
+		bytes, err = GetPorcelainAPI(env).MessageQueryAt(
 			req.Context,
+			tsKey,
 			address.Undef,
 			address.StorageMarketAddress,
 			"getTotalStorage",
@@ -450,3 +573,70 @@ Values will be output as a ratio where the first number is the miner power and s
 		}),
 	},
 }
+
+var minerProvingCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the miner's proofs of spacetime",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"status": minerProvingStatusCmd,
+	},
+}
+
+var minerProvingStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state of the miner's proving periods",
+		ShortDescription: `Shows the current proving period of the node's storage miner, whether its
+PoSt has been submitted, how many attempts it took and which sectors were
+reported as faults, followed by the most recent past proving periods.`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		schedule, err := GetStorageAPI(env).ProvingStatus()
+		if err != nil {
+			return err
+		}
+		return re.Emit(schedule)
+	},
+	Type: storage.ProvingSchedule{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, schedule *storage.ProvingSchedule) error {
+			sw := NewSilentWriter(w)
+			if schedule.Current == nil {
+				sw.Println("no proving period has started")
+				return sw.Error()
+			}
+
+			period := schedule.Current
+			sw.Printf("Proving period: %s - %s\n", period.Start, period.End)
+			sw.Printf("State:          %s\n", period.State)
+			sw.Printf("Attempts:       %d\n", period.Attempts)
+			sw.Printf("Faults:         %s\n", formatFaults(period.Faults))
+			if period.MessageCid.Defined() {
+				sw.Printf("Message:        %s\n", period.MessageCid)
+			}
+			if period.LastError != "" {
+				sw.Printf("Last error:     %s\n", period.LastError)
+			}
+
+			if len(schedule.History) > 0 {
+				sw.Println("History:")
+				for i := len(schedule.History) - 1; i >= 0; i-- {
+					past := schedule.History[i]
+					sw.Printf("  %s - %s\t%s\tfaults: %s\n", past.Start, past.End, past.State, formatFaults(past.Faults))
+				}
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+func formatFaults(faults []uint64) string {
+	if len(faults) == 0 {
+		return "none"
+	}
+	ids := make([]string, len(faults))
+	for i, id := range faults {
+		ids[i] = strconv.FormatUint(id, 10)
+	}
+	return strings.Join(ids, ", ")
+}
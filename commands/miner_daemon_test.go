@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -37,6 +38,7 @@ func TestMinerHelp(t *testing.T) {
 			"miner owner <miner>                     - Show the actor address of <miner>",
 			"miner power <miner>                     - Get the power of a miner versus the total storage market power",
 			"miner set-price <storageprice> <expiry> - Set the minimum price for storage",
+			"miner update-addrs <miner> [<addrs>]... - Publish the addresses the peer of a miner can be reached at",
 			"miner update-peerid <address> <peerid>  - Change the libp2p identity that a miner is operating",
 		}
 
@@ -52,6 +54,12 @@ func TestMinerHelp(t *testing.T) {
 		assert.Contains(t, result, "Issues a new message to the network to update the miner's libp2p identity.")
 	})
 
+	t.Run("update-addrs --help shows update-addrs help", func(t *testing.T) {
+
+		result := runHelpSuccess(t, "miner", "update-addrs", "--help")
+		assert.Contains(t, result, "Issues a new message to the network to update the addresses published in the")
+	})
+
 	t.Run("owner --help shows owner help", func(t *testing.T) {
 
 		result := runHelpSuccess(t, "miner", "owner", "--help")
@@ -217,7 +225,7 @@ func TestMinerCreateSuccess(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "1", "--gas-limit", "100", "200")
+		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "1", "--gas-limit", "300", "200")
 		addr, err := address.NewFromString(strings.Trim(miner.ReadStdout(), "\n"))
 		assert.NoError(t, err)
 		assert.NotEqual(t, addr, address.Undef)
@@ -244,9 +252,15 @@ func TestMinerCreateChargesGas(t *testing.T) {
 	// make sure the FIL shows up in the MinerOwnerAccount
 	startingBalance := queryBalance(t, d, miningMinerOwnerAddr)
 
+	// the gas charged is the gas the message uses, which a preview reports
+	gas := th.RunSuccessFirstLine(d, "miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "300", "--preview", "200")
+	gasUsed, err := strconv.ParseUint(gas, 10, 64)
+	require.NoError(t, err)
+	require.True(t, gasUsed > 100)
+
 	wg.Add(1)
 	go func() {
-		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "100", "200")
+		miner := d.RunSuccess("miner", "create", "--from", fixtures.TestAddresses[2], "--gas-price", "333", "--gas-limit", "300", "200")
 		addr, err := address.NewFromString(strings.Trim(miner.ReadStdout(), "\n"))
 		assert.NoError(t, err)
 		assert.NotEqual(t, addr, address.Undef)
@@ -258,7 +272,7 @@ func TestMinerCreateChargesGas(t *testing.T) {
 
 	expectedBlockReward := consensus.NewDefaultBlockRewarder().BlockRewardAmount()
 	expectedPrice := types.NewAttoFILFromFIL(333)
-	expectedGasCost := big.NewInt(100)
+	expectedGasCost := big.NewInt(int64(gasUsed))
 	expectedBalance := expectedBlockReward.Add(expectedPrice.MulBigInt(expectedGasCost))
 	newBalance := queryBalance(t, d, miningMinerOwnerAddr)
 	assert.Equal(t, expectedBalance.String(), newBalance.Sub(startingBalance).String())
@@ -376,3 +390,41 @@ var testConfig = &gengen.GenesisCfg{
 		},
 	},
 }
+
+func TestMinerProvingStatus(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	out := d.RunSuccess("miner", "proving", "status").ReadStdoutTrimNewlines()
+	assert.Equal(t, "no proving period has started", out)
+}
+
+func TestMinerUpdateAddrs(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := makeTestDaemonWithMinerAndStart(t)
+	defer d.ShutdownSuccess()
+
+	from := fixtures.TestAddresses[0]
+	minerAddr := fixtures.TestMiners[0]
+
+	// a node that isn't behind a NAT has no relay addresses to publish
+	d.RunFail("no relay addresses", "miner", "update-addrs", "--from", from, minerAddr)
+
+	d.RunFail("invalid peer address", "miner", "update-addrs", "--from", from, minerAddr, "not-a-multiaddr")
+
+	gas := th.RunSuccessFirstLine(d,
+		"miner", "update-addrs",
+		"--from", from,
+		"--gas-price", "1",
+		"--gas-limit", "300",
+		"--preview",
+		minerAddr,
+		"/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit",
+	)
+	gasUsed, err := strconv.ParseUint(gas, 10, 64)
+	require.NoError(t, err)
+	assert.True(t, gasUsed > 0)
+}
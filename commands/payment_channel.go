@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
@@ -172,6 +173,12 @@ var lsCmd = &cmds.Command{
 				if err != nil {
 					return err
 				}
+				for _, lane := range pc.Lanes {
+					_, err := fmt.Fprintf(w, "  lane %d: redeemed: %v, nonce: %d\n", lane.ID, lane.Redeemed, lane.Nonce)
+					if err != nil {
+						return err
+					}
+				}
 			}
 			return nil
 		}),
@@ -182,6 +189,14 @@ var voucherCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Create a new voucher from a payment channel",
 		ShortDescription: `Generate a new signed payment voucher for the target of a payment channel.`,
+		LongDescription: `Generate a new signed payment voucher for the target of a payment channel.
+
+Vouchers on different lanes of a channel are redeemed independently, and the
+amount of a voucher is cumulative within its lane. A voucher's nonce may not be
+lower than the nonce of the last voucher redeemed on its lane. A voucher may
+also settle other lanes with --merge, taking over the amounts redeemed on them,
+e.g. --merge 1:3,2:0 merges lanes 1 and 2 whose latest redeemed nonces are 3
+and 0.`,
 	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("channel", true, false, "Channel id of channel from which to create voucher"),
@@ -190,6 +205,9 @@ var voucherCmd = &cmds.Command{
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("from", "Address for which to retrieve channels"),
 		cmdkit.StringOption("validat", "Smallest block height at which target can redeem"),
+		cmdkit.Uint64Option("lane", "Lane of the channel the voucher pays on"),
+		cmdkit.Uint64Option("nonce", "Nonce of the voucher within its lane"),
+		cmdkit.StringOption("merge", "Comma separated lane:nonce pairs of lanes the voucher merges"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		fromAddr, err := fromAddrOrDefault(req, env)
@@ -212,7 +230,12 @@ var voucherCmd = &cmds.Command{
 			return err
 		}
 
-		voucher, err := GetPorcelainAPI(env).PaymentChannelVoucher(req.Context, fromAddr, channel, amount, validAt, nil)
+		lane, err := voucherLaneFromOptions(req)
+		if err != nil {
+			return err
+		}
+
+		voucher, err := GetPorcelainAPI(env).PaymentChannelVoucher(req.Context, fromAddr, channel, amount, validAt, nil, lane)
 		if err != nil {
 			return err
 		}
@@ -232,6 +255,39 @@ var voucherCmd = &cmds.Command{
 	},
 }
 
+// voucherLaneFromOptions parses the lane, nonce and merge options of the
+// voucher command.
+func voucherLaneFromOptions(req *cmds.Request) (*types.VoucherLane, error) {
+	lane := &types.VoucherLane{}
+	if id, ok := req.Options["lane"].(uint64); ok {
+		lane.ID = id
+	}
+	if nonce, ok := req.Options["nonce"].(uint64); ok {
+		lane.Nonce = nonce
+	}
+
+	merges, _ := req.Options["merge"].(string)
+	if merges == "" {
+		return lane, nil
+	}
+	for _, m := range strings.Split(merges, ",") {
+		parts := strings.Split(strings.TrimSpace(m), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid merge %q, expected lane:nonce", m)
+		}
+		mergeLane, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge lane %q", parts[0])
+		}
+		mergeNonce, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge nonce %q", parts[1])
+		}
+		lane.Merges = append(lane.Merges, types.Merge{Lane: mergeLane, Nonce: mergeNonce})
+	}
+	return lane, nil
+}
+
 // RedeemResult type returned from Redeem
 type RedeemResult struct {
 	Cid     cid.Cid
@@ -276,6 +332,7 @@ var redeemCmd = &cmds.Command{
 			voucher.Amount,
 			&voucher.ValidAt,
 			voucher.Condition,
+			&voucher.Lane,
 			[]byte(voucher.Signature),
 			[]interface{}{},
 		}
@@ -451,6 +508,7 @@ var closeCmd = &cmds.Command{
 			voucher.Amount,
 			&voucher.ValidAt,
 			voucher.Condition,
+			&voucher.Lane,
 			[]byte(voucher.Signature),
 			[]interface{}{},
 		}
@@ -204,6 +204,54 @@ func TestPaymentChannelRedeemSuccess(t *testing.T) {
 	assert.Equal(t, voucherAmount, channel.AmountRedeemed)
 }
 
+func TestPaymentChannelRedeemLanes(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	ctx, env := fastesting.NewTestEnvironment(context.Background(), t, fast.FilecoinOpts{})
+
+	// Teardown after test ends
+	defer func() {
+		err := env.Teardown(ctx)
+		require.NoError(t, err)
+	}()
+
+	// Start test
+	rsrc := requireNewPaychResource(ctx, t, env)
+
+	channelExpiry := types.NewBlockHeight(20)
+	channelAmount := types.NewAttoFILFromFIL(1000)
+
+	chanid, _ := rsrc.requirePaymentChannel(ctx, t, channelAmount, channelExpiry)
+
+	redeem := func(amount types.AttoFIL, lane *types.VoucherLane) {
+		voucherStr, err := rsrc.payer.PaychVoucher(ctx, chanid, amount, fast.AOFromAddr(rsrc.payerAddr), fast.AOLane(lane))
+		require.NoError(t, err)
+
+		mcid, err := rsrc.target.PaychRedeem(ctx, voucherStr, fast.AOFromAddr(rsrc.targetAddr), fast.AOPrice(big.NewFloat(1)), fast.AOLimit(300))
+		require.NoError(t, err)
+
+		series.CtxMiningOnce(ctx)
+
+		resp, err := rsrc.target.MessageWait(ctx, mcid)
+		require.NoError(t, err)
+		assert.Equal(t, 0, int(resp.Receipt.ExitCode))
+	}
+
+	redeem(types.NewAttoFILFromFIL(10), &types.VoucherLane{ID: 1})
+	redeem(types.NewAttoFILFromFIL(20), &types.VoucherLane{ID: 2})
+
+	channels, err := rsrc.target.PaychLs(ctx, fast.AOFromAddr(rsrc.payerAddr))
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(30), channels[chanid.String()].AmountRedeemed)
+
+	// a voucher merging lane 2 into lane 1 only pays the difference
+	redeem(types.NewAttoFILFromFIL(45), &types.VoucherLane{ID: 1, Nonce: 1, Merges: []types.Merge{{Lane: 2, Nonce: 0}}})
+
+	channels, err = rsrc.target.PaychLs(ctx, fast.AOFromAddr(rsrc.payerAddr))
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(45), channels[chanid.String()].AmountRedeemed)
+}
+
 func TestPaymentChannelRedeemTooEarlyFails(t *testing.T) {
 	tf.IntegrationTest(t)
 
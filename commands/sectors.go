@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/protocol/storage"
+)
+
+var sectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect and manage the sectors of this node's storage miner",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"list":     sectorsListCmd,
+		"seal-now": sectorsSealNowCmd,
+		"status":   sectorsStatusCmd,
+	},
+}
+
+var sectorsListCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the miner's sectors",
+		ShortDescription: `Lists every sector the storage miner has written pieces into with its state
+(staged, sealing, sealed, committed or failed) and the number of pieces it holds.`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		sectors, err := GetStorageAPI(env).SectorsLs()
+		if err != nil {
+			return err
+		}
+		return re.Emit(sectors)
+	},
+	Type: []*storage.Sector{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sectors []*storage.Sector) error {
+			sw := NewSilentWriter(w)
+			for _, sector := range sectors {
+				sw.Printf("%d\t%s\t%d pieces\n", sector.ID, sector.State, len(sector.Pieces))
+			}
+			return sw.Error()
+		}),
+	},
+}
+
+var sectorsStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state and pieces of a sector",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "The id of the sector"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		sectorID, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "invalid sector id")
+		}
+
+		sector, err := GetStorageAPI(env).SectorStatus(sectorID)
+		if err != nil {
+			return err
+		}
+		return re.Emit(sector)
+	},
+	Type: storage.Sector{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sector *storage.Sector) error {
+			return printSector(NewSilentWriter(w), sector)
+		}),
+	},
+}
+
+var sectorsSealNowCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Seal a staged sector without waiting for auto-seal",
+		ShortDescription: `Schedules sealing of the staged sector <id>. The sector builder seals all
+staged sectors together, so any other staged sectors are sealed as well.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("id", true, false, "The id of the sector"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		sectorID, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "invalid sector id")
+		}
+
+		sector, err := GetStorageAPI(env).SectorSealNow(req.Context, sectorID)
+		if err != nil {
+			return err
+		}
+		return re.Emit(sector)
+	},
+	Type: storage.Sector{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, sector *storage.Sector) error {
+			return printSector(NewSilentWriter(w), sector)
+		}),
+	},
+}
+
+func printSector(sw *SilentWriter, sector *storage.Sector) error {
+	sw.Printf("Sector:  %d\n", sector.ID)
+	sw.Printf("State:   %s\n", sector.State)
+	sw.Printf("Attempt: %d\n", sector.Attempt)
+	if sector.CommitMessageCid.Defined() {
+		sw.Printf("Commit:  %s\n", sector.CommitMessageCid)
+	}
+	if sector.Error != "" {
+		sw.Printf("Error:   %s\n", sector.Error)
+	}
+	for _, retry := range sector.RetriedIn {
+		sw.Printf("Retried in sector %d\n", retry)
+	}
+	sw.Printf("Pieces:  %d\n", len(sector.Pieces))
+	for _, piece := range sector.Pieces {
+		sw.Printf("  %s\t%d bytes\tdeal %s\n", piece.PieceRef, piece.Size, piece.DealCid)
+	}
+	return sw.Error()
+}
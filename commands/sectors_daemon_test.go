@@ -0,0 +1,24 @@
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestSectors(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	out := d.RunSuccess("sectors", "list").ReadStdoutTrimNewlines()
+	assert.Equal(t, "", out)
+
+	d.RunFail("sector not found", "sectors", "status", "1")
+	d.RunFail("invalid sector id", "sectors", "status", "one")
+	d.RunFail("node is not mining", "sectors", "seal-now", "1")
+}
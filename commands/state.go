@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"io"
+	"strings"
+
+	"github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/state"
+)
+
+var stateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect and compare the state of the chain",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"diff": stateDiffCmd,
+	},
+}
+
+var stateDiffCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the actors that changed between the states of two tipsets",
+		ShortDescription: `Each tipset is given as a comma separated list of the CIDs of its blocks.
+Lists every actor that was added, removed or changed going from the state of
+the first tipset to the state of the second, with its code, head, nonce and
+balance before and after.`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("tipsetA", true, false, "Comma separated block CIDs of the tipset to diff from"),
+		cmdkit.StringArg("tipsetB", true, false, "Comma separated block CIDs of the tipset to diff to"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		tsKeyA, err := tipSetKeyFromArgs(strings.Split(req.Arguments[0], ","))
+		if err != nil {
+			return err
+		}
+		tsKeyB, err := tipSetKeyFromArgs(strings.Split(req.Arguments[1], ","))
+		if err != nil {
+			return err
+		}
+
+		changes, err := GetPorcelainAPI(env).StateDiff(req.Context, tsKeyA, tsKeyB)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if err := re.Emit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: state.ActorChange{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c *state.ActorChange) error {
+			sw := NewSilentWriter(w)
+
+			sw.Printf("%s %s\n", c.Kind, c.Address)
+			switch c.Kind {
+			case state.ActorAdded:
+				sw.Printf("\t%s\n", formatActor(c.After))
+			case state.ActorRemoved:
+				sw.Printf("\t%s\n", formatActor(c.Before))
+			default:
+				if c.CodeChanged() {
+					sw.Printf("\tcode:    %s -> %s\n", c.Before.Code, c.After.Code)
+				}
+				if c.HeadChanged() {
+					sw.Printf("\thead:    %s -> %s\n", c.Before.Head, c.After.Head)
+				}
+				if c.NonceChanged() {
+					sw.Printf("\tnonce:   %d -> %d\n", c.Before.Nonce, c.After.Nonce)
+				}
+				if c.BalanceChanged() {
+					sw.Printf("\tbalance: %s -> %s\n", c.Before.Balance, c.After.Balance)
+				}
+			}
+			return sw.Error()
+		}),
+	},
+}
@@ -0,0 +1,52 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/state"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestStateDiff(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	daemon := makeTestDaemonWithMinerAndStart(t)
+	defer daemon.ShutdownSuccess()
+
+	genesisCid := daemon.RunSuccess("chain", "ls").ReadStdoutTrimNewlines()
+	newBlockCid := daemon.RunSuccess("mining", "once", "--enc", "text").ReadStdoutTrimNewlines()
+
+	t.Run("a tipset has no changes from itself", func(t *testing.T) {
+		out := daemon.RunSuccess("state", "diff", newBlockCid, newBlockCid).ReadStdoutTrimNewlines()
+		assert.Empty(t, out)
+	})
+
+	t.Run("mining a block changes the state", func(t *testing.T) {
+		out := daemon.RunSuccess("state", "diff", genesisCid, newBlockCid, "--enc", "json").ReadStdoutTrimNewlines()
+		require.NotEmpty(t, out)
+
+		var changes []state.ActorChange
+		for _, line := range bytes.Split([]byte(out), []byte{'\n'}) {
+			var c state.ActorChange
+			require.NoError(t, json.Unmarshal(line, &c))
+			changes = append(changes, c)
+		}
+		require.NotEmpty(t, changes)
+
+		// The block reward goes to the miner owner.
+		var balanceChanged bool
+		for _, c := range changes {
+			balanceChanged = balanceChanged || c.BalanceChanged()
+		}
+		assert.True(t, balanceChanged)
+
+		text := daemon.RunSuccess("state", "diff", genesisCid, newBlockCid).ReadStdoutTrimNewlines()
+		assert.True(t, strings.Contains(text, "changed") || strings.Contains(text, "added"))
+	})
+}
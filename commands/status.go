@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"io"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/health"
+)
+
+var statusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show whether the node is ready",
+		ShortDescription: `Runs the node's readiness checks and shows their results. A node is ready
+when its chain head is as high as any head its peers report, it is connected to
+at least bootstrap.minPeerThreshold peers, its repo is writable and, if it is a
+storage miner, it has not missed a PoSt deadline. The same checks are served
+on the API server's /ready endpoint, and /health answers liveness probes.`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit(GetHealthChecker(env).Status())
+	},
+	Type: health.Status{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, status *health.Status) error {
+			sw := NewSilentWriter(w)
+			sw.Printf("Ready: %t\n", status.Ready)
+			for _, check := range status.Checks {
+				result := "ok"
+				if !check.OK {
+					result = "FAIL"
+				}
+				sw.Printf("  %-6s %-4s %s\n", check.Name, result, check.Detail)
+			}
+			return sw.Error()
+		}),
+	},
+}
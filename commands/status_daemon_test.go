@@ -0,0 +1,62 @@
+package commands_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/health"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestStatus(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	td := th.NewDaemon(t).Start()
+	defer td.ShutdownSuccess()
+
+	// a lone node with the default peer threshold is ready
+	out := td.RunSuccess("status").ReadStdout()
+	assert.Contains(t, out, "Ready: true")
+	assert.Contains(t, out, "sync")
+	assert.Contains(t, out, "repo   ok   writable")
+
+	var status health.Status
+	require.NoError(t, json.Unmarshal([]byte(td.RunSuccess("status", "--enc=json").ReadStdout()), &status))
+	assert.True(t, status.Ready)
+	require.Len(t, status.Checks, 3)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	td := th.NewDaemon(t).Start()
+	defer td.ShutdownSuccess()
+
+	maddr, err := ma.NewMultiaddr(td.CmdAddr())
+	require.NoError(t, err)
+
+	_, host, err := manet.DialArgs(maddr)
+	require.NoError(t, err)
+
+	res, err := http.Get(fmt.Sprintf("http://%s%s", host, health.HealthPath))
+	require.NoError(t, err)
+	defer res.Body.Close() // nolint: errcheck
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(fmt.Sprintf("http://%s%s", host, health.ReadyPath))
+	require.NoError(t, err)
+	defer res.Body.Close() // nolint: errcheck
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var status health.Status
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.True(t, status.Ready)
+}
@@ -3,12 +3,15 @@ package commands
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ipfs/go-ipfs-cmdkit"
 	"github.com/ipfs/go-ipfs-cmds"
 	"github.com/libp2p/go-libp2p-peer"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/net"
 )
@@ -25,7 +28,9 @@ libp2p peers on the internet.
 	},
 	Subcommands: map[string]*cmds.Command{
 		"connect": swarmConnectCmd,
+		"limits":  swarmLimitsCmd,
 		"peers":   swarmPeersCmd,
+		"protect": swarmProtectCmd,
 	},
 }
 
@@ -40,13 +45,15 @@ var swarmPeersCmd = &cmds.Command{
 		cmdkit.BoolOption("verbose", "v", "Display all extra information"),
 		cmdkit.BoolOption("streams", "Also list information about open streams for each peer"),
 		cmdkit.BoolOption("latency", "Also list information about latency to each peer"),
+		cmdkit.BoolOption("chain-info", "Also list the head, height and weight each peer last reported, with the latency and time of the report"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		verbose, _ := req.Options["verbose"].(bool)
 		latency, _ := req.Options["latency"].(bool)
 		streams, _ := req.Options["streams"].(bool)
+		chainInfo, _ := req.Options["chain-info"].(bool)
 
-		out, err := GetPorcelainAPI(env).NetworkPeers(req.Context, verbose, latency, streams)
+		out, err := GetPorcelainAPI(env).NetworkPeers(req.Context, verbose, latency, streams, chainInfo)
 		if err != nil {
 			return err
 		}
@@ -68,6 +75,10 @@ var swarmPeersCmd = &cmds.Command{
 				}
 				fmt.Fprintln(w) // nolint: errcheck
 
+				if ci := info.ChainInfo; ci != nil {
+					fmt.Fprintf(w, "  head: %s height: %d weight: %d latency: %s last seen: %s\n", ci.Head.String(), ci.Height, ci.Weight, ci.Latency, ci.LastSeen.Format(time.RFC3339)) // nolint: errcheck
+				}
+
 				for _, s := range info.Streams {
 					if s.Protocol == "" {
 						s.Protocol = "<no protocol name>"
@@ -122,3 +133,142 @@ go-filecoin swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUE
 		}),
 	},
 }
+
+// SwarmLimitsResult is the result of the swarm limits command.
+type SwarmLimitsResult struct {
+	LowWater    int
+	HighWater   int
+	GracePeriod string
+	Connections int
+}
+
+var swarmLimitsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show or change the connection limits.",
+		ShortDescription: `
+'go-filecoin swarm limits' shows the connection limits and the number of open
+connections. When the number of open connections exceeds the high water mark,
+connections to unprotected peers are closed until the low water mark is
+reached. Connections younger than the grace period are never closed.
+
+Pass --low-water, --high-water or --grace-period to change the limits until
+the node restarts. Set swarm.connMgrLowWater, swarm.connMgrHighWater and
+swarm.connMgrGracePeriod in the config to change them permanently.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.IntOption("low-water", "Number of connections to trim connections down to"),
+		cmdkit.IntOption("high-water", "Number of connections above which connections are trimmed"),
+		cmdkit.StringOption("grace-period", "How long new connections are exempt from trimming, e.g. 20s"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+
+		limits, _ := api.NetworkConnLimits()
+		changed := false
+		if low, ok := req.Options["low-water"].(int); ok {
+			limits.LowWater = low
+			changed = true
+		}
+		if high, ok := req.Options["high-water"].(int); ok {
+			limits.HighWater = high
+			changed = true
+		}
+		if grace, ok := req.Options["grace-period"].(string); ok {
+			d, err := time.ParseDuration(grace)
+			if err != nil {
+				return errors.Wrapf(err, "invalid grace period %s", grace)
+			}
+			limits.GracePeriod = d
+			changed = true
+		}
+		if changed {
+			if err := api.NetworkSetConnLimits(limits); err != nil {
+				return err
+			}
+		}
+
+		limits, conns := api.NetworkConnLimits()
+		return re.Emit(&SwarmLimitsResult{
+			LowWater:    limits.LowWater,
+			HighWater:   limits.HighWater,
+			GracePeriod: limits.GracePeriod.String(),
+			Connections: conns,
+		})
+	},
+	Type: SwarmLimitsResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *SwarmLimitsResult) error {
+			fmt.Fprintf(w, "low water:    %d\n", res.LowWater)    // nolint: errcheck
+			fmt.Fprintf(w, "high water:   %d\n", res.HighWater)   // nolint: errcheck
+			fmt.Fprintf(w, "grace period: %s\n", res.GracePeriod) // nolint: errcheck
+			fmt.Fprintf(w, "connections:  %d\n", res.Connections) // nolint: errcheck
+			return nil
+		}),
+	},
+}
+
+// SwarmProtectResult is a peer protected from connection trimming and the
+// tags it is protected under.
+type SwarmProtectResult struct {
+	Peer peer.ID
+	Tags []string
+}
+
+var swarmProtectCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Protect peers from connection trimming.",
+		ShortDescription: `
+'go-filecoin swarm protect' protects the connections to the given peers from
+being closed when the node has too many open connections, and lists the
+protected peers. Bootstrap peers, the heartbeat target and the miners of
+in-progress storage deals are always protected.
+
+Pass --remove to remove the protection of the given peers. Peers protected by
+the node itself stay protected.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peer", false, true, "ID of the peer to protect"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("remove", "Remove the protection of the given peers"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+		remove, _ := req.Options["remove"].(bool)
+
+		for _, arg := range req.Arguments {
+			p, err := peer.IDB58Decode(arg)
+			if err != nil {
+				return errors.Wrapf(err, "invalid peer id %s", arg)
+			}
+			if remove {
+				api.NetworkUnprotect(p, net.UserProtectTag)
+			} else {
+				api.NetworkProtect(p, net.UserProtectTag)
+			}
+		}
+
+		protected := api.NetworkProtected()
+		peers := make([]peer.ID, 0, len(protected))
+		for p := range protected {
+			peers = append(peers, p)
+		}
+		sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+
+		for _, p := range peers {
+			if err := re.Emit(&SwarmProtectResult{Peer: p, Tags: protected[p]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: SwarmProtectResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *SwarmProtectResult) error {
+			fmt.Fprintf(w, "%s %s\n", res.Peer.Pretty(), strings.Join(res.Tags, ",")) // nolint: errcheck
+			return nil
+		}),
+	},
+}
@@ -3,6 +3,8 @@ package commands_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	th "github.com/filecoin-project/go-filecoin/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 )
@@ -29,3 +31,43 @@ func TestSwarmConnectPeersInvalid(t *testing.T) {
 		"swarm connect /ip4/hello",
 	)
 }
+
+func TestSwarmLimits(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d := th.NewDaemon(t).Start()
+	defer d.ShutdownSuccess()
+
+	out := d.RunSuccess("swarm", "limits").ReadStdout()
+	assert.Contains(t, out, "low water:    150")
+	assert.Contains(t, out, "high water:   200")
+	assert.Contains(t, out, "grace period: 20s")
+
+	out = d.RunSuccess("swarm", "limits", "--low-water=10", "--high-water=20", "--grace-period=1m").ReadStdout()
+	assert.Contains(t, out, "low water:    10")
+	assert.Contains(t, out, "high water:   20")
+	assert.Contains(t, out, "grace period: 1m0s")
+
+	d.RunFail("must not be lower than low water", "swarm", "limits", "--high-water=5")
+}
+
+func TestSwarmProtect(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	d1 := th.NewDaemon(t, th.SwarmAddr("/ip4/0.0.0.0/tcp/6000")).Start()
+	defer d1.ShutdownSuccess()
+
+	d2 := th.NewDaemon(t, th.SwarmAddr("/ip4/0.0.0.0/tcp/6001")).Start()
+	defer d2.ShutdownSuccess()
+
+	d1.ConnectSuccess(d2)
+	id2 := d2.GetID()
+
+	out := d1.RunSuccess("swarm", "protect", id2).ReadStdout()
+	assert.Contains(t, out, id2+" user")
+
+	out = d1.RunSuccess("swarm", "protect", "--remove", id2).ReadStdout()
+	assert.NotContains(t, out, id2)
+
+	d1.RunFail("invalid peer id", "swarm", "protect", "notapeer")
+}
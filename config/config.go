@@ -17,12 +17,15 @@ import (
 
 // Config is an in memory representation of the filecoin configuration file
 type Config struct {
-	API           *APIConfig           `json:"api"`
-	Bootstrap     *BootstrapConfig     `json:"bootstrap"`
-	Datastore     *DatastoreConfig     `json:"datastore"`
-	Heartbeat     *HeartbeatConfig     `json:"heartbeat"`
-	Mining        *MiningConfig        `json:"mining"`
-	Mpool         *MessagePoolConfig   `json:"mpool"`
+	API       *APIConfig         `json:"api"`
+	Bootstrap *BootstrapConfig   `json:"bootstrap"`
+	Datastore *DatastoreConfig   `json:"datastore"`
+	Heartbeat *HeartbeatConfig   `json:"heartbeat"`
+	Mining    *MiningConfig      `json:"mining"`
+	Mpool     *MessagePoolConfig `json:"mpool"`
+	// Net names the network the node joins, which scopes its pubsub topics,
+	// DHT and hello protocol. The devnets join the same network as nodes that
+	// do not set it.
 	Net           string               `json:"net"`
 	Observability *ObservabilityConfig `json:"observability"`
 	SectorBase    *SectorBaseConfig    `json:"sectorbase"`
@@ -78,11 +81,26 @@ func newDefaultDatastoreConfig() *DatastoreConfig {
 type SwarmConfig struct {
 	Address            string `json:"address"`
 	PublicRelayAddress string `json:"public_relay_address,omitempty"`
+	// ConnMgrLowWater is the number of connections the node trims its
+	// connections down to.
+	ConnMgrLowWater int `json:"connMgrLowWater"`
+	// ConnMgrHighWater is the number of connections above which the node
+	// starts trimming its connections.
+	ConnMgrHighWater int `json:"connMgrHighWater"`
+	// ConnMgrGracePeriod is how long new connections are exempt from
+	// trimming.
+	ConnMgrGracePeriod string `json:"connMgrGracePeriod"`
+	// DisableNATPortMap disables mapping the listening port on the NAT
+	// device with UPnP or NAT-PMP.
+	DisableNATPortMap bool `json:"disableNatPortMap"`
 }
 
 func newDefaultSwarmConfig() *SwarmConfig {
 	return &SwarmConfig{
-		Address: "/ip4/0.0.0.0/tcp/6000",
+		Address:            "/ip4/0.0.0.0/tcp/6000",
+		ConnMgrLowWater:    150,
+		ConnMgrHighWater:   200,
+		ConnMgrGracePeriod: "20s",
 	}
 }
 
@@ -104,9 +122,11 @@ func newDefaultBootstrapConfig() *BootstrapConfig {
 
 // MiningConfig holds all configuration options related to mining.
 type MiningConfig struct {
-	MinerAddress            address.Address `json:"minerAddress"`
-	AutoSealIntervalSeconds uint            `json:"autoSealIntervalSeconds"`
-	StoragePrice            types.AttoFIL   `json:"storagePrice"`
+	MinerAddress            address.Address   `json:"minerAddress"`
+	AutoSealIntervalSeconds uint              `json:"autoSealIntervalSeconds"`
+	StoragePrice            types.AttoFIL     `json:"storagePrice"`
+	DealPolicy              *DealPolicyConfig `json:"dealPolicy"`
+	GasPrice                *GasPriceConfig   `json:"gasPrice"`
 }
 
 func newDefaultMiningConfig() *MiningConfig {
@@ -114,12 +134,83 @@ func newDefaultMiningConfig() *MiningConfig {
 		MinerAddress:            address.Undef,
 		AutoSealIntervalSeconds: 120,
 		StoragePrice:            types.ZeroAttoFIL,
+		DealPolicy:              newDefaultDealPolicyConfig(),
+		GasPrice:                newDefaultGasPriceConfig(),
+	}
+}
+
+// DealPolicyConfig holds the rules a miner applies to incoming storage deal
+// proposals. Zero values for the size, duration and storage limits mean no
+// limit is enforced.
+type DealPolicyConfig struct {
+	// AllowedClients, when non-empty, is the only set of clients deals are accepted from.
+	AllowedClients []address.Address `json:"allowedClients"`
+	// DeniedClients are clients whose deals are always rejected.
+	DeniedClients []address.Address `json:"deniedClients"`
+	MinPieceSize  uint64            `json:"minPieceSize"`
+	MaxPieceSize  uint64            `json:"maxPieceSize"`
+	MinDuration   uint64            `json:"minDuration"`
+	MaxDuration   uint64            `json:"maxDuration"`
+	// MaxClientStorage is the total number of bytes a single client may have
+	// in accepted deals with this miner.
+	MaxClientStorage uint64 `json:"maxClientStorage"`
+	// FilterCommand, when set, is run for every proposal with the proposal
+	// JSON on stdin. A non-zero exit status rejects the deal and the
+	// command's output is returned to the client as the reason.
+	FilterCommand string `json:"filterCommand"`
+}
+
+func newDefaultDealPolicyConfig() *DealPolicyConfig {
+	return &DealPolicyConfig{
+		AllowedClients: []address.Address{},
+		DeniedClients:  []address.Address{},
+	}
+}
+
+// GasPriceConfig controls the gas price of the messages the node sends on
+// its own behalf, such as submitPoSt, commitSector and redeem messages.
+type GasPriceConfig struct {
+	// Default is the price used when there are no recent messages to
+	// estimate from.
+	Default types.AttoFIL `json:"default"`
+	// SampleTipSets is the number of most recent tipsets whose messages are
+	// sampled, in addition to the messages in the pool.
+	SampleTipSets uint `json:"sampleTipSets"`
+	// Percentile of the sampled prices that is used as the estimate.
+	Percentile uint `json:"percentile"`
+	// MaxPrices caps the estimated price by the method the message calls.
+	// Messages calling other methods are not capped.
+	MaxPrices map[string]types.AttoFIL `json:"maxPrices"`
+}
+
+func newDefaultGasPriceConfig() *GasPriceConfig {
+	return &GasPriceConfig{
+		Default:       types.NewGasPrice(1),
+		SampleTipSets: 10,
+		Percentile:    75,
+		MaxPrices: map[string]types.AttoFIL{
+			"commitSector": types.NewGasPrice(100),
+			"redeem":       types.NewGasPrice(100),
+			"submitPoSt":   types.NewGasPrice(1000),
+		},
 	}
 }
 
 // WalletConfig holds all configuration options related to the wallet.
 type WalletConfig struct {
 	DefaultAddress address.Address `json:"defaultAddress,omitempty"`
+	// RemoteSigners are the remote signers holding keys outside the node's
+	// repo.
+	RemoteSigners []RemoteSignerConfig `json:"remoteSigners,omitempty"`
+}
+
+// RemoteSignerConfig configures a remote signer.
+type RemoteSignerConfig struct {
+	// Endpoint is either an http(s) URL or a unix socket path prefixed by
+	// unix://, e.g. unix:///var/run/filecoin-signer.sock.
+	Endpoint string `json:"endpoint"`
+	// Token authenticates the node to the signer.
+	Token string `json:"token"`
 }
 
 func newDefaultWalletConfig() *WalletConfig {
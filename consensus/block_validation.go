@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/filecoin-project/go-filecoin/clock"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
+// ErrFutureBlock is returned by ValidateSyntax for blocks timestamped after
+// the validator's current time. Such blocks may be valid once the time has
+// come, or may just be from a miner whose clock is slightly ahead.
+var ErrFutureBlock = errors.New("block timestamp is in the future")
+
 // BlockValidator defines an interface used to validate a blocks syntax and
 // semantics.
 type BlockValidator interface {
@@ -73,7 +80,7 @@ func (dv *DefaultBlockValidator) ValidateSemantic(ctx context.Context, child *ty
 func (dv *DefaultBlockValidator) ValidateSyntax(ctx context.Context, blk *types.Block) error {
 	now := uint64(dv.Now().Unix())
 	if uint64(blk.Timestamp) > now {
-		return fmt.Errorf("block %s with timestamp %d generate in future at time %d", blk.Cid().String(), blk.Timestamp, now)
+		return errors.Wrapf(ErrFutureBlock, "block %s with timestamp %d generate in future at time %d", blk.Cid().String(), blk.Timestamp, now)
 	}
 	if !blk.StateRoot.Defined() {
 		return fmt.Errorf("block %s has nil StateRoot", blk.Cid().String())
@@ -10,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
 	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/metrics/tracing"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -21,10 +22,15 @@ import (
 var (
 	// Tags
 	msgMethodKey = tag.MustNewKey("consensus/keys/message_method")
+	actorCodeKey = tag.MustNewKey("consensus/keys/actor_code")
 
 	// Timers
 	amTimer = metrics.NewTimerMs("consensus/apply_message", "Duration of message application in milliseconds", msgMethodKey)
 	pbTimer = metrics.NewTimerMs("consensus/process_block", "Duration of block processing in milliseconds")
+
+	// Counters
+	gasUsedCt = metrics.NewInt64Counter("vm/gas_used", "Gas units used by applied messages", msgMethodKey)
+	revertCt  = metrics.NewInt64Counter("vm/reverts", "Number of messages reverted by the receiving actor", actorCodeKey)
 )
 
 // BlockRewarder applies all rewards due to the miner's owner for processing a block including block reward and gas
@@ -444,6 +450,42 @@ func PreviewQueryMethod(ctx context.Context, st state.Tree, vms vm.StorageMap, t
 	return vmCtx.GasUnits(), err
 }
 
+// PreviewMessage estimates the amount of gas that will be used by msg, sent
+// with its nonce and value from its From actor, which must exist in st. Unlike
+// PreviewQueryMethod the preview transfers the message value, so it also
+// covers methods that depend on the value or the sender's actor.
+func PreviewMessage(ctx context.Context, st state.Tree, vms vm.StorageMap, msg *types.Message, optBh *types.BlockHeight) (types.GasUnits, error) {
+	// not committing or flushing storage structures guarantees changes won't make it to stored state tree or datastore
+	cachedSt := state.NewCachedStateTree(st)
+
+	fromActor, err := cachedSt.GetActor(ctx, msg.From)
+	if err != nil {
+		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to get From actor")
+	}
+	toActor, err := cachedSt.GetActor(ctx, msg.To)
+	if err != nil {
+		return types.NewGasUnits(0), errors.ApplyErrorPermanentWrapf(err, "failed to get To actor")
+	}
+
+	// Set the gas limit to the max so that the preview measures the gas the message uses.
+	gasTracker := vm.NewGasTracker()
+	gasTracker.MsgGasLimit = types.BlockGasLimit
+
+	vmCtxParams := vm.NewContextParams{
+		From:        fromActor,
+		To:          toActor,
+		Message:     msg,
+		State:       cachedSt,
+		StorageMap:  vms,
+		GasTracker:  gasTracker,
+		BlockHeight: optBh,
+	}
+	vmCtx := vm.NewVMContext(vmCtxParams)
+	_, _, err = vm.Send(ctx, vmCtx)
+
+	return vmCtx.GasUnits(), err
+}
+
 // attemptApplyMessage encapsulates the work of trying to apply the message in order
 // to make ApplyMessage more readable. The distinction is that attemptApplyMessage
 // should deal with trying to apply the message to the state tree whereas
@@ -507,10 +549,21 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 	vmCtx := vm.NewVMContext(vmCtxParams)
 
 	ret, exitCode, vmErr := vm.Send(ctx, vmCtx)
+	if errors.IsFault(vmErr) && gasTracker.OutOfGas() {
+		// Actors may report failing to access their storage as a fault, but
+		// running out of gas for the access is the message's doing and must
+		// not stop the block from being processed.
+		exitCode, vmErr = exec.ErrInsufficientGas, errors.RevertErrorWrap(vmErr, "Insufficient gas")
+	}
 	if errors.IsFault(vmErr) {
 		return nil, vmErr
 	}
 
+	gasUsedCt.Inc(ctx, int64(vmCtx.GasUnits()))
+	if vmErr != nil {
+		recordRevert(ctx, toActor)
+	}
+
 	// compute gas charge
 	gasCharge := msg.GasPrice.MulBigInt(big.NewInt(int64(vmCtx.GasUnits())))
 
@@ -524,6 +577,35 @@ func (p *DefaultProcessor) attemptApplyMessage(ctx context.Context, st *state.Ca
 	return receipt, vmErr
 }
 
+// recordRevert counts a message reverted by the actor it was sent to.
+func recordRevert(ctx context.Context, to *actor.Actor) {
+	ctx, err := tag.New(ctx, tag.Insert(actorCodeKey, actorCodeName(to)))
+	if err != nil {
+		log.Debugf("failed to insert tag for actor code: %s", err.Error())
+	}
+	revertCt.Inc(ctx, 1)
+}
+
+// actorCodeName returns the name of a builtin actor's code for use in metrics.
+func actorCodeName(a *actor.Actor) string {
+	switch {
+	case a.Empty():
+		return "empty"
+	case a.Code.Equals(types.AccountActorCodeCid):
+		return "account"
+	case a.Code.Equals(types.StorageMarketActorCodeCid):
+		return "storagemarket"
+	case a.Code.Equals(types.PaymentBrokerActorCodeCid):
+		return "paymentbroker"
+	case a.Code.Equals(types.MinerActorCodeCid):
+		return "miner"
+	case a.Code.Equals(types.BootstrapMinerActorCodeCid):
+		return "bootstrapminer"
+	default:
+		return a.Code.String()
+	}
+}
+
 // ApplyMessagesResponse is the output struct of ApplyMessages.  It exists to
 // prevent callers from mistakenly mixing up outputs of the same type.
 type ApplyMessagesResponse struct {
@@ -337,7 +337,7 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	// Stick one empty actor and one fake actor in the state tree so they can talk.
 	fromAddr, toAddr := mockSigner.Addresses[0], mockSigner.Addresses[1]
 
-	act1, act2 := th.RequireNewEmptyActor(types.NewAttoFILFromFIL(0)), th.RequireNewFakeActor(t, vms, toAddr, fakeActorCodeCid)
+	act1, act2 := th.RequireNewEmptyActor(types.NewAttoFILFromFIL(1)), th.RequireNewFakeActor(t, vms, toAddr, fakeActorCodeCid)
 	_, st := th.RequireMakeStateTree(t, cst, map[address.Address]*actor.Actor{
 		address.NetworkAddress: th.RequireNewAccountActor(t, startingNetworkBalance),
 		fromAddr:               act1,
@@ -347,7 +347,7 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	stCid, miner := mustCreateStorageMiner(ctx, t, st, vms, minerAddr, minerOwnerAddr)
 
 	msg := types.NewMessage(fromAddr, toAddr, 0, types.ZeroAttoFIL, "returnRevertError", nil)
-	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(1), types.NewGasUnits(0))
+	smsg, err := types.NewSignedMessage(*msg, &mockSigner, types.NewGasPrice(1), types.NewGasUnits(100))
 	require.NoError(t, err)
 	blk := &types.Block{
 		Height:    20,
@@ -368,13 +368,16 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	assert.Len(t, results[0].Receipt.Return, 0)
 	assert.Contains(t, results[0].ExecutionError.Error(), "boom")
 
-	// 3 & 4. That on VM error the state is rolled back and nonce is inc'd.
-	expectedAct1, expectedAct2 := th.RequireNewEmptyActor(types.NewAttoFILFromFIL(0)), th.RequireNewFakeActor(t, vms, toAddr, fakeActorCodeCid)
+	// 3 & 4. That on VM error the state is rolled back and nonce is inc'd,
+	// while the sender still pays for the gas the storage access used.
+	gasCharge := results[0].Receipt.GasAttoFIL
+	assert.False(t, gasCharge.IsZero())
+	expectedAct1, expectedAct2 := th.RequireNewEmptyActor(types.NewAttoFILFromFIL(1).Sub(gasCharge)), th.RequireNewFakeActor(t, vms, toAddr, fakeActorCodeCid)
 	expectedAct1.IncNonce()
 	blockRewardAmount := NewDefaultBlockRewarder().BlockRewardAmount()
 	expectedStCid, _ := th.RequireMakeStateTree(t, cst, map[address.Address]*actor.Actor{
 		address.NetworkAddress: th.RequireNewAccountActor(t, startingNetworkBalance.Sub(blockRewardAmount)),
-		minerOwnerAddr:         th.RequireNewEmptyActor(blockRewardAmount),
+		minerOwnerAddr:         th.RequireNewEmptyActor(blockRewardAmount.Add(gasCharge)),
 		minerAddr:              miner,
 		fromAddr:               expectedAct1,
 		toAddr:                 expectedAct2,
@@ -385,6 +388,64 @@ func TestProcessBlockVMErrors(t *testing.T) {
 	assert.True(t, expectedStCid.Equals(gotStCid))
 }
 
+func TestProcessBlockOutOfGasForStorage(t *testing.T) {
+	tf.BadUnitTestWithSideEffects(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	vms := th.VMStorage()
+
+	newAddress := address.NewForTestGetter()
+	minerAddr, ownedMinerAddr := newAddress(), newAddress()
+
+	// Install the fake actor so we can execute it.
+	fakeActorCodeCid := types.NewCidForTestGetter()()
+	builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+	defer delete(builtin.Actors, fakeActorCodeCid)
+	mockSigner, _ := types.NewMockSignersAndKeyInfo(2)
+
+	ownerAddr, fakeAddr := mockSigner.Addresses[0], mockSigner.Addresses[1]
+	_, st := th.RequireMakeStateTree(t, cst, map[address.Address]*actor.Actor{
+		address.NetworkAddress: th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(1000000)),
+		ownerAddr:              th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(1)),
+		fakeAddr:               th.RequireNewFakeActor(t, vms, fakeAddr, fakeActorCodeCid),
+	})
+	_, _ = mustCreateStorageMiner(ctx, t, st, vms, ownedMinerAddr, ownerAddr)
+	stCid, _ := mustCreateStorageMiner(ctx, t, st, vms, minerAddr, newAddress())
+
+	// The miner method pays for its own work but not for reading its storage
+	// in actor.WithState, and the fake actor method reports failing to read
+	// its storage as a fault, as builtin actors failing a lookup do.
+	changeWorker, err := abi.ToEncodedValues(ownerAddr)
+	require.NoError(t, err)
+	msg1 := types.NewMessage(ownerAddr, ownedMinerAddr, 0, types.ZeroAttoFIL, "changeWorker", changeWorker)
+	smsg1, err := types.NewSignedMessage(*msg1, &mockSigner, types.NewGasPrice(1), types.NewGasUnits(actor.DefaultGasCost))
+	require.NoError(t, err)
+	msg2 := types.NewMessage(ownerAddr, fakeAddr, 1, types.ZeroAttoFIL, "readStorageAsFault", nil)
+	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner, types.NewGasPrice(1), types.NewGasUnits(0))
+	require.NoError(t, err)
+
+	blk := &types.Block{
+		Height:    20,
+		StateRoot: stCid,
+		Messages:  []*types.SignedMessage{smsg1, smsg2},
+		Miner:     minerAddr,
+	}
+	results, err := NewDefaultProcessor().ProcessBlock(ctx, st, vms, blk, nil)
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.NotEqual(t, uint8(0), result.Receipt.ExitCode)
+		assert.True(t, errors.ShouldRevert(result.ExecutionError))
+		assert.Contains(t, result.ExecutionError.Error(), "gas cost exceeds gas limit")
+	}
+
+	owner, err := st.GetActor(ctx, ownerAddr)
+	require.NoError(t, err)
+	assert.Equal(t, types.Uint64(2), owner.Nonce)
+}
+
 func TestProcessBlockParamsLengthError(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -890,13 +951,13 @@ func TestApplyMessageChargesGas(t *testing.T) {
 		minerActor, err := st.GetActor(ctx, minerAddr)
 		require.NoError(t, err)
 
-		// miner receives (3 FIL/gas * 100 gas * 2 messages)
-		assert.Equal(t, types.NewAttoFILFromFIL(1600), minerActor.Balance)
+		// miner receives (3 FIL/gas * (100 gas * 2 messages + 10 gas for the send))
+		assert.Equal(t, types.NewAttoFILFromFIL(1630), minerActor.Balance)
 
 		accountActor, err := st.GetActor(ctx, addr0)
 		require.NoError(t, err)
 		// sender's resulting balance of FIL
-		assert.Equal(t, types.NewAttoFILFromFIL(1400), accountActor.Balance)
+		assert.Equal(t, types.NewAttoFILFromFIL(1370), accountActor.Balance)
 	})
 
 	t.Run("ApplyMessage when it sends another message with insufficient gas fails with correct message", func(t *testing.T) {
@@ -34,6 +34,10 @@ type Protocol interface {
 	// state pSt.  It returns an error if the transition is invalid.
 	RunStateTransition(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (state.Tree, error)
 
+	// ReplayTipSet re-executes the input ts on top of the parent state pSt and
+	// reports the resulting state and receipts, even if the transition is invalid.
+	ReplayTipSet(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (*ReplayResult, error)
+
 	// ValidateSyntax validates a single block is correctly formed.
 	ValidateSyntax(ctx context.Context, b *types.Block) error
 
@@ -0,0 +1,155 @@
+package consensus
+
+import (
+	"context"
+
+	"github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-ipfs-exchange-offline"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/metrics/tracing"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+// ReplayResult is the outcome of re-executing a tipset on top of its parent
+// state.
+type ReplayResult struct {
+	// TransitionErr is the error returned by RunStateTransition, if any. A
+	// tipset that replays with a non-nil TransitionErr would have been
+	// rejected by the syncer.
+	TransitionErr error
+	// State is the aggregate state computed by applying the tipset's messages
+	// to the parent state.
+	State state.Tree
+	// Store holds State. It reads through to the node's store, but what is
+	// written to it is discarded with it.
+	Store *hamt.CborIpldStore
+	// Receipts holds the receipts computed for each block of the tipset, in
+	// the same order as the blocks of the tipset.
+	Receipts [][]*types.MessageReceipt
+}
+
+// ReplayTipSet re-executes ts on top of the parent state pSt. It runs the full
+// state transition, recording rather than returning its error, and then
+// re-processes each block in isolation to recover the receipts its messages
+// produce. Unlike RunStateTransition it does not stop when the computed state
+// diverges from what the blocks record, so that callers can inspect the
+// divergence. Each run writes to its own throwaway overlay of the node's
+// stores, so runs don't see each other's writes and the replay leaves the
+// node's stores untouched. pSt is not modified.
+func (c *Expected) ReplayTipSet(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (result *ReplayResult, err error) {
+	ctx, span := trace.StartSpan(ctx, "Expected.ReplayTipSet")
+	span.AddAttributes(trace.StringAttribute("tipset", ts.String()))
+	defer tracing.AddErrorEndSpan(ctx, span, &err)
+
+	parentRoot, err := pSt.Flush(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to flush parent state")
+	}
+	newRun := func() (*Expected, state.Tree, error) {
+		bs := newOverlayBlockstore(c.bstore)
+		run := *c
+		run.bstore = bs
+		run.cstore = &hamt.CborIpldStore{Blocks: blockservice.New(bs, offline.Exchange(bs))}
+		st, err := state.LoadStateTree(ctx, run.cstore, parentRoot, builtin.Actors)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to load parent state")
+		}
+		return &run, st, nil
+	}
+
+	result = &ReplayResult{}
+
+	run, transitionSt, err := newRun()
+	if err != nil {
+		return nil, err
+	}
+	_, result.TransitionErr = run.RunStateTransition(ctx, ts, ancestors, transitionSt)
+
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		run, blkSt, err := newRun()
+		if err != nil {
+			return nil, err
+		}
+		results, err := c.processor.ProcessBlock(ctx, blkSt, vm.NewStorageMap(run.bstore), blk, ancestors)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to process block %s", blk.Cid())
+		}
+		receipts := make([]*types.MessageReceipt, len(results))
+		for j, r := range results {
+			receipts[j] = r.Receipt
+		}
+		result.Receipts = append(result.Receipts, receipts)
+
+		// A single block's state is the aggregate state of its tipset.
+		if ts.Len() == 1 {
+			result.State, result.Store = blkSt, run.cstore
+		}
+	}
+
+	if ts.Len() > 1 {
+		run, tsSt, err := newRun()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.processor.ProcessTipSet(ctx, tsSt, vm.NewStorageMap(run.bstore), ts, ancestors); err != nil {
+			return nil, errors.Wrap(err, "failed to process tipset")
+		}
+		result.State, result.Store = tsSt, run.cstore
+	}
+
+	return result, nil
+}
+
+// overlayBlockstore reads through to a base blockstore but keeps the blocks
+// put to it in memory, so they are discarded along with it.
+type overlayBlockstore struct {
+	blockstore.Blockstore
+	base blockstore.Blockstore
+}
+
+func newOverlayBlockstore(base blockstore.Blockstore) *overlayBlockstore {
+	return &overlayBlockstore{
+		Blockstore: blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore())),
+		base:       base,
+	}
+}
+
+// Has returns whether the overlay or the base store has the block.
+func (bs *overlayBlockstore) Has(c cid.Cid) (bool, error) {
+	has, err := bs.Blockstore.Has(c)
+	if err != nil || has {
+		return has, err
+	}
+	return bs.base.Has(c)
+}
+
+// Get returns the block from the overlay, or else from the base store.
+func (bs *overlayBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	blk, err := bs.Blockstore.Get(c)
+	if err == blockstore.ErrNotFound {
+		return bs.base.Get(c)
+	}
+	return blk, err
+}
+
+// GetSize returns the size of the block in the overlay, or else in the base
+// store.
+func (bs *overlayBlockstore) GetSize(c cid.Cid) (int, error) {
+	size, err := bs.Blockstore.GetSize(c)
+	if err == blockstore.ErrNotFound {
+		return bs.base.GetSize(c)
+	}
+	return size, err
+}
@@ -105,7 +105,63 @@ func (ob *Outbox) Send(ctx context.Context, from, to address.Address, value type
 		return cid.Undef, errors.Wrap(err, "failed to sign message")
 	}
 
-	err = ob.validator.Validate(ctx, signed, fromActor)
+	return ob.submit(ctx, head, signed, fromActor)
+}
+
+// SendSigned validates and sends a message signed elsewhere, retaining it in
+// the outbound message queue. The message's nonce must be the next one
+// expected from its sender, so that it does not leave a gap in the queue that
+// would hold up the sender's later messages.
+func (ob *Outbox) SendSigned(ctx context.Context, signed *types.SignedMessage) (out cid.Cid, err error) {
+	defer func() {
+		if err != nil {
+			msgSendErrCt.Inc(ctx, 1)
+		}
+	}()
+
+	ob.nonceLock.Lock()
+	defer ob.nonceLock.Unlock()
+
+	head := ob.chains.GetHead()
+
+	fromActor, err := ob.actors.GetActorAt(ctx, head, signed.From)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "no actor at address %s", signed.From)
+	}
+
+	nonce, err := nextNonce(fromActor, ob.queue, signed.From)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "failed calculating nonce for actor at %s", signed.From)
+	}
+	if uint64(signed.Nonce) != nonce {
+		return cid.Undef, errors.Errorf("invalid nonce %d, expected %d", signed.Nonce, nonce)
+	}
+
+	return ob.submit(ctx, head, signed, fromActor)
+}
+
+// NextNonce returns the nonce the next message sent from an address should
+// have, taking the messages in the outbound queue into account.
+func (ob *Outbox) NextNonce(ctx context.Context, from address.Address) (uint64, error) {
+	ob.nonceLock.Lock()
+	defer ob.nonceLock.Unlock()
+
+	fromActor, err := ob.actors.GetActorAt(ctx, ob.chains.GetHead(), from)
+	if err != nil {
+		return 0, errors.Wrapf(err, "no actor at address %s", from)
+	}
+
+	nonce, err := nextNonce(fromActor, ob.queue, from)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed calculating nonce for actor at %s", from)
+	}
+	return nonce, nil
+}
+
+// submit validates a signed message, then enqueues and publishes it.
+// ob.nonceLock must be held.
+func (ob *Outbox) submit(ctx context.Context, head types.SortedCidSet, signed *types.SignedMessage, fromActor *actor.Actor) (cid.Cid, error) {
+	err := ob.validator.Validate(ctx, signed, fromActor)
 	if err != nil {
 		return cid.Undef, errors.Wrap(err, "invalid message")
 	}
@@ -133,6 +133,107 @@ func TestOutbox(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "account or empty")
 	})
+
+	t.Run("next nonce accounts for queued messages", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := address.NewForTestGetter()()
+		queue := core.NewMessageQueue()
+		provider := &fakeProvider{}
+
+		blk := types.NewBlockForTest(nil, 1)
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		actr.Nonce = 42
+		provider.Set(t, blk, sender, actr)
+
+		ob := core.NewOutbox(w, nullValidator{}, queue, &mockPublisher{}, nullPolicy{}, provider, provider)
+
+		nonce, err := ob.NextNonce(context.Background(), sender)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), nonce)
+
+		_, err = ob.Send(context.Background(), sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), types.NewGasUnits(0), "")
+		require.NoError(t, err)
+
+		nonce, err = ob.NextNonce(context.Background(), sender)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(43), nonce)
+	})
+
+	t.Run("send signed message enqueues and calls publish", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := address.NewForTestGetter()()
+		queue := core.NewMessageQueue()
+		publisher := &mockPublisher{}
+		provider := &fakeProvider{}
+
+		blk := types.NewBlockForTest(nil, 1)
+		blk.Height = 1000
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		actr.Nonce = 42
+		provider.Set(t, blk, sender, actr)
+
+		// the outbox has no signer for the sender, the message is signed elsewhere
+		ob := core.NewOutbox(types.MockSigner{}, nullValidator{}, queue, publisher, nullPolicy{}, provider, provider)
+
+		signed, err := types.NewSignedMessage(*types.NewMessage(sender, toAddr, 42, types.ZeroAttoFIL, "", nil), w, types.NewGasPrice(1), types.NewGasUnits(0))
+		require.NoError(t, err)
+
+		c, err := ob.SendSigned(context.Background(), signed)
+		require.NoError(t, err)
+		expected, err := signed.Cid()
+		require.NoError(t, err)
+		assert.Equal(t, expected, c)
+		assert.Equal(t, signed, publisher.message)
+		assert.Equal(t, uint64(1000), queue.List(sender)[0].Stamp)
+	})
+
+	t.Run("send signed message rejects nonce gaps", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		queue := core.NewMessageQueue()
+		publisher := &mockPublisher{}
+		provider := &fakeProvider{}
+
+		blk := types.NewBlockForTest(nil, 1)
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		actr.Nonce = 42
+		provider.Set(t, blk, sender, actr)
+
+		ob := core.NewOutbox(types.MockSigner{}, nullValidator{}, queue, publisher, nullPolicy{}, provider, provider)
+
+		signed, err := types.NewSignedMessage(*types.NewMessage(sender, address.NewForTestGetter()(), 44, types.ZeroAttoFIL, "", nil), w, types.NewGasPrice(1), types.NewGasUnits(0))
+		require.NoError(t, err)
+
+		_, err = ob.SendSigned(context.Background(), signed)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid nonce 44, expected 42")
+		assert.Empty(t, queue.List(sender))
+		assert.Nil(t, publisher.message)
+	})
+
+	t.Run("send signed message rejects invalid message", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		queue := core.NewMessageQueue()
+		publisher := &mockPublisher{}
+		provider := &fakeProvider{}
+
+		blk := types.NewBlockForTest(nil, 1)
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		provider.Set(t, blk, sender, actr)
+
+		ob := core.NewOutbox(w, nullValidator{rejectMessages: true}, queue, publisher, nullPolicy{}, provider, provider)
+
+		signed, err := types.NewSignedMessage(*types.NewMessage(sender, address.NewForTestGetter()(), 0, types.ZeroAttoFIL, "", nil), w, types.NewGasPrice(1), types.NewGasUnits(0))
+		require.NoError(t, err)
+
+		_, err = ob.SendSigned(context.Background(), signed)
+		assert.Error(t, err)
+		assert.Empty(t, queue.List(sender))
+		assert.Nil(t, publisher.message)
+	})
 }
 
 // A publisher which just stores the last message published.
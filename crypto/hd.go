@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+
+	secp256k1 "github.com/ipsn/go-secp256k1"
+	"github.com/pkg/errors"
+)
+
+// HardenedKeyStart is the index of the first hardened child key. Hardened
+// children can only be derived from private keys.
+const HardenedKeyStart = uint32(0x80000000)
+
+// ErrInvalidChildKey is returned by ExtendedKey.Child for the rare indices
+// that do not produce a valid key. BIP32 callers skip to the next index.
+var ErrInvalidChildKey = errors.New("derived key is invalid")
+
+// masterKeySecret is the HMAC key BIP32 derives master keys with.
+var masterKeySecret = []byte("Bitcoin seed")
+
+// ExtendedKey is a BIP32 extended private key: a secp256k1 private key and
+// the chain code its children are derived with.
+type ExtendedKey struct {
+	Key       []byte
+	ChainCode []byte
+}
+
+// NewMasterKey derives the BIP32 master key of seed.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, masterKeySecret)
+	mac.Write(seed) // nolint: errcheck
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, ErrInvalidChildKey
+	}
+
+	return &ExtendedKey{Key: sum[:32], ChainCode: sum[32:]}, nil
+}
+
+// Child derives the child key of k at index, hardened if index is at least
+// HardenedKeyStart.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedKeyStart {
+		data = append(data, 0)
+		data = append(data, k.Key...)
+	} else {
+		data = append(data, compressedPublicKey(k.Key)...)
+	}
+	var indexBytes [4]byte
+	binary.BigEndian.PutUint32(indexBytes[:], index)
+	data = append(data, indexBytes[:]...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data) // nolint: errcheck
+	sum := mac.Sum(nil)
+
+	n := secp256k1.S256().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidChildKey
+	}
+	child := il.Add(il, new(big.Int).SetBytes(k.Key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, ErrInvalidChildKey
+	}
+
+	key := make([]byte, PrivateKeyBytes)
+	blob := child.Bytes()
+	copy(key[PrivateKeyBytes-len(blob):], blob)
+
+	return &ExtendedKey{Key: key, ChainCode: sum[32:]}, nil
+}
+
+// DeriveKey derives the key at path from seed, e.g. the path
+// m/44'/461'/0'/0/1 is []uint32{44 + HardenedKeyStart, 461 + HardenedKeyStart,
+// HardenedKeyStart, 0, 1}.
+func DeriveKey(seed []byte, path []uint32) (*ExtendedKey, error) {
+	key, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// compressedPublicKey returns the 33 byte compressed public key of sk.
+func compressedPublicKey(sk []byte) []byte {
+	x, y := secp256k1.S256().ScalarBaseMult(sk)
+
+	pk := make([]byte, 33)
+	pk[0] = 0x02 + byte(y.Bit(0))
+	blob := x.Bytes()
+	copy(pk[33-len(blob):], blob)
+	return pk
+}
@@ -0,0 +1,39 @@
+package crypto_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/crypto"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestDeriveKey(t *testing.T) {
+	tf.UnitTest(t)
+
+	// test vector 1 of BIP32
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	h := crypto.HardenedKeyStart
+	cases := []struct {
+		path      []uint32
+		key       string
+		chainCode string
+	}{
+		{nil, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{[]uint32{h}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{[]uint32{h, 1}, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{[]uint32{h, 1, h + 2, 2}, "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd"},
+	}
+
+	for _, c := range cases {
+		key, err := crypto.DeriveKey(seed, c.path)
+		require.NoError(t, err)
+		assert.Equal(t, c.key, hex.EncodeToString(key.Key))
+		assert.Equal(t, c.chainCode, hex.EncodeToString(key.ChainCode))
+	}
+}
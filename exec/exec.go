@@ -8,6 +8,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm/errors"
 )
@@ -77,6 +78,10 @@ type VMContext interface {
 	Charge(cost types.GasUnits) error
 	SampleChainRandomness(sampleHeight *types.BlockHeight) ([]byte, error)
 
+	// VerifySignature and Verifier charge the gas price of each verification.
+	VerifySignature(data []byte, signer address.Address, sig types.Signature) (bool, error)
+	Verifier() proofs.Verifier
+
 	CreateNewActor(addr address.Address, code cid.Cid, initalizationParams interface{}) error
 
 	// TODO: Remove these when Storage above is completely implemented
@@ -50,9 +50,10 @@ require (
 	github.com/libp2p/go-libp2p v0.0.16
 	github.com/libp2p/go-libp2p-autonat-svc v0.0.2
 	github.com/libp2p/go-libp2p-circuit v0.0.4
+	github.com/libp2p/go-libp2p-connmgr v0.0.3
 	github.com/libp2p/go-libp2p-crypto v0.0.1
 	github.com/libp2p/go-libp2p-host v0.0.1
-	github.com/libp2p/go-libp2p-interface-connmgr v0.0.1
+	github.com/libp2p/go-libp2p-interface-connmgr v0.0.3
 	github.com/libp2p/go-libp2p-kad-dht v0.0.8
 	github.com/libp2p/go-libp2p-kbucket v0.1.1 // indirect
 	github.com/libp2p/go-libp2p-metrics v0.0.1
@@ -82,6 +83,7 @@ require (
 	github.com/polydawn/refmt v0.0.0-20190221155625-df39d6c2d992
 	github.com/prometheus/client_golang v0.9.3-0.20190127221311-3c4408c8b829
 	github.com/stretchr/testify v1.3.0
+	github.com/tyler-smith/go-bip39 v1.0.2
 	github.com/whyrusleeping/go-logging v0.0.0-20170515211332-0457bb6b88fc
 	github.com/whyrusleeping/go-sysinfo v0.0.0-20190219211824-4a357d4b90b1
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
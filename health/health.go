@@ -0,0 +1,201 @@
+// Package health reports whether a node is alive and ready to serve, for use
+// by orchestration and monitoring.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+const (
+	// HealthPath is the path of the liveness endpoint on the API server.
+	HealthPath = "/health"
+	// ReadyPath is the path of the readiness endpoint on the API server.
+	ReadyPath = "/ready"
+)
+
+// Names of the readiness checks.
+const (
+	CheckSync  = "sync"
+	CheckPeers = "peers"
+	CheckRepo  = "repo"
+	CheckPoSt  = "post"
+)
+
+var log = logging.Logger("health")
+
+var probeKey = datastore.NewKey("/health/probe")
+
+// ProvingMiner is the part of a storage miner the readiness checks use.
+type ProvingMiner interface {
+	MissedPoStDeadline() bool
+}
+
+// Check is the result of a single readiness check.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// Status is the readiness of a node and the checks it was derived from.
+type Status struct {
+	Ready  bool     `json:"ready"`
+	Checks []*Check `json:"checks"`
+}
+
+// Deps contains the Checker's dependencies.
+type Deps struct {
+	// SyncMode returns the mode of the chain syncer
+	SyncMode func() chain.SyncMode
+	// HeadHeight returns the height of the node's chain head
+	HeadHeight func() (uint64, error)
+	// PeerHeights returns the head heights reported by peers
+	PeerHeights func() []uint64
+	// PeerCount returns the number of connected peers
+	PeerCount func() int
+	// MinPeers is the number of peers a ready node is connected to
+	MinPeers int
+	// Datastore is the repo datastore that must be writable
+	Datastore repo.Datastore
+	// StorageMiner returns the node's storage miner, or nil if it is not mining
+	StorageMiner func() ProvingMiner
+}
+
+// Checker computes the readiness of a node. A node is ready when its chain
+// is caught up, it has enough peers, its repo is writable and, if it is a
+// storage miner, it has not missed a PoSt deadline. The chain counts as
+// caught up when the syncer says so, or when the node's head is as high as
+// the median head its peers report. Peers' heights are not validated, so a
+// single peer reporting a bogus height must not keep the node unready.
+type Checker struct {
+	syncMode     func() chain.SyncMode
+	headHeight   func() (uint64, error)
+	peerHeights  func() []uint64
+	peerCount    func() int
+	minPeers     int
+	ds           repo.Datastore
+	storageMiner func() ProvingMiner
+}
+
+// NewChecker constructs a new Checker.
+func NewChecker(deps *Deps) *Checker {
+	return &Checker{
+		syncMode:     deps.SyncMode,
+		headHeight:   deps.HeadHeight,
+		peerHeights:  deps.PeerHeights,
+		peerCount:    deps.PeerCount,
+		minPeers:     deps.MinPeers,
+		ds:           deps.Datastore,
+		storageMiner: deps.StorageMiner,
+	}
+}
+
+// Status runs the readiness checks.
+func (c *Checker) Status() *Status {
+	checks := []*Check{
+		c.checkSync(),
+		c.checkPeers(),
+		c.checkRepo(),
+	}
+	if miner := c.storageMiner(); miner != nil {
+		checks = append(checks, checkPoSt(miner))
+	}
+
+	status := &Status{Ready: true, Checks: checks}
+	for _, check := range checks {
+		status.Ready = status.Ready && check.OK
+	}
+	return status
+}
+
+func (c *Checker) checkSync() *Check {
+	mode := c.syncMode()
+	if mode == chain.CaughtUp {
+		return &Check{Name: CheckSync, OK: true, Detail: mode.String()}
+	}
+
+	head, err := c.headHeight()
+	if err != nil {
+		return &Check{Name: CheckSync, OK: false, Detail: err.Error()}
+	}
+	target := medianHeight(c.peerHeights())
+	return &Check{
+		Name:   CheckSync,
+		OK:     head >= target,
+		Detail: fmt.Sprintf("%s, height %d of %d", mode, head, target),
+	}
+}
+
+// medianHeight returns the median of heights, taking the lower of the two
+// middle heights when there is an even number of them, or 0 if there are none.
+func medianHeight(heights []uint64) uint64 {
+	if len(heights) == 0 {
+		return 0
+	}
+	sorted := append([]uint64(nil), heights...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)/2]
+}
+
+func (c *Checker) checkPeers() *Check {
+	count := c.peerCount()
+	return &Check{
+		Name:   CheckPeers,
+		OK:     count >= c.minPeers,
+		Detail: fmt.Sprintf("%d peers, %d required", count, c.minPeers),
+	}
+}
+
+func (c *Checker) checkRepo() *Check {
+	check := &Check{Name: CheckRepo, OK: true, Detail: "writable"}
+	err := c.ds.Put(probeKey, []byte(time.Now().UTC().Format(time.RFC3339)))
+	if err == nil {
+		err = c.ds.Delete(probeKey)
+	}
+	if err != nil {
+		check.OK = false
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+func checkPoSt(miner ProvingMiner) *Check {
+	if miner.MissedPoStDeadline() {
+		return &Check{Name: CheckPoSt, OK: false, Detail: "missed PoSt deadline"}
+	}
+	return &Check{Name: CheckPoSt, OK: true, Detail: "no missed deadlines"}
+}
+
+// HandleHealth responds to liveness probes. A node that can answer is alive.
+func (c *Checker) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReady responds to readiness probes with the node's Status, using
+// status 503 if the node is not ready.
+func (c *Checker) HandleReady(w http.ResponseWriter, r *http.Request) {
+	status := c.Status()
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warningf("failed to write health response: %s", err)
+	}
+}
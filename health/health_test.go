@@ -0,0 +1,126 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+type fakeMiner struct {
+	missed bool
+}
+
+func (fm *fakeMiner) MissedPoStDeadline() bool {
+	return fm.missed
+}
+
+func newTestChecker(mode chain.SyncMode, peers int, miner ProvingMiner) *Checker {
+	return NewChecker(&Deps{
+		SyncMode:     func() chain.SyncMode { return mode },
+		HeadHeight:   func() (uint64, error) { return 10, nil },
+		PeerHeights:  func() []uint64 { return []uint64{12} },
+		PeerCount:    func() int { return peers },
+		MinPeers:     2,
+		Datastore:    repo.NewInMemoryRepo().Datastore(),
+		StorageMiner: func() ProvingMiner { return miner },
+	})
+}
+
+func checkNames(status *Status) []string {
+	var names []string
+	for _, check := range status.Checks {
+		names = append(names, check.Name)
+	}
+	return names
+}
+
+func TestCheckerStatus(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("ready when caught up with enough peers", func(t *testing.T) {
+		status := newTestChecker(chain.CaughtUp, 2, nil).Status()
+		assert.True(t, status.Ready)
+		assert.Equal(t, []string{CheckSync, CheckPeers, CheckRepo}, checkNames(status))
+	})
+
+	t.Run("not ready while syncing", func(t *testing.T) {
+		status := newTestChecker(chain.Syncing, 2, nil).Status()
+		assert.False(t, status.Ready)
+		assert.False(t, status.Checks[0].OK)
+		assert.Equal(t, "syncing, height 10 of 12", status.Checks[0].Detail)
+	})
+
+	t.Run("ready while syncing once level with peers", func(t *testing.T) {
+		checker := newTestChecker(chain.Syncing, 2, nil)
+		checker.peerHeights = func() []uint64 { return []uint64{10} }
+		assert.True(t, checker.Status().Ready)
+	})
+
+	t.Run("compares the head to the median peer height", func(t *testing.T) {
+		checker := newTestChecker(chain.Syncing, 2, nil)
+
+		// a single peer reporting a bogus height does not hold the node back
+		checker.peerHeights = func() []uint64 { return []uint64{1000000, 9, 10} }
+		assert.True(t, checker.Status().Ready)
+
+		checker.peerHeights = func() []uint64 { return []uint64{13, 11, 10, 12} }
+		status := checker.Status()
+		assert.False(t, status.Ready)
+		assert.Equal(t, "syncing, height 10 of 11", status.Checks[0].Detail)
+	})
+
+	t.Run("not ready with too few peers", func(t *testing.T) {
+		status := newTestChecker(chain.CaughtUp, 1, nil).Status()
+		assert.False(t, status.Ready)
+		assert.False(t, status.Checks[1].OK)
+		assert.Equal(t, "1 peers, 2 required", status.Checks[1].Detail)
+	})
+
+	t.Run("checks miners for missed PoSt deadlines", func(t *testing.T) {
+		miner := &fakeMiner{}
+		checker := newTestChecker(chain.CaughtUp, 2, miner)
+
+		status := checker.Status()
+		assert.True(t, status.Ready)
+		assert.Equal(t, []string{CheckSync, CheckPeers, CheckRepo, CheckPoSt}, checkNames(status))
+
+		miner.missed = true
+		status = checker.Status()
+		assert.False(t, status.Ready)
+		assert.False(t, status.Checks[3].OK)
+	})
+}
+
+func TestCheckerHandlers(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("health is always ok", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestChecker(chain.Syncing, 0, nil).HandleHealth(rec, httptest.NewRequest("GET", HealthPath, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("ready reports the status", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestChecker(chain.CaughtUp, 2, nil).HandleReady(rec, httptest.NewRequest("GET", ReadyPath, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var status Status
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.True(t, status.Ready)
+	})
+
+	t.Run("ready is unavailable when not ready", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestChecker(chain.Syncing, 2, nil).HandleReady(rec, httptest.NewRequest("GET", ReadyPath, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
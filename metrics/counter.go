@@ -5,6 +5,7 @@ import (
 
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 // Int64Counter wraps an opencensus int64 measure that is uses as a counter.
@@ -13,15 +14,18 @@ type Int64Counter struct {
 	view      *view.View
 }
 
-// NewInt64Counter creates a new Int64Counter with demensionless units.
-func NewInt64Counter(name, desc string) *Int64Counter {
+// NewInt64Counter creates a new Int64Counter with demensionless units. The
+// counter's value is the sum of all increments, broken down by the given tag
+// keys.
+func NewInt64Counter(name, desc string, keys ...tag.Key) *Int64Counter {
 	log.Infof("registering int64 counter: %s - %s", name, desc)
 	iMeasure := stats.Int64(name, desc, stats.UnitDimensionless)
 	iView := &view.View{
 		Name:        name,
 		Measure:     iMeasure,
 		Description: desc,
-		Aggregation: view.Count(),
+		Aggregation: view.Sum(),
+		TagKeys:     keys,
 	}
 	if err := view.Register(iView); err != nil {
 		// a panic here indicates a developer error when creating a view.
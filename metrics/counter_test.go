@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestCounterSumsIncrements(t *testing.T) {
+	tf.BadUnitTestWithSideEffects(t)
+
+	key := tag.MustNewKey("testKey")
+	testCounter := NewInt64Counter("testCounter", "testDesc", key)
+	defer view.Unregister(testCounter.view)
+
+	ctxA, err := tag.New(context.Background(), tag.Insert(key, "a"))
+	require.NoError(t, err)
+	ctxB, err := tag.New(context.Background(), tag.Insert(key, "b"))
+	require.NoError(t, err)
+
+	testCounter.Inc(ctxA, 2)
+	testCounter.Inc(ctxA, 3)
+	testCounter.Inc(ctxB, 1)
+
+	rows, err := view.RetrieveData("testCounter")
+	require.NoError(t, err)
+
+	sums := make(map[string]float64)
+	for _, row := range rows {
+		require.Len(t, row.Tags, 1)
+		sums[row.Tags[0].Value] = row.Data.(*view.SumData).Value
+	}
+	assert.Equal(t, map[string]float64{"a": 5, "b": 1}, sums)
+}
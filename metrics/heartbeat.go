@@ -2,11 +2,11 @@ package metrics
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
+	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log"
 	"github.com/libp2p/go-libp2p-host"
 	"github.com/libp2p/go-libp2p-net"
@@ -15,35 +15,71 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
 // HeartbeatProtocol is the libp2p protocol used for the heartbeat service
 const (
-	HeartbeatProtocol = "fil/heartbeat/1.0.0"
+	HeartbeatProtocol = "fil/heartbeat/2.0.0"
+	// HeartbeatVersion is the version of the Heartbeat message. It is
+	// increased whenever fields are added to heartbeats, so that aggregators
+	// can tell which fields a node reports.
+	HeartbeatVersion = 2
+	// HeartbeatProtectTag is the tag under which the heartbeat target is
+	// protected from connection trimming.
+	HeartbeatProtectTag = "heartbeat"
 	// Minutes to wait before logging connection failure at ERROR level
 	connectionFailureErrorLogPeriodMinutes = 10 * time.Minute
 )
 
 var log = logging.Logger("metrics")
 
+func init() {
+	cbor.RegisterCborType(Heartbeat{})
+}
+
 // Heartbeat contains the information required to determine the current state of a node.
 // Heartbeats are used for aggregating information about nodes in a log aggregator
-// to support alerting and devnet visualization.
+// to support alerting and devnet visualization. Heartbeats are sent as CBOR.
 type Heartbeat struct {
+	// Version is the HeartbeatVersion of the sender
+	Version uint64
 	// Head represents the heaviest tipset the nodes is mining on
 	Head string
 	// Height represents the current height of the Tipset
 	Height uint64
 	// Nickname is the nickname given to the filecoin node by the user
 	Nickname string
-	// TODO: add when implemented
-	// Syncing is `true` iff the node is currently syncing its chain with the network.
-	// Syncing bool
 
 	// Address of this node's active miner. Can be empty - will return the zero address
 	MinerAddress address.Address
+
+	// PeerCount is the number of peers the node is connected to
+	PeerCount uint64
+	// SyncMode is the mode of the chain syncer, "syncing" or "caught up"
+	SyncMode string
+	// MpoolSize is the number of messages in the message pool
+	MpoolSize uint64
+	// SealingQueueDepth is the number of sectors with deals waiting to be sealed
+	SealingQueueDepth uint64
+	// Mining is `true` iff the node is mining blocks
+	Mining bool
+	// LastPoStHeight is the end of the last proving period the miner
+	// submitted a PoSt for, zero if it never did
+	LastPoStHeight uint64
+}
+
+// NodeStatus is the telemetry a node reports in its heartbeats, in addition
+// to its chain head.
+type NodeStatus struct {
+	PeerCount         uint64
+	SyncMode          string
+	MpoolSize         uint64
+	SealingQueueDepth uint64
+	Mining            bool
+	LastPoStHeight    uint64
 }
 
 // HeartbeatService is responsible for sending heartbeats.
@@ -57,6 +93,9 @@ type HeartbeatService struct {
 	// A function that returns the miner's address
 	MinerAddressGetter func() address.Address
 
+	// A function that returns the node's telemetry
+	NodeStatusGetter func() NodeStatus
+
 	streamMu sync.Mutex
 	stream   net.Stream
 }
@@ -71,10 +110,22 @@ func WithMinerAddressGetter(ag func() address.Address) HeartbeatServiceOption {
 	}
 }
 
+// WithNodeStatusGetter returns an option that can be used to set the node
+// telemetry getter.
+func WithNodeStatusGetter(sg func() NodeStatus) HeartbeatServiceOption {
+	return func(service *HeartbeatService) {
+		service.NodeStatusGetter = sg
+	}
+}
+
 func defaultMinerAddressGetter() address.Address {
 	return address.Undef
 }
 
+func defaultNodeStatusGetter() NodeStatus {
+	return NodeStatus{}
+}
+
 // NewHeartbeatService returns a HeartbeatService
 func NewHeartbeatService(h host.Host, hbc *config.HeartbeatConfig, hg func() (types.TipSet, error), options ...HeartbeatServiceOption) *HeartbeatService {
 	srv := &HeartbeatService{
@@ -82,6 +133,7 @@ func NewHeartbeatService(h host.Host, hbc *config.HeartbeatConfig, hg func() (ty
 		Config:             hbc,
 		HeadGetter:         hg,
 		MinerAddressGetter: defaultMinerAddressGetter,
+		NodeStatusGetter:   defaultNodeStatusGetter,
 	}
 
 	for _, option := range options {
@@ -174,15 +226,14 @@ func (hbs *HeartbeatService) Run(ctx context.Context) error {
 	beatTicker := time.NewTicker(bd)
 	defer beatTicker.Stop()
 
-	// TODO use cbor instead of json
-	encoder := json.NewEncoder(hbs.stream)
+	writer := cbu.NewMsgWriter(hbs.stream)
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-beatTicker.C:
 			hb := hbs.Beat(ctx)
-			if err := encoder.Encode(hb); err != nil {
+			if err := writer.WriteMsg(hb); err != nil {
 				hbs.stream.Conn().Close() // nolint: errcheck
 				return err
 			}
@@ -203,11 +254,19 @@ func (hbs *HeartbeatService) Beat(ctx context.Context) Heartbeat {
 		log.Warningf("heartbeat service failed to get chain height: %s", err)
 	}
 	addr := hbs.MinerAddressGetter()
+	status := hbs.NodeStatusGetter()
 	return Heartbeat{
-		Head:         tipset,
-		Height:       height,
-		Nickname:     nick,
-		MinerAddress: addr,
+		Version:           HeartbeatVersion,
+		Head:              tipset,
+		Height:            height,
+		Nickname:          nick,
+		MinerAddress:      addr,
+		PeerCount:         status.PeerCount,
+		SyncMode:          status.SyncMode,
+		MpoolSize:         status.MpoolSize,
+		SealingQueueDepth: status.SealingQueueDepth,
+		Mining:            status.Mining,
+		LastPoStHeight:    status.LastPoStHeight,
 	}
 }
 
@@ -236,6 +295,7 @@ func (hbs *HeartbeatService) Connect(ctx context.Context) error {
 	targetAddr := targetMaddr.Decapsulate(targetPeerAddr)
 
 	hbs.Host.Peerstore().AddAddr(peerid, targetAddr, pstore.PermanentAddrTTL)
+	hbs.Host.ConnManager().Protect(peerid, HeartbeatProtectTag)
 
 	s, err := hbs.Host.NewStream(ctx, peerid, HeartbeatProtocol)
 	if err != nil {
@@ -3,7 +3,6 @@ package metrics_test
 import (
 	"context"
 	"crypto/rand"
-	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -18,6 +17,7 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/testhelpers"
@@ -141,20 +141,35 @@ func TestHeartbeatRunSuccess(t *testing.T) {
 	addr, err := address.NewActorAddress([]byte("miner address"))
 	require.NoError(t, err)
 
+	status := metrics.NodeStatus{
+		PeerCount:         7,
+		SyncMode:          "caught up",
+		MpoolSize:         12,
+		SealingQueueDepth: 3,
+		Mining:            true,
+		LastPoStHeight:    400,
+	}
+
 	// The handle method will run the assertions for the test
 	aggregator.Host.SetStreamHandler(metrics.HeartbeatProtocol, func(s net.Stream) {
 		defer func() {
 			require.NoError(t, s.Close())
 		}()
 
-		dec := json.NewDecoder(s)
 		var hb metrics.Heartbeat
-		require.NoError(t, dec.Decode(&hb))
+		require.NoError(t, cbu.NewMsgReader(s).ReadMsg(&hb))
 
+		assert.Equal(t, uint64(metrics.HeartbeatVersion), hb.Version)
 		assert.Equal(t, expTs.String(), hb.Head)
 		assert.Equal(t, uint64(444), hb.Height)
 		assert.Equal(t, "BobHoblaw", hb.Nickname)
 		assert.Equal(t, addr, hb.MinerAddress)
+		assert.Equal(t, status.PeerCount, hb.PeerCount)
+		assert.Equal(t, status.SyncMode, hb.SyncMode)
+		assert.Equal(t, status.MpoolSize, hb.MpoolSize)
+		assert.Equal(t, status.SealingQueueDepth, hb.SealingQueueDepth)
+		assert.Equal(t, status.Mining, hb.Mining)
+		assert.Equal(t, status.LastPoStHeight, hb.LastPoStHeight)
 		cancel()
 	})
 
@@ -172,6 +187,9 @@ func TestHeartbeatRunSuccess(t *testing.T) {
 		metrics.WithMinerAddressGetter(func() address.Address {
 			return addr
 		}),
+		metrics.WithNodeStatusGetter(func() metrics.NodeStatus {
+			return status
+		}),
 	)
 
 	require.NoError(t, hbs.Connect(ctx))
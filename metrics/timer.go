@@ -57,6 +57,12 @@ func (t *Float64Timer) Start(ctx context.Context) *Stopwatch {
 
 }
 
+// Record records a duration that was not measured with a Stopwatch, rounded
+// to milliseconds.
+func (t *Float64Timer) Record(ctx context.Context, d time.Duration) {
+	stats.Record(ctx, t.measureMs.M(float64(d.Round(time.Millisecond))/1e6))
+}
+
 // Stopwatch contains a start time and a recorder, when stopped it record the
 // duration since start time began via its recorder function.
 type Stopwatch struct {
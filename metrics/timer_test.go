@@ -3,9 +3,11 @@ package metrics
 import (
 	"context"
 	"testing"
+	"time"
 
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opencensus.io/stats/view"
 )
 
@@ -69,3 +71,20 @@ func TestMultipleTimers(t *testing.T) {
 	assert.NotEqual(t, 0, sw2.start)
 
 }
+
+func TestTimerRecord(t *testing.T) {
+	tf.BadUnitTestWithSideEffects(t)
+
+	testTimer := NewTimerMs("testRecord", "testDesc")
+	defer view.Unregister(testTimer.view)
+
+	testTimer.Record(context.Background(), 30*time.Millisecond)
+	testTimer.Record(context.Background(), 90*time.Millisecond)
+
+	rows, err := view.RetrieveData("testRecord")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	data := rows[0].Data.(*view.DistributionData)
+	assert.Equal(t, int64(2), data.Count)
+	assert.Equal(t, float64(60), data.Mean)
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
@@ -23,6 +24,11 @@ import (
 
 var log = logging.Logger("mining")
 
+var (
+	roundsCt = metrics.NewInt64Counter("mining/rounds", "Number of mining rounds in which a ticket was checked")
+	winsCt   = metrics.NewInt64Counter("mining/wins", "Number of mining rounds with a winning ticket")
+)
+
 // Output is the result of a single mining run. It has either a new
 // block or an error, mimicing the golang (retVal, error) pattern.
 // If a mining run's context is canceled there is no output.
@@ -230,8 +236,10 @@ func (w *DefaultWorker) Mine(ctx context.Context, base types.TipSet, nullBlkCoun
 		outCh <- Output{Err: err}
 		return false
 	}
+	roundsCt.Inc(ctx, 1)
 
 	if weHaveAWinner {
+		winsCt.Inc(ctx, 1)
 		next, err := w.Generate(ctx, base, ticket, proof, uint64(nullBlkCount))
 		if err == nil {
 			log.SetTag(ctx, "block", next)
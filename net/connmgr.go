@@ -0,0 +1,254 @@
+package net
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-connmgr"
+	"github.com/libp2p/go-libp2p-interface-connmgr"
+	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BootstrapProtectTag is the tag under which bootstrap peers are
+	// protected from connection trimming.
+	BootstrapProtectTag = "bootstrap"
+	// UserProtectTag is the tag under which the peers protected with
+	// `swarm protect` are protected from connection trimming.
+	UserProtectTag = "user"
+)
+
+// ConnLimits are the limits a ConnManager keeps the number of open
+// connections within.
+type ConnLimits struct {
+	// LowWater is the number of connections trimming closes connections
+	// down to.
+	LowWater int
+	// HighWater is the number of connections above which connections are
+	// trimmed.
+	HighWater int
+	// GracePeriod is how long new connections are exempt from trimming.
+	GracePeriod time.Duration
+}
+
+// Validate returns an error if the limits are inconsistent.
+func (l ConnLimits) Validate() error {
+	if l.LowWater < 0 {
+		return errors.New("low water must not be negative")
+	}
+	if l.HighWater < l.LowWater {
+		return errors.Errorf("high water (%d) must not be lower than low water (%d)", l.HighWater, l.LowWater)
+	}
+	if l.GracePeriod < 0 {
+		return errors.New("grace period must not be negative")
+	}
+	return nil
+}
+
+// ConnManager keeps the number of open connections between a low and a high
+// watermark using the libp2p basic connection manager. On top of it, it lets
+// the limits be changed at runtime and lists the protected peers.
+type ConnManager struct {
+	lk        sync.Mutex
+	limits    ConnLimits
+	mgr       *connmgr.BasicConnMgr
+	protected map[peer.ID]map[string]struct{}
+	// conns are the open connections, which are handed to a new basic
+	// connection manager when the limits change.
+	conns map[inet.Conn]inet.Network
+}
+
+var _ ifconnmgr.ConnManager = (*ConnManager)(nil)
+
+// NewConnManager returns a new ConnManager keeping connections within limits.
+func NewConnManager(limits ConnLimits) (*ConnManager, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return &ConnManager{
+		limits:    limits,
+		mgr:       newBasicConnMgr(limits),
+		protected: make(map[peer.ID]map[string]struct{}),
+		conns:     make(map[inet.Conn]inet.Network),
+	}, nil
+}
+
+func newBasicConnMgr(limits ConnLimits) *connmgr.BasicConnMgr {
+	return connmgr.NewConnManager(limits.LowWater, limits.HighWater, limits.GracePeriod)
+}
+
+// Limits returns the current connection limits.
+func (cm *ConnManager) Limits() ConnLimits {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+	return cm.limits
+}
+
+// SetLimits changes the connection limits. The open connections, their tags
+// and protections are moved to a basic connection manager with the new
+// limits, which trims them once they are older than the grace period.
+func (cm *ConnManager) SetLimits(limits ConnLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	mgr := newBasicConnMgr(limits)
+	for p, tags := range cm.protected {
+		for tag := range tags {
+			mgr.Protect(p, tag)
+		}
+	}
+	tagged := make(map[peer.ID]bool)
+	nn := mgr.Notifee()
+	for c, n := range cm.conns {
+		nn.Connected(n, c)
+		if p := c.RemotePeer(); !tagged[p] {
+			tagged[p] = true
+			if info := cm.mgr.GetTagInfo(p); info != nil {
+				for tag, val := range info.Tags {
+					mgr.TagPeer(p, tag, val)
+				}
+			}
+		}
+	}
+
+	cm.limits = limits
+	cm.mgr = mgr
+	return nil
+}
+
+// ConnCount returns the number of open connections.
+func (cm *ConnManager) ConnCount() int {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+	return len(cm.conns)
+}
+
+func (cm *ConnManager) basic() *connmgr.BasicConnMgr {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+	return cm.mgr
+}
+
+// TagPeer tags p with a value, making its connections less likely to be
+// trimmed.
+func (cm *ConnManager) TagPeer(p peer.ID, tag string, val int) {
+	cm.basic().TagPeer(p, tag, val)
+}
+
+// UntagPeer removes a tag from p.
+func (cm *ConnManager) UntagPeer(p peer.ID, tag string) {
+	cm.basic().UntagPeer(p, tag)
+}
+
+// GetTagInfo returns the tags and connections of p, or nil if p is not
+// connected.
+func (cm *ConnManager) GetTagInfo(p peer.ID) *ifconnmgr.TagInfo {
+	return cm.basic().GetTagInfo(p)
+}
+
+// Protect protects p from connection trimming under tag, until all its tags
+// are removed with Unprotect.
+func (cm *ConnManager) Protect(p peer.ID, tag string) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	tags, ok := cm.protected[p]
+	if !ok {
+		tags = make(map[string]struct{})
+		cm.protected[p] = tags
+	}
+	tags[tag] = struct{}{}
+	cm.mgr.Protect(p, tag)
+}
+
+// Unprotect removes the protection of p under tag, and returns whether p is
+// still protected under other tags.
+func (cm *ConnManager) Unprotect(p peer.ID, tag string) bool {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	cm.mgr.Unprotect(p, tag)
+	tags, ok := cm.protected[p]
+	if !ok {
+		return false
+	}
+	delete(tags, tag)
+	if len(tags) == 0 {
+		delete(cm.protected, p)
+		return false
+	}
+	return true
+}
+
+// Protected returns the protected peers and the tags they are protected
+// under.
+func (cm *ConnManager) Protected() map[peer.ID][]string {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	out := make(map[peer.ID][]string, len(cm.protected))
+	for p, tags := range cm.protected {
+		for t := range tags {
+			out[p] = append(out[p], t)
+		}
+		sort.Strings(out[p])
+	}
+	return out
+}
+
+// TrimOpenConns closes the connections of the least valuable unprotected
+// peers until the number of open connections is at the low watermark.
+func (cm *ConnManager) TrimOpenConns(ctx context.Context) {
+	cm.basic().TrimOpenConns(ctx)
+}
+
+// Notifee returns the network notifiee tracking the open connections.
+func (cm *ConnManager) Notifee() inet.Notifiee {
+	return (*connNotifee)(cm)
+}
+
+// Close releases the resources of the connection manager.
+func (cm *ConnManager) Close() error {
+	return nil
+}
+
+// connNotifee tracks the connections of a ConnManager and passes them on to
+// its basic connection manager.
+type connNotifee ConnManager
+
+func (nn *connNotifee) cm() *ConnManager {
+	return (*ConnManager)(nn)
+}
+
+// Connected records c.
+func (nn *connNotifee) Connected(n inet.Network, c inet.Conn) {
+	cm := nn.cm()
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	cm.conns[c] = n
+	cm.mgr.Notifee().Connected(n, c)
+}
+
+// Disconnected forgets c.
+func (nn *connNotifee) Disconnected(n inet.Network, c inet.Conn) {
+	cm := nn.cm()
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	delete(cm.conns, c)
+	cm.mgr.Notifee().Disconnected(n, c)
+}
+
+func (nn *connNotifee) Listen(n inet.Network, addr ma.Multiaddr)      {}
+func (nn *connNotifee) ListenClose(n inet.Network, addr ma.Multiaddr) {}
+func (nn *connNotifee) OpenedStream(inet.Network, inet.Stream)        {}
+func (nn *connNotifee) ClosedStream(inet.Network, inet.Stream)        {}
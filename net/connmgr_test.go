@@ -0,0 +1,87 @@
+package net
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestConnLimitsValidate(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.NoError(t, ConnLimits{LowWater: 1, HighWater: 2}.Validate())
+	assert.NoError(t, ConnLimits{LowWater: 2, HighWater: 2}.Validate())
+	assert.Error(t, ConnLimits{LowWater: -1, HighWater: 2}.Validate())
+	assert.Error(t, ConnLimits{LowWater: 3, HighWater: 2}.Validate())
+	assert.Error(t, ConnLimits{LowWater: 1, HighWater: 2, GracePeriod: -time.Second}.Validate())
+
+	_, err := NewConnManager(ConnLimits{LowWater: 3, HighWater: 2})
+	assert.Error(t, err)
+}
+
+func TestConnManagerProtect(t *testing.T) {
+	tf.UnitTest(t)
+
+	cm, err := NewConnManager(ConnLimits{LowWater: 1, HighWater: 2})
+	require.NoError(t, err)
+
+	p := peer.ID("peer")
+	cm.Protect(p, "a")
+	cm.Protect(p, "b")
+	assert.Equal(t, map[peer.ID][]string{p: {"a", "b"}}, cm.Protected())
+
+	assert.True(t, cm.Unprotect(p, "a"))
+	assert.False(t, cm.Unprotect(p, "b"))
+	assert.Empty(t, cm.Protected())
+	assert.False(t, cm.Unprotect(p, "b"))
+}
+
+func TestConnManagerTrimOpenConns(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.WithNPeers(ctx, 5)
+	require.NoError(t, err)
+	require.NoError(t, mn.LinkAll())
+
+	h := mn.Hosts()[0]
+	others := mn.Hosts()[1:]
+
+	cm, err := NewConnManager(ConnLimits{LowWater: 10, HighWater: 10})
+	require.NoError(t, err)
+	h.Network().Notify(cm.Notifee())
+
+	for _, o := range others {
+		_, err := mn.ConnectPeers(h.ID(), o.ID())
+		require.NoError(t, err)
+	}
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		return cm.ConnCount() == len(others), nil
+	}))
+
+	// the most valuable and the protected peer survive trimming
+	cm.TagPeer(others[0].ID(), "valuable", 10)
+	cm.Protect(others[1].ID(), "test")
+	assert.Equal(t, 10, cm.GetTagInfo(others[0].ID()).Value)
+
+	require.NoError(t, cm.SetLimits(ConnLimits{LowWater: 2, HighWater: 2}))
+	cm.TrimOpenConns(ctx)
+
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		return cm.ConnCount() == 2, nil
+	}))
+	connected := h.Network().Peers()
+	assert.Len(t, connected, 2)
+	assert.Contains(t, connected, others[0].ID())
+	assert.Contains(t, connected, others[1].ID())
+}
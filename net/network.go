@@ -24,6 +24,9 @@ type SwarmConnInfo struct {
 	Latency string
 	Muxer   string
 	Streams []SwarmStreamInfo
+	// ChainInfo is what the peer last reported about its chain, if
+	// requested and known.
+	ChainInfo *PeerChainInfo `json:",omitempty"`
 }
 
 // SwarmStreamInfo represents details about a single swarm stream.
@@ -68,6 +71,9 @@ type Network struct {
 	metrics.Reporter
 	*Router
 	*Pinger
+	connMgr     *ConnManager
+	peerMgr     *PeerManager
+	networkName string
 }
 
 // New returns a new Network
@@ -78,17 +84,29 @@ func New(
 	router *Router,
 	reporter metrics.Reporter,
 	pinger *Pinger,
+	connMgr *ConnManager,
+	peerMgr *PeerManager,
+	networkName string,
 ) *Network {
 	return &Network{
-		host:       host,
-		Pinger:     pinger,
-		Publisher:  publisher,
-		Reporter:   reporter,
-		Router:     router,
-		Subscriber: subscriber,
+		host:        host,
+		connMgr:     connMgr,
+		peerMgr:     peerMgr,
+		networkName: networkName,
+		Pinger:      pinger,
+		Publisher:   publisher,
+		Reporter:    reporter,
+		Router:      router,
+		Subscriber:  subscriber,
 	}
 }
 
+// NetworkName returns the name of the network the node is on, which scopes
+// its pubsub topics and DHT.
+func (network *Network) NetworkName() string {
+	return network.networkName
+}
+
 // GetPeerAddresses gets the current addresses of the node
 func (network *Network) GetPeerAddresses() []ma.Multiaddr {
 	return network.host.Addrs()
@@ -99,11 +117,58 @@ func (network *Network) GetPeerID() peer.ID {
 	return network.host.ID()
 }
 
+// Reachability returns whether the node can be dialed from the public
+// internet, as inferred from the addresses the node announces.
+func (network *Network) Reachability() Reachability {
+	return reachabilityOf(network.host.Addrs())
+}
+
+// RelayAddrs returns the addresses the node can be dialed at through circuit
+// relays.
+func (network *Network) RelayAddrs() []ma.Multiaddr {
+	var out []ma.Multiaddr
+	for _, addr := range network.host.Addrs() {
+		if IsRelayAddr(addr) {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
 // GetBandwidthStats gets stats on the current bandwidth usage of the network
 func (network *Network) GetBandwidthStats() metrics.Stats {
 	return network.Reporter.GetBandwidthTotals()
 }
 
+// ConnLimits returns the limits the number of open connections is kept
+// within, and the number of open connections.
+func (network *Network) ConnLimits() (ConnLimits, int) {
+	return network.connMgr.Limits(), network.connMgr.ConnCount()
+}
+
+// SetConnLimits changes the limits the number of open connections is kept
+// within.
+func (network *Network) SetConnLimits(limits ConnLimits) error {
+	return network.connMgr.SetLimits(limits)
+}
+
+// Protect protects p from connection trimming under tag.
+func (network *Network) Protect(p peer.ID, tag string) {
+	network.connMgr.Protect(p, tag)
+}
+
+// Unprotect removes the protection of p under tag, and returns whether p is
+// still protected under other tags.
+func (network *Network) Unprotect(p peer.ID, tag string) bool {
+	return network.connMgr.Unprotect(p, tag)
+}
+
+// Protected returns the peers protected from connection trimming and the tags
+// they are protected under.
+func (network *Network) Protected() map[peer.ID][]string {
+	return network.connMgr.Protected()
+}
+
 // ConnectionResult represents the result of an attempted connection from the
 // Connect method.
 type ConnectionResult struct {
@@ -148,8 +213,9 @@ func (network *Network) Connect(ctx context.Context, addrs []string) (<-chan Con
 	return outCh, nil
 }
 
-// Peers lists peers currently available on the network
-func (network *Network) Peers(ctx context.Context, verbose, latency, streams bool) (*SwarmConnInfos, error) {
+// Peers lists peers currently available on the network, with what they last
+// reported about their chains if chainInfo is set.
+func (network *Network) Peers(ctx context.Context, verbose, latency, streams, chainInfo bool) (*SwarmConnInfos, error) {
 	if network.host == nil {
 		return nil, errors.New("node must be online")
 	}
@@ -181,6 +247,11 @@ func (network *Network) Peers(ctx context.Context, verbose, latency, streams boo
 				ci.Streams = append(ci.Streams, SwarmStreamInfo{Protocol: string(s.Protocol())})
 			}
 		}
+		if verbose || chainInfo {
+			if info, ok := network.peerMgr.Get(pid); ok {
+				ci.ChainInfo = &info
+			}
+		}
 		sort.Sort(&ci)
 		out.Peers = append(out.Peers, ci)
 	}
@@ -0,0 +1,266 @@
+package net
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/filecoin-project/go-filecoin/clock"
+	"github.com/filecoin-project/go-filecoin/metrics"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+var logPeerMgr = logging.Logger("net.peermgr")
+
+var peerHeightGa = metrics.NewInt64Gauge("net/peer_max_height", "The greatest head height reported by a connected peer")
+
+// PeerChainInfo is what a peer last reported about its chain.
+type PeerChainInfo struct {
+	Peer     peer.ID
+	Head     types.SortedCidSet
+	Height   uint64
+	Weight   uint64
+	Latency  time.Duration
+	LastSeen time.Time
+}
+
+// latencyTimeout bounds the ping measuring the latency to a new peer.
+const latencyTimeout = 10 * time.Second
+
+// PeerManager records the heads connected peers report, to choose the chains
+// to sync to, and measures the latency to the peers when they first report.
+// Peers are forgotten when they disconnect.
+type PeerManager struct {
+	host   host.Host
+	pinger *Pinger
+	clock  clock.Clock
+
+	lk    sync.Mutex
+	peers map[peer.ID]*PeerChainInfo
+	// failures counts the failed attempts to sync to each reported head,
+	// keyed by the head's string. Heads no peer reports are forgotten.
+	failures map[string]int
+}
+
+// NewPeerManager returns a PeerManager of the peers connected to h, which
+// measures latencies with pinger.
+func NewPeerManager(h host.Host, pinger *Pinger, c clock.Clock) *PeerManager {
+	pm := &PeerManager{
+		host:     h,
+		pinger:   pinger,
+		clock:    c,
+		peers:    make(map[peer.ID]*PeerChainInfo),
+		failures: make(map[string]int),
+	}
+	h.Network().Notify((*peerMgrNotifee)(pm))
+	return pm
+}
+
+// UpdateHead records the head p reported.
+func (pm *PeerManager) UpdateHead(p peer.ID, head types.SortedCidSet, height, weight uint64) {
+	pm.lk.Lock()
+	_, known := pm.peers[p]
+	pm.peers[p] = &PeerChainInfo{
+		Peer:     p,
+		Head:     head,
+		Height:   height,
+		Weight:   weight,
+		LastSeen: pm.clock.Now(),
+	}
+	pm.pruneFailures()
+	maxHeight := pm.maxHeight()
+	pm.lk.Unlock()
+
+	peerHeightGa.Set(context.TODO(), int64(maxHeight))
+
+	if !known {
+		go pm.measureLatency(p)
+	}
+}
+
+// maxHeight returns the greatest height reported by a peer. pm.lk must be held.
+func (pm *PeerManager) maxHeight() uint64 {
+	var max uint64
+	for _, info := range pm.peers {
+		if info.Height > max {
+			max = info.Height
+		}
+	}
+	return max
+}
+
+// pruneFailures forgets the sync failures of heads no peer reports anymore.
+// pm.lk must be held.
+func (pm *PeerManager) pruneFailures() {
+	if len(pm.failures) == 0 {
+		return
+	}
+	reported := make(map[string]struct{}, len(pm.peers))
+	for _, info := range pm.peers {
+		reported[info.Head.String()] = struct{}{}
+	}
+	for key := range pm.failures {
+		if _, ok := reported[key]; !ok {
+			delete(pm.failures, key)
+		}
+	}
+}
+
+// measureLatency pings p once, which records the latency to p in the
+// peerstore.
+func (pm *PeerManager) measureLatency(p peer.ID) {
+	ctx, cancel := context.WithTimeout(context.Background(), latencyTimeout)
+	defer cancel()
+
+	results, err := pm.pinger.Ping(ctx, p)
+	if err != nil {
+		logPeerMgr.Debugf("failed to ping peer %s: %s", p, err)
+		return
+	}
+	select {
+	case <-results:
+	case <-ctx.Done():
+		logPeerMgr.Debugf("timed out pinging peer %s", p)
+	}
+}
+
+// Get returns what p last reported about its chain, if anything.
+func (pm *PeerManager) Get(p peer.ID) (PeerChainInfo, bool) {
+	pm.lk.Lock()
+	info, ok := pm.peers[p]
+	pm.lk.Unlock()
+
+	if !ok {
+		return PeerChainInfo{}, false
+	}
+	out := *info
+	out.Latency = pm.host.Peerstore().LatencyEWMA(p)
+	return out, true
+}
+
+// Peers returns what the peers last reported about their chains, heaviest
+// chains first.
+func (pm *PeerManager) Peers() []PeerChainInfo {
+	pm.lk.Lock()
+	out := make([]PeerChainInfo, 0, len(pm.peers))
+	for _, info := range pm.peers {
+		out = append(out, *info)
+	}
+	pm.lk.Unlock()
+
+	for i := range out {
+		out[i].Latency = pm.host.Peerstore().LatencyEWMA(out[i].Peer)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Weight != out[j].Weight {
+			return out[i].Weight > out[j].Weight
+		}
+		if out[i].Height != out[j].Height {
+			return out[i].Height > out[j].Height
+		}
+		return out[i].LastSeen.After(out[j].LastSeen)
+	})
+	return out
+}
+
+// SyncTargets returns the distinct heads peers reported with a weight above
+// minWeight, most promising first. Reported weights are not verified, so
+// heads that failed to sync fewer times come first, then heads reported by
+// more peers, and only then heavier, higher and more recently reported heads.
+func (pm *PeerManager) SyncTargets(minWeight uint64) []types.SortedCidSet {
+	type target struct {
+		head     types.SortedCidSet
+		failures int
+		peers    int
+		info     PeerChainInfo
+	}
+
+	pm.lk.Lock()
+	var targets []*target
+	byHead := make(map[string]*target)
+	for _, info := range pm.peers {
+		if info.Weight <= minWeight {
+			continue
+		}
+		key := info.Head.String()
+		t, ok := byHead[key]
+		if !ok {
+			t = &target{head: info.Head, failures: pm.failures[key], info: *info}
+			byHead[key] = t
+			targets = append(targets, t)
+		}
+		t.peers++
+		if info.Weight < t.info.Weight {
+			// trust the lowest weight reported for a head
+			t.info.Weight = info.Weight
+		}
+		if info.LastSeen.After(t.info.LastSeen) {
+			t.info.LastSeen = info.LastSeen
+		}
+	}
+	pm.lk.Unlock()
+
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if a.failures != b.failures {
+			return a.failures < b.failures
+		}
+		if a.peers != b.peers {
+			return a.peers > b.peers
+		}
+		if a.info.Weight != b.info.Weight {
+			return a.info.Weight > b.info.Weight
+		}
+		if a.info.Height != b.info.Height {
+			return a.info.Height > b.info.Height
+		}
+		return a.info.LastSeen.After(b.info.LastSeen)
+	})
+
+	out := make([]types.SortedCidSet, len(targets))
+	for i, t := range targets {
+		out[i] = t.head
+	}
+	return out
+}
+
+// SyncFailed records that syncing to head failed, which demotes head among
+// the sync targets until the peers reporting it report another head.
+func (pm *PeerManager) SyncFailed(head types.SortedCidSet) {
+	pm.lk.Lock()
+	defer pm.lk.Unlock()
+	pm.failures[head.String()]++
+	pm.pruneFailures()
+}
+
+// peerMgrNotifee forgets the peers of a PeerManager as they disconnect.
+type peerMgrNotifee PeerManager
+
+// Disconnected forgets the peer of c if it has no other connection.
+func (pn *peerMgrNotifee) Disconnected(n inet.Network, c inet.Conn) {
+	p := c.RemotePeer()
+	if n.Connectedness(p) == inet.Connected {
+		return
+	}
+	pm := (*PeerManager)(pn)
+	pm.lk.Lock()
+	delete(pm.peers, p)
+	pm.pruneFailures()
+	maxHeight := pm.maxHeight()
+	pm.lk.Unlock()
+
+	peerHeightGa.Set(context.TODO(), int64(maxHeight))
+}
+
+func (pn *peerMgrNotifee) Connected(inet.Network, inet.Conn)      {}
+func (pn *peerMgrNotifee) Listen(inet.Network, ma.Multiaddr)      {}
+func (pn *peerMgrNotifee) ListenClose(inet.Network, ma.Multiaddr) {}
+func (pn *peerMgrNotifee) OpenedStream(inet.Network, inet.Stream) {}
+func (pn *peerMgrNotifee) ClosedStream(inet.Network, inet.Stream) {}
@@ -0,0 +1,91 @@
+package net
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/clock"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestPeerManagerSyncTargets(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.FullMeshConnected(ctx, 4)
+	require.NoError(t, err)
+
+	h := mn.Hosts()[0]
+	others := mn.Hosts()[1:]
+	for _, o := range others {
+		ping.NewPingService(o)
+	}
+	pm := NewPeerManager(h, NewPinger(h, ping.NewPingService(h)), clock.NewSystemClock())
+
+	newCid := types.NewCidForTestGetter()
+	light := types.NewSortedCidSet(newCid())
+	heavy := types.NewSortedCidSet(newCid())
+
+	pm.UpdateHead(others[0].ID(), light, 5, 50)
+	pm.UpdateHead(others[1].ID(), heavy, 6, 60)
+	pm.UpdateHead(others[2].ID(), heavy, 6, 60)
+
+	info, ok := pm.Get(others[1].ID())
+	require.True(t, ok)
+	assert.Equal(t, heavy, info.Head)
+	assert.Equal(t, uint64(6), info.Height)
+	assert.Equal(t, uint64(60), info.Weight)
+
+	peers := pm.Peers()
+	require.Len(t, peers, 3)
+	assert.Equal(t, others[0].ID(), peers[2].Peer)
+
+	// heads are deduplicated and only heads heavier than the minimum count
+	assert.Equal(t, []types.SortedCidSet{heavy, light}, pm.SyncTargets(0))
+	assert.Equal(t, []types.SortedCidSet{heavy}, pm.SyncTargets(50))
+	assert.Empty(t, pm.SyncTargets(60))
+
+	// heads that failed to sync are demoted
+	pm.SyncFailed(heavy)
+	assert.Equal(t, []types.SortedCidSet{light, heavy}, pm.SyncTargets(0))
+
+	// the latency is measured when a peer first reports
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		info, _ := pm.Get(others[0].ID())
+		return info.Latency > 0, nil
+	}))
+}
+
+func TestPeerManagerForgetsDisconnectedPeers(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.FullMeshConnected(ctx, 2)
+	require.NoError(t, err)
+
+	h, other := mn.Hosts()[0], mn.Hosts()[1]
+	pm := NewPeerManager(h, NewPinger(h, ping.NewPingService(h)), clock.NewSystemClock())
+
+	pm.UpdateHead(other.ID(), types.NewSortedCidSet(types.NewCidForTestGetter()()), 1, 10)
+	_, ok := pm.Get(other.ID())
+	require.True(t, ok)
+
+	require.NoError(t, mn.DisconnectPeers(h.ID(), other.ID()))
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		_, ok := pm.Get(other.ID())
+		return !ok, nil
+	}))
+	assert.Empty(t, pm.Peers())
+}
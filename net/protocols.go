@@ -4,5 +4,7 @@ import (
 	"github.com/libp2p/go-libp2p-protocol"
 )
 
-// FilecoinDHT is the protocol of the filecoin DHT.
-const FilecoinDHT protocol.ID = "/fil/kad/devnet-3"
+// FilecoinDHT returns the protocol of the filecoin DHT of the named network.
+func FilecoinDHT(networkName string) protocol.ID {
+	return protocol.ID("/fil/kad/" + networkName)
+}
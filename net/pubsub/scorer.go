@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-peer"
+
+	"github.com/filecoin-project/go-filecoin/clock"
+)
+
+const (
+	// MisbehaviourThreshold is the number of invalid messages a peer may send
+	// within MisbehaviourWindow before it is banned.
+	MisbehaviourThreshold = 10
+
+	// MisbehaviourWindow is the period over which a peer's invalid messages
+	// are counted.
+	MisbehaviourWindow = 10 * time.Minute
+
+	// BanDuration is how long a peer that sent too many invalid messages is
+	// banned for.
+	BanDuration = time.Hour
+)
+
+// misbehaviour counts the invalid messages a peer sent since a time.
+type misbehaviour struct {
+	count int
+	since time.Time
+}
+
+// PeerScorer tracks the invalid pubsub messages peers send, and bans peers
+// that keep sending them. Banned peers are disconnected, and their messages
+// and connections are refused until the ban expires.
+type PeerScorer struct {
+	clock clock.Clock
+	onBan func(peer.ID)
+
+	lk           sync.Mutex
+	misbehaviour map[peer.ID]*misbehaviour
+	banned       map[peer.ID]time.Time
+}
+
+// NewPeerScorer returns a PeerScorer calling onBan, e.g. to disconnect the
+// peer, when a peer is banned.
+func NewPeerScorer(c clock.Clock, onBan func(peer.ID)) *PeerScorer {
+	return &PeerScorer{
+		clock:        c,
+		onBan:        onBan,
+		misbehaviour: make(map[peer.ID]*misbehaviour),
+		banned:       make(map[peer.ID]time.Time),
+	}
+}
+
+// Misbehaved records that p sent an invalid message, banning p if it sent
+// MisbehaviourThreshold invalid messages within MisbehaviourWindow.
+func (s *PeerScorer) Misbehaved(p peer.ID) {
+	s.lk.Lock()
+	now := s.clock.Now()
+	m, ok := s.misbehaviour[p]
+	if !ok || now.Sub(m.since) > MisbehaviourWindow {
+		m = &misbehaviour{since: now}
+		s.misbehaviour[p] = m
+	}
+	m.count++
+
+	ban := m.count >= MisbehaviourThreshold
+	if ban {
+		delete(s.misbehaviour, p)
+		s.banned[p] = now.Add(BanDuration)
+	}
+	s.lk.Unlock()
+
+	if ban {
+		log.Warningf("banning peer %s for %s after %d invalid messages", p, BanDuration, MisbehaviourThreshold)
+		s.onBan(p)
+	}
+}
+
+// Misbehaviour returns the number of invalid messages p sent within the
+// current window.
+func (s *PeerScorer) Misbehaviour(p peer.ID) int {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	m, ok := s.misbehaviour[p]
+	if !ok || s.clock.Now().Sub(m.since) > MisbehaviourWindow {
+		return 0
+	}
+	return m.count
+}
+
+// Banned returns true if p is banned.
+func (s *PeerScorer) Banned(p peer.ID) bool {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	until, ok := s.banned[p]
+	if !ok {
+		return false
+	}
+	if !s.clock.Now().Before(until) {
+		delete(s.banned, p)
+		return false
+	}
+	return true
+}
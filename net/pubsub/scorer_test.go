@@ -0,0 +1,64 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/net/pubsub"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestPeerScorer(t *testing.T) {
+	tf.UnitTest(t)
+
+	p := peer.ID("misbehaving")
+
+	t.Run("bans peers that send too many invalid messages", func(t *testing.T) {
+		clock := &testClock{now: time.Unix(1000, 0)}
+		var banned []peer.ID
+		scorer := pubsub.NewPeerScorer(clock, func(p peer.ID) { banned = append(banned, p) })
+
+		for i := 0; i < pubsub.MisbehaviourThreshold-1; i++ {
+			scorer.Misbehaved(p)
+		}
+		assert.Equal(t, pubsub.MisbehaviourThreshold-1, scorer.Misbehaviour(p))
+		assert.False(t, scorer.Banned(p))
+		assert.Empty(t, banned)
+
+		scorer.Misbehaved(p)
+		assert.True(t, scorer.Banned(p))
+		assert.Equal(t, []peer.ID{p}, banned)
+		assert.False(t, scorer.Banned(peer.ID("other")))
+
+		// bans expire
+		clock.now = clock.now.Add(pubsub.BanDuration)
+		assert.False(t, scorer.Banned(p))
+		assert.Equal(t, 0, scorer.Misbehaviour(p))
+	})
+
+	t.Run("forgets misbehaviour outside the window", func(t *testing.T) {
+		clock := &testClock{now: time.Unix(1000, 0)}
+		scorer := pubsub.NewPeerScorer(clock, func(p peer.ID) {})
+
+		for i := 0; i < pubsub.MisbehaviourThreshold-1; i++ {
+			scorer.Misbehaved(p)
+		}
+		clock.now = clock.now.Add(pubsub.MisbehaviourWindow + time.Second)
+		assert.Equal(t, 0, scorer.Misbehaviour(p))
+
+		scorer.Misbehaved(p)
+		assert.Equal(t, 1, scorer.Misbehaviour(p))
+		assert.False(t, scorer.Banned(p))
+	})
+}
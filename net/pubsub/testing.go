@@ -13,6 +13,11 @@ type FakeMessage struct {
 	data   []byte
 }
 
+// NewFakeMessage builds a new fake message from a peer.
+func NewFakeMessage(from peer.ID, data []byte) *FakeMessage {
+	return &FakeMessage{peerID: from, data: data}
+}
+
 // GetFrom returns the message's sender ID
 func (m *FakeMessage) GetFrom() peer.ID {
 	return m.peerID
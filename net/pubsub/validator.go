@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"context"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-peer"
+	libp2p "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Logger("net/pubsub")
+
+// Validator validates the messages of a pubsub topic before they are
+// delivered to subscribers or propagated to other peers.
+type Validator interface {
+	// Topic returns the topic whose messages are validated.
+	Topic() string
+	// Validate returns an error if msg should be dropped. Errors made with
+	// Invalid count against the peer that sent msg.
+	Validate(ctx context.Context, msg Message) error
+}
+
+// invalidError marks an error caused by data a well behaved peer never sends,
+// as opposed to data that is merely stale or not valid yet.
+type invalidError struct {
+	error
+}
+
+// Invalid marks err as caused by data a well behaved peer never sends, e.g.
+// undecodable or badly signed data, so that it counts against the peer
+// sending it.
+func Invalid(err error) error {
+	return invalidError{err}
+}
+
+// IsInvalid is true of errors made with Invalid.
+func IsInvalid(err error) bool {
+	_, ok := errors.Cause(err).(invalidError)
+	return ok
+}
+
+// RegisterValidators registers validators with ps. Messages failing
+// validation are dropped, and the peers that published invalid messages are
+// reported to scorer. Messages published by peers banned by scorer are dropped
+// unvalidated. Since a message names its publisher in its From field, ps must
+// be created with strict signature verification so that the field can't be
+// forged to get other peers banned.
+func RegisterValidators(ps *libp2p.PubSub, self peer.ID, scorer *PeerScorer, validators ...Validator) error {
+	for _, v := range validators {
+		v := v
+		err := ps.RegisterTopicValidator(v.Topic(), func(ctx context.Context, _ peer.ID, msg *libp2p.Message) bool {
+			return validate(ctx, self, scorer, v, msg)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to register validator for topic %s", v.Topic())
+		}
+	}
+	return nil
+}
+
+func validate(ctx context.Context, self peer.ID, scorer *PeerScorer, v Validator, msg Message) bool {
+	from := msg.GetFrom()
+	if from != self && scorer.Banned(from) {
+		return false
+	}
+
+	err := v.Validate(ctx, msg)
+	if err == nil {
+		return true
+	}
+
+	log.Debugf("dropping message on %s from %s: %s", v.Topic(), from, err)
+	if IsInvalid(err) && from != self {
+		scorer.Misbehaved(from)
+	}
+	return false
+}
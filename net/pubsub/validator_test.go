@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/clock"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+type fakeValidator struct {
+	err error
+}
+
+func (fv *fakeValidator) Topic() string {
+	return "topic"
+}
+
+func (fv *fakeValidator) Validate(ctx context.Context, msg Message) error {
+	return fv.err
+}
+
+func TestValidate(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	self := peer.ID("self")
+	from := peer.ID("sender")
+
+	t.Run("accepts valid messages", func(t *testing.T) {
+		scorer := NewPeerScorer(clock.NewSystemClock(), func(peer.ID) {})
+		assert.True(t, validate(ctx, self, scorer, &fakeValidator{}, NewFakeMessage(from, nil)))
+		assert.Equal(t, 0, scorer.Misbehaviour(from))
+	})
+
+	t.Run("penalizes invalid messages", func(t *testing.T) {
+		scorer := NewPeerScorer(clock.NewSystemClock(), func(peer.ID) {})
+		v := &fakeValidator{err: Invalid(errors.New("garbage"))}
+		assert.False(t, validate(ctx, self, scorer, v, NewFakeMessage(from, nil)))
+		assert.Equal(t, 1, scorer.Misbehaviour(from))
+
+		// messages from ourselves are never penalized
+		assert.False(t, validate(ctx, self, scorer, v, NewFakeMessage(self, nil)))
+		assert.Equal(t, 0, scorer.Misbehaviour(self))
+	})
+
+	t.Run("drops other failing messages without penalty", func(t *testing.T) {
+		scorer := NewPeerScorer(clock.NewSystemClock(), func(peer.ID) {})
+		v := &fakeValidator{err: errors.New("stale")}
+		assert.False(t, validate(ctx, self, scorer, v, NewFakeMessage(from, nil)))
+		assert.Equal(t, 0, scorer.Misbehaviour(from))
+	})
+
+	t.Run("drops messages from banned peers", func(t *testing.T) {
+		scorer := NewPeerScorer(clock.NewSystemClock(), func(peer.ID) {})
+		for i := 0; i < MisbehaviourThreshold; i++ {
+			scorer.Misbehaved(from)
+		}
+		assert.False(t, validate(ctx, self, scorer, &fakeValidator{}, NewFakeMessage(from, nil)))
+	})
+
+	t.Run("wrapped invalid errors are invalid", func(t *testing.T) {
+		assert.True(t, IsInvalid(errors.Wrap(Invalid(errors.New("garbage")), "context")))
+		assert.False(t, IsInvalid(errors.New("stale")))
+	})
+
+}
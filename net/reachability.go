@@ -0,0 +1,45 @@
+package net
+
+import (
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// Reachability is whether a node can be dialed from the public internet.
+type Reachability string
+
+const (
+	// ReachabilityUnknown means the reachability of the node has not been
+	// determined yet, e.g. because no peer running the AutoNAT service has
+	// dialed it back.
+	ReachabilityUnknown = Reachability("unknown")
+	// ReachabilityPublic means the node announces a public address peers
+	// can dial it at directly.
+	ReachabilityPublic = Reachability("public")
+	// ReachabilityPrivate means the node is behind a NAT or a firewall, and
+	// can only be dialed through a relay.
+	ReachabilityPrivate = Reachability("private")
+)
+
+// reachabilityOf infers the reachability of a node from the addresses its
+// host announces. Autorelay runs AutoNAT, and once it finds the node is not
+// dialable, announces relay addresses instead of the node's public ones.
+func reachabilityOf(addrs []ma.Multiaddr) Reachability {
+	reachability := ReachabilityUnknown
+	for _, addr := range addrs {
+		if IsRelayAddr(addr) {
+			return ReachabilityPrivate
+		}
+		if manet.IsPublicAddr(addr) {
+			reachability = ReachabilityPublic
+		}
+	}
+	return reachability
+}
+
+// IsRelayAddr returns whether addr dials a peer through a circuit relay.
+func IsRelayAddr(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(circuit.P_CIRCUIT)
+	return err == nil
+}
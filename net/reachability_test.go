@@ -0,0 +1,47 @@
+package net
+
+import (
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestReachabilityOf(t *testing.T) {
+	tf.UnitTest(t)
+
+	addrs := func(ss ...string) []ma.Multiaddr {
+		var out []ma.Multiaddr
+		for _, s := range ss {
+			addr, err := ma.NewMultiaddr(s)
+			require.NoError(t, err)
+			out = append(out, addr)
+		}
+		return out
+	}
+
+	local := "/ip4/127.0.0.1/tcp/6000"
+	private := "/ip4/192.168.1.2/tcp/6000"
+	public := "/ip4/1.2.3.4/tcp/6000"
+	relayed := "/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit"
+
+	assert.Equal(t, ReachabilityUnknown, reachabilityOf(nil))
+	assert.Equal(t, ReachabilityUnknown, reachabilityOf(addrs(local, private)))
+	assert.Equal(t, ReachabilityPublic, reachabilityOf(addrs(local, private, public)))
+	assert.Equal(t, ReachabilityPrivate, reachabilityOf(addrs(local, private, relayed)))
+}
+
+func TestIsRelayAddr(t *testing.T) {
+	tf.UnitTest(t)
+
+	direct, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/6000")
+	require.NoError(t, err)
+	relayed, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	require.NoError(t, err)
+
+	assert.False(t, IsRelayAddr(direct))
+	assert.True(t, IsRelayAddr(relayed))
+}
@@ -1,7 +1,35 @@
 package net
 
-// BlockTopic is the network pubsub topic identifier on which new blocks are announced.
-const BlockTopic = "/fil/blocks/devnet-3"
+// DefaultNetworkName is the name of the network a node joins when none is
+// configured.
+const DefaultNetworkName = "devnet-3"
 
-// MessageTopic is the network pubsub topic identifier on which new messages are announced.
-const MessageTopic = "/fil/msgs/devnet-3"
+// devnets are the networks that shared DefaultNetworkName before the network
+// name was taken from the node's config.
+var devnets = map[string]bool{
+	"devnet-test":    true,
+	"devnet-nightly": true,
+	"devnet-user":    true,
+}
+
+// NetworkName returns the name of the network joined by a node configured
+// with the given net. Nodes without a net and the devnets join
+// DefaultNetworkName.
+func NetworkName(configNet string) string {
+	if configNet == "" || devnets[configNet] {
+		return DefaultNetworkName
+	}
+	return configNet
+}
+
+// BlockTopic returns the pubsub topic identifier on which new blocks are
+// announced on the named network.
+func BlockTopic(networkName string) string {
+	return "/fil/blocks/" + networkName
+}
+
+// MessageTopic returns the pubsub topic identifier on which new messages are
+// announced on the named network.
+func MessageTopic(networkName string) string {
+	return "/fil/msgs/" + networkName
+}
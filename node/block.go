@@ -2,16 +2,22 @@ package node
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
 
+	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/metrics/tracing"
 	"github.com/filecoin-project/go-filecoin/net"
 	"github.com/filecoin-project/go-filecoin/net/pubsub"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
+// blockPropagationTimer records how long after they were mined blocks arrive
+// from the network. Block timestamps have a resolution of one second.
+var blockPropagationTimer = metrics.NewTimerMs("mining/block_propagation_delay", "Delay between mining a block and receiving it from the network in milliseconds")
+
 // AddNewBlock receives a newly mined block and stores, validates and propagates it to the network.
 func (node *Node) AddNewBlock(ctx context.Context, b *types.Block) (err error) {
 	ctx, span := trace.StartSpan(ctx, "Node.AddNewBlock")
@@ -33,7 +39,7 @@ func (node *Node) AddNewBlock(ctx context.Context, b *types.Block) (err error) {
 
 	// TODO: should this just be a cid? Right now receivers ask to fetch
 	// the block over bitswap anyway.
-	return node.PorcelainAPI.PubSubPublish(net.BlockTopic, b.ToNode().RawData())
+	return node.PorcelainAPI.PubSubPublish(net.BlockTopic(node.PorcelainAPI.NetworkName()), b.ToNode().RawData())
 }
 
 func (node *Node) processBlock(ctx context.Context, pubSubMsg pubsub.Message) (err error) {
@@ -51,6 +57,7 @@ func (node *Node) processBlock(ctx context.Context, pubSubMsg pubsub.Message) (e
 	}
 	span.AddAttributes(trace.StringAttribute("block", blk.Cid().String()))
 
+	blockPropagationTimer.Record(ctx, time.Since(time.Unix(int64(blk.Timestamp), 0)))
 	log.Infof("Received new block from network cid: %s", blk.Cid().String())
 	log.Debugf("Received new block from network: %s", blk)
 
@@ -5,6 +5,7 @@ import (
 	ci "github.com/libp2p/go-libp2p-crypto"
 	errors "github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/net"
 	"github.com/filecoin-project/go-filecoin/repo"
 )
 
@@ -25,6 +26,8 @@ func OptionsFromRepo(r repo.Repo) ([]ConfigOpt, error) {
 		),
 	}
 
+	cfgopts = append(cfgopts, NetworkName(net.NetworkName(cfg.Net)))
+
 	dsopt := func(c *Config) error {
 		c.Repo = r
 		return nil
@@ -24,6 +24,7 @@ type InitCfg struct {
 	PeerKey                 ci.PrivKey
 	DefaultWalletAddress    address.Address
 	AutoSealIntervalSeconds uint
+	Mnemonic                string
 }
 
 // InitOpt is an init option function
@@ -51,6 +52,14 @@ func AutoSealIntervalSecondsOpt(autoSealIntervalSeconds uint) InitOpt {
 	}
 }
 
+// MnemonicOpt returns a config option that makes the wallet derive its
+// addresses from the given mnemonic.
+func MnemonicOpt(mnemonic string) InitOpt {
+	return func(c *InitCfg) {
+		c.Mnemonic = mnemonic
+	}
+}
+
 // Init initializes a filecoin node in the given repo.
 func Init(ctx context.Context, r repo.Repo, gen consensus.GenesisInitFunc, opts ...InitOpt) error {
 	cfg := new(InitCfg)
@@ -58,6 +67,15 @@ func Init(ctx context.Context, r repo.Repo, gen consensus.GenesisInitFunc, opts
 		o(cfg)
 	}
 
+	// Check the mnemonic before the chain and keystore are initialized.
+	var seed []byte
+	if cfg.Mnemonic != "" {
+		var err error
+		if seed, err = wallet.SeedFromMnemonic(cfg.Mnemonic); err != nil {
+			return err
+		}
+	}
+
 	bs := bstore.NewBlockstore(r.Datastore())
 	cst := &hamt.CborIpldStore{Blocks: bserv.New(bs, offline.Exchange(bs))}
 
@@ -79,6 +97,12 @@ func Init(ctx context.Context, r repo.Repo, gen consensus.GenesisInitFunc, opts
 		return errors.Wrap(err, "failed to store private key")
 	}
 
+	if seed != nil {
+		if err := setWalletSeed(r, seed); err != nil {
+			return errors.Wrap(err, "failed to set wallet seed")
+		}
+	}
+
 	newConfig := r.Config()
 
 	newConfig.Mining.AutoSealIntervalSeconds = cfg.AutoSealIntervalSeconds
@@ -133,3 +157,13 @@ func newAddress(r repo.Repo) (address.Address, error) {
 
 	return addr, err
 }
+
+// setWalletSeed makes the wallet derive its addresses from seed.
+func setWalletSeed(r repo.Repo, seed []byte) error {
+	backend, err := wallet.NewDSBackend(r.WalletDatastore())
+	if err != nil {
+		return errors.Wrap(err, "failed to set up wallet backend")
+	}
+
+	return backend.SetSeed(seed, 0)
+}
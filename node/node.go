@@ -44,6 +44,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/core"
 	"github.com/filecoin-project/go-filecoin/flags"
+	"github.com/filecoin-project/go-filecoin/health"
 	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/mining"
 	"github.com/filecoin-project/go-filecoin/net"
@@ -54,6 +55,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/plumbing/replay"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs"
@@ -92,6 +94,9 @@ type nodeChainReader interface {
 
 type nodeChainSyncer interface {
 	HandleNewTipset(ctx context.Context, tipsetCids types.SortedCidSet) error
+	SyncBestTarget(ctx context.Context, targets chain.SyncTargets) error
+	Weight(ctx context.Context, ts types.TipSet) (uint64, error)
+	SyncMode() chain.SyncMode
 }
 
 // Node represents a full Filecoin node.
@@ -109,6 +114,9 @@ type Node struct {
 	RetrievalAPI   *retrieval.API
 	StorageAPI     *storage.API
 
+	// Health reports the node's readiness to the API server.
+	Health *health.Checker
+
 	// HeavyTipSetCh is a subscription to the heaviest tipset topic on the chain.
 	HeaviestTipSetCh chan interface{}
 	// HeavyTipSetHandled is a hook for tests because pubsub notifications
@@ -137,7 +145,8 @@ type Node struct {
 	miningDoneWg *sync.WaitGroup
 
 	// Storage Market Interfaces
-	StorageMiner *storage.Miner
+	StorageMiner  *storage.Miner
+	StorageClient *storage.Client
 
 	// Retrieval Interfaces
 	RetrievalMiner *retrieval.Miner
@@ -147,6 +156,8 @@ type Node struct {
 	MessageSub   pubsub.Subscription
 	HelloSvc     *hello.Handler
 	Bootstrapper *net.Bootstrapper
+	// PeerManager records the heads peers report, to choose sync targets.
+	PeerManager *net.PeerManager
 
 	// Data Storage Fields
 
@@ -191,6 +202,7 @@ type Config struct {
 	Rewarder    consensus.BlockRewarder
 	Repo        repo.Repo
 	IsRelay     bool
+	NetworkName string
 }
 
 // ConfigOpt is a configuration option for a filecoin node.
@@ -212,6 +224,15 @@ func IsRelay() ConfigOpt {
 	}
 }
 
+// NetworkName sets the name of the network the node joins, which scopes its
+// pubsub topics, DHT and hello protocol.
+func NetworkName(name string) ConfigOpt {
+	return func(c *Config) error {
+		c.NetworkName = name
+		return nil
+	}
+}
+
 // BlockTime sets the blockTime.
 func BlockTime(blockTime time.Duration) ConfigOpt {
 	return func(c *Config) error {
@@ -284,13 +305,24 @@ func readGenesisCid(ds datastore.Datastore) (cid.Cid, error) {
 // buildHost determines if we are publically dialable.  If so use public
 // Address, if not configure node to announce relay address.
 func (nc *Config) buildHost(ctx context.Context, makeDHT func(host host.Host) (routing.IpfsRouting, error)) (host.Host, error) {
-	// Node must build a host acting as a libp2p relay.  Additionally it
-	// runs the autoNAT service which allows other nodes to check for their
-	// own dialability by having this node attempt to dial them.
 	makeDHTRightType := func(h host.Host) (routing.PeerRouting, error) {
 		return makeDHT(h)
 	}
 
+	// Autorelay runs the node's AutoNAT, and announces the addresses of
+	// relays in place of the node's own when it finds the node is not
+	// publically dialable.
+	opts := []libp2p.Option{
+		libp2p.EnableAutoRelay(),
+		libp2p.Routing(makeDHTRightType),
+	}
+	if !nc.Repo.Config().Swarm.DisableNATPortMap {
+		opts = append(opts, libp2p.NATPortMap())
+	}
+
+	// Node must build a host acting as a libp2p relay.  Additionally it
+	// runs the autoNAT service which allows other nodes to check for their
+	// own dialability by having this node attempt to dial them.
 	if nc.IsRelay {
 		cfg := nc.Repo.Config()
 		publicAddr, err := ma.NewMultiaddr(cfg.Swarm.PublicRelayAddress)
@@ -306,30 +338,39 @@ func (nc *Config) buildHost(ctx context.Context, makeDHT func(host host.Host) (r
 			}
 			return nil
 		}
-		relayHost, err := libp2p.New(
-			ctx,
-			libp2p.EnableRelay(circuit.OptHop),
-			libp2p.EnableAutoRelay(),
-			libp2p.Routing(makeDHTRightType),
-			publicAddrFactory,
-			libp2p.ChainOptions(nc.Libp2pOpts...),
-		)
-		if err != nil {
-			return nil, err
-		}
+		opts = append(opts, libp2p.EnableRelay(circuit.OptHop), publicAddrFactory)
+	}
+
+	peerHost, err := libp2p.New(ctx, append(opts, nc.Libp2pOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if nc.IsRelay {
 		// Set up autoNATService as a streamhandler on the host.
-		_, err = autonatsvc.NewAutoNATService(ctx, relayHost)
-		if err != nil {
+		if _, err := autonatsvc.NewAutoNATService(ctx, peerHost); err != nil {
 			return nil, err
 		}
-		return relayHost, nil
 	}
-	return libp2p.New(
-		ctx,
-		libp2p.EnableAutoRelay(),
-		libp2p.Routing(makeDHTRightType),
-		libp2p.ChainOptions(nc.Libp2pOpts...),
-	)
+	return peerHost, nil
+}
+
+// newConnManager returns a connection manager keeping the number of open
+// connections within the limits of the swarm config.
+func newConnManager(cfg *config.SwarmConfig) (*net.ConnManager, error) {
+	gracePeriod, err := time.ParseDuration(cfg.ConnMgrGracePeriod)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse connection manager grace period %s", cfg.ConnMgrGracePeriod)
+	}
+	connMgr, err := net.NewConnManager(net.ConnLimits{
+		LowWater:    cfg.ConnMgrLowWater,
+		HighWater:   cfg.ConnMgrHighWater,
+		GracePeriod: gracePeriod,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid connection manager limits")
+	}
+	return connMgr, nil
 }
 
 // Build instantiates a filecoin Node from the settings specified in the config.
@@ -338,6 +379,10 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		nc.Repo = repo.NewInMemoryRepo()
 	}
 
+	if nc.NetworkName == "" {
+		nc.NetworkName = net.DefaultNetworkName
+	}
+
 	bs := bstore.NewBlockstore(nc.Repo.Datastore())
 
 	validator := blankValidator{}
@@ -348,6 +393,12 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	bandwidthTracker := p2pmetrics.NewBandwidthCounter()
 	nc.Libp2pOpts = append(nc.Libp2pOpts, libp2p.BandwidthReporter(bandwidthTracker))
 
+	connMgr, err := newConnManager(nc.Repo.Config().Swarm)
+	if err != nil {
+		return nil, err
+	}
+	nc.Libp2pOpts = append(nc.Libp2pOpts, libp2p.ConnectionManager(connMgr))
+
 	if !nc.OfflineMode {
 		makeDHT := func(h host.Host) (routing.IpfsRouting, error) {
 			r, err := dht.New(
@@ -355,7 +406,7 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 				h,
 				dhtopts.Datastore(nc.Repo.Datastore()),
 				dhtopts.NamespacedValidator("v", validator),
-				dhtopts.Protocols(net.FilecoinDHT),
+				dhtopts.Protocols(net.FilecoinDHT(nc.NetworkName)),
 			)
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to setup routing")
@@ -376,6 +427,8 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 
 	// set up pinger
 	pingService := ping.NewPingService(peerHost)
+	pinger := net.NewPinger(peerHost, pingService)
+	peerMgr := net.NewPeerManager(peerHost, pinger, clock.NewSystemClock())
 
 	// setup block validation
 	// TODO when #2961 is resolved do the needful here.
@@ -415,8 +468,14 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		nodeConsensus = consensus.NewExpected(&cstOffline, bs, processor, blkValid, powerTable, genCid, nc.Verifier, nc.BlockTime)
 	}
 
-	// Set up libp2p network
-	fsub, err := libp2pps.NewFloodSub(ctx, peerHost)
+	// Set up libp2p network. Messages must be signed by the peer that
+	// published them, so that invalid ones are scored against their author.
+	// Offline nodes have no peers, nor a peer key to sign with.
+	var psOpts []libp2pps.Option
+	if !nc.OfflineMode {
+		psOpts = append(psOpts, libp2pps.WithMessageSigning(true), libp2pps.WithStrictSignatureVerification(true))
+	}
+	fsub, err := libp2pps.NewFloodSub(ctx, peerHost, psOpts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to set up network")
 	}
@@ -425,16 +484,33 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to set up wallet backend")
 	}
-	fcWallet := wallet.New(backend)
+	backends := []wallet.Backend{backend}
+	for _, signer := range nc.Repo.Config().Wallet.RemoteSigners {
+		remote, err := wallet.NewRemoteBackend(signer.Endpoint, signer.Token)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set up remote wallet backend")
+		}
+		backends = append(backends, remote)
+	}
+	fcWallet := wallet.New(backends...)
 
 	// only the syncer gets the storage which is online connected
 	chainSyncer := chain.NewSyncer(&cstOffline, nodeConsensus, chainStore, fetcher, chain.Syncing)
-	msgPool := core.NewMessagePool(nc.Repo.Config().Mpool, consensus.NewIngestionValidator(chainState, nc.Repo.Config().Mpool))
+	ingestionValidator := consensus.NewIngestionValidator(chainState, nc.Repo.Config().Mpool)
+	msgPool := core.NewMessagePool(nc.Repo.Config().Mpool, ingestionValidator)
+
+	// drop invalid blocks and messages before they are delivered or
+	// propagated, and ban the peers that keep sending them
+	peerScorer := newPeerScorer(peerHost)
+	err = pubsub.RegisterValidators(fsub, peerHost.ID(), peerScorer, newBlockTopicValidator(nc.NetworkName, blkValid), newMessageTopicValidator(nc.NetworkName, ingestionValidator))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up pubsub validators")
+	}
 	inbox := core.NewInbox(msgPool, core.InboxMaxAgeTipsets, chainStore)
 
 	msgQueue := core.NewMessageQueue()
 	outboxPolicy := core.NewMessageQueuePolicy(chainStore, core.OutboxMaxAgeRounds)
-	msgPublisher := newDefaultMessagePublisher(pubsub.NewPublisher(fsub), net.MessageTopic, msgPool)
+	msgPublisher := newDefaultMessagePublisher(pubsub.NewPublisher(fsub), net.MessageTopic(nc.NetworkName), msgPool)
 	outbox := core.NewOutbox(fcWallet, consensus.NewOutboundMessageValidator(), msgQueue, msgPublisher, outboxPolicy, chainStore, chainState)
 
 	PorcelainAPI := porcelain.New(plumbing.New(&plumbing.APIDeps{
@@ -448,8 +524,9 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		MsgPreviewer: msg.NewPreviewer(chainStore, &cstOffline, bs),
 		MsgQueryer:   msg.NewQueryer(chainStore, &cstOffline, bs),
 		MsgWaiter:    msg.NewWaiter(chainStore, bs, &cstOffline),
-		Network:      net.New(peerHost, pubsub.NewPublisher(fsub), pubsub.NewSubscriber(fsub), net.NewRouter(router), bandwidthTracker, net.NewPinger(peerHost, pingService)),
+		Network:      net.New(peerHost, pubsub.NewPublisher(fsub), pubsub.NewSubscriber(fsub), net.NewRouter(router), bandwidthTracker, pinger, connMgr, peerMgr, nc.NetworkName),
 		Outbox:       outbox,
+		Replayer:     replay.NewReplayer(chainStore, &cstOffline, nodeConsensus),
 		Wallet:       fcWallet,
 	}))
 
@@ -469,6 +546,7 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 		OfflineMode:  nc.OfflineMode,
 		Outbox:       outbox,
 		PeerHost:     peerHost,
+		PeerManager:  peerMgr,
 		Repo:         nc.Repo,
 		Wallet:       fcWallet,
 		Router:       router,
@@ -489,6 +567,9 @@ func (nc *Config) Build(ctx context.Context) (*Node, error) {
 	}
 	minPeerThreshold := nd.Repo.Config().Bootstrap.MinPeerThreshold
 	nd.Bootstrapper = net.NewBootstrapper(bpi, nd.Host(), nd.Host().Network(), nd.Router, minPeerThreshold, period)
+	for _, pi := range bpi {
+		connMgr.Protect(pi.ID, net.BootstrapProtectTag)
+	}
 
 	return nd, nil
 }
@@ -517,14 +598,25 @@ func (node *Node) Start(ctx context.Context) error {
 	}
 
 	// Start up 'hello' handshake service
-	syncCallBack := func(pid libp2ppeer.ID, cids []cid.Cid, height uint64) {
+	syncCallBack := func(pid libp2ppeer.ID, cids []cid.Cid, height, weight uint64) {
 		cidSet := types.NewSortedCidSet(cids...)
+		node.PeerManager.UpdateHead(pid, cidSet, height, weight)
 		err := node.Syncer.HandleNewTipset(context.Background(), cidSet)
-		if err != nil {
-			log.Infof("error handling blocks: %s", cidSet.String())
+		if err == nil {
+			return
+		}
+		log.Infof("error handling blocks: %s", cidSet.String())
+		node.PeerManager.SyncFailed(cidSet)
+
+		// fall back to the chains other peers reported
+		if err := node.Syncer.SyncBestTarget(context.Background(), node.PeerManager); err != nil {
+			log.Infof("error syncing to the heads of peers: %s", err)
 		}
 	}
-	node.HelloSvc = hello.New(node.Host(), node.ChainReader.GenesisCid(), syncCallBack, node.PorcelainAPI.ChainHead, node.Repo.Config().Net, flags.Commit)
+	weigh := func(ts types.TipSet) (uint64, error) {
+		return node.Syncer.Weight(context.Background(), ts)
+	}
+	node.HelloSvc = hello.New(node.Host(), node.ChainReader.GenesisCid(), node.PorcelainAPI.NetworkName(), syncCallBack, node.PorcelainAPI.ChainHead, weigh, flags.Commit)
 
 	err = node.setupProtocols()
 	if err != nil {
@@ -533,14 +625,14 @@ func (node *Node) Start(ctx context.Context) error {
 	node.RetrievalMiner = retrieval.NewMiner(node)
 
 	// subscribe to block notifications
-	blkSub, err := node.PorcelainAPI.PubSubSubscribe(net.BlockTopic)
+	blkSub, err := node.PorcelainAPI.PubSubSubscribe(net.BlockTopic(node.PorcelainAPI.NetworkName()))
 	if err != nil {
 		return errors.Wrap(err, "failed to subscribe to blocks topic")
 	}
 	node.BlockSub = blkSub
 
 	// subscribe to message notifications
-	msgSub, err := node.PorcelainAPI.PubSubSubscribe(net.MessageTopic)
+	msgSub, err := node.PorcelainAPI.PubSubSubscribe(net.MessageTopic(node.PorcelainAPI.NetworkName()))
 	if err != nil {
 		return errors.Wrap(err, "failed to subscribe to message topic")
 	}
@@ -583,9 +675,25 @@ func (node *Node) setupHeartbeatServices(ctx context.Context) error {
 		return addr
 	}
 
+	status := func() metrics.NodeStatus {
+		s := metrics.NodeStatus{
+			PeerCount: uint64(len(node.Host().Network().Peers())),
+			SyncMode:  node.Syncer.SyncMode().String(),
+			MpoolSize: uint64(len(node.PorcelainAPI.MessagePoolPending())),
+			Mining:    node.IsMining(),
+		}
+		if node.StorageMiner != nil {
+			s.SealingQueueDepth = uint64(node.StorageMiner.SealingQueueDepth())
+			if h := node.StorageMiner.LastPoStHeight(); h != nil {
+				s.LastPoStHeight = h.AsBigInt().Uint64()
+			}
+		}
+		return s
+	}
+
 	// start the primary heartbeat service
 	if len(node.Repo.Config().Heartbeat.BeatTarget) > 0 {
-		hbs := metrics.NewHeartbeatService(node.Host(), node.Repo.Config().Heartbeat, node.PorcelainAPI.ChainHead, metrics.WithMinerAddressGetter(mag))
+		hbs := metrics.NewHeartbeatService(node.Host(), node.Repo.Config().Heartbeat, node.PorcelainAPI.ChainHead, metrics.WithMinerAddressGetter(mag), metrics.WithNodeStatusGetter(status))
 		go hbs.Start(ctx)
 	}
 
@@ -597,7 +705,7 @@ func (node *Node) setupHeartbeatServices(ctx context.Context) error {
 			BeatPeriod:      "10s",
 			ReconnectPeriod: "10s",
 			Nickname:        node.Repo.Config().Heartbeat.Nickname,
-		}, node.PorcelainAPI.ChainHead, metrics.WithMinerAddressGetter(mag))
+		}, node.PorcelainAPI.ChainHead, metrics.WithMinerAddressGetter(mag), metrics.WithNodeStatusGetter(status))
 		go ahbs.Start(ctx)
 	}
 	return nil
@@ -679,6 +787,14 @@ func (node *Node) handleNewHeaviestTipSet(ctx context.Context, head types.TipSet
 				if err != nil {
 					log.Error(err)
 				}
+				if err := node.StorageMiner.RedeemVouchers(ctx, newHead); err != nil {
+					log.Error(err)
+				}
+			}
+			if node.StorageClient != nil {
+				if err := node.StorageClient.OnNewHeaviestTipSet(newHead); err != nil {
+					log.Error(err)
+				}
 			}
 			node.HeaviestTipSetHandled()
 		case <-ctx.Done():
@@ -779,8 +895,7 @@ func (node *Node) StartMining(ctx context.Context) error {
 		}
 	}
 
-	minerOwnerAddr, err := node.miningOwnerAddress(ctx, minerAddr)
-	if err != nil {
+	if _, err := node.miningOwnerAddress(ctx, minerAddr); err != nil {
 		return errors.Wrapf(err, "failed to get mining owner address for miner %s", minerAddr)
 	}
 
@@ -807,12 +922,21 @@ func (node *Node) StartMining(ctx context.Context) error {
 	}
 
 	// initialize a storage miner
+	// Deals are only resumed the first time mining starts; after a restart of
+	// mining the previous storage miner is still processing its deals.
+	resumeDeals := node.StorageMiner == nil
 	storageMiner, err := initStorageMinerForNode(ctx, node)
 	if err != nil {
 		return errors.Wrap(err, "failed to initialize storage miner")
 	}
 	node.StorageMiner = storageMiner
 
+	if resumeDeals {
+		if err := node.StorageMiner.ResumeDeals(ctx); err != nil {
+			return errors.Wrap(err, "failed to resume storage deals")
+		}
+	}
+
 	// loop, turning sealing-results into commitSector messages to be included
 	// in the chain
 	go func() {
@@ -821,35 +945,14 @@ func (node *Node) StartMining(ctx context.Context) error {
 			case result := <-node.SectorBuilder().SectorSealResults():
 				if result.SealingErr != nil {
 					log.Errorf("failed to seal sector with id %d: %s", result.SectorID, result.SealingErr.Error())
+					node.StorageMiner.OnSealFailed(result.SectorID, result.SealingErr)
 				} else if result.SealingResult != nil {
+					node.StorageMiner.OnSectorSealed(result.SectorID)
 
-					// TODO: determine these algorithmically by simulating call and querying historical prices
-					gasPrice := types.NewGasPrice(1)
-					gasUnits := types.NewGasUnits(300)
-
-					val := result.SealingResult
-					// This call can fail due to, e.g. nonce collisions. Our miners existence depends on this.
-					// We should deal with this, but MessageSendWithRetry is problematic.
-					msgCid, err := node.PorcelainAPI.MessageSend(
-						node.miningCtx,
-						minerOwnerAddr,
-						minerAddr,
-						types.ZeroAttoFIL,
-						gasPrice,
-						gasUnits,
-						"commitSector",
-						val.SectorID,
-						val.CommD[:],
-						val.CommR[:],
-						val.CommRStar[:],
-						val.Proof[:],
-					)
-					if err != nil {
-						log.Errorf("failed to send commitSector message from %s to %s for sector with id %d: %s", minerOwnerAddr, minerAddr, val.SectorID, err)
-						continue
-					}
-
-					node.StorageMiner.OnCommitmentSent(val, msgCid, nil)
+					// This call can fail due to, e.g. nonce collisions, in which case the
+					// storage miner sends the commitment again.
+					msgCid, err := node.StorageMiner.CommitSector(node.miningCtx, result.SealingResult)
+					node.StorageMiner.OnCommitmentSent(result.SealingResult, msgCid, err)
 				}
 			case <-node.miningCtx.Done():
 				return
@@ -866,8 +969,8 @@ func (node *Node) StartMining(ctx context.Context) error {
 					return
 				case <-time.After(time.Duration(node.Repo.Config().Mining.AutoSealIntervalSeconds) * time.Second):
 					log.Info("auto-seal has been triggered")
-					if err := node.SectorBuilder().SealAllStagedSectors(node.miningCtx); err != nil {
-						log.Errorf("scheduler received error from node.SectorBuilder.SealAllStagedSectors (%s) - exiting", err.Error())
+					if err := node.StorageMiner.SealStagedSectors(node.miningCtx); err != nil {
+						log.Errorf("scheduler received error from node.StorageMiner.SealStagedSectors (%s) - exiting", err.Error())
 						return
 					}
 				}
@@ -1025,8 +1128,38 @@ func (node *Node) setupProtocols() error {
 
 	// set up storage client and api
 	smc := storage.NewClient(node.host, node.PorcelainAPI)
-	smcAPI := storage.NewAPI(smc)
+	node.StorageClient = smc
+	smcAPI := storage.NewAPI(smc, node.Repo.DealsDatastore(), func() *storage.Miner {
+		return node.StorageMiner
+	})
 	node.StorageAPI = &smcAPI
+
+	node.Health = health.NewChecker(&health.Deps{
+		SyncMode: node.Syncer.SyncMode,
+		HeadHeight: func() (uint64, error) {
+			head, err := node.PorcelainAPI.ChainHead()
+			if err != nil {
+				return 0, err
+			}
+			return head.Height()
+		},
+		PeerHeights: func() []uint64 {
+			var heights []uint64
+			for _, info := range node.PeerManager.Peers() {
+				heights = append(heights, info.Height)
+			}
+			return heights
+		},
+		PeerCount: func() int { return len(node.Host().Network().Peers()) },
+		MinPeers:  node.Repo.Config().Bootstrap.MinPeerThreshold,
+		Datastore: node.Repo.Datastore(),
+		StorageMiner: func() health.ProvingMiner {
+			if node.StorageMiner == nil {
+				return nil
+			}
+			return node.StorageMiner
+		},
+	})
 	return nil
 }
 
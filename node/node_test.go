@@ -10,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/config"
 	"github.com/filecoin-project/go-filecoin/consensus"
 	"github.com/filecoin-project/go-filecoin/mining"
+	"github.com/filecoin-project/go-filecoin/net"
 	"github.com/filecoin-project/go-filecoin/node"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/protocol/storage"
@@ -185,6 +186,39 @@ func TestOptionWithError(t *testing.T) {
 
 }
 
+func TestNetworkNameFromRepo(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	networkName := func(t *testing.T, r repo.Repo) string {
+		opts, err := node.OptionsFromRepo(r)
+		require.NoError(t, err)
+		c := &node.Config{}
+		for _, opt := range opts {
+			require.NoError(t, opt(c))
+		}
+		return c.NetworkName
+	}
+
+	t.Run("devnets stay on the default network", func(t *testing.T) {
+		r := repo.NewInMemoryRepo()
+		require.NoError(t, node.Init(ctx, r, consensus.DefaultGenesis))
+		assert.Equal(t, net.DefaultNetworkName, networkName(t, r))
+
+		r.Config().Net = "devnet-user"
+		assert.Equal(t, net.DefaultNetworkName, networkName(t, r))
+	})
+
+	t.Run("uses the configured net", func(t *testing.T) {
+		r := repo.NewInMemoryRepo()
+		require.NoError(t, node.Init(ctx, r, consensus.DefaultGenesis))
+		r.Config().Net = "testnet"
+
+		assert.Equal(t, "testnet", networkName(t, r))
+	})
+}
+
 func TestNodeConfig(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -220,7 +254,10 @@ func TestNodeConfig(t *testing.T) {
 	assert.Equal(t, true, n.OfflineMode)
 	assert.Equal(t, defaultCfg.Mining, cfg.Mining)
 	assert.Equal(t, &config.SwarmConfig{
-		Address: "/ip4/0.0.0.0/tcp/0",
+		Address:            "/ip4/0.0.0.0/tcp/0",
+		ConnMgrLowWater:    150,
+		ConnMgrHighWater:   200,
+		ConnMgrGracePeriod: "20s",
 	}, cfg.Swarm)
 }
 
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/clock"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/net"
+	"github.com/filecoin-project/go-filecoin/net/pubsub"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// newPeerScorer returns a scorer of the peers sending pubsub messages to h,
+// which disconnects banned peers and refuses their connections.
+func newPeerScorer(h host.Host) *pubsub.PeerScorer {
+	scorer := pubsub.NewPeerScorer(clock.NewSystemClock(), func(p peer.ID) {
+		if err := h.Network().ClosePeer(p); err != nil {
+			log.Warningf("failed to disconnect banned peer %s: %s", p, err)
+		}
+	})
+
+	h.Network().Notify(&inet.NotifyBundle{
+		ConnectedF: func(_ inet.Network, c inet.Conn) {
+			if scorer.Banned(c.RemotePeer()) {
+				log.Debugf("refusing connection from banned peer %s", c.RemotePeer())
+				c.Close() // nolint: errcheck
+			}
+		},
+	})
+
+	return scorer
+}
+
+// blockTopicValidator drops malformed blocks announced on the block topic.
+// Blocks from the future don't count against their senders, since miners'
+// clocks may be slightly skewed.
+type blockTopicValidator struct {
+	topic     string
+	validator consensus.BlockSyntaxValidator
+}
+
+var _ pubsub.Validator = (*blockTopicValidator)(nil)
+
+func newBlockTopicValidator(networkName string, bv consensus.BlockSyntaxValidator) *blockTopicValidator {
+	return &blockTopicValidator{topic: net.BlockTopic(networkName), validator: bv}
+}
+
+func (btv *blockTopicValidator) Topic() string {
+	return btv.topic
+}
+
+func (btv *blockTopicValidator) Validate(ctx context.Context, msg pubsub.Message) error {
+	blk, err := types.DecodeBlock(msg.GetData())
+	if err != nil {
+		return pubsub.Invalid(errors.Wrap(err, "failed to decode block"))
+	}
+	if err := btv.validator.ValidateSyntax(ctx, blk); err != nil {
+		if errors.Cause(err) == consensus.ErrFutureBlock {
+			return err
+		}
+		return pubsub.Invalid(err)
+	}
+	return nil
+}
+
+// messageIngestionValidator validates a message against the latest state.
+type messageIngestionValidator interface {
+	Validate(ctx context.Context, msg *types.SignedMessage) error
+}
+
+// messageTopicValidator drops badly signed messages and messages the message
+// pool would not accept from the message topic. Only undecodable and badly
+// signed messages count against their senders, since other messages may just
+// be stale.
+type messageTopicValidator struct {
+	topic     string
+	validator messageIngestionValidator
+}
+
+var _ pubsub.Validator = (*messageTopicValidator)(nil)
+
+func newMessageTopicValidator(networkName string, mv messageIngestionValidator) *messageTopicValidator {
+	return &messageTopicValidator{topic: net.MessageTopic(networkName), validator: mv}
+}
+
+func (mtv *messageTopicValidator) Topic() string {
+	return mtv.topic
+}
+
+func (mtv *messageTopicValidator) Validate(ctx context.Context, msg pubsub.Message) error {
+	smsg := &types.SignedMessage{}
+	if err := smsg.Unmarshal(msg.GetData()); err != nil {
+		return pubsub.Invalid(errors.Wrap(err, "failed to decode message"))
+	}
+	if !smsg.VerifySignature() {
+		return pubsub.Invalid(errors.New("invalid message signature"))
+	}
+	return mtv.validator.Validate(ctx, smsg)
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/net"
+	"github.com/filecoin-project/go-filecoin/net/pubsub"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type fakeBlockSyntaxValidator struct {
+	err error
+}
+
+func (fbv *fakeBlockSyntaxValidator) ValidateSyntax(ctx context.Context, blk *types.Block) error {
+	return fbv.err
+}
+
+type fakeMessageIngestionValidator struct {
+	err error
+}
+
+func (fmv *fakeMessageIngestionValidator) Validate(ctx context.Context, msg *types.SignedMessage) error {
+	return fmv.err
+}
+
+func TestBlockTopicValidator(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	from := peer.ID("sender")
+	blk := types.NewBlockForTest(nil, 1)
+	data := blk.ToNode().RawData()
+
+	t.Run("accepts well formed blocks", func(t *testing.T) {
+		btv := newBlockTopicValidator(net.DefaultNetworkName, &fakeBlockSyntaxValidator{})
+		assert.NoError(t, btv.Validate(ctx, pubsub.NewFakeMessage(from, data)))
+	})
+
+	t.Run("rejects undecodable blocks as invalid", func(t *testing.T) {
+		btv := newBlockTopicValidator(net.DefaultNetworkName, &fakeBlockSyntaxValidator{})
+		err := btv.Validate(ctx, pubsub.NewFakeMessage(from, []byte("garbage")))
+		require.Error(t, err)
+		assert.True(t, pubsub.IsInvalid(err))
+	})
+
+	t.Run("rejects malformed blocks as invalid", func(t *testing.T) {
+		btv := newBlockTopicValidator(net.DefaultNetworkName, &fakeBlockSyntaxValidator{err: errors.New("bad block")})
+		err := btv.Validate(ctx, pubsub.NewFakeMessage(from, data))
+		require.Error(t, err)
+		assert.True(t, pubsub.IsInvalid(err))
+	})
+
+	t.Run("rejects blocks from the future without penalty", func(t *testing.T) {
+		btv := newBlockTopicValidator(net.DefaultNetworkName, &fakeBlockSyntaxValidator{err: errors.Wrap(consensus.ErrFutureBlock, "too early")})
+		err := btv.Validate(ctx, pubsub.NewFakeMessage(from, data))
+		require.Error(t, err)
+		assert.False(t, pubsub.IsInvalid(err))
+	})
+}
+
+func TestMessageTopicValidator(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	from := peer.ID("sender")
+	ms, _ := types.NewMockSignersAndKeyInfo(1)
+	newSignedMessage := types.NewSignedMessageForTestGetter(ms)
+
+	t.Run("accepts valid messages", func(t *testing.T) {
+		data, err := newSignedMessage().Marshal()
+		require.NoError(t, err)
+
+		mtv := newMessageTopicValidator(net.DefaultNetworkName, &fakeMessageIngestionValidator{})
+		assert.NoError(t, mtv.Validate(ctx, pubsub.NewFakeMessage(from, data)))
+	})
+
+	t.Run("rejects undecodable messages as invalid", func(t *testing.T) {
+		mtv := newMessageTopicValidator(net.DefaultNetworkName, &fakeMessageIngestionValidator{})
+		err := mtv.Validate(ctx, pubsub.NewFakeMessage(from, []byte("garbage")))
+		require.Error(t, err)
+		assert.True(t, pubsub.IsInvalid(err))
+	})
+
+	t.Run("rejects badly signed messages as invalid", func(t *testing.T) {
+		smsg := newSignedMessage()
+		smsg.Signature = []byte("not a signature")
+		data, err := smsg.Marshal()
+		require.NoError(t, err)
+
+		mtv := newMessageTopicValidator(net.DefaultNetworkName, &fakeMessageIngestionValidator{})
+		err = mtv.Validate(ctx, pubsub.NewFakeMessage(from, data))
+		require.Error(t, err)
+		assert.True(t, pubsub.IsInvalid(err))
+	})
+
+	t.Run("rejects messages failing ingestion without penalty", func(t *testing.T) {
+		data, err := newSignedMessage().Marshal()
+		require.NoError(t, err)
+
+		mtv := newMessageTopicValidator(net.DefaultNetworkName, &fakeMessageIngestionValidator{err: errors.New("nonce too low")})
+		err = mtv.Validate(ctx, pubsub.NewFakeMessage(from, data))
+		require.Error(t, err)
+		assert.False(t, pubsub.IsInvalid(err))
+	})
+}
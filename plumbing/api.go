@@ -29,6 +29,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/plumbing/dag"
 	"github.com/filecoin-project/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/plumbing/replay"
 	"github.com/filecoin-project/go-filecoin/plumbing/strgdls"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/state"
@@ -55,6 +56,7 @@ type API struct {
 	msgWaiter    *msg.Waiter
 	network      *net.Network
 	outbox       *core.Outbox
+	replayer     *replay.Replayer
 	storagedeals *strgdls.Store
 	wallet       *wallet.Wallet
 }
@@ -73,6 +75,7 @@ type APIDeps struct {
 	MsgWaiter    *msg.Waiter
 	Network      *net.Network
 	Outbox       *core.Outbox
+	Replayer     *replay.Replayer
 	Wallet       *wallet.Wallet
 }
 
@@ -92,6 +95,7 @@ func New(deps *APIDeps) *API {
 		msgWaiter:    deps.MsgWaiter,
 		network:      deps.Network,
 		outbox:       deps.Outbox,
+		replayer:     deps.Replayer,
 		storagedeals: deps.Deals,
 		wallet:       deps.Wallet,
 	}
@@ -102,6 +106,11 @@ func (api *API) ActorGet(ctx context.Context, addr address.Address) (*actor.Acto
 	return api.chain.GetActor(ctx, addr)
 }
 
+// ActorGetAt returns an actor from the state of the tipset with the given key
+func (api *API) ActorGetAt(ctx context.Context, tsKey types.SortedCidSet, addr address.Address) (*actor.Actor, error) {
+	return api.chain.GetActorAt(ctx, tsKey, addr)
+}
+
 // ActorGetSignature returns the signature of the given actor's given method.
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
@@ -109,11 +118,22 @@ func (api *API) ActorGetSignature(ctx context.Context, actorAddr address.Address
 	return api.chain.GetActorSignature(ctx, actorAddr, method)
 }
 
+// ActorGetSignatureAt returns the signature of the given actor's given method
+// in the state of the tipset with the given key.
+func (api *API) ActorGetSignatureAt(ctx context.Context, tsKey types.SortedCidSet, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	return api.chain.GetActorSignatureAt(ctx, tsKey, actorAddr, method)
+}
+
 // ActorLs returns a channel with actors from the latest state on the chain
 func (api *API) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
 	return api.chain.LsActors(ctx)
 }
 
+// ActorLsAt returns a channel with actors from the state of the tipset with the given key
+func (api *API) ActorLsAt(ctx context.Context, tsKey types.SortedCidSet) (<-chan state.GetAllActorsResult, error) {
+	return api.chain.LsActorsAt(ctx, tsKey)
+}
+
 // BlockTime returns the block time used by the consensus protocol.
 func (api *API) BlockTime() time.Duration {
 	return api.expected.BlockTime()
@@ -140,6 +160,11 @@ func (api *API) ChainGetBlock(ctx context.Context, id cid.Cid) (*types.Block, er
 	return api.chain.GetBlock(ctx, id)
 }
 
+// ChainGetTipSet returns the tipset with the given key
+func (api *API) ChainGetTipSet(tsKey types.SortedCidSet) (types.TipSet, error) {
+	return api.chain.GetTipSet(tsKey)
+}
+
 // ChainHead returns the head tipset
 func (api *API) ChainHead() (types.TipSet, error) {
 	return api.chain.Head()
@@ -150,6 +175,18 @@ func (api *API) ChainLs(ctx context.Context) (*chain.TipsetIterator, error) {
 	return api.chain.Ls(ctx)
 }
 
+// ChainNotify returns a channel of the changes of the chain head, starting
+// with the current head. It is closed when ctx is done.
+func (api *API) ChainNotify(ctx context.Context) (<-chan cst.HeadChangesResult, error) {
+	return api.chain.Notify(ctx)
+}
+
+// ChainReplay re-executes the tipset with the given key on top of its parent
+// state and compares the computed receipts and state root with those stored.
+func (api *API) ChainReplay(ctx context.Context, tsKey types.SortedCidSet) (*replay.Report, error) {
+	return api.replayer.Replay(ctx, tsKey)
+}
+
 // ChainSampleRandomness produces a slice of random bytes sampled from a TipSet
 // in the blockchain at a given height, useful for things like PoSt challenge seed
 // generation.
@@ -203,6 +240,13 @@ func (api *API) MessagePreview(ctx context.Context, from, to address.Address, me
 	return api.msgPreviewer.Preview(ctx, from, to, method, params...)
 }
 
+// MessagePreviewWithValue previews the Gas cost of a message sending value
+// from the existing actor from by running it locally on the client and
+// recording the amount of Gas used.
+func (api *API) MessagePreviewWithValue(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	return api.msgPreviewer.PreviewMessage(ctx, from, to, value, method, params...)
+}
+
 // MessageQuery calls an actor's method using the most recent chain state. It is read-only,
 // it does not change any state. It is use to interrogate actor state. The from address
 // is optional; if not provided, an address will be chosen from the node's wallet.
@@ -210,6 +254,12 @@ func (api *API) MessageQuery(ctx context.Context, optFrom, to address.Address, m
 	return api.msgQueryer.Query(ctx, optFrom, to, method, params...)
 }
 
+// MessageQueryAt calls an actor's method using the state of the tipset with the given key.
+// Like MessageQuery, it is read-only and the from address is optional.
+func (api *API) MessageQueryAt(ctx context.Context, tsKey types.SortedCidSet, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
+	return api.msgQueryer.QueryAt(ctx, tsKey, optFrom, to, method, params...)
+}
+
 // MessageSend sends a message. It uses the default from address if none is given and signs the
 // message using the wallet. This call "sends" in the sense that it enqueues the
 // message in the msg pool and broadcasts it to the network; it does not wait for the
@@ -219,6 +269,20 @@ func (api *API) MessageSend(ctx context.Context, from, to address.Address, value
 	return api.outbox.Send(ctx, from, to, value, gasPrice, gasLimit, method, params...)
 }
 
+// MessageSendSigned sends a message that was signed elsewhere, e.g. by an
+// offline wallet. Like MessageSend it enqueues the message in the msg pool
+// and broadcasts it to the network after validating it against the latest
+// state.
+func (api *API) MessageSendSigned(ctx context.Context, msg *types.SignedMessage) (cid.Cid, error) {
+	return api.outbox.SendSigned(ctx, msg)
+}
+
+// MessageNextNonce returns the nonce the next message sent from an address
+// should have, accounting for messages sent but not yet mined.
+func (api *API) MessageNextNonce(ctx context.Context, from address.Address) (uint64, error) {
+	return api.outbox.NextNonce(ctx, from)
+}
+
 // MessageFind returns a message and receipt from the blockchain, if it exists.
 func (api *API) MessageFind(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
 	return api.msgWaiter.Find(ctx, msgCid)
@@ -243,6 +307,21 @@ func (api *API) PubSubPublish(topic string, data []byte) error {
 	return api.network.Publish(topic, data)
 }
 
+// NetworkName returns the name of the network the node is on
+func (api *API) NetworkName() string {
+	return api.network.NetworkName()
+}
+
+// NetworkReachability returns whether the node can be dialed from the public internet
+func (api *API) NetworkReachability() net.Reachability {
+	return api.network.Reachability()
+}
+
+// NetworkRelayAddrs returns the addresses the node can be dialed at through circuit relays
+func (api *API) NetworkRelayAddrs() []ma.Multiaddr {
+	return api.network.RelayAddrs()
+}
+
 // NetworkGetBandwidthStats gets stats on the current bandwidth usage of the network
 func (api *API) NetworkGetBandwidthStats() metrics.Stats {
 	return api.network.GetBandwidthStats()
@@ -283,9 +362,45 @@ func (api *API) NetworkConnect(ctx context.Context, addrs []string) (<-chan net.
 	return api.network.Connect(ctx, addrs)
 }
 
-// NetworkPeers lists peers currently available on the network
-func (api *API) NetworkPeers(ctx context.Context, verbose, latency, streams bool) (*net.SwarmConnInfos, error) {
-	return api.network.Peers(ctx, verbose, latency, streams)
+// NetworkPeers lists peers currently available on the network, with what
+// they last reported about their chains if chainInfo is set.
+func (api *API) NetworkPeers(ctx context.Context, verbose, latency, streams, chainInfo bool) (*net.SwarmConnInfos, error) {
+	return api.network.Peers(ctx, verbose, latency, streams, chainInfo)
+}
+
+// NetworkConnLimits returns the limits the number of open connections is kept
+// within, and the number of open connections.
+func (api *API) NetworkConnLimits() (net.ConnLimits, int) {
+	return api.network.ConnLimits()
+}
+
+// NetworkSetConnLimits changes the limits the number of open connections is
+// kept within.
+func (api *API) NetworkSetConnLimits(limits net.ConnLimits) error {
+	return api.network.SetConnLimits(limits)
+}
+
+// NetworkProtect protects a peer from connection trimming under a tag.
+func (api *API) NetworkProtect(p peer.ID, tag string) {
+	api.network.Protect(p, tag)
+}
+
+// NetworkUnprotect removes the protection of a peer under a tag, and returns
+// whether the peer is still protected under other tags.
+func (api *API) NetworkUnprotect(p peer.ID, tag string) bool {
+	return api.network.Unprotect(p, tag)
+}
+
+// NetworkProtected returns the peers protected from connection trimming and
+// the tags they are protected under.
+func (api *API) NetworkProtected() map[peer.ID][]string {
+	return api.network.Protected()
+}
+
+// StateDiff returns the actors that were added, removed or changed going from
+// the state of the first tipset to the state of the second.
+func (api *API) StateDiff(ctx context.Context, tsKeyA, tsKeyB types.SortedCidSet) ([]state.ActorChange, error) {
+	return api.chain.DiffStates(ctx, tsKeyA, tsKeyB)
 }
 
 // SignBytes uses private key information associated with the given address to sign the given bytes.
@@ -318,6 +433,12 @@ func (api *API) WalletImport(kinfos []*types.KeyInfo) ([]address.Address, error)
 	return api.wallet.Import(kinfos)
 }
 
+// WalletRestore makes the wallet derive new addresses from seed and imports
+// the addresses derived from seed that used reports as used
+func (api *API) WalletRestore(seed []byte, used func(address.Address) (bool, error)) ([]address.Address, error) {
+	return api.wallet.Restore(seed, used)
+}
+
 // WalletExport returns the KeyInfos for the given wallet addresses
 func (api *API) WalletExport(addrs []address.Address) ([]*types.KeyInfo, error) {
 	return api.wallet.Export(addrs)
@@ -2,8 +2,8 @@ package cst
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/cskr/pubsub"
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/address"
@@ -23,6 +23,7 @@ type chainReader interface {
 	GetHead() types.SortedCidSet
 	GetTipSet(types.SortedCidSet) (types.TipSet, error)
 	GetTipSetStateRoot(tsKey types.SortedCidSet) (cid.Cid, error)
+	HeadEvents() *pubsub.PubSub
 }
 
 // ChainStateProvider composes a chain and a state store to provide access to
@@ -40,6 +41,10 @@ var (
 	// but hasn't yet been upgraded to an account actor. (The actor implementation might
 	// also genuinely be missing, which is not expected.)
 	ErrNoActorImpl = errors.New("no actor implementation")
+	// ErrNoActor is returned by GetActorSignature when there is no actor at the address.
+	ErrNoActor = errors.New("no actor")
+	// ErrNoExport is returned by GetActorSignature when the actor does not export the method.
+	ErrNoExport = errors.New("no export")
 )
 
 // NewChainStateProvider returns a new ChainStateProvider.
@@ -59,6 +64,11 @@ func (chn *ChainStateProvider) Head() (types.TipSet, error) {
 	return ts, nil
 }
 
+// GetTipSet returns the tipset with the given key.
+func (chn *ChainStateProvider) GetTipSet(tsKey types.SortedCidSet) (types.TipSet, error) {
+	return chn.reader.GetTipSet(tsKey)
+}
+
 // Ls returns an iterator over tipsets from head to genesis.
 func (chn *ChainStateProvider) Ls(ctx context.Context) (*chain.TipsetIterator, error) {
 	ts, err := chn.reader.GetTipSet(chn.reader.GetHead())
@@ -68,6 +78,94 @@ func (chn *ChainStateProvider) Ls(ctx context.Context) (*chain.TipsetIterator, e
 	return chain.IterAncestors(ctx, chn.reader, ts), nil
 }
 
+// HeadChangesResult holds the changes taking the chain from one head to the
+// next, or the error that prevented computing them.
+type HeadChangesResult struct {
+	Changes []chain.HeadChange
+	Error   error
+}
+
+// Notify returns a channel of the changes of the chain head. The first result
+// holds the current head only, and each following result the changes taking
+// the chain from the previous head to a new one. Heads set faster than the
+// results are read are coalesced, so that a slow reader never holds up the
+// chain. The channel is closed when ctx is done or after an error.
+func (chn *ChainStateProvider) Notify(ctx context.Context) (<-chan HeadChangesResult, error) {
+	// subscribe before reading the head so that no head is missed
+	sub := chn.reader.HeadEvents().Sub(chain.NewHeadTopic)
+	head, err := chn.Head()
+	if err != nil {
+		chn.unsubHeadEvents(sub)
+		return nil, err
+	}
+
+	latest := make(chan types.TipSet, 1)
+	go func() {
+		defer chn.unsubHeadEvents(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-sub:
+				if !ok {
+					return
+				}
+				ts, ok := v.(types.TipSet)
+				if !ok {
+					continue
+				}
+				// only the latest head is kept
+				select {
+				case <-latest:
+				default:
+				}
+				latest <- ts
+			}
+		}
+	}()
+
+	out := make(chan HeadChangesResult)
+	go func() {
+		defer close(out)
+
+		prev := head
+		res := HeadChangesResult{Changes: []chain.HeadChange{{Type: chain.HeadChangeCurrent, TipSet: head}}}
+		for {
+			if res.Error != nil || len(res.Changes) > 0 {
+				select {
+				case out <- res:
+				case <-ctx.Done():
+					return
+				}
+				if res.Error != nil {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case ts := <-latest:
+				changes, err := chain.HeadChanges(ctx, chn.reader, prev, ts)
+				res = HeadChangesResult{Changes: changes, Error: err}
+				prev = ts
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// unsubHeadEvents unsubscribes sub from head events, draining it so that
+// pending publications do not block.
+func (chn *ChainStateProvider) unsubHeadEvents(sub chan interface{}) {
+	go func() {
+		for range sub {
+		}
+	}()
+	chn.reader.HeadEvents().Unsub(sub, chain.NewHeadTopic)
+}
+
 // GetBlock gets a block by CID
 func (chn *ChainStateProvider) GetBlock(ctx context.Context, id cid.Cid) (*types.Block, error) {
 	return chn.reader.GetBlock(ctx, id)
@@ -106,12 +204,35 @@ func (chn *ChainStateProvider) GetActorAt(ctx context.Context, tipKey types.Sort
 	return actr, nil
 }
 
+// DiffStates returns the actors that were added, removed or changed going from
+// the state of the tipset with key tsKeyA to the state of the one with key tsKeyB.
+func (chn *ChainStateProvider) DiffStates(ctx context.Context, tsKeyA, tsKeyB types.SortedCidSet) ([]state.ActorChange, error) {
+	rootA, err := chn.reader.GetTipSetStateRoot(tsKeyA)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state root of tipset %s", tsKeyA)
+	}
+	rootB, err := chn.reader.GetTipSetStateRoot(tsKeyB)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state root of tipset %s", tsKeyB)
+	}
+	return state.Diff(ctx, chn.cst, rootA, rootB)
+}
+
 // LsActors returns a channel with actors from the latest state on the chain
 func (chn *ChainStateProvider) LsActors(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
-	st, err := chain.LatestState(ctx, chn.reader, chn.cst)
+	return chn.LsActorsAt(ctx, chn.reader.GetHead())
+}
+
+// LsActorsAt returns a channel with actors from the state at a specified tipset key.
+func (chn *ChainStateProvider) LsActorsAt(ctx context.Context, tipKey types.SortedCidSet) (<-chan state.GetAllActorsResult, error) {
+	stateCid, err := chn.reader.GetTipSetStateRoot(tipKey)
 	if err != nil {
 		return nil, err
 	}
+	st, err := state.LoadStateTree(ctx, chn.cst, stateCid, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state for tipset %s", tipKey)
+	}
 	return state.GetAllActors(ctx, st), nil
 }
 
@@ -119,22 +240,34 @@ func (chn *ChainStateProvider) LsActors(ctx context.Context) (<-chan state.GetAl
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
 func (chn *ChainStateProvider) GetActorSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	return chn.GetActorSignatureAt(ctx, chn.reader.GetHead(), actorAddr, method)
+}
+
+// GetActorSignatureAt returns the signature of the given actor's given method
+// in the state of the tipset with the given key.
+func (chn *ChainStateProvider) GetActorSignatureAt(ctx context.Context, tipKey types.SortedCidSet, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
 	if method == "" {
 		return nil, ErrNoMethod
 	}
 
-	actor, err := chn.GetActor(ctx, actorAddr)
+	stateCid, err := chn.reader.GetTipSetStateRoot(tipKey)
 	if err != nil {
+		return nil, err
+	}
+	st, err := state.LoadStateTree(ctx, chn.cst, stateCid, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state for tipset %s", tipKey)
+	}
+
+	actor, err := st.GetActor(ctx, actorAddr)
+	if state.IsActorNotFoundError(err) {
+		return nil, ErrNoActor
+	} else if err != nil {
 		return nil, errors.Wrap(err, "failed to get actor")
 	} else if actor.Empty() {
 		return nil, ErrNoActorImpl
 	}
 
-	st, err := chain.LatestState(ctx, chn.reader, chn.cst)
-	if err != nil {
-		return nil, err
-	}
-
 	executable, err := st.GetBuiltinActorCode(actor.Code)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load actor code")
@@ -142,7 +275,7 @@ func (chn *ChainStateProvider) GetActorSignature(ctx context.Context, actorAddr
 
 	export, ok := executable.Exports()[method]
 	if !ok {
-		return nil, fmt.Errorf("missing export: %s", method)
+		return nil, errors.Wrapf(ErrNoExport, "missing export: %s", method)
 	}
 
 	return export, nil
@@ -61,3 +61,34 @@ func (p *Previewer) Preview(ctx context.Context, optFrom, to address.Address, me
 	}
 	return usedGas, nil
 }
+
+// PreviewMessage sends a read-only message with value to an actor from the
+// existing actor from, with the nonce from expects next, and returns the gas
+// it uses.
+func (p *Previewer) PreviewMessage(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	encodedParams, err := abi.ToEncodedValues(params...)
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "couldnt encode message params")
+	}
+
+	st, err := chain.LatestState(ctx, p.chainReader, p.cst)
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "could load tree for latest state root")
+	}
+	h, err := p.chainReader.BlockHeight()
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "couldnt get base tipset height")
+	}
+	fromActor, err := st.GetActor(ctx, from)
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "couldnt get from actor")
+	}
+
+	msg := types.NewMessage(from, to, uint64(fromActor.Nonce), value, method, encodedParams)
+	vms := vm.NewStorageMap(p.bs)
+	usedGas, err := consensus.PreviewMessage(ctx, st, vms, msg, types.NewBlockHeight(h))
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrap(err, "message returned an error")
+	}
+	return usedGas, nil
+}
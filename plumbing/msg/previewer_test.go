@@ -53,4 +53,37 @@ func TestPreview(t *testing.T) {
 		require.NotNil(t, returnValue)
 		assert.Equal(t, types.NewGasUnits(100), returnValue)
 	})
+
+	t.Run("previews messages sending value from an existing actor", func(t *testing.T) {
+		newAddr := address.NewForTestGetter()
+		ctx := context.Background()
+		r := repo.NewInMemoryRepo()
+		bs := bstore.NewBlockstore(r.Datastore())
+
+		fakeActorCodeCid := types.NewCidForTestGetter()()
+		fakeActorAddr := newAddr()
+		fromAddr := newAddr()
+		vms := vm.NewStorageMap(bs)
+		fakeActor := th.RequireNewFakeActor(t, vms, fakeActorAddr, fakeActorCodeCid)
+		builtin.Actors[fakeActorCodeCid] = &actor.FakeActor{}
+		defer delete(builtin.Actors, fakeActorCodeCid)
+		testGen := consensus.MakeGenesisFunc(
+			consensus.AddActor(fakeActorAddr, fakeActor),
+			consensus.ActorAccount(fromAddr, types.NewAttoFILFromFIL(10)),
+		)
+		deps := requireCommonDepsWithGifAndBlockstore(t, testGen, r, bs)
+
+		previewer := NewPreviewer(deps.chainStore, deps.cst, deps.blockstore)
+		usedGas, err := previewer.PreviewMessage(ctx, fromAddr, fakeActorAddr, types.NewAttoFILFromFIL(5), "hasReturnValue")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasUnits(100), usedGas)
+
+		// the sender can't cover the value
+		_, err = previewer.PreviewMessage(ctx, fromAddr, fakeActorAddr, types.NewAttoFILFromFIL(20), "hasReturnValue")
+		assert.Error(t, err)
+
+		// the sender must exist
+		_, err = previewer.PreviewMessage(ctx, newAddr(), fakeActorAddr, types.ZeroAttoFIL, "hasReturnValue")
+		assert.Error(t, err)
+	})
 }
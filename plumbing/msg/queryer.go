@@ -9,25 +9,26 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/address"
-	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
 	"github.com/filecoin-project/go-filecoin/vm"
 )
 
 // Abstracts over a store of blockchain state.
 type queryerChainReader interface {
-	BlockHeight() (uint64, error)
 	GetHead() types.SortedCidSet
+	GetTipSet(tsKey types.SortedCidSet) (types.TipSet, error)
 	GetTipSetStateRoot(tsKey types.SortedCidSet) (cid.Cid, error)
 }
 
 // Queryer knows how to send read-only messages for querying actor state.
 type Queryer struct {
-	// To get the head tipset state root.
+	// To get the head tipset and tipset state roots.
 	chainReader queryerChainReader
-	// To load the tree for the head tipset state root.
+	// To load the tree for a tipset state root.
 	cst *hamt.CborIpldStore
 	// For vm storage.
 	bs bstore.Blockstore
@@ -40,16 +41,30 @@ func NewQueryer(chainReader queryerChainReader, cst *hamt.CborIpldStore, bs bsto
 
 // Query sends a read-only message to an actor.
 func (q *Queryer) Query(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
+	return q.QueryAt(ctx, q.chainReader.GetHead(), optFrom, to, method, params...)
+}
+
+// QueryAt sends a read-only message to an actor using the state of the
+// tipset with key tsKey.
+func (q *Queryer) QueryAt(ctx context.Context, tsKey types.SortedCidSet, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
 	encodedParams, err := abi.ToEncodedValues(params...)
 	if err != nil {
 		return nil, errors.Wrap(err, "couldnt encode message params")
 	}
 
-	st, err := chain.LatestState(ctx, q.chainReader, q.cst)
+	stateCid, err := q.chainReader.GetTipSetStateRoot(tsKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldnt get state root for tipset %s", tsKey)
+	}
+	st, err := state.LoadStateTree(ctx, q.cst, stateCid, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldnt load tree for state root")
+	}
+	ts, err := q.chainReader.GetTipSet(tsKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "could load tree for latest state root")
+		return nil, errors.Wrapf(err, "couldnt get tipset %s", tsKey)
 	}
-	h, err := q.chainReader.BlockHeight()
+	h, err := ts.Height()
 	if err != nil {
 		return nil, errors.Wrap(err, "couldnt get base tipset height")
 	}
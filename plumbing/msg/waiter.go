@@ -43,6 +43,8 @@ type ChainMessage struct {
 	Message *types.SignedMessage
 	Block   *types.Block
 	Receipt *types.MessageReceipt
+	// TipSetKey is the key of the tipset including Block.
+	TipSetKey types.SortedCidSet
 }
 
 // NewWaiter returns a new Waiter.
@@ -120,7 +122,7 @@ func (w *Waiter) findMessage(ctx context.Context, ts types.TipSet, msgCid cid.Ci
 					if err != nil {
 						return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
 					}
-					return &ChainMessage{msg, blk, recpt}, true, nil
+					return &ChainMessage{msg, blk, recpt, iterator.Value().ToSortedCidSet()}, true, nil
 				}
 			}
 		}
@@ -159,7 +161,7 @@ func (w *Waiter) waitForMessage(ctx context.Context, ch <-chan interface{}, msgC
 							if err != nil {
 								return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
 							}
-							return &ChainMessage{msg, blk, recpt}, true, nil
+							return &ChainMessage{msg, blk, recpt, raw.ToSortedCidSet()}, true, nil
 						}
 					}
 				}
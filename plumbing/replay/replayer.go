@@ -0,0 +1,208 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/sampling"
+	"github.com/filecoin-project/go-filecoin/state"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// ErrReplayGenesis is returned when asked to replay the genesis tipset, which
+// has no parent state to replay from.
+var ErrReplayGenesis = errors.New("cannot replay the genesis tipset")
+
+// Abstracts over a store of blockchain state.
+type replayerChainReader interface {
+	GetBlock(context.Context, cid.Cid) (*types.Block, error)
+	GetHead() types.SortedCidSet
+	GetTipSet(tsKey types.SortedCidSet) (types.TipSet, error)
+	GetTipSetStateRoot(tsKey types.SortedCidSet) (cid.Cid, error)
+}
+
+// Abstracts over the consensus rules used to re-execute a tipset.
+type tipSetReplayer interface {
+	ReplayTipSet(ctx context.Context, ts types.TipSet, ancestors []types.TipSet, pSt state.Tree) (*consensus.ReplayResult, error)
+}
+
+// ReceiptComparison pairs the receipt recorded in a block for a message with
+// the receipt computed when replaying it. Either receipt is nil when the block
+// and the replay disagree on the number of receipts.
+type ReceiptComparison struct {
+	Block    cid.Cid               `json:"block"`
+	Message  cid.Cid               `json:"message"`
+	Stored   *types.MessageReceipt `json:"stored"`
+	Computed *types.MessageReceipt `json:"computed"`
+	Match    bool                  `json:"match"`
+}
+
+// Report describes the result of replaying a tipset.
+type Report struct {
+	TipSet            types.SortedCidSet  `json:"tipSet"`
+	Height            uint64              `json:"height"`
+	ParentStateRoot   cid.Cid             `json:"parentStateRoot"`
+	StoredStateRoot   cid.Cid             `json:"storedStateRoot"`
+	ComputedStateRoot cid.Cid             `json:"computedStateRoot"`
+	TransitionError   string              `json:"transitionError,omitempty"`
+	Receipts          []ReceiptComparison `json:"receipts"`
+	ActorDiffs        []state.ActorChange `json:"actorDiffs"`
+}
+
+// StateRootMatches is true when the replay computed the stored state root.
+func (r *Report) StateRootMatches() bool {
+	return r.StoredStateRoot.Equals(r.ComputedStateRoot)
+}
+
+// ReceiptsMatch is true when every computed receipt matches the stored one.
+func (r *Report) ReceiptsMatch() bool {
+	for _, rc := range r.Receipts {
+		if !rc.Match {
+			return false
+		}
+	}
+	return true
+}
+
+// Replayer re-executes tipsets already in the chain store and compares the
+// outcome with what was stored when the tipset was first validated.
+type Replayer struct {
+	// To get tipsets, their ancestors and their stored state roots.
+	chainReader replayerChainReader
+	// To load the state trees for the stored state roots.
+	cst *hamt.CborIpldStore
+	// To run the state transition.
+	replayer tipSetReplayer
+}
+
+// NewReplayer constructs a Replayer.
+func NewReplayer(chainReader replayerChainReader, cst *hamt.CborIpldStore, replayer tipSetReplayer) *Replayer {
+	return &Replayer{chainReader, cst, replayer}
+}
+
+// Replay re-executes the tipset with the given key on top of its parent state
+// and reports per-message receipt mismatches, the computed and stored state
+// roots and, when they differ, the actors whose state differs going from the
+// stored to the computed state.
+func (r *Replayer) Replay(ctx context.Context, tsKey types.SortedCidSet) (*Report, error) {
+	ts, err := r.chainReader.GetTipSet(tsKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tipset %s", tsKey)
+	}
+	parentKey, err := ts.Parents()
+	if err != nil {
+		return nil, err
+	}
+	if parentKey.Len() == 0 {
+		return nil, ErrReplayGenesis
+	}
+	parent, err := r.chainReader.GetTipSet(parentKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get parent tipset %s", parentKey)
+	}
+	h, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{TipSet: tsKey, Height: h}
+
+	report.ParentStateRoot, err = r.chainReader.GetTipSetStateRoot(parentKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get parent state root")
+	}
+	report.StoredStateRoot, err = r.chainReader.GetTipSetStateRoot(tsKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get stored state root")
+	}
+	pSt, err := state.LoadStateTree(ctx, r.cst, report.ParentStateRoot, builtin.Actors)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load parent state")
+	}
+
+	ancestorHeight := types.NewBlockHeight(consensus.AncestorRoundsNeeded)
+	ancestors, err := chain.GetRecentAncestors(ctx, parent, r.chainReader, types.NewBlockHeight(h), ancestorHeight, sampling.LookbackParameter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ancestors")
+	}
+
+	result, err := r.replayer.ReplayTipSet(ctx, ts, ancestors, pSt)
+	if err != nil {
+		return nil, err
+	}
+	if result.TransitionErr != nil {
+		report.TransitionError = result.TransitionErr.Error()
+	}
+	report.ComputedStateRoot, err = result.State.Flush(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to flush computed state")
+	}
+
+	for i := 0; i < ts.Len(); i++ {
+		cmps, err := compareReceipts(ts.At(i), result.Receipts[i])
+		if err != nil {
+			return nil, err
+		}
+		report.Receipts = append(report.Receipts, cmps...)
+	}
+
+	if !report.StateRootMatches() {
+		// the computed state is only in the replay's store
+		report.ActorDiffs, err = state.Diff(ctx, result.Store, report.StoredStateRoot, report.ComputedStateRoot)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to diff states")
+		}
+	}
+
+	return report, nil
+}
+
+func compareReceipts(blk *types.Block, computed []*types.MessageReceipt) ([]ReceiptComparison, error) {
+	n := len(blk.MessageReceipts)
+	if len(computed) > n {
+		n = len(computed)
+	}
+
+	cmps := make([]ReceiptComparison, n)
+	for i := 0; i < n; i++ {
+		cmp := ReceiptComparison{Block: blk.Cid()}
+		if i < len(blk.Messages) {
+			msgCid, err := blk.Messages[i].Cid()
+			if err != nil {
+				return nil, err
+			}
+			cmp.Message = msgCid
+		}
+		if i < len(blk.MessageReceipts) {
+			cmp.Stored = blk.MessageReceipts[i]
+		}
+		if i < len(computed) {
+			cmp.Computed = computed[i]
+		}
+		cmp.Match = receiptsEqual(cmp.Stored, cmp.Computed)
+		cmps[i] = cmp
+	}
+	return cmps, nil
+}
+
+func receiptsEqual(a, b *types.MessageReceipt) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.ExitCode != b.ExitCode || !a.GasAttoFIL.Equal(b.GasAttoFIL) || len(a.Return) != len(b.Return) {
+		return false
+	}
+	for i := range a.Return {
+		if !bytes.Equal(a.Return[i], b.Return[i]) {
+			return false
+		}
+	}
+	return true
+}
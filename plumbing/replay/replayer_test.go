@@ -0,0 +1,263 @@
+package replay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-ipfs-exchange-offline"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/consensus"
+	"github.com/filecoin-project/go-filecoin/proofs"
+	"github.com/filecoin-project/go-filecoin/state"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/vm"
+)
+
+func TestReplay(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("replaying a tipset reproduces its receipts and state root", func(t *testing.T) {
+		f := newReplayFixture(t)
+		ts := f.requireAddChild(t, nil, nil)
+
+		report, err := f.replayer.Replay(ctx, ts.ToSortedCidSet())
+		require.NoError(t, err)
+
+		assert.Empty(t, report.TransitionError)
+		assert.True(t, f.parentRoot.Equals(report.ParentStateRoot))
+		assert.True(t, report.StateRootMatches())
+		assert.True(t, report.ReceiptsMatch())
+		assert.Empty(t, report.ActorDiffs)
+
+		require.Len(t, report.Receipts, 1)
+		msgCid, err := ts.At(0).Messages[0].Cid()
+		require.NoError(t, err)
+		assert.True(t, msgCid.Equals(report.Receipts[0].Message))
+		assert.Equal(t, uint8(0), report.Receipts[0].Computed.ExitCode)
+	})
+
+	t.Run("replaying a tipset reports receipts and state that differ from the chain", func(t *testing.T) {
+		f := newReplayFixture(t)
+		// record the parent state and a failed receipt instead of the real ones
+		ts := f.requireAddChild(t, &f.parentRoot, &types.MessageReceipt{ExitCode: 1})
+
+		report, err := f.replayer.Replay(ctx, ts.ToSortedCidSet())
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, report.TransitionError)
+		assert.True(t, f.parentRoot.Equals(report.StoredStateRoot))
+		assert.False(t, report.StateRootMatches())
+		assert.False(t, report.ReceiptsMatch())
+
+		var changed []address.Address
+		for _, change := range report.ActorDiffs {
+			changed = append(changed, change.Address)
+		}
+		assert.Contains(t, changed, f.sender)
+		assert.Contains(t, changed, f.recipient)
+	})
+
+	t.Run("replaying a tipset leaves the node's stores untouched", func(t *testing.T) {
+		f := newReplayFixture(t)
+		ts := f.requireAddChild(t, nil, nil)
+		before := requireCountBlocks(ctx, t, f.bs)
+
+		_, err := f.replayer.Replay(ctx, ts.ToSortedCidSet())
+		require.NoError(t, err)
+
+		assert.Equal(t, before, requireCountBlocks(ctx, t, f.bs))
+	})
+
+	t.Run("the genesis tipset cannot be replayed", func(t *testing.T) {
+		f := newReplayFixture(t)
+
+		_, err := f.replayer.Replay(ctx, f.parent.ToSortedCidSet())
+		assert.Equal(t, ErrReplayGenesis, err)
+	})
+}
+
+type replayFixture struct {
+	bs         blockstore.Blockstore
+	cst        *hamt.CborIpldStore
+	scratchBs  blockstore.Blockstore
+	scratchCst *hamt.CborIpldStore
+	chain      *fakeReplayerChainReader
+	processor  *consensus.DefaultProcessor
+	replayer   *Replayer
+	signer     types.MockSigner
+	sender     address.Address
+	recipient  address.Address
+	miner      address.Address
+	worker     address.Address
+	parent     types.TipSet
+	parentRoot cid.Cid
+}
+
+// newReplayFixture sets up a chain holding a genesis tipset whose state funds
+// the sender, and a Replayer that replays the chain's tipsets with the rules of
+// Expected consensus. The states of the tipsets added to the chain are
+// computed in scratch stores, so that only replaying them can write them to
+// the chain's stores.
+func newReplayFixture(t *testing.T) *replayFixture {
+	bs, cst := newStores()
+	scratchBs, scratchCst := newStores()
+
+	signer, kis := types.NewMockSignersAndKeyInfo(2)
+	sender, err := kis[0].Address()
+	require.NoError(t, err)
+	worker, err := kis[1].Address()
+	require.NoError(t, err)
+	addrGetter := address.NewForTestGetter()
+
+	f := &replayFixture{
+		bs:         bs,
+		cst:        cst,
+		scratchBs:  scratchBs,
+		scratchCst: scratchCst,
+		chain:      newFakeReplayerChainReader(),
+		processor:  th.NewTestProcessor(),
+		signer:     signer,
+		sender:     sender,
+		recipient:  addrGetter(),
+		miner:      addrGetter(),
+		worker:     worker,
+	}
+
+	// The block rewards are paid to the owner of the blocks' miner, so the
+	// miner's storage is written to both stores as well.
+	pid := th.RequireRandomPeerID(t)
+	actors := map[address.Address]*actor.Actor{
+		sender:  th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(100)),
+		f.miner: th.RequireNewMinerActor(t, vm.NewStorageMap(bs), f.miner, worker, 10, pid, types.ZeroAttoFIL),
+	}
+	th.RequireNewMinerActor(t, vm.NewStorageMap(scratchBs), f.miner, worker, 10, pid, types.ZeroAttoFIL)
+	f.parentRoot, _ = th.RequireMakeStateTree(t, cst, actors)
+	th.RequireMakeStateTree(t, scratchCst, actors)
+	genesis := &types.Block{StateRoot: f.parentRoot}
+	f.parent = th.RequireNewTipSet(t, genesis)
+	f.chain.add(f.parent, f.parentRoot)
+
+	expected := consensus.NewExpected(cst, bs, f.processor, th.NewFakeBlockValidator(), &th.TestView{}, genesis.Cid(), proofs.NewFakeVerifier(true, nil), th.BlockTimeTest)
+	f.replayer = NewReplayer(f.chain, cst, expected)
+	return f
+}
+
+// requireAddChild adds a tipset to the chain holding a single block that sends
+// funds from the sender to the recipient. The state root and receipt recorded
+// for it are those computed by applying the block to the parent state, unless
+// given.
+func (f *replayFixture) requireAddChild(t *testing.T, stateRoot *cid.Cid, receipt *types.MessageReceipt) types.TipSet {
+	ctx := context.Background()
+
+	msg := types.NewMessage(f.sender, f.recipient, 0, types.NewAttoFILFromFIL(10), "", nil)
+	smsg, err := types.NewSignedMessage(*msg, &f.signer, types.NewGasPrice(1), types.NewGasUnits(1000))
+	require.NoError(t, err)
+
+	blk := th.NewValidTestBlockFromTipSet(f.parent, f.parentRoot, 1, f.miner, f.worker, f.signer)
+	blk.Messages = []*types.SignedMessage{smsg}
+
+	st, err := state.LoadStateTree(ctx, f.scratchCst, f.parentRoot, builtin.Actors)
+	require.NoError(t, err)
+	vms := vm.NewStorageMap(f.scratchBs)
+	results, err := f.processor.ProcessBlock(ctx, st, vms, blk, []types.TipSet{f.parent})
+	require.NoError(t, err)
+	require.NoError(t, vms.Flush())
+	root, err := st.Flush(ctx)
+	require.NoError(t, err)
+
+	if stateRoot != nil {
+		root = *stateRoot
+	}
+	if receipt == nil {
+		receipt = results[0].Receipt
+	}
+	blk.StateRoot = root
+	blk.MessageReceipts = []*types.MessageReceipt{receipt}
+
+	ts := th.RequireNewTipSet(t, blk)
+	f.chain.add(ts, root)
+	return ts
+}
+
+func newStores() (blockstore.Blockstore, *hamt.CborIpldStore) {
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	return bs, &hamt.CborIpldStore{Blocks: blockservice.New(bs, offline.Exchange(bs))}
+}
+
+func requireCountBlocks(ctx context.Context, t *testing.T, bs blockstore.Blockstore) int {
+	keys, err := bs.AllKeysChan(ctx)
+	require.NoError(t, err)
+	n := 0
+	for range keys {
+		n++
+	}
+	return n
+}
+
+type fakeReplayerChainReader struct {
+	head       types.SortedCidSet
+	blocks     map[cid.Cid]*types.Block
+	tipSets    map[string]types.TipSet
+	stateRoots map[string]cid.Cid
+}
+
+func newFakeReplayerChainReader() *fakeReplayerChainReader {
+	return &fakeReplayerChainReader{
+		blocks:     make(map[cid.Cid]*types.Block),
+		tipSets:    make(map[string]types.TipSet),
+		stateRoots: make(map[string]cid.Cid),
+	}
+}
+
+func (cr *fakeReplayerChainReader) add(ts types.TipSet, stateRoot cid.Cid) {
+	key := ts.ToSortedCidSet()
+	for i := 0; i < ts.Len(); i++ {
+		cr.blocks[ts.At(i).Cid()] = ts.At(i)
+	}
+	cr.tipSets[key.String()] = ts
+	cr.stateRoots[key.String()] = stateRoot
+	cr.head = key
+}
+
+func (cr *fakeReplayerChainReader) GetBlock(ctx context.Context, c cid.Cid) (*types.Block, error) {
+	blk, ok := cr.blocks[c]
+	if !ok {
+		return nil, errors.Errorf("no block %s", c)
+	}
+	return blk, nil
+}
+
+func (cr *fakeReplayerChainReader) GetHead() types.SortedCidSet {
+	return cr.head
+}
+
+func (cr *fakeReplayerChainReader) GetTipSet(tsKey types.SortedCidSet) (types.TipSet, error) {
+	ts, ok := cr.tipSets[tsKey.String()]
+	if !ok {
+		return types.TipSet{}, errors.Errorf("no tipset %s", tsKey)
+	}
+	return ts, nil
+}
+
+func (cr *fakeReplayerChainReader) GetTipSetStateRoot(tsKey types.SortedCidSet) (cid.Cid, error) {
+	root, ok := cr.stateRoots[tsKey.String()]
+	if !ok {
+		return cid.Undef, errors.Errorf("no tipset %s", tsKey)
+	}
+	return root, nil
+}
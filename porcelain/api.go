@@ -7,6 +7,7 @@ import (
 
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 
 	minerActor "github.com/filecoin-project/go-filecoin/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
@@ -49,6 +50,12 @@ func (a *API) ChainBlockHeight() (*types.BlockHeight, error) {
 	return ChainBlockHeight(a)
 }
 
+// ChainTipSetAtHeight returns the tipset of the heaviest chain at the given
+// height, or the closest one below it when the height only has null blocks.
+func (a *API) ChainTipSetAtHeight(ctx context.Context, height uint64) (types.TipSet, error) {
+	return ChainTipSetAtHeight(ctx, a, height)
+}
+
 // CreatePayments establishes a payment channel and create multiple payments against it
 func (a *API) CreatePayments(ctx context.Context, config CreatePaymentsParams) (*CreatePaymentsReturn, error) {
 	return CreatePayments(ctx, a, config)
@@ -76,6 +83,25 @@ func (a *API) DealsLs(ctx context.Context) (<-chan *StorageDealLsResult, error)
 	return DealsLs(ctx, a)
 }
 
+// GasPriceEstimate estimates the gas price for a message the node sends on
+// its own behalf calling method
+func (a *API) GasPriceEstimate(ctx context.Context, method string) (types.AttoFIL, error) {
+	return GasPriceEstimate(ctx, a, method)
+}
+
+// GasLimitEstimate estimates the gas limit for a message the node sends on
+// its own behalf by previewing it
+func (a *API) GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	return GasLimitEstimate(ctx, a, from, to, value, method, params...)
+}
+
+// MessageDecode decodes the params and return values of a message using the
+// signature of the method it invokes in the state of the tipset with key
+// tsKey, or of the head if tsKey is empty.
+func (a *API) MessageDecode(ctx context.Context, msg *types.Message, receipt *types.MessageReceipt, tsKey types.SortedCidSet) (*DecodedMessage, error) {
+	return MessageDecode(ctx, a, msg, receipt, tsKey)
+}
+
 // MessagePoolWait waits for the message pool to have at least messageCount unmined messages.
 // It's useful for integration testing.
 func (a *API) MessagePoolWait(ctx context.Context, messageCount uint) ([]*types.SignedMessage, error) {
@@ -135,6 +161,12 @@ func (a *API) MinerGetPeerID(ctx context.Context, minerAddr address.Address) (pe
 	return MinerGetPeerID(ctx, a, minerAddr)
 }
 
+// MinerGetPeerAddrs queries for the addresses the peer of the given miner
+// can be reached at
+func (a *API) MinerGetPeerAddrs(ctx context.Context, minerAddr address.Address) ([]ma.Multiaddr, error) {
+	return MinerGetPeerAddrs(ctx, a, minerAddr)
+}
+
 // MinerSetPrice configures the price of storage. See implementation for details.
 func (a *API) MinerSetPrice(ctx context.Context, from address.Address, miner address.Address, gasPrice types.AttoFIL, gasLimit types.GasUnits, price types.AttoFIL, expiry *big.Int) (MinerSetPriceResponse, error) {
 	return MinerSetPrice(ctx, a, from, miner, gasPrice, gasLimit, price, expiry)
@@ -162,6 +194,18 @@ func (a *API) WalletBalance(ctx context.Context, address address.Address) (types
 	return WalletBalance(ctx, a, address)
 }
 
+// WalletBalanceAt returns the balance of the given wallet address in the state
+// of the tipset with the given key.
+func (a *API) WalletBalanceAt(ctx context.Context, tsKey types.SortedCidSet, address address.Address) (types.AttoFIL, error) {
+	return WalletBalanceAt(ctx, a, tsKey, address)
+}
+
+// WalletRestore restores the hierarchical deterministic wallet with the given
+// mnemonic, importing the used addresses derived from it.
+func (a *API) WalletRestore(ctx context.Context, mnemonic string) ([]address.Address, error) {
+	return WalletRestore(ctx, a.API, mnemonic)
+}
+
 // WalletDefaultAddress returns a default wallet address from the config.
 // If none is set it picks the first address in the wallet and sets it as the default in the config.
 func (a *API) WalletDefaultAddress() (address.Address, error) {
@@ -185,8 +229,9 @@ func (a *API) PaymentChannelVoucher(
 	amount types.AttoFIL,
 	validAt *types.BlockHeight,
 	condition *types.Predicate,
+	lane *types.VoucherLane,
 ) (voucher *types.PaymentVoucher, err error) {
-	return PaymentChannelVoucher(ctx, a, fromAddr, channel, amount, validAt, condition)
+	return PaymentChannelVoucher(ctx, a, fromAddr, channel, amount, validAt, condition, lane)
 }
 
 // ClientListAsks returns a channel with asks from the latest chain state
@@ -1,6 +1,11 @@
 package porcelain
 
 import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/chain"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
@@ -20,3 +25,43 @@ func ChainBlockHeight(plumbing chBlockHeightPlumbing) (*types.BlockHeight, error
 	}
 	return types.NewBlockHeight(height), nil
 }
+
+type chTipSetAtHeightPlumbing interface {
+	ChainLs(ctx context.Context) (*chain.TipsetIterator, error)
+}
+
+// ChainTipSetAtHeight returns the tipset of the heaviest chain at the given
+// height. When there is no tipset at that height because of null blocks, it
+// returns the closest tipset below it, whose state is the one in effect at
+// the given height.
+func ChainTipSetAtHeight(ctx context.Context, plumbing chTipSetAtHeightPlumbing, height uint64) (types.TipSet, error) {
+	iter, err := plumbing.ChainLs(ctx)
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+
+	headHeight, err := iter.Value().Height()
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+	if height > headHeight {
+		return types.UndefTipSet, errors.Errorf("height %d is above the chain head at height %d", height, headHeight)
+	}
+
+	for ; !iter.Complete(); err = iter.Next() {
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		h, err := iter.Value().Height()
+		if err != nil {
+			return types.UndefTipSet, err
+		}
+		if h <= height {
+			return iter.Value(), nil
+		}
+	}
+	if err != nil {
+		return types.UndefTipSet, err
+	}
+	return types.UndefTipSet, errors.Errorf("no tipset found at height %d", height)
+}
@@ -0,0 +1,54 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type chTsahTestPlumbing struct {
+	provider *th.FakeChainProvider
+	head     types.TipSet
+}
+
+func (p *chTsahTestPlumbing) ChainLs(ctx context.Context) (*chain.TipsetIterator, error) {
+	return chain.IterAncestors(ctx, p.provider, p.head), nil
+}
+
+func TestChainTipSetAtHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	provider := th.NewFakeChainProvider()
+	genesis := provider.NewBlock(0)
+	b1 := provider.NewBlock(1, genesis)
+	b2 := provider.NewBlock(2, b1)
+	plumbing := &chTsahTestPlumbing{provider, th.RequireNewTipSet(t, b2)}
+
+	t.Run("returns the tipset at the given height", func(t *testing.T) {
+		ts, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, 1)
+		require.NoError(t, err)
+		assert.Equal(t, th.RequireNewTipSet(t, b1), ts)
+
+		ts, err = porcelain.ChainTipSetAtHeight(ctx, plumbing, 0)
+		require.NoError(t, err)
+		assert.Equal(t, th.RequireNewTipSet(t, genesis), ts)
+
+		ts, err = porcelain.ChainTipSetAtHeight(ctx, plumbing, 2)
+		require.NoError(t, err)
+		assert.Equal(t, th.RequireNewTipSet(t, b2), ts)
+	})
+
+	t.Run("fails above the chain head", func(t *testing.T) {
+		_, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, 3)
+		assert.EqualError(t, err, "height 3 is above the chain head at height 2")
+	})
+}
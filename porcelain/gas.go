@@ -0,0 +1,124 @@
+package porcelain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// The subset of plumbing used by GasPriceEstimate
+type gasPricePlumbing interface {
+	ChainLs(ctx context.Context) (*chain.TipsetIterator, error)
+	ConfigGet(dottedPath string) (interface{}, error)
+	MessagePoolPending() []*types.SignedMessage
+	WalletAddresses() []address.Address
+}
+
+// GasPriceEstimate estimates the gas price a message calling method must pay
+// to be included in the chain promptly. The estimate is the configured
+// percentile of the prices of the messages included in recent tipsets and of
+// those waiting in the message pool, other than the node's own messages, so
+// the node does not drive its own estimates. It is at least the configured
+// default price and capped by the configured maximum price for method. It is
+// meant for messages the node sends on its own behalf.
+func GasPriceEstimate(ctx context.Context, plumbing gasPricePlumbing, method string) (types.AttoFIL, error) {
+	cfgValue, err := plumbing.ConfigGet("mining.gasPrice")
+	if err != nil {
+		return types.ZeroAttoFIL, err
+	}
+	cfg, ok := cfgValue.(*config.GasPriceConfig)
+	if !ok || cfg == nil {
+		return types.ZeroAttoFIL, errors.New("could not retrieve gasPrice from config")
+	}
+
+	own := make(map[address.Address]bool)
+	for _, addr := range plumbing.WalletAddresses() {
+		own[addr] = true
+	}
+
+	prices, err := recentGasPrices(ctx, plumbing, cfg.SampleTipSets, own)
+	if err != nil {
+		return types.ZeroAttoFIL, errors.Wrap(err, "failed to sample gas prices from the chain")
+	}
+	for _, msg := range plumbing.MessagePoolPending() {
+		if !own[msg.From] {
+			prices = append(prices, msg.GasPrice)
+		}
+	}
+
+	price := cfg.Default
+	if len(prices) > 0 {
+		sort.Slice(prices, func(i, j int) bool { return prices[i].LessThan(prices[j]) })
+		percentile := cfg.Percentile
+		if percentile > 100 {
+			percentile = 100
+		}
+		price = prices[(len(prices)-1)*int(percentile)/100]
+	}
+	if price.LessThan(cfg.Default) {
+		price = cfg.Default
+	}
+
+	if maxPrice, ok := cfg.MaxPrices[method]; ok && price.GreaterThan(maxPrice) {
+		price = maxPrice
+	}
+	return price, nil
+}
+
+// gasLimitMargin is the percentage by which GasLimitEstimate pads the gas a
+// message used in its preview, to cover state changing before it is mined.
+const gasLimitMargin = 20
+
+// The subset of plumbing used by GasLimitEstimate
+type gasLimitPlumbing interface {
+	MessagePreviewWithValue(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error)
+}
+
+// GasLimitEstimate estimates the gas limit for a message sending value from
+// from to call method on to with params. The estimate is the gas the message
+// uses when previewed against the latest state, with a margin for the state
+// changing before the message is mined, capped by the block gas limit. It is
+// meant for messages the node sends on its own behalf.
+func GasLimitEstimate(ctx context.Context, plumbing gasLimitPlumbing, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	used, err := plumbing.MessagePreviewWithValue(ctx, from, to, value, method, params...)
+	if err != nil {
+		return types.NewGasUnits(0), errors.Wrapf(err, "failed to preview %s", method)
+	}
+
+	limit := used + used*gasLimitMargin/100
+	if limit > types.BlockGasLimit {
+		limit = types.BlockGasLimit
+	}
+	return limit, nil
+}
+
+// recentGasPrices returns the gas prices of the messages not sent from an
+// address in own that were included in the tipsetCount most recent tipsets of
+// the heaviest chain.
+func recentGasPrices(ctx context.Context, plumbing gasPricePlumbing, tipsetCount uint, own map[address.Address]bool) ([]types.AttoFIL, error) {
+	iter, err := plumbing.ChainLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []types.AttoFIL
+	for i := uint(0); i < tipsetCount && !iter.Complete(); i++ {
+		for _, blk := range iter.Value().ToSlice() {
+			for _, msg := range blk.Messages {
+				if !own[msg.From] {
+					prices = append(prices, msg.GasPrice)
+				}
+			}
+		}
+		if err := iter.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return prices, nil
+}
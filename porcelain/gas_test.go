@@ -0,0 +1,176 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/plumbing/cfg"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/repo"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type gasPriceTestPlumbing struct {
+	config   *cfg.Config
+	provider *th.FakeChainProvider
+	head     types.TipSet
+	pending  []*types.SignedMessage
+	wallet   []address.Address
+}
+
+func (p *gasPriceTestPlumbing) ChainLs(ctx context.Context) (*chain.TipsetIterator, error) {
+	return chain.IterAncestors(ctx, p.provider, p.head), nil
+}
+
+func (p *gasPriceTestPlumbing) ConfigGet(dottedPath string) (interface{}, error) {
+	return p.config.Get(dottedPath)
+}
+
+func (p *gasPriceTestPlumbing) MessagePoolPending() []*types.SignedMessage {
+	return p.pending
+}
+
+func (p *gasPriceTestPlumbing) WalletAddresses() []address.Address {
+	return p.wallet
+}
+
+func gasPricedMessages(prices ...int64) []*types.SignedMessage {
+	var msgs []*types.SignedMessage
+	for _, price := range prices {
+		msgs = append(msgs, &types.SignedMessage{MeteredMessage: types.MeteredMessage{GasPrice: types.NewGasPrice(price)}})
+	}
+	return msgs
+}
+
+func newGasPriceTestPlumbing(t *testing.T, included [][]*types.SignedMessage, pending []*types.SignedMessage) *gasPriceTestPlumbing {
+	provider := th.NewFakeChainProvider()
+	head := provider.NewBlock(0)
+	for i, msgs := range included {
+		head = provider.NewBlockWithMessages(uint64(i+1), msgs, head)
+	}
+	return &gasPriceTestPlumbing{
+		config:   cfg.NewConfig(repo.NewInMemoryRepo()),
+		provider: provider,
+		head:     th.RequireNewTipSet(t, head),
+		pending:  pending,
+	}
+}
+
+func TestGasPriceEstimate(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("uses the default price without samples", func(t *testing.T) {
+		plumbing := newGasPriceTestPlumbing(t, nil, nil)
+
+		price, err := porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(1), price)
+	})
+
+	t.Run("takes the percentile of included and pending prices", func(t *testing.T) {
+		plumbing := newGasPriceTestPlumbing(t,
+			[][]*types.SignedMessage{gasPricedMessages(1, 2), gasPricedMessages(3, 4)},
+			gasPricedMessages(5, 6, 7, 8, 9))
+		require.NoError(t, plumbing.config.Set("mining.gasPrice.percentile", "50"))
+
+		price, err := porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(5), price)
+
+		// only the most recent tipset is sampled
+		require.NoError(t, plumbing.config.Set("mining.gasPrice.sampleTipSets", "1"))
+		price, err = porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(6), price)
+	})
+
+	t.Run("caps the price by method", func(t *testing.T) {
+		plumbing := newGasPriceTestPlumbing(t, nil, gasPricedMessages(500, 500))
+
+		price, err := porcelain.GasPriceEstimate(ctx, plumbing, "commitSector")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(100), price)
+
+		price, err = porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(500), price)
+
+		price, err = porcelain.GasPriceEstimate(ctx, plumbing, "addAsk")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(500), price)
+	})
+
+	t.Run("uses at least the default price", func(t *testing.T) {
+		plumbing := newGasPriceTestPlumbing(t, nil, gasPricedMessages(0, 0))
+		require.NoError(t, plumbing.config.Set("mining.gasPrice.default", `"0.000000000000000003"`))
+
+		price, err := porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(3), price)
+	})
+
+	t.Run("ignores the node's own messages", func(t *testing.T) {
+		own := gasPricedMessages(900)
+		own[0].From = address.TestAddress
+		plumbing := newGasPriceTestPlumbing(t, [][]*types.SignedMessage{own}, append(gasPricedMessages(5), own...))
+		plumbing.wallet = []address.Address{address.TestAddress}
+		require.NoError(t, plumbing.config.Set("mining.gasPrice.percentile", "100"))
+
+		price, err := porcelain.GasPriceEstimate(ctx, plumbing, "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(5), price)
+	})
+}
+
+type gasLimitTestPlumbing struct {
+	used  types.GasUnits
+	err   error
+	value types.AttoFIL
+}
+
+func (p *gasLimitTestPlumbing) MessagePreviewWithValue(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	p.value = value
+	return p.used, p.err
+}
+
+func TestGasLimitEstimate(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	from, to := addrGetter(), addrGetter()
+
+	t.Run("pads the gas used in the preview", func(t *testing.T) {
+		plumbing := &gasLimitTestPlumbing{used: types.NewGasUnits(1000)}
+
+		limit, err := porcelain.GasLimitEstimate(ctx, plumbing, from, to, types.NewAttoFILFromFIL(2), "submitPoSt")
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasUnits(1200), limit)
+		assert.Equal(t, types.NewAttoFILFromFIL(2), plumbing.value)
+	})
+
+	t.Run("caps the limit at the block gas limit", func(t *testing.T) {
+		plumbing := &gasLimitTestPlumbing{used: types.BlockGasLimit}
+
+		limit, err := porcelain.GasLimitEstimate(ctx, plumbing, from, to, types.ZeroAttoFIL, "commitSector")
+		require.NoError(t, err)
+		assert.Equal(t, types.BlockGasLimit, limit)
+	})
+
+	t.Run("fails when the preview fails", func(t *testing.T) {
+		plumbing := &gasLimitTestPlumbing{err: errors.New("boom")}
+
+		_, err := porcelain.GasLimitEstimate(ctx, plumbing, from, to, types.ZeroAttoFIL, "commitSector")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,80 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// DecodedMessage holds the params of a message and the return values of its
+// receipt decoded with the signature of the method it invokes. Params is nil
+// when the params don't match the signature. Return is nil when there is no
+// receipt, the message failed or its return values don't match the signature.
+type DecodedMessage struct {
+	Method string       `json:"method"`
+	Params []*abi.Value `json:"params"`
+	Return []*abi.Value `json:"return"`
+}
+
+type mdPlumbing interface {
+	ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+	ActorGetSignatureAt(ctx context.Context, tsKey types.SortedCidSet, actorAddr address.Address, method string) (*exec.FunctionSignature, error)
+}
+
+// MessageDecode decodes the params of msg and the return values of receipt,
+// which may be nil, using the exported signature of the invoked method in the
+// state of the tipset with key tsKey, or of the head if tsKey is empty. It
+// returns nil when the message invokes no method, or its target does not
+// exist, has no code or does not export the method. Params and return values
+// that cannot be decoded are left undecoded, since anyone can send a message
+// with arbitrary params.
+func MessageDecode(ctx context.Context, plumbing mdPlumbing, msg *types.Message, receipt *types.MessageReceipt, tsKey types.SortedCidSet) (*DecodedMessage, error) {
+	var sig *exec.FunctionSignature
+	var err error
+	if tsKey.Len() == 0 {
+		sig, err = plumbing.ActorGetSignature(ctx, msg.To, msg.Method)
+	} else {
+		sig, err = plumbing.ActorGetSignatureAt(ctx, tsKey, msg.To, msg.Method)
+	}
+	switch errors.Cause(err) {
+	case cst.ErrNoMethod, cst.ErrNoActorImpl, cst.ErrNoActor, cst.ErrNoExport:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get signature of %s", msg.Method)
+	}
+
+	decoded := &DecodedMessage{Method: msg.Method}
+	if params, err := abi.DecodeValues(msg.Params, sig.Params); err == nil {
+		decoded.Params = params
+	}
+
+	if receipt == nil || receipt.ExitCode != 0 {
+		return decoded, nil
+	}
+	decoded.Return = decodeReturn(receipt.Return, sig.Return)
+	return decoded, nil
+}
+
+// decodeReturn decodes the return values ret with retTypes, or returns nil if
+// they don't match.
+func decodeReturn(ret [][]byte, retTypes []abi.Type) []*abi.Value {
+	if len(ret) != len(retTypes) {
+		return nil
+	}
+	var vals []*abi.Value
+	for i, t := range retTypes {
+		val, err := abi.Deserialize(ret[i], t)
+		if err != nil {
+			return nil
+		}
+		vals = append(vals, val)
+	}
+	return vals
+}
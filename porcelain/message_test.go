@@ -0,0 +1,123 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/abi"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type mdTestPlumbing struct {
+	sig *exec.FunctionSignature
+	err error
+
+	tsKey types.SortedCidSet
+}
+
+func (mdtp *mdTestPlumbing) ActorGetSignature(ctx context.Context, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	return mdtp.sig, mdtp.err
+}
+
+func (mdtp *mdTestPlumbing) ActorGetSignatureAt(ctx context.Context, tsKey types.SortedCidSet, actorAddr address.Address, method string) (*exec.FunctionSignature, error) {
+	mdtp.tsKey = tsKey
+	return mdtp.sig, mdtp.err
+}
+
+func TestMessageDecode(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	from, to := addrGetter(), addrGetter()
+
+	sig := &exec.FunctionSignature{
+		Params: []abi.Type{abi.Address, abi.BytesAmount},
+		Return: []abi.Type{abi.Integer},
+	}
+	params, err := abi.ToEncodedValues(from, types.NewBytesAmount(1024))
+	require.NoError(t, err)
+	msg := types.NewMessage(from, to, 0, types.ZeroAttoFIL, "someMethod", params)
+
+	ret, err := (&abi.Value{Type: abi.BytesAmount, Val: types.NewBytesAmount(7)}).Serialize()
+	require.NoError(t, err)
+
+	t.Run("decodes params and return values", func(t *testing.T) {
+		receipt := &types.MessageReceipt{ExitCode: 0, Return: [][]byte{ret}}
+
+		decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{sig: sig}, msg, receipt, types.SortedCidSet{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "someMethod", decoded.Method)
+		require.Len(t, decoded.Params, 2)
+		assert.Equal(t, from, decoded.Params[0].Val)
+		assert.Equal(t, "1024", decoded.Params[1].String())
+		require.Len(t, decoded.Return, 1)
+		assert.Equal(t, abi.Integer, decoded.Return[0].Type)
+	})
+
+	t.Run("skips return values of failed messages", func(t *testing.T) {
+		receipt := &types.MessageReceipt{ExitCode: 1}
+
+		decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{sig: sig}, msg, receipt, types.SortedCidSet{})
+		require.NoError(t, err)
+		assert.Len(t, decoded.Params, 2)
+		assert.Nil(t, decoded.Return)
+	})
+
+	t.Run("returns nil for messages that invoke no method", func(t *testing.T) {
+		decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{err: cst.ErrNoMethod}, msg, nil, types.SortedCidSet{})
+		require.NoError(t, err)
+		assert.Nil(t, decoded)
+	})
+
+	t.Run("returns nil for missing actors and exports", func(t *testing.T) {
+		for _, sigErr := range []error{cst.ErrNoActor, errors.Wrap(cst.ErrNoExport, "missing export: someMethod")} {
+			decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{err: sigErr}, msg, nil, types.SortedCidSet{})
+			require.NoError(t, err)
+			assert.Nil(t, decoded)
+		}
+	})
+
+	t.Run("decodes with the signature in the state of the message's tipset", func(t *testing.T) {
+		tsKey := types.NewSortedCidSet(types.NewCidForTestGetter()())
+		plumbing := &mdTestPlumbing{sig: sig}
+
+		_, err := porcelain.MessageDecode(ctx, plumbing, msg, nil, tsKey)
+		require.NoError(t, err)
+		assert.True(t, tsKey.Equals(plumbing.tsKey))
+	})
+
+	t.Run("leaves params that don't match the signature undecoded", func(t *testing.T) {
+		receipt := &types.MessageReceipt{ExitCode: 0, Return: [][]byte{ret}}
+		for _, params := range [][]byte{nil, []byte("garbage")} {
+			bad := types.NewMessage(from, to, 0, types.ZeroAttoFIL, "someMethod", params)
+
+			decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{sig: sig}, bad, receipt, types.SortedCidSet{})
+			require.NoError(t, err)
+			assert.Equal(t, "someMethod", decoded.Method)
+			assert.Nil(t, decoded.Params)
+			assert.Len(t, decoded.Return, 1)
+		}
+	})
+
+	t.Run("leaves return values that don't match the signature undecoded", func(t *testing.T) {
+		for _, ret := range [][][]byte{nil, {[]byte("garbage"), []byte("garbage")}} {
+			receipt := &types.MessageReceipt{ExitCode: 0, Return: ret}
+
+			decoded, err := porcelain.MessageDecode(ctx, &mdTestPlumbing{sig: sig}, msg, receipt, types.SortedCidSet{})
+			require.NoError(t, err)
+			assert.Len(t, decoded.Params, 2)
+			assert.Nil(t, decoded.Return)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/abi"
@@ -382,3 +383,18 @@ func MinerGetPeerID(ctx context.Context, plumbing mgpidAPI, minerAddr address.Ad
 	}
 	return pid, nil
 }
+
+// MinerGetPeerAddrs queries for the addresses the peer of the given miner can
+// be reached at, as published by the miner.
+func MinerGetPeerAddrs(ctx context.Context, plumbing mgpidAPI, minerAddr address.Address) ([]ma.Multiaddr, error) {
+	res, err := plumbing.MessageQuery(ctx, address.Undef, minerAddr, "getPeerAddrs")
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := abi.Deserialize(res[0], abi.Multiaddrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode to multiaddrs from message-bytes")
+	}
+	return val.Val.([]ma.Multiaddr), nil
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 
 	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
 	"github.com/stretchr/testify/assert"
@@ -456,6 +457,29 @@ func TestMinerGetPeerID(t *testing.T) {
 	assert.Equal(t, expected, id)
 }
 
+type minerGetPeerAddrsPlumbing struct {
+	addrs []ma.Multiaddr
+}
+
+func (mgpap *minerGetPeerAddrsPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
+	out, err := (&abi.Value{Type: abi.Multiaddrs, Val: mgpap.addrs}).Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{out}, nil
+}
+
+func TestMinerGetPeerAddrs(t *testing.T) {
+	tf.UnitTest(t)
+
+	relayAddr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/6000/ipfs/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	require.NoError(t, err)
+
+	addrs, err := MinerGetPeerAddrs(context.Background(), &minerGetPeerAddrsPlumbing{[]ma.Multiaddr{relayAddr}}, address.TestAddress2)
+	require.NoError(t, err)
+	assert.Equal(t, []ma.Multiaddr{relayAddr}, addrs)
+}
+
 type minerGetAskPlumbing struct{}
 
 func (mgop *minerGetAskPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
@@ -11,6 +11,7 @@ import (
 // The subset of plumbing used by MessagePoolWait
 type mpwPlumbing interface {
 	MessagePoolPending() []*types.SignedMessage
+	NetworkName() string
 	PubSubSubscribe(topic string) (pubsub.Subscription, error)
 }
 
@@ -18,7 +19,7 @@ type mpwPlumbing interface {
 func MessagePoolWait(ctx context.Context, plumbing mpwPlumbing, messageCount uint) ([]*types.SignedMessage, error) {
 	pending := plumbing.MessagePoolPending()
 	if len(pending) < int(messageCount) {
-		subscription, err := plumbing.PubSubSubscribe(net.MessageTopic)
+		subscription, err := plumbing.PubSubSubscribe(net.MessageTopic(plumbing.NetworkName()))
 		defer subscription.Cancel()
 		if err != nil {
 			return nil, err
@@ -34,8 +34,12 @@ func (plumbing *fakeMpoolWaitPlumbing) MessagePoolPending() []*types.SignedMessa
 	return plumbing.pending
 }
 
+func (plumbing *fakeMpoolWaitPlumbing) NetworkName() string {
+	return net.DefaultNetworkName
+}
+
 func (plumbing *fakeMpoolWaitPlumbing) PubSubSubscribe(topic string) (pubsub.Subscription, error) {
-	subscription := pubsub.NewFakeSubscription(net.MessageTopic, 1)
+	subscription := pubsub.NewFakeSubscription(topic, 1)
 	plumbing.subscription = subscription
 	return subscription, nil
 }
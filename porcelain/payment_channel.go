@@ -66,7 +66,12 @@ func PaymentChannelVoucher(
 	amount types.AttoFIL,
 	validAt *types.BlockHeight,
 	condition *types.Predicate,
+	lane *types.VoucherLane,
 ) (voucher *types.PaymentVoucher, err error) {
+	if lane == nil {
+		lane = &types.VoucherLane{}
+	}
+
 	if fromAddr.Empty() {
 		fromAddr, err = plumbing.WalletDefaultAddress()
 		if err != nil {
@@ -79,7 +84,7 @@ func PaymentChannelVoucher(
 		fromAddr,
 		address.PaymentBrokerAddress,
 		"voucher",
-		channel, amount, validAt, condition,
+		channel, amount, validAt, condition, lane,
 	)
 	if err != nil {
 		return nil, err
@@ -89,7 +94,7 @@ func PaymentChannelVoucher(
 		return nil, err
 	}
 
-	sig, err := paymentbroker.SignVoucher(channel, amount, validAt, fromAddr, condition, plumbing)
+	sig, err := paymentbroker.SignVoucher(channel, amount, validAt, fromAddr, condition, lane, plumbing)
 	if err != nil {
 		return nil, err
 	}
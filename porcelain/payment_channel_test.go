@@ -104,6 +104,7 @@ func TestPaymentChannelVoucher(t *testing.T) {
 				Method: "someMethod",
 				Params: []interface{}{"params"},
 			},
+			nil,
 		)
 		require.NoError(t, err)
 		assert.Equal(t, expectedVoucher.Channel, voucher.Channel)
@@ -61,6 +61,15 @@ type CreatePaymentsParams struct {
 
 	// GasLimit is the maximum amount of gas to be paid creating the payment channel.
 	GasLimit types.GasUnits
+
+	// Channel, when set, is an existing payment channel the payments are made
+	// from instead of opening a new one. It must contain enough funds.
+	Channel *types.ChannelID
+
+	// Lane is the lane of the payment channel the payments are made on.
+	// Payments from a shared channel each use their own lane, so that a
+	// voucher for one set of payments never pays out another's.
+	Lane uint64
 }
 
 // CreatePaymentsReturn collects relevant stats from the create payments process
@@ -82,6 +91,8 @@ type CreatePaymentsReturn struct {
 }
 
 // CreatePayments establishes a payment channel and creates multiple payments against it.
+// If config.Channel is set the payments are made from that channel instead,
+// on config.Lane of that channel.
 //
 // Each payment except the last will get a condition that calls verifyPieceInclusion on the recipient's miner
 // actor to ensure the storage miner is still storing the file at the time of redemption.
@@ -115,31 +126,9 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 		CreatePaymentsParams: config,
 	}
 
-	// Create channel
-	response.ChannelMsgCid, err = plumbing.MessageSend(ctx,
-		config.From,
-		address.PaymentBrokerAddress,
-		config.Value,
-		config.GasPrice,
-		config.GasLimit,
-		"createChannel",
-		config.To,
-		&config.ChannelExpiry)
-	if err != nil {
-		return response, err
-	}
-
-	// wait for response
-	err = plumbing.MessageWait(ctx, response.ChannelMsgCid, func(block *types.Block, message *types.SignedMessage, receipt *types.MessageReceipt) error {
-		if receipt.ExitCode != 0 {
-			return fmt.Errorf("createChannel failed %d", receipt.ExitCode)
-		}
-
-		response.Channel = types.NewChannelIDFromBytes(receipt.Return[0])
-		response.GasAttoFIL = receipt.GasAttoFIL
-		return nil
-	})
-	if err != nil {
+	if config.Channel != nil {
+		response.Channel = config.Channel
+	} else if err := createChannel(ctx, plumbing, response); err != nil {
 		return response, err
 	}
 
@@ -157,12 +146,13 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 	}
 
 	// generate payments
+	totalAmount := config.Value
 	response.Vouchers = []*types.PaymentVoucher{}
 	voucherAmount := types.ZeroAttoFIL
 	for i := 0; uint64(i+1)*config.PaymentInterval < config.Duration; i++ {
 		voucherAmount = voucherAmount.Add(valuePerPayment)
-		if voucherAmount.GreaterThan(config.Value) {
-			voucherAmount = config.Value
+		if voucherAmount.GreaterThan(totalAmount) {
+			voucherAmount = totalAmount
 		}
 
 		validAt := currentHeight.Add(types.NewBlockHeight(uint64(i+1) * config.PaymentInterval))
@@ -174,7 +164,7 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 
 	// create last payment
 	validAt := currentHeight.Add(types.NewBlockHeight(config.Duration))
-	err = createPayment(ctx, plumbing, response, config.Value, validAt, nil)
+	err = createPayment(ctx, plumbing, response, totalAmount, validAt, nil)
 	if err != nil {
 		return response, err
 	}
@@ -182,6 +172,34 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 	return response, nil
 }
 
+// createChannel opens the payment channel the payments are made from.
+func createChannel(ctx context.Context, plumbing cpPlumbing, response *CreatePaymentsReturn) error {
+	var err error
+	response.ChannelMsgCid, err = plumbing.MessageSend(ctx,
+		response.From,
+		address.PaymentBrokerAddress,
+		response.Value,
+		response.GasPrice,
+		response.GasLimit,
+		"createChannel",
+		response.To,
+		&response.ChannelExpiry)
+	if err != nil {
+		return err
+	}
+
+	// wait for response
+	return plumbing.MessageWait(ctx, response.ChannelMsgCid, func(block *types.Block, message *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != 0 {
+			return fmt.Errorf("createChannel failed %d", receipt.ExitCode)
+		}
+
+		response.Channel = types.NewChannelIDFromBytes(receipt.Return[0])
+		response.GasAttoFIL = receipt.GasAttoFIL
+		return nil
+	})
+}
+
 func createPayment(ctx context.Context, plumbing cpPlumbing, response *CreatePaymentsReturn, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) error {
 	ret, err := plumbing.MessageQuery(ctx,
 		response.From,
@@ -191,6 +209,7 @@ func createPayment(ctx context.Context, plumbing cpPlumbing, response *CreatePay
 		amount,
 		validAt,
 		condition,
+		&types.VoucherLane{ID: response.Lane},
 	)
 	if err != nil {
 		return err
@@ -201,7 +220,7 @@ func createPayment(ctx context.Context, plumbing cpPlumbing, response *CreatePay
 		return err
 	}
 
-	sig, err := paymentbroker.SignVoucher(&voucher.Channel, amount, validAt, voucher.Payer, condition, plumbing)
+	sig, err := paymentbroker.SignVoucher(&voucher.Channel, amount, validAt, voucher.Payer, condition, &voucher.Lane, plumbing)
 	if err != nil {
 		return err
 	}
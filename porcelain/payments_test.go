@@ -61,6 +61,7 @@ func newTestCreatePaymentsPlumbing() *paymentsTestPlumbing {
 				Amount:    params[1].(types.AttoFIL),
 				ValidAt:   *params[2].(*types.BlockHeight),
 				Condition: params[3].(*types.Predicate),
+				Lane:      *params[4].(*types.VoucherLane),
 			}
 			voucherBytes, err := actor.MarshalStorage(voucher)
 			if err != nil {
@@ -165,6 +166,29 @@ func TestCreatePayments(t *testing.T) {
 		assert.Nil(t, paymentResponse.Vouchers[9].Condition)
 	})
 
+	t.Run("Makes payments from an existing channel", func(t *testing.T) {
+		plumbing := newTestCreatePaymentsPlumbing()
+		plumbing.messageSend = func(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+			return cid.Undef, errors.New("no channel should be created")
+		}
+
+		config := validPaymentsConfig()
+		config.Channel = types.NewChannelID(channelID)
+		config.Lane = 3
+		paymentResponse, err := CreatePayments(context.Background(), plumbing, config)
+		require.NoError(t, err)
+
+		assert.Equal(t, config.Channel, paymentResponse.Channel)
+		assert.False(t, paymentResponse.ChannelMsgCid.Defined())
+		require.Len(t, paymentResponse.Vouchers, 10)
+
+		// payments are made on the given lane
+		for _, voucher := range paymentResponse.Vouchers {
+			assert.Equal(t, uint64(3), voucher.Lane.ID)
+		}
+		assert.Equal(t, config.Value, paymentResponse.Vouchers[9].Amount)
+	})
+
 	t.Run("Payments constructed correctly when paymentInterval does not divide duration", func(t *testing.T) {
 		config := validPaymentsConfig()
 
@@ -162,6 +162,7 @@ func buildDealRedeemParams(ctx context.Context, plumbing dealRedeemPlumbing, dea
 		voucher.Amount,
 		&voucher.ValidAt,
 		voucher.Condition,
+		&voucher.Lane,
 		[]byte(voucher.Signature),
 		[]interface{}{},
 	}, nil
@@ -8,6 +8,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
 )
 
 // ErrNoDefaultFromAddress is returned when a default wallet address couldn't be determined (eg, there are zero addresses in the wallet).
@@ -19,7 +20,20 @@ type wbPlumbing interface {
 
 // WalletBalance gets the current balance associated with an address
 func WalletBalance(ctx context.Context, plumbing wbPlumbing, addr address.Address) (types.AttoFIL, error) {
-	act, err := plumbing.ActorGet(ctx, addr)
+	return actorBalance(plumbing.ActorGet(ctx, addr))
+}
+
+type wbaPlumbing interface {
+	ActorGetAt(ctx context.Context, tsKey types.SortedCidSet, addr address.Address) (*actor.Actor, error)
+}
+
+// WalletBalanceAt gets the balance associated with an address in the state of
+// the tipset with the given key.
+func WalletBalanceAt(ctx context.Context, plumbing wbaPlumbing, tsKey types.SortedCidSet, addr address.Address) (types.AttoFIL, error) {
+	return actorBalance(plumbing.ActorGetAt(ctx, tsKey, addr))
+}
+
+func actorBalance(act *actor.Actor, err error) (types.AttoFIL, error) {
 	if err != nil {
 		if state.IsActorNotFoundError(err) {
 			// if the account doesn't exit, the balance should be zero
@@ -61,3 +75,29 @@ func WalletDefaultAddress(plumbing wdaPlumbing) (address.Address, error) {
 
 	return address.Undef, ErrNoDefaultFromAddress
 }
+
+type wrPlumbing interface {
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	WalletRestore(seed []byte, used func(address.Address) (bool, error)) ([]address.Address, error)
+}
+
+// WalletRestore restores the hierarchical deterministic wallet with the given
+// mnemonic, importing the addresses derived from it that have actors in the
+// state tree and deriving new addresses after them.
+func WalletRestore(ctx context.Context, plumbing wrPlumbing, mnemonic string) ([]address.Address, error) {
+	seed, err := wallet.SeedFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	return plumbing.WalletRestore(seed, func(addr address.Address) (bool, error) {
+		act, err := plumbing.ActorGet(ctx, addr)
+		if err != nil {
+			if state.IsActorNotFoundError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return !act.Empty() || act.Balance.IsPositive() || act.Nonce > 0, nil
+	})
+}
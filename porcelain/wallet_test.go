@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/address"
@@ -22,6 +23,10 @@ type wbTestPlumbing struct {
 	balance types.AttoFIL
 }
 
+type wbaTestPlumbing struct {
+	balances map[string]types.AttoFIL
+}
+
 type wdaTestPlumbing struct {
 	config *cfg.Config
 	wallet *wallet.Wallet
@@ -42,6 +47,14 @@ func (wbtp *wbTestPlumbing) ActorGet(ctx context.Context, addr address.Address)
 	return testActor, nil
 }
 
+func (wbatp *wbaTestPlumbing) ActorGetAt(ctx context.Context, tsKey types.SortedCidSet, addr address.Address) (*actor.Actor, error) {
+	balance, ok := wbatp.balances[tsKey.String()]
+	if !ok {
+		return nil, errors.New("unknown tipset")
+	}
+	return actor.NewActor(cid.Undef, balance), nil
+}
+
 func (wdatp *wdaTestPlumbing) ConfigGet(dottedPath string) (interface{}, error) {
 	return wdatp.config.Get(dottedPath)
 }
@@ -75,6 +88,30 @@ func TestWalletBalance(t *testing.T) {
 	})
 }
 
+func TestWalletBalanceAt(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cidGetter := types.NewCidForTestGetter()
+	oldKey := types.NewSortedCidSet(cidGetter())
+	newKey := types.NewSortedCidSet(cidGetter())
+
+	plumbing := &wbaTestPlumbing{
+		balances: map[string]types.AttoFIL{
+			oldKey.String(): types.NewAttoFILFromFIL(10),
+			newKey.String(): types.NewAttoFILFromFIL(20),
+		},
+	}
+
+	balance, err := porcelain.WalletBalanceAt(ctx, plumbing, oldKey, address.Undef)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(10), balance)
+
+	balance, err = porcelain.WalletBalanceAt(ctx, plumbing, newKey, address.Undef)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(20), balance)
+}
+
 func TestWalletDefaultAddress(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -119,3 +156,61 @@ func isInList(needle address.Address, haystack []address.Address) bool {
 	}
 	return false
 }
+
+type wrTestPlumbing struct {
+	wallet *wallet.Wallet
+	actors map[address.Address]*actor.Actor
+}
+
+type wrTestActorNotFoundError struct{}
+
+func (wrTestActorNotFoundError) Error() string       { return "actor not found" }
+func (wrTestActorNotFoundError) ActorNotFound() bool { return true }
+
+func (wrtp *wrTestPlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	act, ok := wrtp.actors[addr]
+	if !ok {
+		return nil, wrTestActorNotFoundError{}
+	}
+	return act, nil
+}
+
+func (wrtp *wrTestPlumbing) WalletRestore(seed []byte, used func(address.Address) (bool, error)) ([]address.Address, error) {
+	return wrtp.wallet.Restore(seed, used)
+}
+
+func TestWalletRestore(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mnemonic, err := wallet.NewMnemonic()
+	require.NoError(t, err)
+	seed, err := wallet.SeedFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	derived := func(index uint32) address.Address {
+		ki, err := wallet.DeriveKeyInfo(seed, index)
+		require.NoError(t, err)
+		addr, err := ki.Address()
+		require.NoError(t, err)
+		return addr
+	}
+
+	backend, err := wallet.NewDSBackend(repo.NewInMemoryRepo().WalletDatastore())
+	require.NoError(t, err)
+	plumbing := &wrTestPlumbing{
+		wallet: wallet.New(backend),
+		actors: map[address.Address]*actor.Actor{
+			derived(1): actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1)),
+			// an empty actor without funds is unused
+			derived(2): actor.NewActor(cid.Undef, types.ZeroAttoFIL),
+		},
+	}
+
+	restored, err := porcelain.WalletRestore(ctx, plumbing, mnemonic)
+	require.NoError(t, err)
+	assert.Equal(t, []address.Address{derived(1)}, restored)
+
+	_, err = porcelain.WalletRestore(ctx, plumbing, "not a valid mnemonic")
+	assert.Error(t, err)
+}
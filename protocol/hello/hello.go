@@ -19,6 +19,7 @@ import (
 )
 
 var versionErrCt = metrics.NewInt64Counter("hello_version_error", "Number of errors encountered in hello protocol due to incorrect version")
+var networkErrCt = metrics.NewInt64Counter("hello_network_error", "Number of errors encountered in hello protocol due to peers on other networks")
 var genesisErrCt = metrics.NewInt64Counter("hello_genesis_error", "Number of errors encountered in hello protocol due to incorrect genesis block")
 var helloMsgErrCt = metrics.NewInt64Counter("hello_message_error", "Number of errors encountered in hello protocol due to malformed message")
 
@@ -27,7 +28,15 @@ func init() {
 }
 
 // Protocol is the libp2p protocol identifier for the hello protocol.
-const protocol = "/fil/hello/1.0.0"
+const protocol = "/fil/hello/2.0.0"
+
+// MinProtocolVersion and MaxProtocolVersion bound the versions of the chain
+// protocol this node interoperates with. Peers whose range of versions does
+// not overlap with ours are disconnected.
+const (
+	MinProtocolVersion = 1
+	MaxProtocolVersion = 1
+)
 
 var log = logging.Logger("/fil/hello")
 
@@ -35,14 +44,21 @@ var log = logging.Logger("/fil/hello")
 type Message struct {
 	HeaviestTipSetCids   []cid.Cid
 	HeaviestTipSetHeight uint64
+	HeaviestTipSetWeight uint64
 	GenesisHash          cid.Cid
-	CommitSha            string
+	NetworkName          string
+	MinProtocolVersion   uint64
+	MaxProtocolVersion   uint64
+	// CommitSha is the commit the sender was built from, for diagnostics.
+	CommitSha string
 }
 
-type syncCallback func(from peer.ID, cids []cid.Cid, height uint64)
+type syncCallback func(from peer.ID, cids []cid.Cid, height, weight uint64)
 
 type getTipSetFunc func() (types.TipSet, error)
 
+type getWeightFunc func(types.TipSet) (uint64, error)
+
 // Handler implements the 'Hello' protocol handler. Upon connecting to a new
 // node, we send them a message containing some information about the state of
 // our chain, and receive the same information from them. This is used to
@@ -52,6 +68,9 @@ type Handler struct {
 
 	genesis cid.Cid
 
+	// networkName is the name of the network the node is on.
+	networkName string
+
 	// chainSyncCB is called when new peers tell us about their chain
 	chainSyncCB syncCallback
 
@@ -59,19 +78,26 @@ type Handler struct {
 	// for filling out our hello messages.
 	getHeaviestTipSet getTipSetFunc
 
-	net       string
-	commitSha string
+	// getWeight is used to compute the weight of the heaviest tipset.
+	getWeight getWeightFunc
+
+	minVersion uint64
+	maxVersion uint64
+	commitSha  string
 }
 
 // New creates a new instance of the hello protocol and registers it to
 // the given host, with the provided callbacks.
-func New(h host.Host, gen cid.Cid, syncCallback syncCallback, getHeaviestTipSet getTipSetFunc, net string, commitSha string) *Handler {
+func New(h host.Host, gen cid.Cid, networkName string, syncCallback syncCallback, getHeaviestTipSet getTipSetFunc, getWeight getWeightFunc, commitSha string) *Handler {
 	hello := &Handler{
 		host:              h,
 		genesis:           gen,
+		networkName:       networkName,
 		chainSyncCB:       syncCallback,
 		getHeaviestTipSet: getHeaviestTipSet,
-		net:               net,
+		getWeight:         getWeight,
+		minVersion:        MinProtocolVersion,
+		maxVersion:        MaxProtocolVersion,
 		commitSha:         commitSha,
 	}
 	h.SetStreamHandler(protocol, hello.handleNewStream)
@@ -96,13 +122,19 @@ func (h *Handler) handleNewStream(s net.Stream) {
 	}
 
 	switch err := h.processHelloMessage(from, &hello); err {
+	case ErrWrongNetwork:
+		log.Debugf("peer is on network %q, daemon is on network %q, disconnecting from peer: %s", hello.NetworkName, h.networkName, from)
+		networkErrCt.Inc(context.TODO(), 1)
+		s.Conn().Close() // nolint: errcheck
+		return
 	case ErrBadGenesis:
 		log.Debugf("genesis cid: %s does not match: %s, disconnecting from peer: %s", &hello.GenesisHash, h.genesis, from)
 		genesisErrCt.Inc(context.TODO(), 1)
 		s.Conn().Close() // nolint: errcheck
 		return
 	case ErrWrongVersion:
-		log.Debugf("code not at same version: peer has version %s, daemon has version %s, disconnecting from peer: %s", hello.CommitSha, h.commitSha, from)
+		log.Debugf("incompatible protocol versions: peer supports %d-%d (commit %s), daemon supports %d-%d (commit %s), disconnecting from peer: %s",
+			hello.MinProtocolVersion, hello.MaxProtocolVersion, hello.CommitSha, h.minVersion, h.maxVersion, h.commitSha, from)
 		versionErrCt.Inc(context.TODO(), 1)
 		s.Conn().Close() // nolint: errcheck
 		return
@@ -112,25 +144,32 @@ func (h *Handler) handleNewStream(s net.Stream) {
 	}
 }
 
+// ErrWrongNetwork is the error returned when a peer is on another network.
+var ErrWrongNetwork = fmt.Errorf("network mismatch")
+
 // ErrBadGenesis is the error returned when a mismatch in genesis blocks happens.
 var ErrBadGenesis = fmt.Errorf("bad genesis block")
 
-// ErrWrongVersion is the error returned when a mismatch in the code version happens.
-var ErrWrongVersion = fmt.Errorf("code version mismatch")
+// ErrWrongVersion is the error returned when the protocol versions supported
+// by a peer do not overlap with ours.
+var ErrWrongVersion = fmt.Errorf("protocol version mismatch")
 
 func (h *Handler) processHelloMessage(from peer.ID, msg *Message) error {
+	if msg.NetworkName != h.networkName {
+		return ErrWrongNetwork
+	}
 	if !msg.GenesisHash.Equals(h.genesis) {
 		return ErrBadGenesis
 	}
-	if (h.net == "devnet-test" || h.net == "devnet-user") && msg.CommitSha != h.commitSha {
+	if msg.MinProtocolVersion > msg.MaxProtocolVersion || msg.MaxProtocolVersion < h.minVersion || msg.MinProtocolVersion > h.maxVersion {
 		return ErrWrongVersion
 	}
 
-	h.chainSyncCB(from, msg.HeaviestTipSetCids, msg.HeaviestTipSetHeight)
+	h.chainSyncCB(from, msg.HeaviestTipSetCids, msg.HeaviestTipSetHeight, msg.HeaviestTipSetWeight)
 	return nil
 }
 
-func (h *Handler) getOurHelloMessage() *Message {
+func (h *Handler) getOurHelloMessage() (*Message, error) {
 	heaviest, err := h.getHeaviestTipSet()
 	if err != nil {
 		panic("cannot fetch chain head")
@@ -139,24 +178,35 @@ func (h *Handler) getOurHelloMessage() *Message {
 	if err != nil {
 		panic("somehow heaviest tipset is empty")
 	}
+	weight, err := h.getWeight(heaviest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute weight of heaviest tipset %s: %s", heaviest, err)
+	}
 
 	return &Message{
 		GenesisHash:          h.genesis,
+		NetworkName:          h.networkName,
 		HeaviestTipSetCids:   heaviest.ToSortedCidSet().ToSlice(),
 		HeaviestTipSetHeight: height,
+		HeaviestTipSetWeight: weight,
+		MinProtocolVersion:   h.minVersion,
+		MaxProtocolVersion:   h.maxVersion,
 		CommitSha:            h.commitSha,
-	}
+	}, nil
 }
 
 func (h *Handler) sayHello(ctx context.Context, p peer.ID) error {
+	msg, err := h.getOurHelloMessage()
+	if err != nil {
+		return err
+	}
+
 	s, err := h.host.NewStream(ctx, p, protocol)
 	if err != nil {
 		return err
 	}
 	defer s.Close() // nolint: errcheck
 
-	msg := h.getOurHelloMessage()
-
 	return cbu.NewMsgWriter(s).WriteMsg(&msg)
 }
 
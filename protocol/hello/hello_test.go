@@ -22,8 +22,8 @@ type mockSyncCallback struct {
 	mock.Mock
 }
 
-func (msb *mockSyncCallback) SyncCallback(p peer.ID, cids []cid.Cid, h uint64) {
-	msb.Called(p, cids, h)
+func (msb *mockSyncCallback) SyncCallback(p peer.ID, cids []cid.Cid, h, w uint64) {
+	msb.Called(p, cids, h, w)
 }
 
 type mockHeaviestGetter struct {
@@ -34,6 +34,12 @@ func (mhg *mockHeaviestGetter) getHeaviestTipSet() (types.TipSet, error) {
 	return mhg.heaviest, nil
 }
 
+// mockWeight weighs tipsets ten times their height.
+func mockWeight(ts types.TipSet) (uint64, error) {
+	h, err := ts.Height()
+	return h * 10, err
+}
+
 func TestHelloHandshake(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -54,11 +60,11 @@ func TestHelloHandshake(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet, "", "")
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet, "", "")
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg1.getHeaviestTipSet, mockWeight, "")
+	New(b, genesisA.Cid(), "test", msc2.SyncCallback, hg2.getHeaviestTipSet, mockWeight, "")
 
-	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3)).Return()
-	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2)).Return()
+	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3), uint64(30)).Return()
+	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2), uint64(20)).Return()
 
 	require.NoError(t, mn.LinkAll())
 	require.NoError(t, mn.ConnectAllButSelf())
@@ -108,11 +114,11 @@ func TestHelloBadGenesis(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet, "", "")
-	New(b, genesisB.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet, "", "")
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg1.getHeaviestTipSet, mockWeight, "")
+	New(b, genesisB.Cid(), "test", msc2.SyncCallback, hg2.getHeaviestTipSet, mockWeight, "")
 
-	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
-	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
 	require.NoError(t, mn.LinkAll())
 	require.NoError(t, mn.ConnectAllButSelf())
@@ -123,7 +129,7 @@ func TestHelloBadGenesis(t *testing.T) {
 	msc2.AssertNumberOfCalls(t, "SyncCallback", 0)
 }
 
-func TestHelloWrongVersion(t *testing.T) {
+func TestHelloWrongNetwork(t *testing.T) {
 	tf.UnitTest(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -132,20 +138,22 @@ func TestHelloWrongVersion(t *testing.T) {
 	mn, err := mocknet.WithNPeers(ctx, 2)
 	assert.NoError(t, err)
 
-	a, b := mn.Hosts()[0], mn.Hosts()[1]
+	a := mn.Hosts()[0]
+	b := mn.Hosts()[1]
 
 	genesisA := &types.Block{Nonce: 451}
 
-	heavy := th.RequireNewTipSet(t, &types.Block{Nonce: 1000, Height: 2})
+	heavy1 := th.RequireNewTipSet(t, &types.Block{Nonce: 1000, Height: 2})
+	heavy2 := th.RequireNewTipSet(t, &types.Block{Nonce: 1001, Height: 3})
 
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
-	hg := &mockHeaviestGetter{heavy}
+	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg.getHeaviestTipSet, "devnet-user", "sha1")
-	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg1.getHeaviestTipSet, mockWeight, "")
+	New(b, genesisA.Cid(), "other", msc2.SyncCallback, hg2.getHeaviestTipSet, mockWeight, "")
 
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg.getHeaviestTipSet, "devnet-user", "sha2")
-	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
 	require.NoError(t, mn.LinkAll())
 	require.NoError(t, mn.ConnectAllButSelf())
@@ -156,7 +164,7 @@ func TestHelloWrongVersion(t *testing.T) {
 	msc2.AssertNumberOfCalls(t, "SyncCallback", 0)
 }
 
-func TestHelloWrongVersionTestDevnet(t *testing.T) {
+func TestHelloWrongVersion(t *testing.T) {
 	tf.UnitTest(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -174,11 +182,12 @@ func TestHelloWrongVersionTestDevnet(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg := &mockHeaviestGetter{heavy}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg.getHeaviestTipSet, "devnet-test", "sha1")
-	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg.getHeaviestTipSet, mockWeight, "sha1")
+	msc1.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg.getHeaviestTipSet, "devnet-test", "sha2")
-	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything).Return()
+	hb := New(b, genesisA.Cid(), "test", msc2.SyncCallback, hg.getHeaviestTipSet, mockWeight, "sha1")
+	hb.minVersion, hb.maxVersion = MaxProtocolVersion+1, MaxProtocolVersion+2
+	msc2.On("SyncCallback", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 
 	require.NoError(t, mn.LinkAll())
 	require.NoError(t, mn.ConnectAllButSelf())
@@ -189,6 +198,42 @@ func TestHelloWrongVersionTestDevnet(t *testing.T) {
 	msc2.AssertNumberOfCalls(t, "SyncCallback", 0)
 }
 
+func TestHelloOverlappingVersions(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	assert.NoError(t, err)
+
+	a, b := mn.Hosts()[0], mn.Hosts()[1]
+
+	genesisA := &types.Block{Nonce: 451}
+
+	heavy := th.RequireNewTipSet(t, &types.Block{Nonce: 1000, Height: 2})
+
+	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
+	hg := &mockHeaviestGetter{heavy}
+
+	// different commits speaking a common protocol version get along
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg.getHeaviestTipSet, mockWeight, "sha1")
+	msc1.On("SyncCallback", b.ID(), heavy.ToSortedCidSet().ToSlice(), uint64(2), uint64(20)).Return()
+
+	hb := New(b, genesisA.Cid(), "test", msc2.SyncCallback, hg.getHeaviestTipSet, mockWeight, "sha2")
+	hb.minVersion, hb.maxVersion = MaxProtocolVersion, MaxProtocolVersion+1
+	msc2.On("SyncCallback", a.ID(), heavy.ToSortedCidSet().ToSlice(), uint64(2), uint64(20)).Return()
+
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+
+	require.NoError(t, th.WaitForIt(10, 50*time.Millisecond, func() (bool, error) {
+		return len(msc1.Calls) > 0 && len(msc2.Calls) > 0, nil
+	}))
+	msc1.AssertExpectations(t)
+	msc2.AssertExpectations(t)
+}
+
 func TestHelloMultiBlock(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -217,11 +262,11 @@ func TestHelloMultiBlock(t *testing.T) {
 	msc1, msc2 := new(mockSyncCallback), new(mockSyncCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	New(a, genesisA.Cid(), msc1.SyncCallback, hg1.getHeaviestTipSet, "", "")
-	New(b, genesisA.Cid(), msc2.SyncCallback, hg2.getHeaviestTipSet, "", "")
+	New(a, genesisA.Cid(), "test", msc1.SyncCallback, hg1.getHeaviestTipSet, mockWeight, "")
+	New(b, genesisA.Cid(), "test", msc2.SyncCallback, hg2.getHeaviestTipSet, mockWeight, "")
 
-	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3)).Return()
-	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2)).Return()
+	msc1.On("SyncCallback", b.ID(), heavy2.ToSortedCidSet().ToSlice(), uint64(3), uint64(30)).Return()
+	msc2.On("SyncCallback", a.ID(), heavy1.ToSortedCidSet().ToSlice(), uint64(2), uint64(20)).Return()
 
 	assert.NoError(t, mn.LinkAll())
 	assert.NoError(t, mn.ConnectAllButSelf())
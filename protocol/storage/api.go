@@ -4,20 +4,26 @@ import (
 	"context"
 
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/repo"
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
-// API here is the API for a storage client.
+// API here is the API for a storage client and the node's storage miner.
 type API struct {
-	sc *Client
+	sc      *Client
+	dealsDs repo.Datastore
+	miner   func() *Miner
 }
 
-// NewAPI creates a new API for a storage client.
-func NewAPI(storageClient *Client) API {
-	return API{sc: storageClient}
+// NewAPI creates a new API for a storage client. dealsDs is the datastore
+// the node's storage miner persists its state in, and miner returns the
+// running storage miner, or nil if the node is not mining.
+func NewAPI(storageClient *Client, dealsDs repo.Datastore, miner func() *Miner) API {
+	return API{sc: storageClient, dealsDs: dealsDs, miner: miner}
 }
 
 // ProposeStorageDeal calls the storage client ProposeDeal function
@@ -36,3 +42,28 @@ func (a *API) QueryStorageDeal(ctx context.Context, prop cid.Cid) (*storagedeal.
 func (a *API) Payments(ctx context.Context, dealCid cid.Cid) ([]*types.PaymentVoucher, error) {
 	return a.sc.LoadVouchersForDeal(ctx, dealCid)
 }
+
+// ProvingStatus returns the persisted state of the storage miner's proving scheduler.
+func (a *API) ProvingStatus() (*ProvingSchedule, error) {
+	return LoadProvingSchedule(a.dealsDs)
+}
+
+// SectorsLs returns the storage miner's sectors ordered by id.
+func (a *API) SectorsLs() ([]*Sector, error) {
+	return LoadSectors(a.dealsDs)
+}
+
+// SectorStatus returns the storage miner's record of a sector.
+func (a *API) SectorStatus(sectorID uint64) (*Sector, error) {
+	return LoadSector(a.dealsDs, sectorID)
+}
+
+// SectorSealNow schedules sealing of a staged sector. It fails if the node is
+// not mining.
+func (a *API) SectorSealNow(ctx context.Context, sectorID uint64) (*Sector, error) {
+	miner := a.miner()
+	if miner == nil {
+		return nil, errors.New("node is not mining")
+	}
+	return miner.SealNow(ctx, sectorID)
+}
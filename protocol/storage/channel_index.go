@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+)
+
+// channelIndex indexes the miner's deals with unsettled payments by payment
+// channel, so they can be redeemed without listing every deal the miner has
+// stored. It also tracks the proposals being handled for each channel and the
+// channels with a redeem or close message that has not been mined yet, and
+// decides when a channel is no longer used and may be closed.
+type channelIndex struct {
+	lk sync.Mutex
+
+	// loaded is true once the deals stored before the miner started have
+	// been indexed.
+	loaded bool
+	// deals holds the proposal cids of the unsettled deals of each channel.
+	deals map[string]map[cid.Cid]struct{}
+	// proposing counts the proposals being handled for each channel.
+	proposing map[string]int
+	// redeeming holds the channels with a redeem or close message that has
+	// not been mined yet.
+	redeeming map[string]bool
+	// closing holds the channels a close message has been sent for.
+	closing map[string]bool
+}
+
+func newChannelIndex() *channelIndex {
+	return &channelIndex{
+		deals:     make(map[string]map[cid.Cid]struct{}),
+		proposing: make(map[string]int),
+		redeeming: make(map[string]bool),
+		closing:   make(map[string]bool),
+	}
+}
+
+func (ci *channelIndex) isLoaded() bool {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	return ci.loaded
+}
+
+func (ci *channelIndex) setLoaded() {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	ci.loaded = true
+}
+
+// add records that the deal with proposalCid pays through the channel.
+func (ci *channelIndex) add(key string, proposalCid cid.Cid) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if _, ok := ci.deals[key]; !ok {
+		ci.deals[key] = make(map[cid.Cid]struct{})
+	}
+	ci.deals[key][proposalCid] = struct{}{}
+}
+
+// remove drops the deal with proposalCid, once its payment is settled.
+func (ci *channelIndex) remove(key string, proposalCid cid.Cid) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	delete(ci.deals[key], proposalCid)
+	if len(ci.deals[key]) == 0 {
+		delete(ci.deals, key)
+	}
+}
+
+// channels returns the keys of the channels with unsettled deals in a stable
+// order.
+func (ci *channelIndex) channels() []string {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	keys := make([]string, 0, len(ci.deals))
+	for key := range ci.deals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dealCids returns the proposal cids of the unsettled deals of the channel.
+func (ci *channelIndex) dealCids(key string) []cid.Cid {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	cids := make([]cid.Cid, 0, len(ci.deals[key]))
+	for c := range ci.deals[key] {
+		cids = append(cids, c)
+	}
+	sort.Slice(cids, func(i, j int) bool { return cids[i].KeyString() < cids[j].KeyString() })
+	return cids
+}
+
+// beginProposal records that a proposal paying through the channel is being
+// handled. It returns false if the channel is being closed, in which case the
+// proposal must be rejected.
+func (ci *channelIndex) beginProposal(key string) bool {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if ci.closing[key] {
+		return false
+	}
+	ci.proposing[key]++
+	return true
+}
+
+// endProposal records that a proposal begun with beginProposal was handled.
+func (ci *channelIndex) endProposal(key string) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	ci.proposing[key]--
+	if ci.proposing[key] <= 0 {
+		delete(ci.proposing, key)
+	}
+}
+
+// startClose marks the channel as closing if the deal with proposalCid is
+// the only one using it, and returns whether it did. Proposals for the
+// channel are rejected until the close fails.
+func (ci *channelIndex) startClose(key string, proposalCid cid.Cid) bool {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if ci.proposing[key] > 0 {
+		return false
+	}
+	for c := range ci.deals[key] {
+		if !c.Equals(proposalCid) {
+			return false
+		}
+	}
+	ci.closing[key] = true
+	return true
+}
+
+// setClosing records whether a close message for the channel is pending or
+// has been mined.
+func (ci *channelIndex) setClosing(key string, closing bool) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if closing {
+		ci.closing[key] = true
+	} else {
+		delete(ci.closing, key)
+	}
+}
+
+func (ci *channelIndex) isRedeeming(key string) bool {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	return ci.redeeming[key]
+}
+
+func (ci *channelIndex) setRedeeming(key string, redeeming bool) {
+	ci.lk.Lock()
+	defer ci.lk.Unlock()
+	if redeeming {
+		ci.redeeming[key] = true
+	} else {
+		delete(ci.redeeming, key)
+	}
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type channelManagerAPI interface {
+	ChainBlockHeight() (*types.BlockHeight, error)
+	DealsLs(context.Context) (<-chan *porcelain.StorageDealLsResult, error)
+	GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error)
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error)
+	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
+}
+
+// channelAllocation is the lane of an existing payment channel set aside for
+// the payments of a new deal.
+type channelAllocation struct {
+	// Channel is the payment channel the deal is paid from.
+	Channel *types.ChannelID
+
+	// Lane is the lane of the channel the deal's vouchers are drawn on.
+	Lane uint64
+
+	// Eol is the channel's end of life once the allocation is made.
+	Eol *types.BlockHeight
+
+	// MsgCid is the message that added funds to the channel or extended it,
+	// or nil if the channel already had enough funds.
+	MsgCid *cid.Cid
+}
+
+// channelTopUp tracks the messages adding funds to a channel that have been
+// sent but not yet mined.
+type channelTopUp struct {
+	amount types.AttoFIL
+	eol    *types.BlockHeight
+	msgCid cid.Cid
+	count  int
+}
+
+// channelManager reuses the client's open payment channels to a miner for
+// new deals with the miner, rather than opening a channel per deal. Each deal
+// is paid on its own lane of the channel, so a voucher for one deal can never
+// redeem the payments of another, and the channel is topped up and extended
+// as needed to cover the payments of all its lanes.
+type channelManager struct {
+	api channelManagerAPI
+
+	lk sync.Mutex
+	// lanes are the lanes of each payment channel reserved for deals proposed
+	// by this node and their amounts, keyed by channelAllocationKey. They
+	// cover deals that are not stored yet. Reservations of proposed deals are
+	// never released, since a proposal may reach the miner even if the client
+	// never learns about it.
+	lanes map[string]map[uint64]types.AttoFIL
+	// topUps are the funds being added to each payment channel by messages
+	// that have not been mined yet, keyed by channelAllocationKey.
+	topUps map[string]*channelTopUp
+}
+
+func newChannelManager(api channelManagerAPI) *channelManager {
+	return &channelManager{
+		api:    api,
+		lanes:  make(map[string]map[uint64]types.AttoFIL),
+		topUps: make(map[string]*channelTopUp),
+	}
+}
+
+// allocate reserves a lane for value in one of payer's open payment channels
+// to target, adding funds to the channel and extending it to expiry as
+// needed. It returns nil if payer has no channel to target that can be
+// reused. The channel lock is not held while waiting for channel messages to
+// be mined, so other deals can be allocated in the meantime.
+func (cm *channelManager) allocate(ctx context.Context, payer, target address.Address, value types.AttoFIL, expiry *types.BlockHeight) (*channelAllocation, error) {
+	allocation, topUp, err := cm.reserveLane(ctx, payer, target, value, expiry)
+	if err != nil || allocation == nil || allocation.MsgCid == nil {
+		return allocation, err
+	}
+
+	err = cm.waitChannelMessage(ctx, *allocation.MsgCid)
+
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	key := channelAllocationKey(payer, allocation.Channel)
+	if topUp != nil {
+		// the funds are part of the channel once the message is mined
+		pending := cm.topUps[key]
+		pending.amount = pending.amount.Sub(*topUp)
+		pending.count--
+		if pending.count == 0 {
+			delete(cm.topUps, key)
+		}
+	}
+	if err != nil {
+		// no proposal is made for the allocation, so its lane can be reused
+		delete(cm.lanes[key], allocation.Lane)
+		return nil, err
+	}
+	return allocation, nil
+}
+
+// reserveLane picks the channel and lane for a new allocation, reserves the
+// lane and sends any message needed to fund the channel. It returns the
+// amount sent to the channel if it sent a message.
+func (cm *channelManager) reserveLane(ctx context.Context, payer, target address.Address, value types.AttoFIL, expiry *types.BlockHeight) (*channelAllocation, *types.AttoFIL, error) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	height, err := cm.api.ChainBlockHeight()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get block height")
+	}
+
+	ret, err := cm.api.MessageQuery(ctx, address.Undef, address.PaymentBrokerAddress, "ls", payer)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list payment channels")
+	}
+	var channels map[string]*paymentbroker.PaymentChannel
+	if err := cbor.DecodeInto(ret[0], &channels); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode payment channels")
+	}
+
+	var channelKey string
+	var channel *paymentbroker.PaymentChannel
+	for key, ch := range channels {
+		if ch.Target != target || ch.Eol.LessEqual(height) {
+			continue
+		}
+		if channel == nil || ch.Eol.GreaterThan(channel.Eol) {
+			channelKey, channel = key, ch
+		}
+	}
+	if channel == nil {
+		return nil, nil, nil
+	}
+
+	chid, err := channelIDFromKey(channelKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := channelAllocationKey(payer, chid)
+
+	lanes, err := cm.allocatedLanes(ctx, payer, chid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Lane 0 belongs to the deal that opened the channel, so reused channels
+	// start at lane 1.
+	lane := uint64(1)
+	allocated := types.ZeroAttoFIL
+	for id, amount := range lanes {
+		allocated = allocated.Add(amount)
+		if id >= lane {
+			lane = id + 1
+		}
+	}
+
+	funds, eol := channel.Amount, channel.Eol
+	pending := cm.topUps[key]
+	if pending != nil {
+		funds = funds.Add(pending.amount)
+		if pending.eol.GreaterThan(eol) {
+			eol = pending.eol
+		}
+	}
+
+	allocation := &channelAllocation{
+		Channel: chid,
+		Lane:    lane,
+		Eol:     eol,
+	}
+
+	topUp := types.ZeroAttoFIL
+	if required := allocated.Add(value); required.GreaterThan(funds) {
+		topUp = required.Sub(funds)
+	}
+
+	var msgCid cid.Cid
+	if eol.LessThan(expiry) {
+		allocation.Eol = expiry
+		msgCid, err = cm.sendChannelMessage(ctx, payer, topUp, "extend", chid, expiry)
+	} else if topUp.IsPositive() {
+		msgCid, err = cm.sendChannelMessage(ctx, payer, topUp, "addFunds", chid)
+	} else if pending != nil {
+		// the channel has enough funds once the messages already sent are
+		// mined, which happens no later than the last of them
+		pendingCid := pending.msgCid
+		allocation.MsgCid = &pendingCid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sent *types.AttoFIL
+	if msgCid.Defined() {
+		allocation.MsgCid = &msgCid
+		sent = &topUp
+		if pending == nil {
+			pending = &channelTopUp{amount: types.ZeroAttoFIL, eol: eol}
+			cm.topUps[key] = pending
+		}
+		pending.amount = pending.amount.Add(topUp)
+		pending.eol = allocation.Eol
+		pending.msgCid = msgCid
+		pending.count++
+	}
+
+	cm.reserveLocked(key, lane, value)
+	return allocation, sent, nil
+}
+
+// reserve records that lane of payer's channel chid is allocated to a deal
+// paying amount.
+func (cm *channelManager) reserve(payer address.Address, chid *types.ChannelID, lane uint64, amount types.AttoFIL) {
+	cm.lk.Lock()
+	defer cm.lk.Unlock()
+
+	cm.reserveLocked(channelAllocationKey(payer, chid), lane, amount)
+}
+
+func (cm *channelManager) reserveLocked(key string, lane uint64, amount types.AttoFIL) {
+	if cm.lanes[key] == nil {
+		cm.lanes[key] = make(map[uint64]types.AttoFIL)
+	}
+	cm.lanes[key][lane] = amount
+}
+
+// allocatedLanes are the lanes of payer's channel chid already allocated to
+// deals and the amounts they pay, either by the vouchers of stored deals or
+// by reservations.
+func (cm *channelManager) allocatedLanes(ctx context.Context, payer address.Address, chid *types.ChannelID) (map[uint64]types.AttoFIL, error) {
+	key := channelAllocationKey(payer, chid)
+	lanes := make(map[uint64]types.AttoFIL)
+	for lane, amount := range cm.lanes[key] {
+		lanes[lane] = amount
+	}
+
+	dealsCh, err := cm.api.DealsLs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deals")
+	}
+	for result := range dealsCh {
+		if result.Err != nil {
+			return nil, errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if deal.Proposal == nil || deal.Proposal.Payment.Channel == nil || deal.Proposal.Payment.Payer != payer {
+			continue
+		}
+		if deal.Response != nil && deal.Response.State == storagedeal.Rejected {
+			continue
+		}
+		if paymentChannelKey(deal.Proposal) != key {
+			continue
+		}
+		lane := deal.Proposal.Payment.Lane
+		if amount := finalVoucherAmount(&deal); amount.GreaterThan(lanes[lane]) {
+			lanes[lane] = amount
+		}
+	}
+	return lanes, nil
+}
+
+// sendChannelMessage sends a message to the payment broker adding value to a
+// channel of payer.
+func (cm *channelManager) sendChannelMessage(ctx context.Context, payer address.Address, value types.AttoFIL, method string, params ...interface{}) (cid.Cid, error) {
+	gasLimit, err := cm.api.GasLimitEstimate(ctx, payer, address.PaymentBrokerAddress, value, method, params...)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "failed to estimate %s gas limit", method)
+	}
+
+	msgCid, err := cm.api.MessageSend(
+		ctx,
+		payer,
+		address.PaymentBrokerAddress,
+		value,
+		types.NewAttoFIL(big.NewInt(CreateChannelGasPrice)),
+		gasLimit,
+		method,
+		params...,
+	)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "failed to send %s message", method)
+	}
+	return msgCid, nil
+}
+
+// waitChannelMessage waits for a message sent by sendChannelMessage to
+// succeed.
+func (cm *channelManager) waitChannelMessage(ctx context.Context, msgCid cid.Cid) error {
+	return cm.api.MessageWait(ctx, msgCid, func(blk *types.Block, msg *types.SignedMessage, receipt *types.MessageReceipt) error {
+		if receipt.ExitCode != uint8(0) {
+			return fmt.Errorf("payment channel message %s failed %d", msgCid, receipt.ExitCode)
+		}
+		return nil
+	})
+}
+
+func channelAllocationKey(payer address.Address, chid *types.ChannelID) string {
+	return payer.String() + "/" + chid.KeyString()
+}
+
+func channelIDFromKey(key string) (*types.ChannelID, error) {
+	chid, ok := types.NewChannelIDFromString(key, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid payment channel id %s", key)
+	}
+	return chid, nil
+}
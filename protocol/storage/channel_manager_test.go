@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+type channelManagerTestAPI struct {
+	t        *testing.T
+	height   *types.BlockHeight
+	channels map[string]*paymentbroker.PaymentChannel
+	deals    []*storagedeal.Deal
+	sent     []string
+	values   []types.AttoFIL
+	msgCid   cid.Cid
+	cids     func() cid.Cid
+	unmined  map[cid.Cid]func()
+}
+
+func newChannelManagerTestAPI(t *testing.T) *channelManagerTestAPI {
+	return &channelManagerTestAPI{
+		t:        t,
+		height:   types.NewBlockHeight(100),
+		channels: make(map[string]*paymentbroker.PaymentChannel),
+		cids:     types.NewCidForTestGetter(),
+		unmined:  make(map[cid.Cid]func()),
+	}
+}
+
+func (api *channelManagerTestAPI) ChainBlockHeight() (*types.BlockHeight, error) {
+	return api.height, nil
+}
+
+func (api *channelManagerTestAPI) DealsLs(_ context.Context) (<-chan *porcelain.StorageDealLsResult, error) {
+	out := make(chan *porcelain.StorageDealLsResult, len(api.deals))
+	for _, deal := range api.deals {
+		out <- &porcelain.StorageDealLsResult{Deal: *deal}
+	}
+	close(out)
+	return out, nil
+}
+
+func (api *channelManagerTestAPI) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
+	channelsBytes, err := actor.MarshalStorage(api.channels)
+	require.NoError(api.t, err)
+	return [][]byte{channelsBytes}, nil
+}
+
+// MessageSend records addFunds and extend messages, which are applied to the
+// test channels once they are waited for.
+func (api *channelManagerTestAPI) GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	return types.NewGasUnits(300), nil
+}
+
+func (api *channelManagerTestAPI) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	api.sent = append(api.sent, method)
+	api.values = append(api.values, value)
+	api.msgCid = api.cids()
+	api.unmined[api.msgCid] = func() {
+		channel := api.channels[params[0].(*types.ChannelID).KeyString()]
+		channel.Amount = channel.Amount.Add(value)
+		if method == "extend" {
+			channel.Eol = params[1].(*types.BlockHeight)
+		}
+	}
+	return api.msgCid, nil
+}
+
+func (api *channelManagerTestAPI) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
+	if apply, ok := api.unmined[msgCid]; ok {
+		apply()
+		delete(api.unmined, msgCid)
+	}
+	return cb(&types.Block{}, &types.SignedMessage{}, &types.MessageReceipt{ExitCode: 0})
+}
+
+func (api *channelManagerTestAPI) addChannel(id uint64, target address.Address, amount uint64, eol uint64) *types.ChannelID {
+	chid := types.NewChannelID(id)
+	api.channels[chid.KeyString()] = &paymentbroker.PaymentChannel{
+		Target:         target,
+		Amount:         types.NewAttoFILFromFIL(amount),
+		AmountRedeemed: types.ZeroAttoFIL,
+		AgreedEol:      types.NewBlockHeight(eol),
+		Eol:            types.NewBlockHeight(eol),
+	}
+	return chid
+}
+
+func TestChannelManagerAllocate(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addresses := address.NewForTestGetter()
+	payer, target := addresses(), addresses()
+
+	t.Run("returns nil without an open channel to the target", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		api.addChannel(1, addresses(), 100, 1000)
+		api.addChannel(2, target, 100, 100)
+		cm := newChannelManager(api)
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(10), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Nil(t, allocation)
+		assert.Empty(t, api.sent)
+	})
+
+	t.Run("adds funds to a reused channel", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		chid := api.addChannel(1, target, 10, 1000)
+		cm := newChannelManager(api)
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		require.NotNil(t, allocation)
+		assert.Equal(t, chid, allocation.Channel)
+		assert.Equal(t, uint64(1), allocation.Lane)
+		assert.True(t, types.NewBlockHeight(1000).Equal(allocation.Eol))
+		assert.Equal(t, &api.msgCid, allocation.MsgCid)
+		assert.Equal(t, []string{"addFunds"}, api.sent)
+		assert.True(t, types.NewAttoFILFromFIL(15).Equal(api.channels[chid.KeyString()].Amount))
+
+		// the next allocation gets the next lane
+		allocation, err = cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(5), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), allocation.Lane)
+		assert.True(t, types.NewAttoFILFromFIL(20).Equal(api.channels[chid.KeyString()].Amount))
+	})
+
+	t.Run("sends no message when the channel has enough funds", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		api.addChannel(1, target, 100, 1000)
+		cm := newChannelManager(api)
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Nil(t, allocation.MsgCid)
+		assert.Empty(t, api.sent)
+	})
+
+	t.Run("extends a channel expiring too early", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		chid := api.addChannel(1, target, 10, 1000)
+		cm := newChannelManager(api)
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(2000))
+		require.NoError(t, err)
+		assert.True(t, types.NewBlockHeight(2000).Equal(allocation.Eol))
+		assert.Equal(t, []string{"extend"}, api.sent)
+		assert.True(t, types.NewBlockHeight(2000).Equal(api.channels[chid.KeyString()].Eol))
+		assert.True(t, types.NewAttoFILFromFIL(15).Equal(api.channels[chid.KeyString()].Amount))
+	})
+
+	t.Run("allocates a lane after the lanes of stored deals", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		chid := api.addChannel(1, target, 40, 1000)
+		api.deals = []*storagedeal.Deal{{
+			Proposal: &storagedeal.Proposal{Payment: storagedeal.PaymentInfo{
+				Payer:    payer,
+				Channel:  chid,
+				Lane:     3,
+				Vouchers: []*types.PaymentVoucher{{Amount: types.NewAttoFILFromFIL(20)}, {Amount: types.NewAttoFILFromFIL(30)}},
+			}},
+			Response: &storagedeal.Response{State: storagedeal.Accepted},
+		}}
+		cm := newChannelManager(api)
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), allocation.Lane)
+
+		// the channel covers the lanes of both deals
+		assert.Equal(t, []string{"addFunds"}, api.sent)
+		assert.True(t, types.NewAttoFILFromFIL(45).Equal(api.channels[chid.KeyString()].Amount))
+	})
+
+	t.Run("allocates a lane after reservations of new channels", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		chid := api.addChannel(1, target, 100, 1000)
+		cm := newChannelManager(api)
+		cm.reserve(payer, chid, 0, types.NewAttoFILFromFIL(90))
+
+		allocation, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), allocation.Lane)
+		assert.True(t, types.NewAttoFILFromFIL(105).Equal(api.channels[chid.KeyString()].Amount))
+	})
+
+	t.Run("counts funds of messages that are not mined yet", func(t *testing.T) {
+		api := newChannelManagerTestAPI(t)
+		chid := api.addChannel(1, target, 10, 1000)
+		cm := newChannelManager(api)
+
+		// the first allocation has sent its message but not waited for it
+		first, _, err := cm.reserveLane(ctx, payer, target, types.NewAttoFILFromFIL(15), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		require.NotNil(t, first.MsgCid)
+
+		second, err := cm.allocate(ctx, payer, target, types.NewAttoFILFromFIL(5), types.NewBlockHeight(500))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), second.Lane)
+		require.Len(t, api.values, 2)
+		assert.True(t, types.NewAttoFILFromFIL(5).Equal(api.values[1]))
+
+		require.NoError(t, api.MessageWait(ctx, *first.MsgCid, func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error { return nil }))
+		assert.True(t, types.NewAttoFILFromFIL(20).Equal(api.channels[chid.KeyString()].Amount))
+	})
+}
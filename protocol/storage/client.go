@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
 	"github.com/libp2p/go-libp2p-host"
 	"github.com/libp2p/go-libp2p-peer"
+	pstore "github.com/libp2p/go-libp2p-peerstore"
 	"github.com/libp2p/go-libp2p-protocol"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/multiformats/go-multistream"
 	"github.com/pkg/errors"
 
@@ -45,9 +48,6 @@ const (
 
 	// CreateChannelGasPrice is the gas price of the message used to create the payment channel
 	CreateChannelGasPrice = 1
-
-	// CreateChannelGasLimit is the gas limit of the message used to create the payment channel
-	CreateChannelGasLimit = 300
 )
 
 type clientPorcelainAPI interface {
@@ -58,11 +58,15 @@ type clientPorcelainAPI interface {
 	DAGGetFileSize(context.Context, cid.Cid) (uint64, error)
 	DealPut(*storagedeal.Deal) error
 	DealsLs(context.Context) (<-chan *porcelain.StorageDealLsResult, error)
+	GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error)
+	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error
 	MinerGetAsk(ctx context.Context, minerAddr address.Address, askID uint64) (miner.Ask, error)
 	MinerGetSectorSize(ctx context.Context, minerAddr address.Address) (*types.BytesAmount, error)
 	MinerGetOwnerAddress(ctx context.Context, minerAddr address.Address) (address.Address, error)
 	MinerGetPeerID(ctx context.Context, minerAddr address.Address) (peer.ID, error)
+	MinerGetPeerAddrs(ctx context.Context, minerAddr address.Address) ([]ma.Multiaddr, error)
 	types.Signer
 	PingMinerWithTimeout(ctx context.Context, p peer.ID, to time.Duration) error
 	WalletDefaultAddress() (address.Address, error)
@@ -71,18 +75,33 @@ type clientPorcelainAPI interface {
 // Client is used to make deals directly with storage miners.
 type Client struct {
 	api                 clientPorcelainAPI
+	channels            *channelManager
 	host                host.Host
 	log                 logging.EventLogger
 	ProtocolRequestFunc func(ctx context.Context, protocol protocol.ID, peer peer.ID, host host.Host, request interface{}, response interface{}) error
+
+	protectedLk sync.Mutex
+	// protected are the in-progress deals whose miners are protected from
+	// connection trimming, keyed by proposal cid.
+	protected map[cid.Cid]protectedDeal
+}
+
+// protectedDeal is the miner of an in-progress deal and the block height at
+// which the deal ends.
+type protectedDeal struct {
+	miner peer.ID
+	end   *types.BlockHeight
 }
 
 // NewClient creates a new storage client.
 func NewClient(host host.Host, api clientPorcelainAPI) *Client {
 	smc := &Client{
 		api:                 api,
+		channels:            newChannelManager(api),
 		host:                host,
 		log:                 logging.Logger("storage/client"),
 		ProtocolRequestFunc: MakeProtocolRequest,
+		protected:           make(map[cid.Cid]protectedDeal),
 	}
 	return smc
 }
@@ -93,7 +112,7 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	ctxSetup, cancel := context.WithTimeout(ctx, 5*smc.api.BlockTime())
 	defer cancel()
 
-	pid, err := smc.api.MinerGetPeerID(ctxSetup, miner)
+	pid, err := smc.minerPeer(ctxSetup, miner)
 	if err != nil {
 		return nil, err
 	}
@@ -171,19 +190,35 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 		return nil, ctxSetup.Err()
 	}
 
-	// create payment information
-	cpResp, err := smc.api.CreatePayments(ctxSetup, porcelain.CreatePaymentsParams{
+	// reuse an open payment channel to the miner if there is one
+	paymentParams := porcelain.CreatePaymentsParams{
 		From:            fromAddress,
 		To:              minerOwner,
-		Value:           price.MulBigInt(big.NewInt(int64(size * duration))),
+		Value:           totalPrice,
 		Duration:        duration,
 		MinerAddress:    miner,
 		CommP:           commP,
 		PaymentInterval: VoucherInterval,
 		ChannelExpiry:   *chainHeight.Add(types.NewBlockHeight(duration + ChannelExpiryInterval)),
 		GasPrice:        types.NewAttoFIL(big.NewInt(CreateChannelGasPrice)),
-		GasLimit:        types.NewGasUnits(CreateChannelGasLimit),
-	})
+	}
+	allocation, err := smc.channels.allocate(ctxSetup, fromAddress, minerOwner, totalPrice, &paymentParams.ChannelExpiry)
+	if err != nil {
+		return nil, errors.Wrap(err, "error allocating payment channel")
+	}
+	if allocation != nil {
+		paymentParams.Channel = allocation.Channel
+		paymentParams.Lane = allocation.Lane
+		paymentParams.ChannelExpiry = *allocation.Eol
+	} else {
+		paymentParams.GasLimit, err = smc.api.GasLimitEstimate(ctxSetup, fromAddress, address.PaymentBrokerAddress, totalPrice, "createChannel", minerOwner, &paymentParams.ChannelExpiry)
+		if err != nil {
+			return nil, errors.Wrap(err, "error estimating payment channel gas limit")
+		}
+	}
+
+	// create payment information
+	cpResp, err := smc.api.CreatePayments(ctxSetup, paymentParams)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating payment")
 	}
@@ -191,8 +226,14 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	proposal.Payment.Channel = cpResp.Channel
 	proposal.Payment.PayChActor = address.PaymentBrokerAddress
 	proposal.Payment.Payer = fromAddress
-	proposal.Payment.ChannelMsgCid = &cpResp.ChannelMsgCid
+	proposal.Payment.Lane = paymentParams.Lane
 	proposal.Payment.Vouchers = cpResp.Vouchers
+	if allocation != nil {
+		proposal.Payment.ChannelMsgCid = allocation.MsgCid
+	} else {
+		proposal.Payment.ChannelMsgCid = &cpResp.ChannelMsgCid
+		smc.channels.reserve(fromAddress, cpResp.Channel, paymentParams.Lane, totalPrice)
+	}
 
 	signedProposal, err := proposal.NewSignedProposal(fromAddress, smc.api)
 	if err != nil {
@@ -217,6 +258,9 @@ func (smc *Client) ProposeDeal(ctx context.Context, miner address.Address, data
 	if err := smc.recordResponse(ctx, &response, miner, proposal); err != nil {
 		return nil, errors.Wrap(err, "failed to track response")
 	}
+
+	// keep the connection to the miner open while the deal is in progress
+	smc.protectDeal(pid, response.ProposalCid, chainHeight.Add(types.NewBlockHeight(duration)))
 	smc.log.Debugf("proposed deal for: %s, %v\n", miner.String(), proposal)
 
 	return &response, nil
@@ -266,6 +310,25 @@ func (smc *Client) minerForProposal(ctx context.Context, c cid.Cid) (address.Add
 	return storageDeal.Miner, nil
 }
 
+// minerPeer returns the peer of miner, after adding the addresses the miner
+// publishes to the peerstore so that miners behind a NAT can be reached
+// through their relays.
+func (smc *Client) minerPeer(ctx context.Context, miner address.Address) (peer.ID, error) {
+	pid, err := smc.api.MinerGetPeerID(ctx, miner)
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := smc.api.MinerGetPeerAddrs(ctx, miner)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) > 0 {
+		smc.host.Peerstore().AddAddrs(pid, addrs, pstore.TempAddrTTL)
+	}
+	return pid, nil
+}
+
 // QueryDeal queries an in-progress proposal.
 func (smc *Client) QueryDeal(ctx context.Context, proposalCid cid.Cid) (*storagedeal.Response, error) {
 	mineraddr, err := smc.minerForProposal(ctx, proposalCid)
@@ -273,7 +336,7 @@ func (smc *Client) QueryDeal(ctx context.Context, proposalCid cid.Cid) (*storage
 		return nil, err
 	}
 
-	minerpid, err := smc.api.MinerGetPeerID(ctx, mineraddr)
+	minerpid, err := smc.minerPeer(ctx, mineraddr)
 	if err != nil {
 		return nil, err
 	}
@@ -285,9 +348,68 @@ func (smc *Client) QueryDeal(ctx context.Context, proposalCid cid.Cid) (*storage
 		return nil, errors.Wrap(err, "error querying deal")
 	}
 
+	switch resp.State {
+	case storagedeal.Complete, storagedeal.Failed, storagedeal.Rejected:
+		smc.unprotectDeal(proposalCid)
+	}
+
 	return &resp, nil
 }
 
+// OnNewHeaviestTipSet stops protecting the connections to the miners of
+// deals that have ended as of ts.
+func (smc *Client) OnNewHeaviestTipSet(ts types.TipSet) error {
+	height, err := ts.Height()
+	if err != nil {
+		return errors.Wrap(err, "failed to get block height")
+	}
+	h := types.NewBlockHeight(height)
+
+	smc.protectedLk.Lock()
+	var ended []cid.Cid
+	for proposalCid, deal := range smc.protected {
+		if h.GreaterEqual(deal.end) {
+			ended = append(ended, proposalCid)
+		}
+	}
+	smc.protectedLk.Unlock()
+
+	for _, proposalCid := range ended {
+		smc.unprotectDeal(proposalCid)
+	}
+	return nil
+}
+
+// protectDeal protects the connection to the miner of a deal ending at end
+// from trimming.
+func (smc *Client) protectDeal(miner peer.ID, proposalCid cid.Cid, end *types.BlockHeight) {
+	smc.protectedLk.Lock()
+	defer smc.protectedLk.Unlock()
+
+	smc.protected[proposalCid] = protectedDeal{miner: miner, end: end}
+	smc.host.ConnManager().Protect(miner, dealProtectTag(proposalCid))
+}
+
+// unprotectDeal removes the protection of the connection to the miner of a
+// deal once the deal has completed, failed or ended.
+func (smc *Client) unprotectDeal(proposalCid cid.Cid) {
+	smc.protectedLk.Lock()
+	defer smc.protectedLk.Unlock()
+
+	deal, ok := smc.protected[proposalCid]
+	if !ok {
+		return
+	}
+	delete(smc.protected, proposalCid)
+	smc.host.ConnManager().Unprotect(deal.miner, dealProtectTag(proposalCid))
+}
+
+// dealProtectTag is the tag under which the miner of an in-progress deal is
+// protected from connection trimming.
+func dealProtectTag(proposalCid cid.Cid) string {
+	return "deal:" + proposalCid.String()
+}
+
 func (smc *Client) isMaybeDupDeal(ctx context.Context, p *storagedeal.Proposal) bool {
 	dealsCh, err := smc.api.DealsLs(ctx)
 	if err != nil {
@@ -10,10 +10,13 @@ import (
 	"github.com/libp2p/go-libp2p-host"
 	"github.com/libp2p/go-libp2p-peer"
 	"github.com/libp2p/go-libp2p-protocol"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/actor"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/miner"
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	. "github.com/filecoin-project/go-filecoin/protocol/storage"
@@ -152,9 +155,52 @@ func TestProposeDealFailsWhenADealAlreadyExists(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestProposeDealReusesPaymentChannel(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addressCreator := address.NewForTestGetter()
+
+	var proposal *storagedeal.SignedDealProposal
+	testNode := newTestClientNode(func(request interface{}) (interface{}, error) {
+		proposal = request.(*storagedeal.SignedDealProposal)
+		pcid, err := convert.ToCid(proposal.Proposal)
+		require.NoError(t, err)
+		return &storagedeal.Response{
+			State:       storagedeal.Accepted,
+			ProposalCid: pcid,
+		}, nil
+	})
+
+	testAPI := newTestClientAPI(t)
+	existingChannel := types.NewChannelID(5)
+	testAPI.channels[existingChannel.KeyString()] = &paymentbroker.PaymentChannel{
+		Target:         address.TestAddress,
+		Amount:         types.ZeroAttoFIL,
+		AmountRedeemed: types.ZeroAttoFIL,
+		AgreedEol:      types.NewBlockHeight(20000),
+		Eol:            types.NewBlockHeight(20000),
+	}
+
+	client := NewClient(th.NewFakeHost(), testAPI)
+	client.ProtocolRequestFunc = testNode.MakeTestProtocolRequest
+
+	_, err := client.ProposeDeal(ctx, addressCreator(), types.SomeCid(), uint64(67), uint64(10000), false)
+	require.NoError(t, err)
+
+	// the channel is funded for the deal rather than a new one being created
+	require.Len(t, testAPI.sent, 1)
+	assert.Equal(t, "addFunds", testAPI.sent[0])
+	assert.Equal(t, existingChannel, proposal.Payment.Channel)
+	assert.Equal(t, &testAPI.msgCid, proposal.Payment.ChannelMsgCid)
+	assert.Equal(t, uint64(1), proposal.Payment.Lane)
+}
+
 type clientTestAPI struct {
 	blockHeight *types.BlockHeight
 	channelID   *types.ChannelID
+	channels    map[string]*paymentbroker.PaymentChannel
+	sent        []string
 	msgCid      cid.Cid
 	payer       address.Address
 	target      address.Address
@@ -171,6 +217,7 @@ func newTestClientAPI(t *testing.T) *clientTestAPI {
 		blockHeight: types.NewBlockHeight(773),
 		msgCid:      cidGetter(),
 		channelID:   types.NewChannelID(23),
+		channels:    make(map[string]*paymentbroker.PaymentChannel),
 		payer:       addressGetter(),
 		target:      addressGetter(),
 		perPayment:  types.NewAttoFILFromFIL(10),
@@ -195,10 +242,14 @@ func (ctp *clientTestAPI) CreatePayments(ctx context.Context, config porcelain.C
 		GasAttoFIL:           types.NewAttoFILFromFIL(100),
 		Vouchers:             make([]*types.PaymentVoucher, 10),
 	}
+	if config.Channel != nil {
+		resp.Channel = config.Channel
+		resp.ChannelMsgCid = cid.Undef
+	}
 
 	for i := 0; i < 10; i++ {
 		resp.Vouchers[i] = &types.PaymentVoucher{
-			Channel: *ctp.channelID,
+			Channel: *resp.Channel,
 			Payer:   ctp.payer,
 			Target:  ctp.target,
 			Amount:  ctp.perPayment.MulBigInt(big.NewInt(int64(i + 1))),
@@ -240,6 +291,10 @@ func (ctp *clientTestAPI) MinerGetPeerID(ctx context.Context, minerAddr address.
 	return id, nil
 }
 
+func (ctp *clientTestAPI) MinerGetPeerAddrs(ctx context.Context, minerAddr address.Address) ([]ma.Multiaddr, error) {
+	return nil, nil
+}
+
 func (ctp *clientTestAPI) PingMinerWithTimeout(ctx context.Context, p peer.ID, to time.Duration) error {
 	return nil
 }
@@ -303,5 +358,23 @@ func (ctp *clientTestAPI) DealPut(storageDeal *storagedeal.Deal) error {
 }
 
 func (ctp *clientTestAPI) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error) {
+	if method == "ls" {
+		channelsBytes, err := actor.MarshalStorage(ctp.channels)
+		require.NoError(ctp.testing, err)
+		return [][]byte{channelsBytes}, nil
+	}
 	return [][]byte{{byte(types.TestProofsMode)}}, nil
 }
+
+func (ctp *clientTestAPI) GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	return types.NewGasUnits(300), nil
+}
+
+func (ctp *clientTestAPI) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
+	ctp.sent = append(ctp.sent, method)
+	return ctp.msgCid, nil
+}
+
+func (ctp *clientTestAPI) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
+	return cb(&types.Block{}, &types.SignedMessage{}, &types.MessageReceipt{ExitCode: 0})
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/config"
+	"github.com/filecoin-project/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// dealFilterTimeout bounds how long an external deal filter command may run.
+const dealFilterTimeout = 30 * time.Second
+
+// DealPolicyFailedReason is the reason a proposal is rejected for when the
+// deal policy could not be evaluated.
+const DealPolicyFailedReason = "miner could not evaluate its deal policy"
+
+// DealPolicy decides whether a miner accepts a storage deal proposal.
+type DealPolicy interface {
+	// Evaluate returns the reason the proposal is rejected for, which is sent
+	// to the client, or "" if the proposal is accepted. An error means the
+	// policy could not be evaluated; the miner logs it and rejects the
+	// proposal with DealPolicyFailedReason, so internal errors are not sent
+	// to clients.
+	Evaluate(ctx context.Context, p *storagedeal.Proposal) (string, error)
+}
+
+// dealPolicyPorcelain is the subset of the porcelain API that ConfigDealPolicy needs.
+type dealPolicyPorcelain interface {
+	ChainBlockHeight() (*types.BlockHeight, error)
+	ConfigGet(dottedPath string) (interface{}, error)
+	DealsLs(context.Context) (<-chan *porcelain.StorageDealLsResult, error)
+}
+
+// ConfigDealPolicy is a DealPolicy driven by the mining.dealPolicy config
+// section. The config is read for every proposal so that changes take effect
+// without restarting the miner.
+type ConfigDealPolicy struct {
+	minerAddr    address.Address
+	porcelainAPI dealPolicyPorcelain
+}
+
+var _ DealPolicy = (*ConfigDealPolicy)(nil)
+
+// NewConfigDealPolicy returns a ConfigDealPolicy for the given miner.
+func NewConfigDealPolicy(minerAddr address.Address, porcelainAPI dealPolicyPorcelain) *ConfigDealPolicy {
+	return &ConfigDealPolicy{
+		minerAddr:    minerAddr,
+		porcelainAPI: porcelainAPI,
+	}
+}
+
+// Evaluate checks the proposal against the configured client lists, piece
+// size and duration bounds, per-client storage quota and filter command.
+func (dp *ConfigDealPolicy) Evaluate(ctx context.Context, p *storagedeal.Proposal) (string, error) {
+	policy, err := dp.loadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	client := p.Payment.Payer
+	if containsAddress(policy.DeniedClients, client) {
+		return fmt.Sprintf("deals from client %s are not accepted", client), nil
+	}
+	if len(policy.AllowedClients) > 0 && !containsAddress(policy.AllowedClients, client) {
+		return fmt.Sprintf("deals from client %s are not accepted", client), nil
+	}
+
+	if p.Size == nil {
+		return "proposed deal has no size", nil
+	}
+	size := p.Size.Uint64()
+	if policy.MinPieceSize > 0 && size < policy.MinPieceSize {
+		return fmt.Sprintf("piece is %d bytes but minimum piece size is %d bytes", size, policy.MinPieceSize), nil
+	}
+	if policy.MaxPieceSize > 0 && size > policy.MaxPieceSize {
+		return fmt.Sprintf("piece is %d bytes but maximum piece size is %d bytes", size, policy.MaxPieceSize), nil
+	}
+
+	if policy.MinDuration > 0 && p.Duration < policy.MinDuration {
+		return fmt.Sprintf("duration is %d blocks but minimum duration is %d blocks", p.Duration, policy.MinDuration), nil
+	}
+	if policy.MaxDuration > 0 && p.Duration > policy.MaxDuration {
+		return fmt.Sprintf("duration is %d blocks but maximum duration is %d blocks", p.Duration, policy.MaxDuration), nil
+	}
+
+	if policy.MaxClientStorage > 0 {
+		stored, err := dp.clientStorage(ctx, client)
+		if err != nil {
+			return "", err
+		}
+		if stored+size > policy.MaxClientStorage {
+			return fmt.Sprintf("client has %d bytes in active deals with this miner, adding %d bytes would exceed the limit of %d bytes", stored, size, policy.MaxClientStorage), nil
+		}
+	}
+
+	if policy.FilterCommand != "" {
+		return runDealFilter(ctx, policy.FilterCommand, p)
+	}
+
+	return "", nil
+}
+
+func (dp *ConfigDealPolicy) loadConfig() (*config.DealPolicyConfig, error) {
+	policy, err := dp.porcelainAPI.ConfigGet("mining.dealPolicy")
+	if err != nil {
+		return nil, err
+	}
+	policyConfig, ok := policy.(*config.DealPolicyConfig)
+	if !ok || policyConfig == nil {
+		return nil, errors.New("could not retrieve dealPolicy from config")
+	}
+	return policyConfig, nil
+}
+
+// clientStorage sums the sizes of the client's active deals with this miner,
+// that is the deals that have been accepted, have not since been rejected or
+// failed, and whose last payment is not yet due.
+func (dp *ConfigDealPolicy) clientStorage(ctx context.Context, client address.Address) (uint64, error) {
+	height, err := dp.porcelainAPI.ChainBlockHeight()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get block height")
+	}
+
+	dealCh, err := dp.porcelainAPI.DealsLs(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list deals")
+	}
+
+	var total uint64
+	for result := range dealCh {
+		if result.Err != nil {
+			return 0, errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if deal.Miner != dp.minerAddr || deal.Proposal == nil || deal.Response == nil {
+			continue
+		}
+		if deal.Proposal.Payment.Payer != client || deal.Proposal.Size == nil {
+			continue
+		}
+		if !isActiveDeal(&deal, height) {
+			continue
+		}
+		total += deal.Proposal.Size.Uint64()
+	}
+	return total, nil
+}
+
+// isActiveDeal returns whether the deal still occupies storage at height. A
+// deal ends with its last payment voucher.
+func isActiveDeal(deal *storagedeal.Deal, height *types.BlockHeight) bool {
+	switch deal.Response.State {
+	case storagedeal.Accepted, storagedeal.Started, storagedeal.Staged, storagedeal.Complete:
+	default:
+		return false
+	}
+
+	vouchers := deal.Proposal.Payment.Vouchers
+	if len(vouchers) == 0 {
+		return true
+	}
+	return height.LessThan(&vouchers[len(vouchers)-1].ValidAt)
+}
+
+// runDealFilter runs the operator's filter command through the shell with
+// the proposal JSON on stdin. The proposal is rejected if the command exits
+// with a non-zero status, using its output as the reason.
+func runDealFilter(ctx context.Context, command string, p *storagedeal.Proposal) (string, error) {
+	proposalJSON, err := json.Marshal(p)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode proposal for deal filter")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dealFilterTimeout)
+	defer cancel()
+
+	cmd := osexec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(proposalJSON)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", errors.New("deal filter timed out")
+	}
+	if _, exited := err.(*osexec.ExitError); err != nil && !exited {
+		return "", errors.Wrap(err, "failed to run deal filter")
+	}
+	if err != nil {
+		reason := strings.TrimSpace(string(out))
+		if reason == "" {
+			return "rejected by deal filter", nil
+		}
+		return fmt.Sprintf("rejected by deal filter: %s", reason), nil
+	}
+	return "", nil
+}
+
+func containsAddress(addrs []address.Address, addr address.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
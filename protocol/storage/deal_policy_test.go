@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestConfigDealPolicy(t *testing.T) {
+	tf.UnitTest(t)
+
+	minerAddr := address.NewForTestGetter()()
+
+	setup := func(t *testing.T) (*minerTestPorcelain, *ConfigDealPolicy, *storagedeal.Proposal) {
+		porcelainAPI := newMinerTestPorcelain(t)
+		proposal := testSignedDealProposal(porcelainAPI, nil, defaultPieceSize)
+		return porcelainAPI, NewConfigDealPolicy(minerAddr, porcelainAPI), &proposal.Proposal
+	}
+
+	t.Run("accepts proposals with the default config", func(t *testing.T) {
+		_, policy, proposal := setup(t)
+		requireAccepted(t, policy, proposal)
+	})
+
+	t.Run("client lists", func(t *testing.T) {
+		porcelainAPI, policy, proposal := setup(t)
+		payer := fmt.Sprintf(`["%s"]`, porcelainAPI.payerAddress)
+		other := fmt.Sprintf(`["%s"]`, address.TestAddress)
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.allowedClients", other))
+		assert.Contains(t, requireRejected(t, policy, proposal), "are not accepted")
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.allowedClients", payer))
+		requireAccepted(t, policy, proposal)
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.deniedClients", payer))
+		assert.Contains(t, requireRejected(t, policy, proposal), "are not accepted")
+	})
+
+	t.Run("piece size and duration bounds", func(t *testing.T) {
+		testCases := []struct {
+			key     string
+			value   string
+			message string
+		}{
+			{"minPieceSize", "1001", "piece is 1000 bytes but minimum piece size is 1001 bytes"},
+			{"maxPieceSize", "999", "piece is 1000 bytes but maximum piece size is 999 bytes"},
+			{"minDuration", "10001", "duration is 10000 blocks but minimum duration is 10001 blocks"},
+			{"maxDuration", "9999", "duration is 10000 blocks but maximum duration is 9999 blocks"},
+		}
+
+		for _, tc := range testCases {
+			porcelainAPI, policy, proposal := setup(t)
+			require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy."+tc.key, tc.value))
+
+			assert.Equal(t, tc.message, requireRejected(t, policy, proposal), tc.key)
+		}
+	})
+
+	t.Run("per client storage quota", func(t *testing.T) {
+		porcelainAPI, policy, proposal := setup(t)
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.maxClientStorage", "2500"))
+
+		cidGetter := types.NewCidForTestGetter()
+		putDeal := func(miner address.Address, state storagedeal.State, vouchers ...*types.PaymentVoucher) {
+			p := *proposal
+			p.Payment.Vouchers = vouchers
+			require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+				Miner:    miner,
+				Proposal: &p,
+				Response: &storagedeal.Response{State: state, ProposalCid: cidGetter()},
+			}))
+		}
+
+		ended := &types.PaymentVoucher{ValidAt: *porcelainAPI.blockHeight}
+		running := &types.PaymentVoucher{ValidAt: *porcelainAPI.blockHeight.Add(types.NewBlockHeight(1))}
+
+		// only active deals with this miner count towards the quota
+		putDeal(minerAddr, storagedeal.Accepted)
+		putDeal(minerAddr, storagedeal.Rejected)
+		putDeal(minerAddr, storagedeal.Failed)
+		putDeal(minerAddr, storagedeal.Unknown)
+		putDeal(minerAddr, storagedeal.Complete, running, ended)
+		putDeal(address.TestAddress, storagedeal.Accepted)
+		requireAccepted(t, policy, proposal)
+
+		putDeal(minerAddr, storagedeal.Complete, ended, running)
+		assert.Equal(t, "client has 2000 bytes in active deals with this miner, adding 1000 bytes would exceed the limit of 2500 bytes", requireRejected(t, policy, proposal))
+	})
+
+	t.Run("filter command", func(t *testing.T) {
+		porcelainAPI, policy, proposal := setup(t)
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.filterCommand", `"grep -q PieceRef"`))
+		requireAccepted(t, policy, proposal)
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.filterCommand", `"cat > /dev/null; echo no room for you; exit 1"`))
+		assert.Equal(t, "rejected by deal filter: no room for you", requireRejected(t, policy, proposal))
+
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.filterCommand", `"cat > /dev/null; exit 1"`))
+		assert.Equal(t, "rejected by deal filter", requireRejected(t, policy, proposal))
+	})
+}
+
+func requireAccepted(t *testing.T, policy DealPolicy, proposal *storagedeal.Proposal) {
+	reason, err := policy.Evaluate(context.Background(), proposal)
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+}
+
+func requireRejected(t *testing.T, policy DealPolicy, proposal *storagedeal.Proposal) string {
+	reason, err := policy.Evaluate(context.Background(), proposal)
+	require.NoError(t, err)
+	require.NotEqual(t, "", reason)
+	return reason
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestClientUnprotectsEndedDeals(t *testing.T) {
+	tf.UnitTest(t)
+
+	// protecting and unprotecting deals does not use the porcelain api
+	client := NewClient(th.NewFakeHost(), nil)
+	proposalCid := types.NewCidForTestGetter()()
+	client.protectDeal(peer.ID("miner"), proposalCid, types.NewBlockHeight(800))
+
+	require.NoError(t, client.OnNewHeaviestTipSet(th.RequireNewTipSet(t, &types.Block{Height: 799})))
+	assert.Contains(t, client.protected, proposalCid)
+
+	require.NoError(t, client.OnNewHeaviestTipSet(th.RequireNewTipSet(t, &types.Block{Height: 800})))
+	assert.NotContains(t, client.protected, proposalCid)
+}
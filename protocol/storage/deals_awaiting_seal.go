@@ -91,6 +91,47 @@ func (dealsAwaitingSeal *dealsAwaitingSeal) attachDealToSector(ctx context.Conte
 	delete(dealsAwaitingSeal.SealedSectors, sectorID)
 }
 
+// detachDealFromSector stops tracking the deal as waiting for the sector to seal.
+func (dealsAwaitingSeal *dealsAwaitingSeal) detachDealFromSector(sectorID uint64, dealCid cid.Cid) {
+	dealsAwaitingSeal.l.Lock()
+	defer dealsAwaitingSeal.l.Unlock()
+
+	var remaining []cid.Cid
+	for _, c := range dealsAwaitingSeal.SectorsToDeals[sectorID] {
+		if !c.Equals(dealCid) {
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(dealsAwaitingSeal.SectorsToDeals, sectorID)
+		return
+	}
+	dealsAwaitingSeal.SectorsToDeals[sectorID] = remaining
+}
+
+// sectorCount returns the number of sectors with deals waiting to be sealed.
+func (dealsAwaitingSeal *dealsAwaitingSeal) sectorCount() int {
+	dealsAwaitingSeal.l.Lock()
+	defer dealsAwaitingSeal.l.Unlock()
+
+	return len(dealsAwaitingSeal.SectorsToDeals)
+}
+
+// hasDeal reports whether the deal is attached to a sector that has not finished sealing.
+func (dealsAwaitingSeal *dealsAwaitingSeal) hasDeal(dealCid cid.Cid) bool {
+	dealsAwaitingSeal.l.Lock()
+	defer dealsAwaitingSeal.l.Unlock()
+
+	for _, deals := range dealsAwaitingSeal.SectorsToDeals {
+		for _, c := range deals {
+			if c.Equals(dealCid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (dealsAwaitingSeal *dealsAwaitingSeal) onSealSuccess(ctx context.Context, sector *sectorbuilder.SealedSectorMetadata, commitMessageCID cid.Cid) {
 	dealsAwaitingSeal.l.Lock()
 	defer dealsAwaitingSeal.l.Unlock()
@@ -15,17 +15,19 @@ import (
 	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log"
 	dag "github.com/ipfs/go-merkledag"
-	uio "github.com/ipfs/go-unixfs/io"
 	"github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
 	"github.com/libp2p/go-libp2p-protocol"
 	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 
 	"github.com/filecoin-project/go-filecoin/abi"
 	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/address"
 	cbu "github.com/filecoin-project/go-filecoin/cborutil"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/metrics"
 	"github.com/filecoin-project/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
@@ -37,12 +39,19 @@ import (
 
 var log = logging.Logger("/fil/storage")
 
+var (
+	dealStateKey = tag.MustNewKey("storage/keys/deal_state")
+
+	dealsCt        = metrics.NewInt64Counter("storage/deals", "Number of storage deals that entered each state", dealStateKey)
+	sealingQueueGa = metrics.NewInt64Gauge("storage/sealing_queue_depth", "Number of sectors with deals waiting to be sealed")
+	// [>=1s, >=10s, >=1m, >=5m, >=15m, >=30m, >=1h, >=2h, >=4h, >=8h]
+	sealTimer = metrics.NewTimerWithBuckets("storage/seal_duration", "Duration of sealing a sector in milliseconds", stats.UnitMilliseconds,
+		[]float64{1e3, 1e4, 6e4, 3e5, 9e5, 1.8e6, 3.6e6, 7.2e6, 1.44e7, 2.88e7})
+)
+
 const makeDealProtocol = protocol.ID("/fil/storage/mk/1.0.0")
 const queryDealProtocol = protocol.ID("/fil/storage/qry/1.0.0")
 
-// TODO: replace this with a queries to pick reasonable gas price and limits.
-const submitPostGasPrice = 1
-
 const waitForPaymentChannelDuration = 2 * time.Minute
 
 const dealsAwatingSealDatastorePrefix = "dealsAwaitingSeal"
@@ -54,14 +63,26 @@ type Miner struct {
 
 	dealsAwaitingSealDs repo.Datastore
 
-	postInProcessLk sync.Mutex
-	postInProcess   *types.BlockHeight
+	provingLk       sync.Mutex
+	provingSchedule *ProvingSchedule
 
 	dealsAwaitingSeal *dealsAwaitingSeal
 
+	// sectorsLk guards the sector records persisted in dealsAwaitingSealDs,
+	// and sealStopwatches.
+	sectorsLk sync.Mutex
+	// sealStopwatches time the sectors the miner scheduled for sealing.
+	sealStopwatches map[uint64]*metrics.Stopwatch
+
+	// channels indexes the miner's deals with unsettled payments by payment
+	// channel.
+	channels *channelIndex
+
 	porcelainAPI minerPorcelain
 	node         node
 
+	dealPolicy DealPolicy
+
 	proposalAcceptor func(m *Miner, p *storagedeal.Proposal) (*storagedeal.Response, error)
 	proposalRejector func(m *Miner, p *storagedeal.Proposal, reason string) (*storagedeal.Response, error)
 }
@@ -76,6 +97,10 @@ type minerPorcelain interface {
 
 	DealGet(context.Context, cid.Cid) (*storagedeal.Deal, error)
 	DealPut(*storagedeal.Deal) error
+	DealsLs(context.Context) (<-chan *porcelain.StorageDealLsResult, error)
+
+	GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error)
+	GasPriceEstimate(ctx context.Context, method string) (types.AttoFIL, error)
 
 	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error)
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, params ...interface{}) ([][]byte, error)
@@ -105,6 +130,8 @@ func NewMiner(minerAddr, minerOwnerAddr address.Address, nd node, dealsDs repo.D
 		porcelainAPI:        porcelainAPI,
 		dealsAwaitingSealDs: dealsDs,
 		node:                nd,
+		dealPolicy:          NewConfigDealPolicy(minerAddr, porcelainAPI),
+		channels:            newChannelIndex(),
 		proposalAcceptor:    acceptProposal,
 		proposalRejector:    rejectProposal,
 	}
@@ -115,6 +142,10 @@ func NewMiner(minerAddr, minerOwnerAddr address.Address, nd node, dealsDs repo.D
 	sm.dealsAwaitingSeal.onSuccess = sm.onCommitSuccess
 	sm.dealsAwaitingSeal.onFail = sm.onCommitFail
 
+	if err := sm.loadProvingSchedule(); err != nil {
+		return nil, errors.Wrap(err, "failed to load proving schedule when creating miner")
+	}
+
 	nd.Host().SetStreamHandler(makeDealProtocol, sm.handleMakeDeal)
 	nd.Host().SetStreamHandler(queryDealProtocol, sm.handleQueryDeal)
 
@@ -155,6 +186,24 @@ func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.Sig
 		return sm.proposalRejector(sm, p, fmt.Sprint("invalid deal signature"))
 	}
 
+	reason, err := sm.dealPolicy.Evaluate(ctx, p)
+	if err != nil {
+		log.Errorf("failed to evaluate deal policy for proposal from %s: %s", p.Payment.Payer, err)
+		return sm.proposalRejector(sm, p, DealPolicyFailedReason)
+	}
+	if reason != "" {
+		return sm.proposalRejector(sm, p, reason)
+	}
+
+	if p.Payment.Channel != nil {
+		// keep the channel from being closed while the proposal is handled
+		key := paymentChannelKey(p)
+		if !sm.channels.beginProposal(key) {
+			return sm.proposalRejector(sm, p, "payment channel is being closed")
+		}
+		defer sm.channels.endProposal(key)
+	}
+
 	if err := sm.validateDealPayment(ctx, p); err != nil {
 		return sm.proposalRejector(sm, p, err.Error())
 	}
@@ -202,9 +251,31 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposa
 		return fmt.Errorf("miner account (%s) is not target of payment channel (%s)", sm.minerOwnerAddr.String(), channel.Target.String())
 	}
 
-	// confirm channel contains enough funds
-	if channel.Amount.LessThan(expectedPrice) {
-		return fmt.Errorf("payment channel does not contain enough funds (%s < %s)", channel.Amount.String(), expectedPrice.String())
+	// confirm the deal's lane is not used by the miner's other deals or by
+	// vouchers already redeemed, and that the channel contains enough funds
+	// for this deal on top of the lanes of the miner's other deals
+	lane := p.Payment.Lane
+	lanes, err := sm.channelLanes(ctx, p)
+	if err != nil {
+		return err
+	}
+	if _, ok := lanes[lane]; ok {
+		return fmt.Errorf("payment channel lane %d is already used by another deal", lane)
+	}
+	if channel.LaneRedeemed(lane).IsPositive() {
+		return fmt.Errorf("payment channel lane %d has already been redeemed", lane)
+	}
+	for _, ls := range channel.Lanes {
+		if ls.ID == lane && ls.Nonce > 0 {
+			return fmt.Errorf("payment channel lane %d has already been redeemed", lane)
+		}
+	}
+	allocated := types.ZeroAttoFIL
+	for _, amount := range lanes {
+		allocated = allocated.Add(amount)
+	}
+	if channel.Amount.LessThan(allocated.Add(expectedPrice)) {
+		return fmt.Errorf("payment channel does not contain enough funds (%s < %s)", channel.Amount.Sub(allocated).String(), expectedPrice.String())
 	}
 
 	// start with current block height
@@ -227,8 +298,13 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposa
 
 	lastValidAt := expectedFirstPayment
 	for _, v := range p.Payment.Vouchers {
+		// confirm the voucher pays on the deal's lane only
+		if v.Lane.ID != lane || len(v.Lane.Merges) > 0 {
+			return fmt.Errorf("voucher must pay on lane %d of the payment channel without merges", lane)
+		}
+
 		// confirm signature is valid against expected actor and channel id
-		if !paymentbroker.VerifyVoucherSignature(p.Payment.Payer, p.Payment.Channel, v.Amount, &v.ValidAt, v.Condition, v.Signature) {
+		if !paymentbroker.VerifyVoucherSignature(p.Payment.Payer, p.Payment.Channel, v.Amount, &v.ValidAt, v.Condition, &v.Lane, v.Signature) {
 			return errors.New("invalid signature in voucher")
 		}
 
@@ -255,6 +331,9 @@ func (sm *Miner) validateDealPayment(ctx context.Context, p *storagedeal.Proposa
 	if lastVoucher.Amount.LessThan(p.TotalPrice) {
 		return fmt.Errorf("last payment (%s) does not cover total price (%s)", lastVoucher.Amount.String(), p.TotalPrice.String())
 	}
+	if channel.Amount.LessThan(allocated.Add(lastVoucher.Amount)) {
+		return fmt.Errorf("payment channel does not contain enough funds for last payment (%s < %s)", channel.Amount.Sub(allocated).String(), lastVoucher.Amount.String())
+	}
 
 	// require channel expires at or after last voucher + ChannelExpiryInterval
 	expectedEol := lastVoucher.ValidAt.Add(types.NewBlockHeight(ChannelExpiryInterval))
@@ -279,23 +358,78 @@ func (sm *Miner) getStoragePrice() (types.AttoFIL, error) {
 
 // some parts of this should be porcelain
 func (sm *Miner) getPaymentChannel(ctx context.Context, p *storagedeal.Proposal) (*paymentbroker.PaymentChannel, error) {
-	// wait for create channel message
-	messageCid := p.Payment.ChannelMsgCid
+	// wait for the message creating or funding the channel, if any. A reused
+	// channel that already holds enough funds has none.
+	if messageCid := p.Payment.ChannelMsgCid; messageCid != nil {
+		waitCtx, waitCancel := context.WithDeadline(ctx, time.Now().Add(waitForPaymentChannelDuration))
+		err := sm.porcelainAPI.MessageWait(waitCtx, *messageCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
+			return nil
+		})
+		waitCancel()
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				return nil, errors.Wrap(err, "Timeout waiting for payment channel")
+			}
+			return nil, err
+		}
+	}
 
-	waitCtx, waitCancel := context.WithDeadline(ctx, time.Now().Add(waitForPaymentChannelDuration))
-	err := sm.porcelainAPI.MessageWait(waitCtx, *messageCid, func(blk *types.Block, smsg *types.SignedMessage, receipt *types.MessageReceipt) error {
-		return nil
-	})
-	waitCancel()
+	return sm.queryPaymentChannel(ctx, p)
+}
+
+// channelLanes returns the lanes of the proposal's payment channel used by
+// the miner's other deals and the amounts they pay.
+func (sm *Miner) channelLanes(ctx context.Context, p *storagedeal.Proposal) (map[uint64]types.AttoFIL, error) {
+	proposalCid, err := convert.ToCid(p)
 	if err != nil {
-		if err == context.DeadlineExceeded {
-			return nil, errors.Wrap(err, "Timeout waiting for payment channel")
+		return nil, errors.Wrap(err, "failed to get cid of proposal")
+	}
+
+	dealsCh, err := sm.porcelainAPI.DealsLs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deals")
+	}
+
+	lanes := make(map[uint64]types.AttoFIL)
+	for result := range dealsCh {
+		if result.Err != nil {
+			return nil, errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if deal.Miner != sm.minerAddr || deal.Proposal == nil || deal.Response == nil {
+			continue
+		}
+		if deal.Response.State == storagedeal.Rejected || deal.Response.ProposalCid.Equals(proposalCid) {
+			continue
+		}
+		if deal.Proposal.Payment.Channel == nil || paymentChannelKey(deal.Proposal) != paymentChannelKey(p) {
+			continue
+		}
+		lane := deal.Proposal.Payment.Lane
+		if amount := finalVoucherAmount(&deal); amount.GreaterThan(lanes[lane]) {
+			lanes[lane] = amount
 		}
-		return nil, err
 	}
+	return lanes, nil
+}
 
+// queryPaymentChannel looks up the proposal's payment channel in the payment broker.
+func (sm *Miner) queryPaymentChannel(ctx context.Context, p *storagedeal.Proposal) (*paymentbroker.PaymentChannel, error) {
 	payer := p.Payment.Payer
 
+	channels, err := sm.queryPayerChannels(ctx, payer)
+	if err != nil {
+		return nil, err
+	}
+	channel, ok := channels[p.Payment.Channel.KeyString()]
+	if !ok {
+		return nil, fmt.Errorf("could not find payment channel for payer %s and id %s", payer.String(), p.Payment.Channel.KeyString())
+	}
+	return channel, nil
+}
+
+// queryPayerChannels returns the open payment channels of payer keyed by channel id.
+func (sm *Miner) queryPayerChannels(ctx context.Context, payer address.Address) (map[string]*paymentbroker.PaymentChannel, error) {
 	ret, err := sm.porcelainAPI.MessageQuery(ctx, address.Undef, address.PaymentBrokerAddress, "ls", payer)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error getting payment channel for payer")
@@ -305,11 +439,7 @@ func (sm *Miner) getPaymentChannel(ctx context.Context, p *storagedeal.Proposal)
 	if err := cbor.DecodeInto(ret[0], &channels); err != nil {
 		return nil, errors.Wrap(err, "Could not decode payment channels for payer")
 	}
-	channel, ok := channels[p.Payment.Channel.KeyString()]
-	if !ok {
-		return nil, fmt.Errorf("could not find payment channel for payer %s and id %s", payer.String(), p.Payment.Channel.KeyString())
-	}
-	return channel, nil
+	return channels, nil
 }
 
 func acceptProposal(sm *Miner, p *storagedeal.Proposal) (*storagedeal.Response, error) {
@@ -337,6 +467,10 @@ func acceptProposal(sm *Miner, p *storagedeal.Proposal) (*storagedeal.Response,
 	if err := sm.porcelainAPI.DealPut(storageDeal); err != nil {
 		return nil, errors.Wrap(err, "Could not persist miner deal")
 	}
+	recordDealState(storagedeal.Accepted)
+	if p.Payment.Channel != nil {
+		sm.channels.add(paymentChannelKey(p), proposalCid)
+	}
 
 	// TODO: use some sort of nicer scheduler
 	go sm.processStorageDeal(proposalCid)
@@ -365,6 +499,7 @@ func rejectProposal(sm *Miner, p *storagedeal.Proposal, reason string) (*storage
 	if err := sm.porcelainAPI.DealPut(storageDeal); err != nil {
 		return nil, errors.Wrap(err, "failed to save miner deal")
 	}
+	recordDealState(storagedeal.Rejected)
 
 	return resp, nil
 }
@@ -374,16 +509,29 @@ func (sm *Miner) updateDealResponse(ctx context.Context, proposalCid cid.Cid, f
 	if err != nil {
 		return errors.Wrapf(err, "failed to get retrive deal with proposal CID %s", proposalCid.String())
 	}
+	previous := storageDeal.Response.State
 	f(storageDeal.Response)
 	err = sm.porcelainAPI.DealPut(storageDeal)
 	if err != nil {
 		return errors.Wrap(err, "failed to store updated deal response in datastore")
 	}
+	if storageDeal.Response.State != previous {
+		recordDealState(storageDeal.Response.State)
+	}
 
 	log.Debugf("Miner.updatedeal.Response(%s) - %d", proposalCid.String(), storageDeal.Response)
 	return nil
 }
 
+// recordDealState counts a deal entering state.
+func recordDealState(state storagedeal.State) {
+	ctx, err := tag.New(context.Background(), tag.Insert(dealStateKey, state.String()))
+	if err != nil {
+		log.Debugf("failed to insert tag for deal state: %s", err.Error())
+	}
+	dealsCt.Inc(ctx, 1)
+}
+
 func (sm *Miner) processStorageDeal(proposalCid cid.Cid) {
 	log.Debugf("Miner.processStorageDeal(%s)", proposalCid.String())
 	ctx, cancel := context.WithCancel(context.Background())
@@ -392,9 +540,10 @@ func (sm *Miner) processStorageDeal(proposalCid cid.Cid) {
 	d, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
 	if err != nil {
 		log.Errorf("could not retrieve deal with proposal CID %s: %s", proposalCid.String(), err)
+		return
 	}
 	if d.Response.State != storagedeal.Accepted {
-		// TODO: handle resumption of deal processing across miner restarts
+		// Deals interrupted by a restart are picked up by ResumeDeals.
 		log.Error("attempted to process an already started deal")
 		return
 	}
@@ -427,20 +576,6 @@ func (sm *Miner) processStorageDeal(proposalCid cid.Cid) {
 		}
 	}
 
-	dagService := dag.NewDAGService(sm.node.BlockService())
-
-	rootIpldNode, err := dagService.Get(ctx, d.Proposal.PieceRef)
-	if err != nil {
-		fail("internal error", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
-	r, err := uio.NewDagReader(ctx, rootIpldNode, dagService)
-	if err != nil {
-		fail("internal error", fmt.Sprintf("failed to add piece: %s", err))
-		return
-	}
-
 	// There is a race here that requires us to use dealsAwaitingSeal below. If the
 	// sector gets sealed and OnCommitmentSent is called right after
 	// AddPiece returns but before we record the sector/deal mapping we might
@@ -450,7 +585,8 @@ func (sm *Miner) processStorageDeal(proposalCid cid.Cid) {
 	//
 	// Also, this pattern of not being able to set up book-keeping ahead of
 	// the call is inelegant.
-	sectorID, err := sm.node.SectorBuilder().AddPiece(ctx, d.Proposal.PieceRef, d.Proposal.Size.Uint64(), r)
+	piece := SectorPiece{DealCid: proposalCid, PieceRef: d.Proposal.PieceRef, Size: d.Proposal.Size.Uint64()}
+	sectorID, err := sm.stagePiece(ctx, piece, 1)
 	if err != nil {
 		fail("failed to submit seal proof", fmt.Sprintf("failed to add piece: %s", err))
 		return
@@ -471,6 +607,142 @@ func (sm *Miner) processStorageDeal(proposalCid cid.Cid) {
 	}
 }
 
+// resumeRetryInterval is how long ResumeDeals waits before checking again
+// the deals whose payment channel could not be looked up.
+var resumeRetryInterval = time.Minute
+
+// ResumeDeals picks up the deals that were left unfinished when the miner last
+// stopped. Accepted deals are fetched and staged again, staged deals are
+// re-attached to the sectors recording their pieces so they hear about
+// sealing, and deals whose
+// payment channel has expired in the meantime are failed. Deals whose payment
+// channel cannot be looked up are checked again later.
+func (sm *Miner) ResumeDeals(ctx context.Context) error {
+	unfinished, err := sm.unfinishedDeals(ctx)
+	if err != nil {
+		return err
+	}
+	return sm.resumeDeals(ctx, unfinished)
+}
+
+func (sm *Miner) resumeDeals(ctx context.Context, deals []storagedeal.Deal) error {
+	toProcess, retry, err := sm.resumableDeals(ctx, deals)
+	if err != nil {
+		return err
+	}
+
+	for _, proposalCid := range toProcess {
+		log.Infof("resuming storage deal %s", proposalCid)
+		go sm.processStorageDeal(proposalCid)
+	}
+
+	if len(retry) > 0 {
+		time.AfterFunc(resumeRetryInterval, func() {
+			if err := sm.resumeDeals(context.Background(), retry); err != nil {
+				log.Errorf("failed to resume storage deals: %s", err)
+			}
+		})
+	}
+	return nil
+}
+
+// unfinishedDeals returns the miner's deals that are accepted or staged.
+func (sm *Miner) unfinishedDeals(ctx context.Context) ([]storagedeal.Deal, error) {
+	dealCh, err := sm.porcelainAPI.DealsLs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deals")
+	}
+
+	// Collect the deals before updating any of them so we are not writing to
+	// the datastore while iterating over it.
+	var unfinished []storagedeal.Deal
+	for result := range dealCh {
+		if result.Err != nil {
+			return nil, errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if deal.Miner != sm.minerAddr || deal.Proposal == nil || deal.Response == nil {
+			continue
+		}
+		if deal.Response.State == storagedeal.Accepted || deal.Response.State == storagedeal.Staged {
+			unfinished = append(unfinished, deal)
+		}
+	}
+	return unfinished, nil
+}
+
+// resumableDeals does the book-keeping for ResumeDeals. It returns the cids
+// of the accepted deals that still need to be processed, and the deals whose
+// payment channel could not be looked up and need to be checked again.
+func (sm *Miner) resumableDeals(ctx context.Context, unfinished []storagedeal.Deal) ([]cid.Cid, []storagedeal.Deal, error) {
+	blockHeight, err := sm.porcelainAPI.ChainBlockHeight()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not get current block height")
+	}
+
+	dealSectors, err := sm.dealSectors()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var toProcess []cid.Cid
+	var retry []storagedeal.Deal
+	payerChannels := make(map[address.Address]map[string]*paymentbroker.PaymentChannel)
+	for _, deal := range unfinished {
+		proposalCid := deal.Response.ProposalCid
+
+		payer := deal.Proposal.Payment.Payer
+		channels, ok := payerChannels[payer]
+		if !ok {
+			channels, err = sm.queryPayerChannels(ctx, payer)
+			if err != nil {
+				log.Errorf("failed to look up payment channel of deal %s, will retry: %s", proposalCid, err)
+				retry = append(retry, deal)
+				continue
+			}
+			payerChannels[payer] = channels
+		}
+
+		channel, ok := channels[deal.Proposal.Payment.Channel.KeyString()]
+		if !ok || !channel.Eol.GreaterThan(blockHeight) {
+			log.Infof("failing deal %s: payment channel is no longer available", proposalCid)
+			sm.failDeal(ctx, proposalCid, "payment channel expired before the deal completed")
+			continue
+		}
+
+		switch deal.Response.State {
+		case storagedeal.Accepted:
+			toProcess = append(toProcess, proposalCid)
+		case storagedeal.Staged:
+			if sm.dealsAwaitingSeal.hasDeal(proposalCid) {
+				continue
+			}
+			sectorID, ok := dealSectors[proposalCid.KeyString()]
+			if !ok {
+				sm.failDeal(ctx, proposalCid, "staged deal has no sector")
+				continue
+			}
+			sm.dealsAwaitingSeal.attachDealToSector(ctx, sectorID, proposalCid)
+		}
+	}
+
+	if err := sm.saveDealsAwaitingSeal(); err != nil {
+		return nil, nil, err
+	}
+
+	return toProcess, retry, nil
+}
+
+func (sm *Miner) failDeal(ctx context.Context, proposalCid cid.Cid, message string) {
+	err := sm.updateDealResponse(ctx, proposalCid, func(resp *storagedeal.Response) {
+		resp.State = storagedeal.Failed
+		resp.Message = message
+	})
+	if err != nil {
+		log.Errorf("could not update deal %s to 'Failed' state: %s", proposalCid, err)
+	}
+}
+
 func (sm *Miner) loadDealsAwaitingSeal() error {
 	sm.dealsAwaitingSeal = newDealsAwaitingSeal()
 
@@ -495,10 +767,55 @@ func (sm *Miner) saveDealsAwaitingSeal() error {
 	if err != nil {
 		return errors.Wrap(err, "could not save deal awaiting seal record to disk, in-memory deals differ from persisted deals!")
 	}
+	sealingQueueGa.Set(context.Background(), int64(sm.dealsAwaitingSeal.sectorCount()))
 
 	return nil
 }
 
+// commitRetryInterval is how long the miner waits before sending the
+// commitment of a sealed sector again after sending it failed.
+var commitRetryInterval = time.Minute
+
+// CommitSector sends the commitSector message for a sealed sector.
+func (sm *Miner) CommitSector(ctx context.Context, sector *sectorbuilder.SealedSectorMetadata) (cid.Cid, error) {
+	params := []interface{}{
+		sector.SectorID,
+		sector.CommD[:],
+		sector.CommR[:],
+		sector.CommRStar[:],
+		sector.Proof[:],
+	}
+
+	gasLimit, err := sm.porcelainAPI.GasLimitEstimate(ctx, sm.minerOwnerAddr, sm.minerAddr, types.ZeroAttoFIL, "commitSector", params...)
+	if err != nil {
+		// Still send the commitment, the miner only pays for the gas it uses.
+		log.Warningf("failed to estimate commitSector gas limit for sector with id %d, using the block gas limit: %s", sector.SectorID, err)
+		gasLimit = types.BlockGasLimit
+	}
+	gasPrice, err := sm.porcelainAPI.GasPriceEstimate(ctx, "commitSector")
+	if err != nil {
+		// Dropping the commitment would lose the sealed sector, so fall back to the configured price.
+		log.Warningf("failed to estimate commitSector gas price for sector with id %d, using the default gas price: %s", sector.SectorID, err)
+		if gasPrice, err = sm.getDefaultGasPrice(); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	return sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, sm.minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, "commitSector", params...)
+}
+
+func (sm *Miner) getDefaultGasPrice() (types.AttoFIL, error) {
+	gasPrice, err := sm.porcelainAPI.ConfigGet("mining.gasPrice.default")
+	if err != nil {
+		return types.ZeroAttoFIL, err
+	}
+	gasPriceAF, ok := gasPrice.(types.AttoFIL)
+	if !ok {
+		return types.ZeroAttoFIL, errors.New("could not retrieve the default gas price from config")
+	}
+	return gasPriceAF, nil
+}
+
 // OnCommitmentSent is a callback, called when a sector seal message was posted to the chain.
 func (sm *Miner) OnCommitmentSent(sector *sectorbuilder.SealedSectorMetadata, msgCid cid.Cid, err error) {
 	ctx := context.Background()
@@ -506,18 +823,37 @@ func (sm *Miner) OnCommitmentSent(sector *sectorbuilder.SealedSectorMetadata, ms
 	log.Debug("Miner.OnCommitmentSent")
 
 	if err != nil {
-		log.Errorf("failed sealing sector: %d: %s:", sectorID, err)
-		errMsg := fmt.Sprintf("failed sealing sector: %d", sectorID)
-		sm.dealsAwaitingSeal.onSealFail(ctx, sector.SectorID, errMsg)
-	} else {
-		sm.dealsAwaitingSeal.onSealSuccess(ctx, sector, msgCid)
+		log.Errorf("failed sending commitment for sector %d, will retry: %s", sectorID, err)
+		sm.onCommitmentSendFailed(sector, err)
+		return
 	}
+
+	sm.updateSector(sectorID, func(s *Sector) {
+		s.State = SectorCommitted
+		s.CommitMessageCid = msgCid
+		s.Error = ""
+	})
+	sm.dealsAwaitingSeal.onSealSuccess(ctx, sector, msgCid)
 	if err := sm.saveDealsAwaitingSeal(); err != nil {
 		log.Errorf("failed persisting deals awaiting seal: %s", err)
 		sm.dealsAwaitingSeal.onSealFail(ctx, sector.SectorID, "failed persisting deals awaiting seal")
 	}
 }
 
+// onCommitmentSendFailed records that the commitment of a sealed sector could
+// not be sent and sends it again later. The sector and its deals are kept, as
+// its proof is still valid.
+func (sm *Miner) onCommitmentSendFailed(sector *sectorbuilder.SealedSectorMetadata, sendErr error) {
+	sm.updateSector(sector.SectorID, func(s *Sector) {
+		s.Error = sendErr.Error()
+	})
+
+	time.AfterFunc(commitRetryInterval, func() {
+		msgCid, err := sm.CommitSector(context.Background(), sector)
+		sm.OnCommitmentSent(sector, msgCid, err)
+	})
+}
+
 func (sm *Miner) onCommitSuccess(ctx context.Context, dealCid cid.Cid, sector *sectorbuilder.SealedSectorMetadata) {
 	pieceInfo, err := sm.findPieceInfo(ctx, dealCid, sector)
 	if err != nil {
@@ -667,6 +1003,39 @@ func (sm *Miner) handleQueryDeal(s inet.Stream) {
 	}
 }
 
+// SealingQueueDepth returns the number of sectors with deals waiting to be
+// sealed.
+func (sm *Miner) SealingQueueDepth() int {
+	return sm.dealsAwaitingSeal.sectorCount()
+}
+
+// LastPoStHeight returns the end of the last proving period the miner
+// submitted a PoSt for, or nil if it never did.
+func (sm *Miner) LastPoStHeight() *types.BlockHeight {
+	sm.provingLk.Lock()
+	defer sm.provingLk.Unlock()
+
+	if sm.provingSchedule == nil {
+		return nil
+	}
+	if period := sm.provingSchedule.LastSubmitted(); period != nil {
+		return period.End
+	}
+	return nil
+}
+
+// MissedPoStDeadline reports whether the miner missed the PoSt deadline of
+// its current or most recently ended proving period.
+func (sm *Miner) MissedPoStDeadline() bool {
+	sm.provingLk.Lock()
+	defer sm.provingLk.Unlock()
+
+	if sm.provingSchedule == nil {
+		return false
+	}
+	return sm.provingSchedule.MissedDeadline()
+}
+
 // OnNewHeaviestTipSet is a callback called by node, every time the the latest
 // head is updated. It is used to check if we are in a new proving period and
 // need to trigger PoSt submission.
@@ -714,14 +1083,6 @@ func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) error {
 		return errors.Errorf("failed to get proving period: %s", err)
 	}
 
-	sm.postInProcessLk.Lock()
-	defer sm.postInProcessLk.Unlock()
-
-	if sm.postInProcess != nil && sm.postInProcess.Equal(provingPeriodEnd) {
-		// post is already being generated for this period, nothing to do
-		return nil
-	}
-
 	height, err := ts.Height()
 	if err != nil {
 		return errors.Errorf("failed to get block height: %s", err)
@@ -730,18 +1091,42 @@ func (sm *Miner) OnNewHeaviestTipSet(ts types.TipSet) error {
 	// the block height of the new heaviest tipset
 	h := types.NewBlockHeight(height)
 
-	if h.GreaterEqual(provingPeriodStart) {
-		if h.LessEqual(provingPeriodEnd) {
-			// we are in a new proving period, lets get this post going
-			sm.postInProcess = provingPeriodEnd
+	sm.provingLk.Lock()
+	defer sm.provingLk.Unlock()
 
-			go sm.submitPoSt(ctx, provingPeriodStart, provingPeriodEnd, inputs)
-		} else {
-			// we are too late
-			// TODO: figure out faults and payments here
-			return errors.Errorf("too late start=%s  end=%s current=%s", provingPeriodStart, provingPeriodEnd, h)
+	period := sm.provingSchedule.period(provingPeriodStart, provingPeriodEnd)
+	if period.State != ProvingPending {
+		// post is already being generated, was submitted or was given up on
+		// for this period, nothing to do
+		return nil
+	}
+
+	if h.LessThan(provingPeriodStart) {
+		return nil
+	}
+
+	// Stop starting new attempts close to the deadline so the submission has
+	// time to make it into the chain.
+	cutoff := provingPeriodEnd.Sub(types.NewBlockHeight(postSubmitMargin))
+	if h.GreaterEqual(cutoff) {
+		// we are too late, give up on this period
+		log.Errorf("missed PoSt deadline: too late start=%s  end=%s current=%s", provingPeriodStart, provingPeriodEnd, h)
+		period.State = ProvingMissed
+		if err := sm.saveProvingSchedule(); err != nil {
+			log.Errorf("failed to save proving schedule: %s", err)
 		}
+		return nil
 	}
+
+	// we are in the proving period and have no PoSt for it yet, lets get this post going
+	period.State = ProvingGenerating
+	period.Attempts++
+	if err := sm.saveProvingSchedule(); err != nil {
+		log.Errorf("failed to save proving schedule: %s", err)
+	}
+
+	go sm.submitPoSt(ctx, provingPeriodStart, provingPeriodEnd, inputs)
+
 	return nil
 }
 
@@ -764,22 +1149,95 @@ func (sm *Miner) submitPoSt(ctx context.Context, start, end *types.BlockHeight,
 	submission, err := prover.CalculatePoSt(ctx, start, end, inputs)
 	if err != nil {
 		log.Errorf("failed to calculate PoSt: %s", err)
+		sm.recordPoSt(end, func(period *ProvingPeriod) {
+			period.State = ProvingPending
+			period.LastError = err.Error()
+		})
 		return
 	}
+
+	// Sectors that could not be proven are reported as faults. They don't
+	// count toward the miner's power but stay in its proving set, so they
+	// count again once they are proven.
+	faults := types.NewIntSet(submission.Faults...)
 	// TODO #2998. The done set should be updated by CLI users.
 	// Using the 0 value is just a placeholder until that work lands.
 	done := types.EmptyIntSet()
 
-	gasPrice := types.NewGasPrice(submitPostGasPrice)
-	_, err = sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, sm.minerAddr, submission.Fee, gasPrice, submission.GasLimit, "submitPoSt", submission.Proofs, done)
+	gasPrice, err := sm.porcelainAPI.GasPriceEstimate(ctx, "submitPoSt")
+	if err != nil {
+		log.Errorf("failed to estimate PoSt gas price: %s", err)
+		sm.recordPoSt(end, func(period *ProvingPeriod) {
+			period.State = ProvingPending
+			period.LastError = err.Error()
+		})
+		return
+	}
+
+	gasLimit, err := sm.porcelainAPI.GasLimitEstimate(ctx, sm.minerOwnerAddr, sm.minerAddr, submission.Fee, "submitPoSt", submission.Proofs, faults, done)
+	if err != nil {
+		log.Errorf("failed to estimate PoSt gas limit: %s", err)
+		sm.recordPoSt(end, func(period *ProvingPeriod) {
+			period.State = ProvingPending
+			period.LastError = err.Error()
+		})
+		return
+	}
+
+	msgCid, err := sm.porcelainAPI.MessageSend(ctx, sm.minerOwnerAddr, sm.minerAddr, submission.Fee, gasPrice, gasLimit, "submitPoSt", submission.Proofs, faults, done)
 	if err != nil {
 		log.Errorf("failed to submit PoSt: %s", err)
+		sm.recordPoSt(end, func(period *ProvingPeriod) {
+			period.State = ProvingPending
+			period.LastError = err.Error()
+		})
 		return
 	}
 
+	sm.recordPoSt(end, func(period *ProvingPeriod) {
+		period.State = ProvingSubmitted
+		period.LastError = ""
+		period.Faults = submission.Faults
+		period.MessageCid = msgCid
+	})
+
 	log.Info("submitted PoSt")
 }
 
+// recordPoSt updates and persists the proving period ending at end, if it is
+// still the current one. A failed attempt is left pending so that it is
+// retried on the next tipset.
+func (sm *Miner) recordPoSt(end *types.BlockHeight, f func(*ProvingPeriod)) {
+	sm.provingLk.Lock()
+	defer sm.provingLk.Unlock()
+
+	period := sm.provingSchedule.Current
+	if period == nil || !period.End.Equal(end) {
+		return
+	}
+	f(period)
+	if err := sm.saveProvingSchedule(); err != nil {
+		log.Errorf("failed to save proving schedule: %s", err)
+	}
+}
+
+func (sm *Miner) loadProvingSchedule() error {
+	schedule, err := LoadProvingSchedule(sm.dealsAwaitingSealDs)
+	if err != nil {
+		return err
+	}
+	// an attempt that was running when the miner stopped has to be retried
+	if schedule.Current != nil && schedule.Current.State == ProvingGenerating {
+		schedule.Current.State = ProvingPending
+	}
+	sm.provingSchedule = schedule
+	return nil
+}
+
+func (sm *Miner) saveProvingSchedule() error {
+	return saveProvingSchedule(sm.dealsAwaitingSealDs, sm.provingSchedule)
+}
+
 //
 // Implementation of ProofReader.
 //
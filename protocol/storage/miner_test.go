@@ -50,6 +50,8 @@ func TestReceiveStorageProposal(t *testing.T) {
 		miner := Miner{
 			porcelainAPI:   porcelainAPI,
 			minerOwnerAddr: porcelainAPI.targetAddress,
+			dealPolicy:     NewConfigDealPolicy(address.Undef, porcelainAPI),
+			channels:       newChannelIndex(),
 			proposalAcceptor: func(m *Miner, p *storagedeal.Proposal) (*storagedeal.Response, error) {
 				accepted = true
 				return &storagedeal.Response{State: storagedeal.Accepted}, nil
@@ -97,6 +99,18 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Contains(t, res.Message, "could not find payment channel")
 	})
 
+	t.Run("Rejects proposals on a payment channel being closed", func(t *testing.T) {
+		_, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+
+		miner.channels.setClosing(paymentChannelKey(&proposal.Proposal), true)
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Rejected, res.State)
+		assert.Equal(t, "payment channel is being closed", res.Message)
+	})
+
 	t.Run("Rejects proposals with wrong target", func(t *testing.T) {
 		_, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 
@@ -184,6 +198,56 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Contains(t, res.Message, "voucher amount")
 	})
 
+	t.Run("Rejects proposals on a lane used by an earlier deal", func(t *testing.T) {
+		porcelainAPI, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+		earlierDealOnChannelTestSetup(t, porcelainAPI, miner)
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Rejected, res.State)
+		assert.Contains(t, res.Message, "lane 0 is already used by another deal")
+	})
+
+	t.Run("Rejects proposals with vouchers on another lane", func(t *testing.T) {
+		porcelainAPI, miner, _ := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+		earlierDealOnChannelTestSetup(t, porcelainAPI, miner)
+
+		vouchers := testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc)
+		proposal := testSignedDealProposal(porcelainAPI, vouchers, defaultPieceSize)
+		proposal.Payment.Lane = 1
+		proposal, err := proposal.Proposal.NewSignedProposal(porcelainAPI.payerAddress, porcelainAPI.signer)
+		require.NoError(t, err)
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Rejected, res.State)
+		assert.Contains(t, res.Message, "voucher must pay on lane 1")
+	})
+
+	t.Run("Accepts proposals paying on their own lane", func(t *testing.T) {
+		porcelainAPI, miner, _ := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+		earlierDealOnChannelTestSetup(t, porcelainAPI, miner)
+
+		vouchers := testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc)
+		for _, v := range vouchers {
+			v.Lane = types.VoucherLane{ID: 1}
+			signature, err := paymentbroker.SignVoucher(porcelainAPI.channelID, v.Amount, &v.ValidAt, porcelainAPI.payerAddress, nil, &v.Lane, porcelainAPI.signer)
+			require.NoError(t, err)
+			v.Signature = signature
+		}
+		proposal := testSignedDealProposal(porcelainAPI, vouchers, defaultPieceSize)
+		proposal.Payment.Lane = 1
+		proposal, err := proposal.Proposal.NewSignedProposal(porcelainAPI.payerAddress, porcelainAPI.signer)
+		require.NoError(t, err)
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Accepted, res.State, res.Message)
+	})
+
 	t.Run("Rejects proposals with invalid signature", func(t *testing.T) {
 		_, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
 		proposal.Signature = []byte{'0', '0', '0'}
@@ -195,11 +259,35 @@ func TestReceiveStorageProposal(t *testing.T) {
 		assert.Equal(t, "invalid deal signature", res.Message)
 	})
 
+	t.Run("Rejects proposals refused by the deal policy", func(t *testing.T) {
+		porcelainAPI, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+		require.NoError(t, porcelainAPI.config.Set("mining.dealPolicy.maxDuration", "100"))
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Rejected, res.State)
+		assert.Equal(t, "duration is 10000 blocks but maximum duration is 100 blocks", res.Message)
+	})
+
+	t.Run("Rejects proposals with a fixed reason when the deal policy fails", func(t *testing.T) {
+		_, miner, proposal := defaultMinerTestSetup(t, VoucherInterval, defaultAmountInc)
+		miner.dealPolicy = &failingDealPolicy{}
+
+		res, err := miner.receiveStorageProposal(context.Background(), proposal)
+		require.NoError(t, err)
+
+		assert.Equal(t, storagedeal.Rejected, res.State)
+		assert.Equal(t, DealPolicyFailedReason, res.Message)
+	})
+
 	t.Run("Rejects proposals piece larger than sector size", func(t *testing.T) {
 		porcelainAPI := newMinerTestPorcelain(t)
 		miner := Miner{
 			porcelainAPI:   porcelainAPI,
 			minerOwnerAddr: porcelainAPI.targetAddress,
+			dealPolicy:     NewConfigDealPolicy(address.Undef, porcelainAPI),
+			channels:       newChannelIndex(),
 			proposalAcceptor: func(m *Miner, p *storagedeal.Proposal) (*storagedeal.Response, error) {
 				return &storagedeal.Response{State: storagedeal.Accepted}, nil
 			},
@@ -262,6 +350,110 @@ func TestDealsAwaitingSealPersistence(t *testing.T) {
 	})
 }
 
+func TestResumeDeals(t *testing.T) {
+	tf.UnitTest(t)
+
+	setup := func(t *testing.T) (*minerTestPorcelain, *Miner, func(address.Address, storagedeal.State) cid.Cid) {
+		porcelainAPI := newMinerTestPorcelain(t)
+		miner := newTestMiner(porcelainAPI)
+		miner.minerAddr = address.NewForTestGetter()()
+		miner.dealsAwaitingSealDs = repo.NewInMemoryRepo().DealsDatastore()
+		require.NoError(t, miner.loadDealsAwaitingSeal())
+
+		proposal := testSignedDealProposal(porcelainAPI, nil, defaultPieceSize).Proposal
+		cidGetter := types.NewCidForTestGetter()
+		putDeal := func(minerAddr address.Address, state storagedeal.State) cid.Cid {
+			proposalCid := cidGetter()
+			require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+				Miner:    minerAddr,
+				Proposal: &proposal,
+				Response: &storagedeal.Response{State: state, ProposalCid: proposalCid},
+			}))
+			return proposalCid
+		}
+		return porcelainAPI, miner, putDeal
+	}
+
+	t.Run("resumes accepted deals and re-attaches staged deals", func(t *testing.T) {
+		porcelainAPI, miner, putDeal := setup(t)
+
+		accepted := putDeal(miner.minerAddr, storagedeal.Accepted)
+		staged := putDeal(miner.minerAddr, storagedeal.Staged)
+		stagedWithoutSector := putDeal(miner.minerAddr, storagedeal.Staged)
+		complete := putDeal(miner.minerAddr, storagedeal.Complete)
+		otherMiner := putDeal(address.TestAddress, storagedeal.Accepted)
+
+		// the staged deal was retried in sector 7 after sector 6 failed to seal
+		require.NoError(t, saveSector(miner.dealsAwaitingSealDs, &Sector{ID: 6, State: SectorFailed, Pieces: []SectorPiece{{DealCid: staged}, {DealCid: stagedWithoutSector}}}))
+		require.NoError(t, saveSector(miner.dealsAwaitingSealDs, &Sector{ID: 7, State: SectorStaged, Pieces: []SectorPiece{{DealCid: staged}}}))
+
+		unfinished, err := miner.unfinishedDeals(context.Background())
+		require.NoError(t, err)
+		toProcess, retry, err := miner.resumableDeals(context.Background(), unfinished)
+		require.NoError(t, err)
+
+		assert.Equal(t, []cid.Cid{accepted}, toProcess)
+		assert.Empty(t, retry)
+		assert.Equal(t, []cid.Cid{staged}, miner.dealsAwaitingSeal.SectorsToDeals[7])
+		assert.Equal(t, storagedeal.Failed, porcelainAPI.deals[stagedWithoutSector].Response.State)
+		assert.Equal(t, storagedeal.Complete, porcelainAPI.deals[complete].Response.State)
+		assert.Equal(t, storagedeal.Accepted, porcelainAPI.deals[otherMiner].Response.State)
+
+		// the re-attached deal is persisted
+		require.NoError(t, miner.loadDealsAwaitingSeal())
+		assert.Equal(t, []cid.Cid{staged}, miner.dealsAwaitingSeal.SectorsToDeals[7])
+	})
+
+	t.Run("fails deals whose payment channel expired", func(t *testing.T) {
+		porcelainAPI, miner, putDeal := setup(t)
+		porcelainAPI.blockHeight = porcelainAPI.channelEol
+
+		accepted := putDeal(miner.minerAddr, storagedeal.Accepted)
+		staged := putDeal(miner.minerAddr, storagedeal.Staged)
+		require.NoError(t, saveSector(miner.dealsAwaitingSealDs, &Sector{ID: 7, State: SectorStaged, Pieces: []SectorPiece{{DealCid: staged}}}))
+
+		unfinished, err := miner.unfinishedDeals(context.Background())
+		require.NoError(t, err)
+		toProcess, retry, err := miner.resumableDeals(context.Background(), unfinished)
+		require.NoError(t, err)
+
+		assert.Empty(t, toProcess)
+		assert.Empty(t, retry)
+		assert.Empty(t, miner.dealsAwaitingSeal.SectorsToDeals)
+		for _, proposalCid := range []cid.Cid{accepted, staged} {
+			resp := porcelainAPI.deals[proposalCid].Response
+			assert.Equal(t, storagedeal.Failed, resp.State)
+			assert.Equal(t, "payment channel expired before the deal completed", resp.Message)
+		}
+	})
+
+	t.Run("retries deals whose payment channel cannot be looked up", func(t *testing.T) {
+		porcelainAPI, miner, putDeal := setup(t)
+		porcelainAPI.messageHandlers["ls"] = func(address.Address, types.AttoFIL, ...interface{}) ([][]byte, error) {
+			return nil, errors.New("state unavailable")
+		}
+
+		accepted := putDeal(miner.minerAddr, storagedeal.Accepted)
+
+		unfinished, err := miner.unfinishedDeals(context.Background())
+		require.NoError(t, err)
+		toProcess, retry, err := miner.resumableDeals(context.Background(), unfinished)
+		require.NoError(t, err)
+
+		assert.Empty(t, toProcess)
+		require.Len(t, retry, 1)
+		assert.Equal(t, accepted, retry[0].Response.ProposalCid)
+		assert.Equal(t, storagedeal.Accepted, porcelainAPI.deals[accepted].Response.State)
+
+		// the deal is resumed once its channel can be looked up
+		delete(porcelainAPI.messageHandlers, "ls")
+		toProcess, retry, err = miner.resumableDeals(context.Background(), retry)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{accepted}, toProcess)
+		assert.Empty(t, retry)
+	})
+}
+
 func TestOnCommitmentAddedToChain(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -429,8 +621,61 @@ func TestOnNewHeaviestTipSet(t *testing.T) {
 		time.Sleep(1 * time.Second)
 
 		// assert proof generated in sector builder is sent to submitPoSt
-		require.Equal(t, 2, len(postParams))
+		require.Equal(t, 3, len(postParams))
 		assert.Equal(t, []types.PoStProof{[]byte("test proof")}, postParams[0])
+		assert.Equal(t, types.EmptyIntSet(), postParams[1])
+		assert.Equal(t, types.EmptyIntSet(), postParams[2])
+
+		// the submission is recorded in the persisted proving schedule
+		schedule, err := LoadProvingSchedule(miner.dealsAwaitingSealDs)
+		require.NoError(t, err)
+		require.NotNil(t, schedule.Current)
+		assert.Equal(t, ProvingSubmitted, schedule.Current.State)
+		assert.True(t, types.NewBlockHeight(40003).Equal(schedule.Current.End))
+		assert.Equal(t, uint64(1), schedule.Current.Attempts)
+
+		// a new tipset in the same period does not post again
+		postParams = []interface{}{}
+		require.NoError(t, miner.OnNewHeaviestTipSet(ts))
+		time.Sleep(100 * time.Millisecond)
+		assert.Empty(t, postParams)
+	})
+
+	t.Run("retries failed PoSt submissions", func(t *testing.T) {
+		api, miner, _ := minerWithAcceptedDealTestSetup(t, proposalCid, sector.SectorID)
+
+		calls := 0
+		handlers := successMessageHandlers(t)
+		handlers["submitPoSt"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			calls++
+			if calls == 1 {
+				return nil, errors.New("nonce collision")
+			}
+			return [][]byte{}, nil
+		}
+		api.messageHandlers = handlers
+
+		height := uint64(20500)
+		api.blockHeight = types.NewBlockHeight(height)
+		ts, err := types.NewTipSet(&types.Block{Height: types.Uint64(height)})
+		require.NoError(t, err)
+
+		require.NoError(t, miner.OnNewHeaviestTipSet(ts))
+		time.Sleep(1 * time.Second)
+
+		schedule, err := LoadProvingSchedule(miner.dealsAwaitingSealDs)
+		require.NoError(t, err)
+		assert.Equal(t, ProvingPending, schedule.Current.State)
+		assert.Contains(t, schedule.Current.LastError, "nonce collision")
+
+		require.NoError(t, miner.OnNewHeaviestTipSet(ts))
+		time.Sleep(1 * time.Second)
+
+		schedule, err = LoadProvingSchedule(miner.dealsAwaitingSealDs)
+		require.NoError(t, err)
+		assert.Equal(t, ProvingSubmitted, schedule.Current.State)
+		assert.Equal(t, uint64(2), schedule.Current.Attempts)
+		assert.Equal(t, "", schedule.Current.LastError)
 	})
 
 	t.Run("Does not post if block height is too low", func(t *testing.T) {
@@ -459,7 +704,7 @@ func TestOnNewHeaviestTipSet(t *testing.T) {
 		time.Sleep(1 * time.Second)
 	})
 
-	t.Run("Errors if past proving period", func(t *testing.T) {
+	t.Run("Gives up if past proving period", func(t *testing.T) {
 		// create new miner with deal in the accepted state and mapped to a sector
 		api, miner, _ := minerWithAcceptedDealTestSetup(t, proposalCid, sector.SectorID)
 
@@ -476,12 +721,18 @@ func TestOnNewHeaviestTipSet(t *testing.T) {
 		ts, err := types.NewTipSet(block)
 		require.NoError(t, err)
 
-		err = miner.OnNewHeaviestTipSet(ts)
-		require.Error(t, err)
-		assert.Contains(t, err.Error(), "too late start")
+		require.NoError(t, miner.OnNewHeaviestTipSet(ts))
 
 		// Sleep to ensure submit post is not called
 		time.Sleep(1 * time.Second)
+
+		schedule, err := LoadProvingSchedule(miner.dealsAwaitingSealDs)
+		require.NoError(t, err)
+		assert.Equal(t, ProvingMissed, schedule.Current.State)
+
+		// later tipsets in the missed period are ignored
+		require.NoError(t, miner.OnNewHeaviestTipSet(ts))
+		time.Sleep(100 * time.Millisecond)
 	})
 }
 
@@ -560,7 +811,9 @@ type minerTestPorcelain struct {
 	paymentStart    *types.BlockHeight
 	randError       bool
 	deals           map[cid.Cid]*storagedeal.Deal
+	dealsListed     int
 	messageHandlers map[string]func(address.Address, types.AttoFIL, ...interface{}) ([][]byte, error)
+	messageWait     func(cid.Cid) error
 
 	testing *testing.T
 }
@@ -619,6 +872,14 @@ func (mtp *minerTestPorcelain) ActorGetSignature(ctx context.Context, actorAddr
 	return builtin.Actors[types.MinerActorCodeCid].Exports()[method], nil
 }
 
+func (mtp *minerTestPorcelain) GasLimitEstimate(ctx context.Context, from, to address.Address, value types.AttoFIL, method string, params ...interface{}) (types.GasUnits, error) {
+	return types.NewGasUnits(300), nil
+}
+
+func (mtp *minerTestPorcelain) GasPriceEstimate(ctx context.Context, method string) (types.AttoFIL, error) {
+	return types.NewGasPrice(1), nil
+}
+
 func (mtp *minerTestPorcelain) MessageSend(ctx context.Context, from, to address.Address, val types.AttoFIL, gasPrice types.AttoFIL, gasLimit types.GasUnits, method string, params ...interface{}) (cid.Cid, error) {
 	handler, ok := mtp.messageHandlers[method]
 	if ok {
@@ -671,6 +932,9 @@ func (mtp *minerTestPorcelain) ChainBlockHeight() (*types.BlockHeight, error) {
 }
 
 func (mtp *minerTestPorcelain) MessageWait(ctx context.Context, msgCid cid.Cid, cb func(*types.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
+	if mtp.messageWait != nil {
+		return mtp.messageWait(msgCid)
+	}
 	return nil
 }
 
@@ -678,6 +942,8 @@ func newTestMiner(api *minerTestPorcelain) *Miner {
 	return &Miner{
 		porcelainAPI:   api,
 		minerOwnerAddr: api.targetAddress,
+		dealPolicy:     NewConfigDealPolicy(address.Undef, api),
+		channels:       newChannelIndex(),
 		proposalAcceptor: func(m *Miner, p *storagedeal.Proposal) (*storagedeal.Response, error) {
 			return &storagedeal.Response{State: storagedeal.Accepted}, nil
 		},
@@ -707,6 +973,7 @@ func minerWithAcceptedDealTestSetup(t *testing.T, proposalCid cid.Cid, sectorID
 	// create the dealsAwaitingSeal to manage the deal prior to sealing
 	err := miner.loadDealsAwaitingSeal()
 	require.NoError(t, err)
+	require.NoError(t, miner.loadProvingSchedule())
 
 	// wire dealsAwaitingSeal with the actual commit success functionality
 	miner.dealsAwaitingSeal.onSuccess = miner.onCommitSuccess
@@ -732,6 +999,17 @@ func minerWithAcceptedDealTestSetup(t *testing.T, proposalCid cid.Cid, sectorID
 	return porcelainAPI, miner, proposal
 }
 
+// earlierDealOnChannelTestSetup stores a deal of the miner paid on lane 0 of
+// the test payment channel.
+func earlierDealOnChannelTestSetup(t *testing.T, porcelainAPI *minerTestPorcelain, miner *Miner) {
+	earlier := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, VoucherInterval, defaultAmountInc), defaultPieceSize)
+	require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+		Miner:    miner.minerAddr,
+		Proposal: &earlier.Proposal,
+		Response: &storagedeal.Response{State: storagedeal.Accepted, ProposalCid: types.NewCidForTestGetter()()},
+	}))
+}
+
 func newMinerTestSetup(porcelainAPI *minerTestPorcelain, voucherInterval int, amountInc uint64) (*Miner, *storagedeal.SignedDealProposal) {
 	vouchers := testPaymentVouchers(porcelainAPI, voucherInterval, amountInc)
 	miner := newTestMiner(porcelainAPI)
@@ -744,7 +1022,7 @@ func testPaymentVouchers(porcelainAPI *minerTestPorcelain, voucherInterval int,
 	for i := 0; i < 10; i++ {
 		validAt := porcelainAPI.paymentStart.Add(types.NewBlockHeight(uint64((i + 1) * voucherInterval)))
 		amount := types.NewAttoFILFromFIL(uint64(i+1) * amountInc)
-		signature, err := paymentbroker.SignVoucher(porcelainAPI.channelID, amount, validAt, porcelainAPI.payerAddress, nil, porcelainAPI.signer)
+		signature, err := paymentbroker.SignVoucher(porcelainAPI.channelID, amount, validAt, porcelainAPI.payerAddress, nil, nil, porcelainAPI.signer)
 		require.NoError(porcelainAPI.testing, err, "could not sign valid proposal")
 
 		vouchers[i] = &types.PaymentVoucher{
@@ -760,6 +1038,12 @@ func testPaymentVouchers(porcelainAPI *minerTestPorcelain, voucherInterval int,
 
 }
 
+type failingDealPolicy struct{}
+
+func (fdp *failingDealPolicy) Evaluate(ctx context.Context, p *storagedeal.Proposal) (string, error) {
+	return "", errors.New("failed to list deals: datastore closed")
+}
+
 func testSectorMetadata(pieceRef cid.Cid) *sectorbuilder.SealedSectorMetadata {
 	sectorID := uint64(777)
 
@@ -809,3 +1093,13 @@ func (mtp *minerTestPorcelain) DealPut(storageDeal *storagedeal.Deal) error {
 	mtp.deals[storageDeal.Response.ProposalCid] = storageDeal
 	return nil
 }
+
+func (mtp *minerTestPorcelain) DealsLs(_ context.Context) (<-chan *porcelain.StorageDealLsResult, error) {
+	mtp.dealsListed++
+	out := make(chan *porcelain.StorageDealLsResult, len(mtp.deals))
+	for _, storageDeal := range mtp.deals {
+		out <- &porcelain.StorageDealLsResult{Deal: *storageDeal}
+	}
+	close(out)
+	return out, nil
+}
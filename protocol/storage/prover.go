@@ -10,9 +10,6 @@ import (
 	"github.com/filecoin-project/go-filecoin/types"
 )
 
-// This will likely depend on the sector size and proving period.
-const submitPostGasLimit = 300
-
 // ProofReader provides information about the blockchain to the proving process.
 type ProofReader interface {
 	// ChainHeight returns the current height of the best chain.
@@ -46,9 +43,10 @@ type PoStInputs struct {
 
 // PoStSubmission is the information to be submitted on-chain for a proof.
 type PoStSubmission struct {
-	Proofs   []types.PoStProof
-	Fee      types.AttoFIL
-	GasLimit types.GasUnits
+	Proofs []types.PoStProof
+	// Faults are the ids of the sectors that could not be proven.
+	Faults []uint64
+	Fee    types.AttoFIL
 }
 
 // NewProver constructs a new Prover.
@@ -79,10 +77,8 @@ func (sm *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeig
 		return nil, errors.Wrap(err, "failed to generate PoSt")
 	}
 
-	// Compute fees.
 	if len(faults) != 0 {
 		log.Warningf("some faults when generating PoSt: %v", faults)
-		// TODO: include faults in submission https://github.com/filecoin-project/go-filecoin/issues/2889
 	}
 
 	height, err := sm.chain.ChainHeight()
@@ -102,8 +98,8 @@ func (sm *Prover) CalculatePoSt(ctx context.Context, start, end *types.BlockHeig
 	}
 
 	return &PoStSubmission{
-		Proofs:   proofs,
-		Fee:      types.ZeroAttoFIL,
-		GasLimit: types.NewGasUnits(submitPostGasLimit),
+		Proofs: proofs,
+		Faults: faults,
+		Fee:    types.ZeroAttoFIL,
 	}, nil
 }
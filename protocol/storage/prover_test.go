@@ -42,6 +42,20 @@ func TestProver(t *testing.T) {
 		assert.Equal(t, fake.proofs, submission.Proofs)
 	})
 
+	t.Run("reports faults", func(t *testing.T) {
+		fake := &fakeProverDeps{
+			seed:   fakeSeed,
+			height: end.Sub(types.NewBlockHeight(1)),
+			proofs: []types.PoStProof{{1, 2, 3, 4}},
+			faults: []uint64{3, 7},
+		}
+		prover := storage.NewProver(actorAddress, ownerAddress, fake, fake)
+
+		submission, e := prover.CalculatePoSt(ctx, start, end, fakeInputs)
+		require.NoError(t, e)
+		assert.Equal(t, []uint64{3, 7}, submission.Faults)
+	})
+
 	t.Run("fails without chain height", func(t *testing.T) {
 		fake := &fakeProverDeps{
 			seed:   fakeSeed,
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const provingScheduleDatastorePrefix = "provingSchedule"
+
+// postSubmitMargin is the number of blocks before the end of a proving period
+// after which no new PoSt attempts are started, leaving time for the
+// submission to be included in the chain.
+const postSubmitMargin = 10
+
+// maxProvingHistory is the number of past proving periods that are kept.
+const maxProvingHistory = 10
+
+// ProvingState is the state of the miner's PoSt for a single proving period.
+type ProvingState string
+
+const (
+	// ProvingPending means no PoSt has been generated for the period yet.
+	ProvingPending = ProvingState("pending")
+	// ProvingGenerating means a PoSt is being generated for the period.
+	ProvingGenerating = ProvingState("generating")
+	// ProvingSubmitted means the PoSt for the period was sent to the chain.
+	ProvingSubmitted = ProvingState("submitted")
+	// ProvingMissed means no PoSt could be submitted before the deadline.
+	ProvingMissed = ProvingState("missed")
+)
+
+// ProvingPeriod records the miner's progress proving a single proving period.
+type ProvingPeriod struct {
+	Start *types.BlockHeight `json:"start"`
+	End   *types.BlockHeight `json:"end"`
+	State ProvingState       `json:"state"`
+	// Attempts is the number of times PoSt generation was started.
+	Attempts uint64 `json:"attempts"`
+	// LastError is the reason the most recent attempt failed.
+	LastError string `json:"lastError,omitempty"`
+	// Faults are the sectors reported as faulty in the submitted PoSt.
+	Faults []uint64 `json:"faults"`
+	// MessageCid is the cid of the submitPoSt message.
+	MessageCid cid.Cid `json:"messageCid"`
+}
+
+// ProvingSchedule is the persisted state of the miner's proving scheduler.
+type ProvingSchedule struct {
+	Current *ProvingPeriod   `json:"current"`
+	History []*ProvingPeriod `json:"history"`
+}
+
+// period returns the schedule's record for the proving period ending at end,
+// archiving the current period if it is a different one.
+func (ps *ProvingSchedule) period(start, end *types.BlockHeight) *ProvingPeriod {
+	if ps.Current != nil && ps.Current.End.Equal(end) {
+		return ps.Current
+	}
+
+	if ps.Current != nil {
+		if ps.Current.State != ProvingSubmitted {
+			ps.Current.State = ProvingMissed
+		}
+		ps.History = append(ps.History, ps.Current)
+		if len(ps.History) > maxProvingHistory {
+			ps.History = ps.History[len(ps.History)-maxProvingHistory:]
+		}
+	}
+
+	ps.Current = &ProvingPeriod{
+		Start: start,
+		End:   end,
+		State: ProvingPending,
+	}
+	return ps.Current
+}
+
+// LastSubmitted returns the most recent proving period a PoSt was submitted
+// for, or nil if none was.
+func (ps *ProvingSchedule) LastSubmitted() *ProvingPeriod {
+	if ps.Current != nil && ps.Current.State == ProvingSubmitted {
+		return ps.Current
+	}
+	for i := len(ps.History) - 1; i >= 0; i-- {
+		if ps.History[i].State == ProvingSubmitted {
+			return ps.History[i]
+		}
+	}
+	return nil
+}
+
+// MissedDeadline reports whether the PoSt deadline of the current or the
+// most recently ended proving period was missed.
+func (ps *ProvingSchedule) MissedDeadline() bool {
+	if ps.Current != nil && ps.Current.State == ProvingMissed {
+		return true
+	}
+	if len(ps.History) > 0 {
+		return ps.History[len(ps.History)-1].State == ProvingMissed
+	}
+	return false
+}
+
+// LoadProvingSchedule reads the proving schedule persisted in the datastore.
+// It returns an empty schedule if none has been saved yet.
+func LoadProvingSchedule(ds repo.Datastore) (*ProvingSchedule, error) {
+	schedule := &ProvingSchedule{}
+
+	key := datastore.KeyWithNamespaces([]string{provingScheduleDatastorePrefix})
+	result, notFound := ds.Get(key)
+	if notFound == nil {
+		if err := json.Unmarshal(result, schedule); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal proving schedule from datastore")
+		}
+	}
+
+	return schedule, nil
+}
+
+func saveProvingSchedule(ds repo.Datastore, schedule *ProvingSchedule) error {
+	marshalledSchedule, err := json.Marshal(schedule)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal proving schedule")
+	}
+	key := datastore.KeyWithNamespaces([]string{provingScheduleDatastorePrefix})
+	if err := ds.Put(key, marshalledSchedule); err != nil {
+		return errors.Wrap(err, "could not save proving schedule to disk")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestProvingSchedule(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("archives the previous period when a new one starts", func(t *testing.T) {
+		schedule := &ProvingSchedule{}
+
+		first := schedule.period(types.NewBlockHeight(0), types.NewBlockHeight(100))
+		assert.Equal(t, ProvingPending, first.State)
+		assert.Equal(t, first, schedule.period(types.NewBlockHeight(0), types.NewBlockHeight(100)))
+
+		first.State = ProvingSubmitted
+		second := schedule.period(types.NewBlockHeight(100), types.NewBlockHeight(200))
+		assert.Equal(t, second, schedule.Current)
+		assert.Equal(t, []*ProvingPeriod{first}, schedule.History)
+		assert.Equal(t, ProvingSubmitted, first.State)
+
+		// a period that never got a PoSt is recorded as missed
+		schedule.period(types.NewBlockHeight(200), types.NewBlockHeight(300))
+		assert.Equal(t, ProvingMissed, second.State)
+	})
+
+	t.Run("finds the last submitted period", func(t *testing.T) {
+		schedule := &ProvingSchedule{}
+		assert.Nil(t, schedule.LastSubmitted())
+
+		first := schedule.period(types.NewBlockHeight(0), types.NewBlockHeight(100))
+		first.State = ProvingSubmitted
+		assert.Equal(t, first, schedule.LastSubmitted())
+
+		// a pending and a missed period don't count
+		schedule.period(types.NewBlockHeight(100), types.NewBlockHeight(200))
+		schedule.period(types.NewBlockHeight(200), types.NewBlockHeight(300))
+		assert.Equal(t, first, schedule.LastSubmitted())
+	})
+
+	t.Run("detects a missed deadline", func(t *testing.T) {
+		schedule := &ProvingSchedule{}
+		assert.False(t, schedule.MissedDeadline())
+
+		first := schedule.period(types.NewBlockHeight(0), types.NewBlockHeight(100))
+		assert.False(t, schedule.MissedDeadline())
+		first.State = ProvingMissed
+		assert.True(t, schedule.MissedDeadline())
+
+		// the missed period is still the latest one that ended
+		second := schedule.period(types.NewBlockHeight(100), types.NewBlockHeight(200))
+		assert.True(t, schedule.MissedDeadline())
+
+		second.State = ProvingSubmitted
+		schedule.period(types.NewBlockHeight(200), types.NewBlockHeight(300))
+		assert.False(t, schedule.MissedDeadline())
+	})
+
+	t.Run("keeps a bounded history", func(t *testing.T) {
+		schedule := &ProvingSchedule{}
+		for i := uint64(0); i < maxProvingHistory+5; i++ {
+			schedule.period(types.NewBlockHeight(i*100), types.NewBlockHeight((i+1)*100))
+		}
+
+		require.Len(t, schedule.History, maxProvingHistory)
+		assert.Equal(t, types.NewBlockHeight(500), schedule.History[0].End)
+	})
+
+	t.Run("round trips through the datastore", func(t *testing.T) {
+		ds := repo.NewInMemoryRepo().DealsDatastore()
+
+		empty, err := LoadProvingSchedule(ds)
+		require.NoError(t, err)
+		assert.Nil(t, empty.Current)
+
+		schedule := &ProvingSchedule{}
+		period := schedule.period(types.NewBlockHeight(0), types.NewBlockHeight(100))
+		period.State = ProvingSubmitted
+		period.Faults = []uint64{4}
+		period.MessageCid = types.NewCidForTestGetter()()
+		require.NoError(t, saveProvingSchedule(ds, schedule))
+
+		loaded, err := LoadProvingSchedule(ds)
+		require.NoError(t, err)
+		require.NotNil(t, loaded.Current)
+		assert.True(t, loaded.Current.End.Equal(types.NewBlockHeight(100)))
+		assert.Equal(t, ProvingSubmitted, loaded.Current.State)
+		assert.Equal(t, []uint64{4}, loaded.Current.Faults)
+		assert.Equal(t, period.MessageCid, loaded.Current.MessageCid)
+	})
+}
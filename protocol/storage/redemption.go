@@ -0,0 +1,380 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor/builtin/paymentbroker"
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// redeemMargin is the number of blocks before a payment channel's Eol by
+// which the miner redeems the vouchers it holds for the channel.
+const redeemMargin = 20
+
+// RedeemVouchers redeems the payment vouchers of the miner's deals as of the
+// tipset ts. Each deal is paid on its own lane of its payment channel, so its
+// vouchers are redeemed separately from those of other deals. A deal's
+// vouchers are held until all of them are valid, at which point the largest
+// one is redeemed, or until the channel is about to expire, at which point
+// the largest valid one is. Once only one deal uses a channel, no proposal
+// for it is being handled, and the deal's vouchers are all valid, the channel
+// is closed with its largest voucher instead. Funds left in channels that
+// expired or were closed before they could be redeemed are recorded as missed
+// on the deals. Redeem and close messages are recorded on the deals until
+// they are mined, so a restarted miner waits for them instead of sending
+// them again.
+func (sm *Miner) RedeemVouchers(ctx context.Context, ts types.TipSet) error {
+	height, err := ts.Height()
+	if err != nil {
+		return errors.Wrap(err, "failed to get block height")
+	}
+	h := types.NewBlockHeight(height)
+
+	if err := sm.loadChannelIndex(ctx); err != nil {
+		return err
+	}
+
+	payerChannels := make(map[address.Address]map[string]*paymentbroker.PaymentChannel)
+	for _, key := range sm.channels.channels() {
+		if sm.channels.isRedeeming(key) {
+			continue
+		}
+
+		deals, err := sm.unsettledChannelDeals(ctx, key)
+		if err != nil {
+			return err
+		}
+		if len(deals) == 0 {
+			continue
+		}
+
+		if pending := pendingRedemptions(deals); len(pending) > 0 {
+			// messages sent before the miner restarted
+			sm.channels.setRedeeming(key, true)
+			go sm.waitForRedemption(ctx, key, pending)
+			continue
+		}
+
+		payment := deals[0].Proposal.Payment
+		channels, ok := payerChannels[payment.Payer]
+		if !ok {
+			channels, err = sm.queryPayerChannels(ctx, payment.Payer)
+			if err != nil {
+				return err
+			}
+			payerChannels[payment.Payer] = channels
+		}
+
+		if err := sm.redeemChannel(ctx, h, key, channels[payment.Channel.KeyString()], deals); err != nil {
+			log.Errorf("failed to redeem vouchers of payment channel %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
+
+// loadChannelIndex indexes the deals stored before the miner started, once.
+func (sm *Miner) loadChannelIndex(ctx context.Context) error {
+	if sm.channels.isLoaded() {
+		return nil
+	}
+
+	dealsCh, err := sm.porcelainAPI.DealsLs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list deals")
+	}
+	for result := range dealsCh {
+		if result.Err != nil {
+			return errors.Wrap(result.Err, "failed to list deals")
+		}
+		deal := result.Deal
+		if !sm.hasUnsettledPayment(&deal) {
+			continue
+		}
+		key := paymentChannelKey(deal.Proposal)
+		sm.channels.add(key, deal.Response.ProposalCid)
+		if deal.Redemption != nil && deal.Redemption.Close {
+			sm.channels.setClosing(key, true)
+		}
+	}
+	sm.channels.setLoaded()
+	return nil
+}
+
+// unsettledChannelDeals returns the indexed deals of the channel whose
+// payment is not settled yet, and drops the others from the index.
+func (sm *Miner) unsettledChannelDeals(ctx context.Context, key string) ([]*storagedeal.Deal, error) {
+	var deals []*storagedeal.Deal
+	for _, proposalCid := range sm.channels.dealCids(key) {
+		deal, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get deal with proposal CID %s", proposalCid.String())
+		}
+		if !sm.hasUnsettledPayment(deal) {
+			sm.channels.remove(key, proposalCid)
+			continue
+		}
+		deals = append(deals, deal)
+	}
+	return deals, nil
+}
+
+// pendingRedemptions returns the deals with a redeem or close message that
+// has not been mined yet.
+func pendingRedemptions(deals []*storagedeal.Deal) []*storagedeal.Deal {
+	var pending []*storagedeal.Deal
+	for _, deal := range deals {
+		if deal.Redemption != nil {
+			pending = append(pending, deal)
+		}
+	}
+	return pending
+}
+
+// redeemChannel redeems the vouchers of deals paid through the payment
+// channel, or closes the channel, if it is time to do so. channel is nil if
+// the channel no longer exists.
+func (sm *Miner) redeemChannel(ctx context.Context, h *types.BlockHeight, key string, channel *paymentbroker.PaymentChannel, deals []*storagedeal.Deal) error {
+	if channel == nil || h.GreaterEqual(channel.Eol) {
+		// nothing more can be redeemed from the channel
+		for _, deal := range deals {
+			err := sm.updateDeal(ctx, deal.Response.ProposalCid, func(d *storagedeal.Deal) {
+				d.Missed = finalVoucherAmount(d).Sub(d.Redeemed)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	allFinal := true
+	var finals []bool
+	var bestDeals []*storagedeal.Deal
+	var bests []*types.PaymentVoucher
+	for _, deal := range deals {
+		best, final := bestLaneVoucher(h, channel, deal)
+		allFinal = allFinal && final
+		if best != nil {
+			bestDeals, bests, finals = append(bestDeals, deal), append(bests, best), append(finals, final)
+		}
+	}
+	if len(bests) == 0 {
+		return nil
+	}
+
+	var redemptions []*storagedeal.Deal
+	deadline := channel.Eol.Sub(types.NewBlockHeight(redeemMargin))
+	for i, best := range bests {
+		deal := bestDeals[i]
+		method := "redeem"
+		if allFinal && len(bests) == 1 && sm.channels.startClose(key, deal.Response.ProposalCid) {
+			method = "close"
+		} else if !finals[i] && h.LessThan(deadline) {
+			// wait for the remaining vouchers so they can be redeemed at once
+			continue
+		}
+
+		msgCid, err := sm.sendRedemption(ctx, method, deal, best)
+		if err != nil {
+			if method == "close" {
+				sm.channels.setClosing(key, false)
+			}
+			log.Errorf("failed to redeem vouchers of payment channel %s: %s", key, err)
+			continue
+		}
+		log.Infof("sent %s message %s for %s on lane %d of payment channel %s", method, msgCid, best.Amount, best.Lane.ID, key)
+
+		redemption := &storagedeal.Redemption{MsgCid: msgCid, Amount: best.Amount, Close: method == "close"}
+		deal.Redemption = redemption
+		err = sm.updateDeal(ctx, deal.Response.ProposalCid, func(d *storagedeal.Deal) {
+			d.Redemption = redemption
+		})
+		if err != nil {
+			log.Errorf("failed to record %s message for deal %s: %s", method, deal.Response.ProposalCid, err)
+		}
+		redemptions = append(redemptions, deal)
+	}
+	if len(redemptions) == 0 {
+		return nil
+	}
+
+	sm.channels.setRedeeming(key, true)
+	go sm.waitForRedemption(ctx, key, redemptions)
+
+	return nil
+}
+
+// bestLaneVoucher returns the largest voucher of deal that is valid at h and
+// pays more than has been redeemed on the deal's lane of channel, if any, and
+// whether all of the deal's vouchers are valid.
+func bestLaneVoucher(h *types.BlockHeight, channel *paymentbroker.PaymentChannel, deal *storagedeal.Deal) (*types.PaymentVoucher, bool) {
+	if deal.Response.State != storagedeal.Complete {
+		// conditional vouchers can only be redeemed once the piece is sealed
+		return nil, false
+	}
+
+	redeemed := channel.LaneRedeemed(deal.Proposal.Payment.Lane)
+
+	final := true
+	var best *types.PaymentVoucher
+	for _, v := range deal.Proposal.Payment.Vouchers {
+		if h.LessThan(&v.ValidAt) {
+			final = false
+			continue
+		}
+		if v.Amount.LessEqual(redeemed) {
+			continue
+		}
+		if best == nil || v.Amount.GreaterThan(best.Amount) {
+			best = v
+		}
+	}
+	return best, final
+}
+
+// sendRedemption sends a redeem or close message for voucher of deal.
+func (sm *Miner) sendRedemption(ctx context.Context, method string, deal *storagedeal.Deal, voucher *types.PaymentVoucher) (cid.Cid, error) {
+	gasPrice, err := sm.porcelainAPI.GasPriceEstimate(ctx, method)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to estimate gas price")
+	}
+
+	conditionParams := []interface{}{}
+	if voucher.Condition != nil && deal.Response.ProofInfo != nil {
+		conditionParams = []interface{}{deal.Response.ProofInfo.SectorID, deal.Response.ProofInfo.PieceInclusionProof}
+	}
+
+	params := []interface{}{
+		voucher.Payer,
+		&voucher.Channel,
+		voucher.Amount,
+		&voucher.ValidAt,
+		voucher.Condition,
+		&voucher.Lane,
+		[]byte(voucher.Signature),
+		conditionParams,
+	}
+	gasLimit, err := sm.porcelainAPI.GasLimitEstimate(ctx, sm.minerOwnerAddr, address.PaymentBrokerAddress, types.ZeroAttoFIL, method, params...)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to estimate gas limit")
+	}
+
+	msgCid, err := sm.porcelainAPI.MessageSend(
+		ctx,
+		sm.minerOwnerAddr,
+		address.PaymentBrokerAddress,
+		types.ZeroAttoFIL,
+		gasPrice,
+		gasLimit,
+		method,
+		params...,
+	)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "failed to send %s message", method)
+	}
+	return msgCid, nil
+}
+
+// waitForRedemption records the amounts redeemed on deals once their redeem
+// or close messages have been mined successfully. Messages that failed are
+// cleared from the deals so their vouchers can be redeemed again, while those
+// that could not be waited for stay recorded to be waited for later.
+func (sm *Miner) waitForRedemption(ctx context.Context, key string, deals []*storagedeal.Deal) {
+	defer sm.channels.setRedeeming(key, false)
+
+	for _, deal := range deals {
+		r := deal.Redemption
+		failed := false
+		err := sm.porcelainAPI.MessageWait(ctx, r.MsgCid, func(blk *types.Block, msg *types.SignedMessage, receipt *types.MessageReceipt) error {
+			if receipt.ExitCode != uint8(0) {
+				failed = true
+				return errors.Errorf("message failed with exit code %d", receipt.ExitCode)
+			}
+			return nil
+		})
+		if err != nil && !failed {
+			log.Errorf("failed to wait for redemption of payment channel %s: %s", key, err)
+			continue
+		}
+		if failed {
+			log.Errorf("failed to redeem vouchers of payment channel %s: %s", key, err)
+			if r.Close {
+				sm.channels.setClosing(key, false)
+			}
+		}
+
+		err = sm.updateDeal(ctx, deal.Response.ProposalCid, func(d *storagedeal.Deal) {
+			d.Redemption = nil
+			if failed {
+				return
+			}
+			if redeemed := redeemedAmount(d, r.Amount); redeemed.GreaterThan(d.Redeemed) {
+				d.Redeemed = redeemed
+			}
+		})
+		if err != nil {
+			log.Errorf("failed to record redemption for deal %s: %s", deal.Response.ProposalCid, err)
+		}
+	}
+}
+
+// hasUnsettledPayment returns true if deal is one of the miner's deals whose
+// vouchers have not all been redeemed or missed.
+func (sm *Miner) hasUnsettledPayment(deal *storagedeal.Deal) bool {
+	if deal.Miner != sm.minerAddr || deal.Proposal == nil || deal.Response == nil {
+		return false
+	}
+	if deal.Proposal.Payment.Channel == nil || len(deal.Proposal.Payment.Vouchers) == 0 {
+		return false
+	}
+	if deal.Response.State == storagedeal.Rejected || deal.Response.State == storagedeal.Failed {
+		return false
+	}
+	return deal.Redeemed.Add(deal.Missed).LessThan(finalVoucherAmount(deal))
+}
+
+func (sm *Miner) updateDeal(ctx context.Context, proposalCid cid.Cid, f func(*storagedeal.Deal)) error {
+	storageDeal, err := sm.porcelainAPI.DealGet(ctx, proposalCid)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get retrive deal with proposal CID %s", proposalCid.String())
+	}
+	f(storageDeal)
+	if err := sm.porcelainAPI.DealPut(storageDeal); err != nil {
+		return errors.Wrap(err, "failed to store updated deal in datastore")
+	}
+	return nil
+}
+
+func paymentChannelKey(p *storagedeal.Proposal) string {
+	return p.Payment.Payer.String() + "/" + p.Payment.Channel.KeyString()
+}
+
+// finalVoucherAmount is the total amount the deal's vouchers pay on the
+// deal's lane of its channel.
+func finalVoucherAmount(deal *storagedeal.Deal) types.AttoFIL {
+	amount := types.ZeroAttoFIL
+	for _, v := range deal.Proposal.Payment.Vouchers {
+		if v.Amount.GreaterThan(amount) {
+			amount = v.Amount
+		}
+	}
+	return amount
+}
+
+// redeemedAmount is the part of the deal's payment covered by redeeming
+// amount on its lane, counting only whole vouchers.
+func redeemedAmount(deal *storagedeal.Deal, amount types.AttoFIL) types.AttoFIL {
+	redeemed := types.ZeroAttoFIL
+	for _, v := range deal.Proposal.Payment.Vouchers {
+		if v.Amount.LessEqual(amount) && v.Amount.GreaterThan(redeemed) {
+			redeemed = v.Amount
+		}
+	}
+	return redeemed
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestRedeemVouchers(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	// vouchers become valid every 10 blocks after the payment start at 773
+	// and pay 1 FIL each, up to 10 FIL at 873
+	redemptionTestSetup := func(t *testing.T, state storagedeal.State) (*minerTestPorcelain, *Miner, cid.Cid, *[]types.AttoFIL) {
+		porcelainAPI := newMinerTestPorcelain(t)
+		miner := newTestMiner(porcelainAPI)
+
+		proposal := testSignedDealProposal(porcelainAPI, testPaymentVouchers(porcelainAPI, 10, 1), 1000)
+		proposalCid := types.NewCidForTestGetter()()
+		require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+			Miner:    address.Undef,
+			Proposal: &proposal.Proposal,
+			Response: &storagedeal.Response{State: state, ProposalCid: proposalCid},
+		}))
+
+		var sent []types.AttoFIL
+		record := func(to address.Address, val types.AttoFIL, params ...interface{}) ([][]byte, error) {
+			sent = append(sent, params[2].(types.AttoFIL))
+			return nil, nil
+		}
+		porcelainAPI.messageHandlers["redeem"] = record
+		porcelainAPI.messageHandlers["close"] = record
+
+		return porcelainAPI, miner, proposalCid, &sent
+	}
+
+	redeemAt := func(t *testing.T, miner *Miner, height uint64) {
+		block := &types.Block{Height: types.Uint64(height)}
+		require.NoError(t, miner.RedeemVouchers(ctx, th.RequireNewTipSet(t, block)))
+
+		// wait for sent redemptions to be recorded
+		pending := func() bool {
+			miner.channels.lk.Lock()
+			defer miner.channels.lk.Unlock()
+			return len(miner.channels.redeeming) > 0
+		}
+		for start := time.Now(); pending(); time.Sleep(10 * time.Millisecond) {
+			require.True(t, time.Since(start) < 5*time.Second, "redemption was not recorded")
+		}
+	}
+
+	t.Run("holds vouchers until the channel's vouchers are final", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+
+		redeemAt(t, miner, 800)
+		assert.Empty(t, *sent)
+		assert.True(t, porcelainAPI.deals[proposalCid].Redeemed.IsZero())
+	})
+
+	t.Run("closes the channel with the final voucher", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+
+		redeemAt(t, miner, 900)
+		require.Len(t, *sent, 1)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal((*sent)[0]))
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(porcelainAPI.deals[proposalCid].Redeemed))
+
+		// a settled deal is not redeemed again
+		redeemAt(t, miner, 901)
+		assert.Len(t, *sent, 1)
+		assert.Nil(t, porcelainAPI.deals[proposalCid].Redemption)
+		assert.Empty(t, miner.channels.channels())
+	})
+
+	t.Run("does not close the channel while a proposal for it is handled", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, _ := redemptionTestSetup(t, storagedeal.Complete)
+		porcelainAPI.messageHandlers["close"] = func(to address.Address, val types.AttoFIL, params ...interface{}) ([][]byte, error) {
+			t.Fatal("the channel must not be closed while a proposal for it is handled")
+			return nil, nil
+		}
+
+		key := paymentChannelKey(porcelainAPI.deals[proposalCid].Proposal)
+		require.True(t, miner.channels.beginProposal(key))
+
+		redeemAt(t, miner, 900)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(porcelainAPI.deals[proposalCid].Redeemed))
+	})
+
+	t.Run("rejects proposals while the channel is closed", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, _ := redemptionTestSetup(t, storagedeal.Complete)
+		key := paymentChannelKey(porcelainAPI.deals[proposalCid].Proposal)
+
+		// the close message is not waited for until it is mined
+		mined := make(chan struct{})
+		porcelainAPI.messageWait = func(msgCid cid.Cid) error {
+			<-mined
+			return nil
+		}
+		block := &types.Block{Height: types.Uint64(900)}
+		require.NoError(t, miner.RedeemVouchers(ctx, th.RequireNewTipSet(t, block)))
+		assert.False(t, miner.channels.beginProposal(key))
+
+		close(mined)
+		redeemAt(t, miner, 901)
+		assert.False(t, miner.channels.beginProposal(key))
+	})
+
+	t.Run("waits for a recorded redemption instead of sending it again", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+		deal := porcelainAPI.deals[proposalCid]
+		deal.Redemption = &storagedeal.Redemption{MsgCid: proposalCid, Amount: types.NewAttoFILFromFIL(10), Close: true}
+
+		redeemAt(t, miner, 900)
+		assert.Empty(t, *sent)
+		assert.Nil(t, deal.Redemption)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(deal.Redeemed))
+	})
+
+	t.Run("lists deals only once", func(t *testing.T) {
+		porcelainAPI, miner, _, _ := redemptionTestSetup(t, storagedeal.Complete)
+
+		redeemAt(t, miner, 800)
+		redeemAt(t, miner, 801)
+		assert.Equal(t, 1, porcelainAPI.dealsListed)
+	})
+
+	t.Run("redeems the best voucher before the channel expires", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+		porcelainAPI.channelEol = types.NewBlockHeight(820)
+
+		redeemAt(t, miner, 805)
+		require.Len(t, *sent, 1)
+		assert.True(t, types.NewAttoFILFromFIL(3).Equal((*sent)[0]))
+		assert.True(t, types.NewAttoFILFromFIL(3).Equal(porcelainAPI.deals[proposalCid].Redeemed))
+	})
+
+	t.Run("redeems each deal of a shared channel on its own lane", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, _ := redemptionTestSetup(t, storagedeal.Complete)
+
+		vouchers := testPaymentVouchers(porcelainAPI, 10, 2)
+		for _, v := range vouchers {
+			v.Lane = types.VoucherLane{ID: 1}
+		}
+		proposal := testSignedDealProposal(porcelainAPI, vouchers, 1000)
+		proposal.Payment.Lane = 1
+		cidGetter := types.NewCidForTestGetter()
+		cidGetter()
+		otherCid := cidGetter()
+		require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+			Miner:    address.Undef,
+			Proposal: &proposal.Proposal,
+			Response: &storagedeal.Response{State: storagedeal.Complete, ProposalCid: otherCid},
+		}))
+
+		lanes := make(map[uint64]types.AttoFIL)
+		porcelainAPI.messageHandlers["redeem"] = func(to address.Address, val types.AttoFIL, params ...interface{}) ([][]byte, error) {
+			lanes[params[5].(*types.VoucherLane).ID] = params[2].(types.AttoFIL)
+			return nil, nil
+		}
+		porcelainAPI.messageHandlers["close"] = func(to address.Address, val types.AttoFIL, params ...interface{}) ([][]byte, error) {
+			t.Fatal("the channel must not be closed while it pays more than one deal")
+			return nil, nil
+		}
+
+		redeemAt(t, miner, 900)
+		require.Len(t, lanes, 2)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(lanes[0]))
+		assert.True(t, types.NewAttoFILFromFIL(20).Equal(lanes[1]))
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(porcelainAPI.deals[proposalCid].Redeemed))
+		assert.True(t, types.NewAttoFILFromFIL(20).Equal(porcelainAPI.deals[otherCid].Redeemed))
+	})
+
+	t.Run("does not redeem vouchers of deals that are not complete", func(t *testing.T) {
+		_, miner, _, sent := redemptionTestSetup(t, storagedeal.Staged)
+
+		redeemAt(t, miner, 900)
+		assert.Empty(t, *sent)
+	})
+
+	t.Run("records missed funds when the channel expired", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+		porcelainAPI.channelEol = types.NewBlockHeight(850)
+
+		redeemAt(t, miner, 850)
+		assert.Empty(t, *sent)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(porcelainAPI.deals[proposalCid].Missed))
+	})
+
+	t.Run("records missed funds when the channel is gone", func(t *testing.T) {
+		porcelainAPI, miner, proposalCid, sent := redemptionTestSetup(t, storagedeal.Complete)
+		porcelainAPI.noChannels = true
+
+		redeemAt(t, miner, 800)
+		assert.Empty(t, *sent)
+		assert.True(t, types.NewAttoFILFromFIL(10).Equal(porcelainAPI.deals[proposalCid].Missed))
+	})
+}
@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dag "github.com/ipfs/go-merkledag"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/metrics"
+	"github.com/filecoin-project/go-filecoin/repo"
+)
+
+const sectorsDatastorePrefix = "sectors"
+
+// maxSealAttempts is the number of sectors the pieces of a failed sector are
+// tried in before their deals are failed.
+const maxSealAttempts = 3
+
+// ErrSectorNotFound is returned when the miner has no record of a sector.
+var ErrSectorNotFound = errors.New("sector not found")
+
+// SectorState is the lifecycle state of a sector managed by the storage miner.
+type SectorState string
+
+const (
+	// SectorStaged means the sector is accepting pieces and has not been sealed.
+	SectorStaged = SectorState("staged")
+	// SectorSealing means sealing of the sector has been scheduled.
+	SectorSealing = SectorState("sealing")
+	// SectorSealed means the sector has been sealed but not committed.
+	SectorSealed = SectorState("sealed")
+	// SectorCommitted means the sector's commitSector message has been sent.
+	SectorCommitted = SectorState("committed")
+	// SectorFailed means sealing the sector failed.
+	SectorFailed = SectorState("failed")
+)
+
+// SectorPiece is a deal's piece that was written into a sector.
+type SectorPiece struct {
+	DealCid  cid.Cid `json:"dealCid"`
+	PieceRef cid.Cid `json:"pieceRef"`
+	Size     uint64  `json:"size"`
+}
+
+// Sector records what the storage miner knows about one of its sectors.
+type Sector struct {
+	ID     uint64        `json:"id"`
+	State  SectorState   `json:"state"`
+	Pieces []SectorPiece `json:"pieces"`
+	// Attempt counts how many sectors the pieces in this one have been tried
+	// in, including this one.
+	Attempt uint64 `json:"attempt"`
+	// RetriedIn are the sectors the pieces were moved to after this sector
+	// failed to seal.
+	RetriedIn []uint64 `json:"retriedIn,omitempty"`
+	// CommitMessageCid is the cid of the commitSector message for the sector.
+	CommitMessageCid cid.Cid `json:"commitMessageCid"`
+	// Error describes why sealing the sector or sending its commitment failed.
+	Error string `json:"error,omitempty"`
+}
+
+// LoadSector reads the record of a single sector from the datastore.
+func LoadSector(ds repo.Datastore, sectorID uint64) (*Sector, error) {
+	value, err := ds.Get(sectorKey(sectorID))
+	if err == datastore.ErrNotFound {
+		return nil, ErrSectorNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read sector from datastore")
+	}
+
+	var sector Sector
+	if err := json.Unmarshal(value, &sector); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal sector")
+	}
+	return &sector, nil
+}
+
+// LoadSectors reads the records of all sectors from the datastore, ordered by id.
+func LoadSectors(ds repo.Datastore) ([]*Sector, error) {
+	results, err := ds.Query(query.Query{Prefix: "/" + sectorsDatastorePrefix})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query sectors from datastore")
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query sectors from datastore")
+	}
+
+	sectors := make([]*Sector, 0, len(entries))
+	for _, entry := range entries {
+		var sector Sector
+		if err := json.Unmarshal(entry.Value, &sector); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal sector")
+		}
+		sectors = append(sectors, &sector)
+	}
+
+	sort.Slice(sectors, func(i, j int) bool { return sectors[i].ID < sectors[j].ID })
+	return sectors, nil
+}
+
+func saveSector(ds repo.Datastore, sector *Sector) error {
+	value, err := json.Marshal(sector)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal sector")
+	}
+	if err := ds.Put(sectorKey(sector.ID), value); err != nil {
+		return errors.Wrap(err, "could not save sector to disk")
+	}
+	return nil
+}
+
+func sectorKey(sectorID uint64) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{sectorsDatastorePrefix, strconv.FormatUint(sectorID, 10)})
+}
+
+// dealSectors maps the cids of the deals whose pieces are in a sector that has
+// not failed to the id of that sector.
+func (sm *Miner) dealSectors() (map[string]uint64, error) {
+	sectors, err := LoadSectors(sm.dealsAwaitingSealDs)
+	if err != nil {
+		return nil, err
+	}
+
+	dealSectors := make(map[string]uint64)
+	for _, sector := range sectors {
+		if sector.State == SectorFailed {
+			continue
+		}
+		for _, piece := range sector.Pieces {
+			dealSectors[piece.DealCid.KeyString()] = sector.ID
+		}
+	}
+	return dealSectors, nil
+}
+
+// stagePiece writes a deal's piece into a staged sector and records it.
+// attempt is how many sectors the piece has been tried in, including this one.
+func (sm *Miner) stagePiece(ctx context.Context, piece SectorPiece, attempt uint64) (uint64, error) {
+	dagService := dag.NewDAGService(sm.node.BlockService())
+
+	rootIpldNode, err := dagService.Get(ctx, piece.PieceRef)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := uio.NewDagReader(ctx, rootIpldNode, dagService)
+	if err != nil {
+		return 0, err
+	}
+
+	sectorID, err := sm.node.SectorBuilder().AddPiece(ctx, piece.PieceRef, piece.Size, r)
+	if err != nil {
+		return 0, err
+	}
+
+	sm.updateSector(sectorID, func(s *Sector) {
+		s.Pieces = append(s.Pieces, piece)
+		if attempt > s.Attempt {
+			s.Attempt = attempt
+		}
+	})
+
+	return sectorID, nil
+}
+
+// updateSector applies f to the record of the sector and persists it,
+// creating a record for a newly staged sector if there is none.
+func (sm *Miner) updateSector(sectorID uint64, f func(*Sector)) {
+	sm.sectorsLk.Lock()
+	defer sm.sectorsLk.Unlock()
+
+	sector, err := LoadSector(sm.dealsAwaitingSealDs, sectorID)
+	if err == ErrSectorNotFound {
+		sector = &Sector{ID: sectorID, State: SectorStaged, Attempt: 1}
+	} else if err != nil {
+		log.Errorf("could not load sector %d: %s", sectorID, err)
+		return
+	}
+
+	f(sector)
+
+	if err := saveSector(sm.dealsAwaitingSealDs, sector); err != nil {
+		log.Errorf("could not save sector %d: %s", sectorID, err)
+	}
+}
+
+// startSealTimer starts timing the sealing of a sector. Sectors the sector
+// builder seals on its own once they are full are not timed. sm.sectorsLk
+// must be held.
+func (sm *Miner) startSealTimer(ctx context.Context, sectorID uint64) {
+	if sm.sealStopwatches == nil {
+		sm.sealStopwatches = make(map[uint64]*metrics.Stopwatch)
+	}
+	sm.sealStopwatches[sectorID] = sealTimer.Start(ctx)
+}
+
+// stopSealTimer records the sealing duration of a sector, if it was timed.
+// sm.sectorsLk must be held.
+func (sm *Miner) stopSealTimer(sectorID uint64) {
+	if sw, ok := sm.sealStopwatches[sectorID]; ok {
+		sw.Stop(context.Background())
+		delete(sm.sealStopwatches, sectorID)
+	}
+}
+
+// SealStagedSectors schedules sealing of all staged sectors.
+func (sm *Miner) SealStagedSectors(ctx context.Context) error {
+	sm.sectorsLk.Lock()
+	defer sm.sectorsLk.Unlock()
+
+	return sm.sealStagedSectors(ctx)
+}
+
+// sealStagedSectors schedules sealing of all staged sectors and marks them as
+// sealing. sm.sectorsLk must be held, so that a sector sealed in the meantime
+// is not marked as sealing again.
+func (sm *Miner) sealStagedSectors(ctx context.Context) error {
+	if err := sm.node.SectorBuilder().SealAllStagedSectors(ctx); err != nil {
+		return err
+	}
+
+	sectors, err := LoadSectors(sm.dealsAwaitingSealDs)
+	if err != nil {
+		return err
+	}
+	for _, sector := range sectors {
+		if sector.State != SectorStaged {
+			continue
+		}
+		sector.State = SectorSealing
+		sm.startSealTimer(ctx, sector.ID)
+		if err := saveSector(sm.dealsAwaitingSealDs, sector); err != nil {
+			log.Errorf("could not save sector %d: %s", sector.ID, err)
+		}
+	}
+	return nil
+}
+
+// SealNow schedules sealing of a staged sector without waiting for auto-seal.
+// The sector builder can only seal all staged sectors at once, so any other
+// staged sectors are sealed as well.
+func (sm *Miner) SealNow(ctx context.Context, sectorID uint64) (*Sector, error) {
+	sm.sectorsLk.Lock()
+	defer sm.sectorsLk.Unlock()
+
+	sector, err := LoadSector(sm.dealsAwaitingSealDs, sectorID)
+	if err != nil {
+		return nil, err
+	}
+	if sector.State != SectorStaged {
+		return nil, fmt.Errorf("sector %d is %s, only staged sectors can be sealed", sectorID, sector.State)
+	}
+
+	if err := sm.sealStagedSectors(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to seal staged sectors")
+	}
+
+	return LoadSector(sm.dealsAwaitingSealDs, sectorID)
+}
+
+// OnSectorSealed is called when the sector builder has sealed a sector.
+func (sm *Miner) OnSectorSealed(sectorID uint64) {
+	sm.updateSector(sectorID, func(s *Sector) {
+		s.State = SectorSealed
+		sm.stopSealTimer(s.ID)
+	})
+}
+
+// OnSealFailed is called when the sector builder failed to seal a sector.
+func (sm *Miner) OnSealFailed(sectorID uint64, err error) {
+	sm.onSealFailed(context.Background(), sectorID, err)
+}
+
+// onSealFailed retries the pieces of a sector that failed to seal in a new
+// sector. Once a piece has failed maxSealAttempts times its deal is failed.
+func (sm *Miner) onSealFailed(ctx context.Context, sectorID uint64, sealErr error) {
+	sector, err := LoadSector(sm.dealsAwaitingSealDs, sectorID)
+	if err != nil && err != ErrSectorNotFound {
+		log.Errorf("could not load failed sector %d: %s", sectorID, err)
+	}
+
+	var retriedIn []uint64
+	if sector != nil && sector.Attempt < maxSealAttempts {
+		for _, piece := range sector.Pieces {
+			newSectorID, err := sm.stagePiece(ctx, piece, sector.Attempt+1)
+			if err != nil {
+				log.Errorf("could not retry piece %s of failed sector %d: %s", piece.PieceRef, sectorID, err)
+				break
+			}
+			log.Infof("retrying piece %s of failed sector %d in sector %d", piece.PieceRef, sectorID, newSectorID)
+
+			sm.dealsAwaitingSeal.detachDealFromSector(sectorID, piece.DealCid)
+			sm.dealsAwaitingSeal.attachDealToSector(ctx, newSectorID, piece.DealCid)
+			if len(retriedIn) == 0 || retriedIn[len(retriedIn)-1] != newSectorID {
+				retriedIn = append(retriedIn, newSectorID)
+			}
+		}
+	}
+
+	sm.updateSector(sectorID, func(s *Sector) {
+		s.State = SectorFailed
+		sm.stopSealTimer(s.ID)
+		s.Error = sealErr.Error()
+		s.RetriedIn = retriedIn
+	})
+
+	// fail the deals that could not be moved to another sector
+	errMsg := fmt.Sprintf("failed sealing sector: %d", sectorID)
+	sm.dealsAwaitingSeal.onSealFail(ctx, sectorID, errMsg)
+	if err := sm.saveDealsAwaitingSeal(); err != nil {
+		log.Errorf("failed persisting deals awaiting seal: %s", err)
+	}
+}
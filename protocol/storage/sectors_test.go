@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	bserv "github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipfs/go-ipfs-exchange-offline"
+	dag "github.com/ipfs/go-merkledag"
+	"github.com/libp2p/go-libp2p-host"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/proofs/sectorbuilder"
+	"github.com/filecoin-project/go-filecoin/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/repo"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestSectorLifecycle(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("tracks a sector from staging to commitment", func(t *testing.T) {
+		_, miner, nd, piece := sectorTestSetup(t)
+
+		sectorID, err := miner.stagePiece(ctx, piece, 1)
+		require.NoError(t, err)
+
+		sector, err := LoadSector(miner.dealsAwaitingSealDs, sectorID)
+		require.NoError(t, err)
+		assert.Equal(t, SectorStaged, sector.State)
+		assert.Equal(t, []SectorPiece{piece}, sector.Pieces)
+		assert.Equal(t, uint64(1), sector.Attempt)
+
+		sector, err = miner.SealNow(ctx, sectorID)
+		require.NoError(t, err)
+		assert.Equal(t, SectorSealing, sector.State)
+		assert.Equal(t, 1, nd.sb.sealCalls)
+
+		_, err = miner.SealNow(ctx, sectorID)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only staged sectors can be sealed")
+
+		miner.OnSectorSealed(sectorID)
+		sector, err = LoadSector(miner.dealsAwaitingSealDs, sectorID)
+		require.NoError(t, err)
+		assert.Equal(t, SectorSealed, sector.State)
+
+		msgCid := types.NewCidForTestGetter()()
+		miner.OnCommitmentSent(&sectorbuilder.SealedSectorMetadata{SectorID: sectorID}, msgCid, nil)
+		sector, err = LoadSector(miner.dealsAwaitingSealDs, sectorID)
+		require.NoError(t, err)
+		assert.Equal(t, SectorCommitted, sector.State)
+		assert.Equal(t, msgCid, sector.CommitMessageCid)
+	})
+
+	t.Run("lists sectors in order", func(t *testing.T) {
+		_, miner, _, piece := sectorTestSetup(t)
+
+		for i := 0; i < 3; i++ {
+			_, err := miner.stagePiece(ctx, piece, 1)
+			require.NoError(t, err)
+		}
+
+		sectors, err := LoadSectors(miner.dealsAwaitingSealDs)
+		require.NoError(t, err)
+		require.Len(t, sectors, 3)
+		for i, sector := range sectors {
+			assert.Equal(t, uint64(i+1), sector.ID)
+		}
+
+		_, err = LoadSector(miner.dealsAwaitingSealDs, 42)
+		assert.Equal(t, ErrSectorNotFound, err)
+		_, err = miner.SealNow(ctx, 42)
+		assert.Equal(t, ErrSectorNotFound, err)
+	})
+
+	t.Run("sends the commitment again when sending it failed", func(t *testing.T) {
+		porcelainAPI, miner, _, piece := sectorTestSetup(t)
+
+		defer func(interval time.Duration) { commitRetryInterval = interval }(commitRetryInterval)
+		commitRetryInterval = time.Millisecond
+
+		sectorID, err := miner.stagePiece(ctx, piece, 1)
+		require.NoError(t, err)
+		miner.dealsAwaitingSeal.attachDealToSector(ctx, sectorID, piece.DealCid)
+		miner.OnSectorSealed(sectorID)
+
+		// the commitment is sent again once the failure has been checked
+		release := make(chan struct{})
+		sent := make(chan uint64, 1)
+		porcelainAPI.messageHandlers["commitSector"] = func(a address.Address, v types.AttoFIL, p ...interface{}) ([][]byte, error) {
+			<-release
+			sent <- p[0].(uint64)
+			return nil, nil
+		}
+
+		sealed := &sectorbuilder.SealedSectorMetadata{SectorID: sectorID}
+		miner.OnCommitmentSent(sealed, cid.Undef, errors.New("nonce collision"))
+
+		// the sector and its deal are kept
+		sector, err := LoadSector(miner.dealsAwaitingSealDs, sectorID)
+		require.NoError(t, err)
+		assert.Equal(t, SectorSealed, sector.State)
+		assert.Equal(t, "nonce collision", sector.Error)
+		assert.Empty(t, sector.RetriedIn)
+		assert.Equal(t, storagedeal.Staged, porcelainAPI.deals[piece.DealCid].Response.State)
+		close(release)
+
+		select {
+		case id := <-sent:
+			assert.Equal(t, sectorID, id)
+		case <-time.After(5 * time.Second):
+			t.Fatal("commitment was not sent again")
+		}
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			sector, err = LoadSector(miner.dealsAwaitingSealDs, sectorID)
+			require.NoError(t, err)
+			if sector.State == SectorCommitted || time.Now().After(deadline) {
+				break
+			}
+		}
+		assert.Equal(t, SectorCommitted, sector.State)
+		assert.Empty(t, sector.Error)
+	})
+
+	t.Run("retries the pieces of a failed sector", func(t *testing.T) {
+		porcelainAPI, miner, _, piece := sectorTestSetup(t)
+
+		sectorID, err := miner.stagePiece(ctx, piece, 1)
+		require.NoError(t, err)
+		miner.dealsAwaitingSeal.attachDealToSector(ctx, sectorID, piece.DealCid)
+
+		for attempt := uint64(1); attempt < maxSealAttempts; attempt++ {
+			miner.OnSealFailed(sectorID, errors.New("seal failed"))
+
+			failed, err := LoadSector(miner.dealsAwaitingSealDs, sectorID)
+			require.NoError(t, err)
+			assert.Equal(t, SectorFailed, failed.State)
+			assert.Equal(t, "seal failed", failed.Error)
+			require.Len(t, failed.RetriedIn, 1)
+
+			retry, err := LoadSector(miner.dealsAwaitingSealDs, failed.RetriedIn[0])
+			require.NoError(t, err)
+			assert.Equal(t, SectorStaged, retry.State)
+			assert.Equal(t, attempt+1, retry.Attempt)
+
+			deal := porcelainAPI.deals[piece.DealCid]
+			assert.Equal(t, storagedeal.Staged, deal.Response.State)
+			assert.Nil(t, deal.Response.ProofInfo)
+			assert.Equal(t, []cid.Cid{piece.DealCid}, miner.dealsAwaitingSeal.SectorsToDeals[retry.ID])
+
+			sectorID = retry.ID
+		}
+
+		// the last attempt fails the deal
+		miner.OnSealFailed(sectorID, errors.New("seal failed"))
+		failed, err := LoadSector(miner.dealsAwaitingSealDs, sectorID)
+		require.NoError(t, err)
+		assert.Empty(t, failed.RetriedIn)
+		assert.Equal(t, storagedeal.Failed, porcelainAPI.deals[piece.DealCid].Response.State)
+	})
+}
+
+func sectorTestSetup(t *testing.T) (*minerTestPorcelain, *Miner, *sectorTestNode, SectorPiece) {
+	bs := bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+	nd := &sectorTestNode{
+		bs: bserv.New(bs, offline.Exchange(bs)),
+		sb: &sectorTestSectorBuilder{},
+	}
+
+	data := dag.NewRawNode([]byte("sector test piece"))
+	require.NoError(t, nd.bs.AddBlock(data))
+
+	porcelainAPI := newMinerTestPorcelain(t)
+	miner := newTestMiner(porcelainAPI)
+	miner.node = nd
+	miner.dealsAwaitingSealDs = repo.NewInMemoryRepo().DealsDatastore()
+	require.NoError(t, miner.loadDealsAwaitingSeal())
+	miner.dealsAwaitingSeal.onSuccess = miner.onCommitSuccess
+	miner.dealsAwaitingSeal.onFail = miner.onCommitFail
+
+	dealCid := types.NewCidForTestGetter()()
+	require.NoError(t, porcelainAPI.DealPut(&storagedeal.Deal{
+		Proposal: &storagedeal.Proposal{PieceRef: data.Cid(), Size: types.NewBytesAmount(uint64(len(data.RawData())))},
+		Response: &storagedeal.Response{State: storagedeal.Staged, ProposalCid: dealCid},
+	}))
+
+	piece := SectorPiece{DealCid: dealCid, PieceRef: data.Cid(), Size: uint64(len(data.RawData()))}
+	return porcelainAPI, miner, nd, piece
+}
+
+type sectorTestNode struct {
+	bs bserv.BlockService
+	sb *sectorTestSectorBuilder
+}
+
+func (tn *sectorTestNode) BlockService() bserv.BlockService           { return tn.bs }
+func (tn *sectorTestNode) Host() host.Host                            { return nil }
+func (tn *sectorTestNode) SectorBuilder() sectorbuilder.SectorBuilder { return tn.sb }
+
+// sectorTestSectorBuilder puts every piece into a new sector.
+type sectorTestSectorBuilder struct {
+	testSectorBuilder
+	lastSectorID uint64
+	sealCalls    int
+}
+
+func (sb *sectorTestSectorBuilder) AddPiece(ctx context.Context, pieceRef cid.Cid, pieceSize uint64, pieceReader io.Reader) (uint64, error) {
+	if _, err := io.Copy(ioutil.Discard, pieceReader); err != nil {
+		return 0, err
+	}
+	sb.lastSectorID++
+	return sb.lastSectorID, nil
+}
+
+func (sb *sectorTestSectorBuilder) SealAllStagedSectors(ctx context.Context) error {
+	sb.sealCalls++
+	return nil
+}
@@ -16,6 +16,7 @@ func init() {
 	cbor.RegisterCborType(ProofInfo{})
 	cbor.RegisterCborType(QueryRequest{})
 	cbor.RegisterCborType(Deal{})
+	cbor.RegisterCborType(Redemption{})
 }
 
 // PaymentInfo contains all the payment related information for a storage deal.
@@ -32,8 +33,14 @@ type PaymentInfo struct {
 	Channel *types.ChannelID
 
 	// ChannelMsgCid is the B58 encoded CID of the message used to create the channel (so the miner can wait for it).
+	// When an existing channel is reused it is the message that added funds to
+	// the channel, or nil if the channel already had sufficient funds.
 	ChannelMsgCid *cid.Cid
 
+	// Lane is the lane of the payment channel the vouchers of this deal are
+	// drawn on. Deals sharing a channel each use their own lane.
+	Lane uint64
+
 	// Vouchers is a set of payments from the client to the miner that can be
 	// cashed out contingent on the agreed upon data being provably within a
 	// live sector in the miners control on-chain
@@ -121,6 +128,25 @@ type Deal struct {
 	Miner    address.Address
 	Proposal *Proposal
 	Response *Response
+
+	// Redeemed is the amount the miner has redeemed from the deal's vouchers.
+	Redeemed types.AttoFIL
+	// Missed is the amount of the deal's vouchers that can no longer be
+	// redeemed because the payment channel expired or was closed first.
+	Missed types.AttoFIL
+	// Redemption is the redeem or close message sent for the deal's vouchers
+	// that has not been mined yet, if any.
+	Redemption *Redemption
+}
+
+// Redemption is a message redeeming a deal's vouchers.
+type Redemption struct {
+	// MsgCid is the cid of the redeem or close message.
+	MsgCid cid.Cid
+	// Amount is the amount the message redeems on the deal's lane.
+	Amount types.AttoFIL
+	// Close is true if the message closes the payment channel.
+	Close bool
 }
 
 // ProofInfo contains the details about a seal proof, that the client needs to know to verify that his deal was posted on chain.
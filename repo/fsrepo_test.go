@@ -53,7 +53,27 @@ const (
 	"mining": {
 		"minerAddress": "empty",
 		"autoSealIntervalSeconds": 120,
-		"storagePrice": "0"
+		"storagePrice": "0",
+		"dealPolicy": {
+			"allowedClients": [],
+			"deniedClients": [],
+			"minPieceSize": 0,
+			"maxPieceSize": 0,
+			"minDuration": 0,
+			"maxDuration": 0,
+			"maxClientStorage": 0,
+			"filterCommand": ""
+		},
+		"gasPrice": {
+			"default": "0.000000000000000001",
+			"sampleTipSets": 10,
+			"percentile": 75,
+			"maxPrices": {
+				"commitSector": "0.0000000000000001",
+				"redeem": "0.0000000000000001",
+				"submitPoSt": "0.000000000000001"
+			}
+		}
 	},
 	"mpool": {
 		"maxPoolSize": 10000,
@@ -76,7 +96,11 @@ const (
 		"rootdir": ""
 	},
 	"swarm": {
-		"address": "/ip4/0.0.0.0/tcp/6000"
+		"address": "/ip4/0.0.0.0/tcp/6000",
+		"connMgrLowWater": 150,
+		"connMgrHighWater": 200,
+		"connMgrGracePeriod": "20s",
+		"disableNatPortMap": false
 	},
 	"wallet": {
 		"defaultAddress": "empty"
@@ -0,0 +1,196 @@
+package state
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// ChangeKind describes how an actor differs between two state trees.
+type ChangeKind string
+
+const (
+	// ActorAdded is the kind of change for an actor present only in the second tree.
+	ActorAdded = ChangeKind("added")
+	// ActorRemoved is the kind of change for an actor present only in the first tree.
+	ActorRemoved = ChangeKind("removed")
+	// ActorChanged is the kind of change for an actor present in both trees
+	// with different code, head, nonce or balance.
+	ActorChanged = ChangeKind("changed")
+)
+
+// ActorChange describes the difference between the state of an actor in two
+// state trees. Before is nil for added actors and After is nil for removed ones.
+type ActorChange struct {
+	Address address.Address `json:"address"`
+	Kind    ChangeKind      `json:"kind"`
+	Before  *actor.Actor    `json:"before"`
+	After   *actor.Actor    `json:"after"`
+}
+
+// CodeChanged is true when the actor's code differs.
+func (c ActorChange) CodeChanged() bool {
+	return c.Kind != ActorChanged || !c.Before.Code.Equals(c.After.Code)
+}
+
+// HeadChanged is true when the actor's storage head differs.
+func (c ActorChange) HeadChanged() bool {
+	return c.Kind != ActorChanged || !c.Before.Head.Equals(c.After.Head)
+}
+
+// NonceChanged is true when the actor's nonce differs.
+func (c ActorChange) NonceChanged() bool {
+	return c.Kind != ActorChanged || c.Before.Nonce != c.After.Nonce
+}
+
+// BalanceChanged is true when the actor's balance differs.
+func (c ActorChange) BalanceChanged() bool {
+	return c.Kind != ActorChanged || !c.Before.Balance.Equal(c.After.Balance)
+}
+
+// Diff returns the actors that were added, removed or changed going from the
+// state tree rooted at rootA to the one rooted at rootB, sorted by address.
+// Both HAMTs are walked together and subtrees that are shared by the two
+// trees are skipped, so the cost is proportional to the size of the change
+// rather than to the number of actors.
+func Diff(ctx context.Context, store *hamt.CborIpldStore, rootA, rootB cid.Cid) ([]ActorChange, error) {
+	if rootA.Equals(rootB) {
+		return nil, nil
+	}
+
+	a, err := hamt.LoadNode(ctx, store, rootA)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", rootA)
+	}
+	b, err := hamt.LoadNode(ctx, store, rootB)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state %s", rootB)
+	}
+
+	var changes []ActorChange
+	if err := diffNodes(ctx, store, a, b, &changes); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Address.String() < changes[j].Address.String()
+	})
+	return changes, nil
+}
+
+// diffNodes compares two HAMT nodes at the same depth. Pointers are stored
+// compactly, so the bitfield is used to line up the pointers of both nodes
+// that cover the same slot.
+func diffNodes(ctx context.Context, store *hamt.CborIpldStore, a, b *hamt.Node, changes *[]ActorChange) error {
+	width := a.Bitfield.BitLen()
+	if b.Bitfield.BitLen() > width {
+		width = b.Bitfield.BitLen()
+	}
+
+	ai, bi := 0, 0
+	for slot := 0; slot < width; slot++ {
+		var pa, pb *hamt.Pointer
+		if a.Bitfield.Bit(slot) == 1 {
+			pa = a.Pointers[ai]
+			ai++
+		}
+		if b.Bitfield.Bit(slot) == 1 {
+			pb = b.Pointers[bi]
+			bi++
+		}
+		if err := diffPointers(ctx, store, pa, pb, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func diffPointers(ctx context.Context, store *hamt.CborIpldStore, a, b *hamt.Pointer, changes *[]ActorChange) error {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	// Identical subtrees contain identical actors.
+	if a != nil && b != nil && a.Link.Defined() && b.Link.Defined() {
+		if a.Link.Equals(b.Link) {
+			return nil
+		}
+		na, err := hamt.LoadNode(ctx, store, a.Link)
+		if err != nil {
+			return err
+		}
+		nb, err := hamt.LoadNode(ctx, store, b.Link)
+		if err != nil {
+			return err
+		}
+		return diffNodes(ctx, store, na, nb, changes)
+	}
+
+	// Otherwise at least one side holds its actors inline, so the slot is
+	// small enough to compare actor by actor.
+	before := map[address.Address]*actor.Actor{}
+	if err := collectActors(ctx, store, a, before); err != nil {
+		return err
+	}
+	after := map[address.Address]*actor.Actor{}
+	if err := collectActors(ctx, store, b, after); err != nil {
+		return err
+	}
+
+	for addr, act := range after {
+		prev, ok := before[addr]
+		switch {
+		case !ok:
+			*changes = append(*changes, ActorChange{Address: addr, Kind: ActorAdded, After: act})
+		case !actorsEqual(prev, act):
+			*changes = append(*changes, ActorChange{Address: addr, Kind: ActorChanged, Before: prev, After: act})
+		}
+	}
+	for addr, act := range before {
+		if _, ok := after[addr]; !ok {
+			*changes = append(*changes, ActorChange{Address: addr, Kind: ActorRemoved, Before: act})
+		}
+	}
+	return nil
+}
+
+// collectActors adds every actor under p to out.
+func collectActors(ctx context.Context, store *hamt.CborIpldStore, p *hamt.Pointer, out map[address.Address]*actor.Actor) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.Link.Defined() {
+		n, err := hamt.LoadNode(ctx, store, p.Link)
+		if err != nil {
+			return err
+		}
+		return forEachActor(ctx, store, n, func(addr address.Address, act *actor.Actor) error {
+			out[addr] = act
+			return nil
+		})
+	}
+
+	for _, kv := range p.KVs {
+		var act actor.Actor
+		if err := hackTransferObject(kv.Value, &act); err != nil {
+			return err
+		}
+		addr, err := address.NewFromString(kv.Key)
+		if err != nil {
+			return err
+		}
+		out[addr] = &act
+	}
+	return nil
+}
+
+func actorsEqual(a, b *actor.Actor) bool {
+	return a.Code.Equals(b.Code) && a.Head.Equals(b.Head) && a.Nonce == b.Nonce && a.Balance.Equal(b.Balance)
+}
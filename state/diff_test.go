@@ -0,0 +1,100 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/actor"
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+func TestDiff(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	t.Run("identical roots have no changes", func(t *testing.T) {
+		cst := hamt.NewCborStore()
+		tree := NewEmptyStateTree(cst)
+		addr := address.NewForTestGetter()()
+		root := MustSetActor(tree, addr, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1)))
+
+		changes, err := Diff(ctx, cst, root, root)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("reports added, removed and changed actors", func(t *testing.T) {
+		cst := hamt.NewCborStore()
+		addrGetter := address.NewForTestGetter()
+		kept, changed, removed, added := addrGetter(), addrGetter(), addrGetter(), addrGetter()
+
+		treeA := NewEmptyStateTree(cst)
+		MustSetActor(treeA, kept, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1)))
+		MustSetActor(treeA, changed, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2)))
+		rootA := MustSetActor(treeA, removed, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(3)))
+
+		treeB, err := LoadStateTree(ctx, cst, rootA, nil)
+		require.NoError(t, err)
+		changedAfter := actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(5))
+		changedAfter.IncNonce()
+		MustSetActor(treeB, changed, changedAfter)
+		MustSetActor(treeB, added, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(4)))
+		require.NoError(t, treeB.(*tree).root.Delete(ctx, removed.String()))
+		rootB := MustFlush(treeB)
+
+		changes, err := Diff(ctx, cst, rootA, rootB)
+		require.NoError(t, err)
+		require.Len(t, changes, 3)
+
+		byAddr := map[address.Address]ActorChange{}
+		for _, c := range changes {
+			byAddr[c.Address] = c
+		}
+
+		assert.Equal(t, ActorAdded, byAddr[added].Kind)
+		assert.Nil(t, byAddr[added].Before)
+		assert.True(t, types.NewAttoFILFromFIL(4).Equal(byAddr[added].After.Balance))
+
+		assert.Equal(t, ActorRemoved, byAddr[removed].Kind)
+		assert.Nil(t, byAddr[removed].After)
+
+		c := byAddr[changed]
+		assert.Equal(t, ActorChanged, c.Kind)
+		assert.True(t, c.BalanceChanged())
+		assert.True(t, c.NonceChanged())
+		assert.False(t, c.HeadChanged())
+		assert.False(t, c.CodeChanged())
+	})
+
+	t.Run("walks deep trees", func(t *testing.T) {
+		cst := hamt.NewCborStore()
+		addrGetter := address.NewForTestGetter()
+
+		treeA := NewEmptyStateTree(cst)
+		var addrs []address.Address
+		for i := 0; i < 500; i++ {
+			addr := addrGetter()
+			addrs = append(addrs, addr)
+			require.NoError(t, treeA.SetActor(ctx, addr, actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))))
+		}
+		rootA := MustFlush(treeA)
+
+		treeB, err := LoadStateTree(ctx, cst, rootA, nil)
+		require.NoError(t, err)
+		MustSetActor(treeB, addrs[42], actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(2)))
+		rootB := MustFlush(treeB)
+
+		changes, err := Diff(ctx, cst, rootA, rootB)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, addrs[42], changes[0].Address)
+		assert.Equal(t, ActorChanged, changes[0].Kind)
+	})
+}
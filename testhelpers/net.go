@@ -37,9 +37,9 @@ func NewFakeHost() host.Host {
 
 // minimal implementation of host.Host interface
 
-func (fh *FakeHost) Addrs() []ma.Multiaddr              { panic("not implemented") } // nolint: golint
-func (fh *FakeHost) Close() error                       { panic("not implemented") } // nolint: golint
-func (fh *FakeHost) ConnManager() ifconnmgr.ConnManager { panic("not implemented") } // nolint: golint
+func (fh *FakeHost) Addrs() []ma.Multiaddr              { panic("not implemented") }        // nolint: golint
+func (fh *FakeHost) Close() error                       { panic("not implemented") }        // nolint: golint
+func (fh *FakeHost) ConnManager() ifconnmgr.ConnManager { return &ifconnmgr.NullConnMgr{} } // nolint: golint
 func (fh *FakeHost) Connect(ctx context.Context, pi pstore.PeerInfo) error { // nolint: golint
 	return fh.ConnectImpl(ctx, pi)
 }
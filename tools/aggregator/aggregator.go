@@ -0,0 +1,150 @@
+// Package aggregator is a reference implementation of a heartbeat
+// aggregator. It accepts heartbeat streams from filecoin nodes and keeps the
+// latest heartbeat each node sent, so that dashboards and alerting can be
+// built on top of it.
+package aggregator
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-host"
+	"github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/chain"
+	"github.com/filecoin-project/go-filecoin/metrics"
+)
+
+var log = logging.Logger("aggregator")
+
+// NodeState is the latest heartbeat received from a node.
+type NodeState struct {
+	Peer      peer.ID
+	Heartbeat metrics.Heartbeat
+	// Received is the time the heartbeat was received
+	Received time.Time
+}
+
+// Summary describes the network as seen by the aggregator.
+type Summary struct {
+	// Nodes is the number of nodes that sent a heartbeat
+	Nodes int
+	// MaxHeight is the greatest height reported by any node
+	MaxHeight uint64
+	// Heads maps each reported head to the number of nodes on it
+	Heads map[string]int
+	// Miners is the number of nodes that are mining
+	Miners int
+	// Syncing is the number of nodes that are still syncing the chain
+	Syncing int
+}
+
+// Aggregator collects heartbeats sent to a libp2p host.
+type Aggregator struct {
+	host host.Host
+
+	lk    sync.Mutex
+	nodes map[peer.ID]NodeState
+}
+
+// New creates an aggregator that accepts heartbeats sent to h.
+func New(h host.Host) *Aggregator {
+	a := &Aggregator{
+		host:  h,
+		nodes: make(map[peer.ID]NodeState),
+	}
+	h.SetStreamHandler(metrics.HeartbeatProtocol, a.handleStream)
+	return a
+}
+
+// Close stops accepting heartbeats.
+func (a *Aggregator) Close() {
+	a.host.RemoveStreamHandler(metrics.HeartbeatProtocol)
+}
+
+func (a *Aggregator) handleStream(s net.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	from := s.Conn().RemotePeer()
+	r := cbu.NewMsgReader(s)
+	for {
+		var hb metrics.Heartbeat
+		if err := r.ReadMsg(&hb); err != nil {
+			if err != io.EOF {
+				log.Debugf("failed to read heartbeat from %s: %s", from, err)
+			}
+			return
+		}
+		a.record(from, hb)
+	}
+}
+
+func (a *Aggregator) record(from peer.ID, hb metrics.Heartbeat) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	a.nodes[from] = NodeState{
+		Peer:      from,
+		Heartbeat: hb,
+		Received:  time.Now(),
+	}
+}
+
+// Node returns the latest heartbeat received from p.
+func (a *Aggregator) Node(p peer.ID) (NodeState, bool) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	ns, ok := a.nodes[p]
+	return ns, ok
+}
+
+// Nodes returns the latest heartbeat of every node, ordered by peer id.
+func (a *Aggregator) Nodes() []NodeState {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	out := make([]NodeState, 0, len(a.nodes))
+	for _, ns := range a.nodes {
+		out = append(out, ns)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Peer < out[j].Peer })
+	return out
+}
+
+// Prune forgets nodes that have not sent a heartbeat since before.
+func (a *Aggregator) Prune(before time.Time) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	for p, ns := range a.nodes {
+		if ns.Received.Before(before) {
+			delete(a.nodes, p)
+		}
+	}
+}
+
+// Summary summarizes the latest heartbeats of all nodes.
+func (a *Aggregator) Summary() Summary {
+	nodes := a.Nodes()
+	s := Summary{
+		Nodes: len(nodes),
+		Heads: make(map[string]int),
+	}
+	for _, ns := range nodes {
+		hb := ns.Heartbeat
+		if hb.Height > s.MaxHeight {
+			s.MaxHeight = hb.Height
+		}
+		s.Heads[hb.Head]++
+		if hb.Mining {
+			s.Miners++
+		}
+		if hb.SyncMode == chain.Syncing.String() {
+			s.Syncing++
+		}
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cbu "github.com/filecoin-project/go-filecoin/cborutil"
+	"github.com/filecoin-project/go-filecoin/metrics"
+	th "github.com/filecoin-project/go-filecoin/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestAggregatorCollectsHeartbeats(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.FullMeshConnected(ctx, 3)
+	require.NoError(t, err)
+
+	agg := New(mn.Hosts()[0])
+	defer agg.Close()
+
+	send := func(from int, hbs ...metrics.Heartbeat) {
+		s, err := mn.Hosts()[from].NewStream(ctx, mn.Hosts()[0].ID(), metrics.HeartbeatProtocol)
+		require.NoError(t, err)
+		defer s.Close() // nolint: errcheck
+		w := cbu.NewMsgWriter(s)
+		for _, hb := range hbs {
+			require.NoError(t, w.WriteMsg(hb))
+		}
+	}
+
+	send(1,
+		metrics.Heartbeat{Version: metrics.HeartbeatVersion, Head: "a", Height: 1, SyncMode: "syncing"},
+		metrics.Heartbeat{Version: metrics.HeartbeatVersion, Head: "b", Height: 2, SyncMode: "caught up", Mining: true},
+	)
+	send(2, metrics.Heartbeat{Version: metrics.HeartbeatVersion, Head: "c", Height: 1, SyncMode: "syncing"})
+
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		ns, ok := agg.Node(mn.Hosts()[1].ID())
+		return ok && ns.Heartbeat.Height == 2 && len(agg.Nodes()) == 2, nil
+	}))
+
+	// only the latest heartbeat of each node is kept
+	ns, _ := agg.Node(mn.Hosts()[1].ID())
+	assert.Equal(t, "b", ns.Heartbeat.Head)
+
+	assert.Equal(t, Summary{
+		Nodes:     2,
+		MaxHeight: 2,
+		Heads:     map[string]int{"b": 1, "c": 1},
+		Miners:    1,
+		Syncing:   1,
+	}, agg.Summary())
+
+	agg.Prune(time.Now().Add(time.Minute))
+	assert.Empty(t, agg.Nodes())
+}
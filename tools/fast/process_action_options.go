@@ -3,6 +3,7 @@ package fast
 import (
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/libp2p/go-libp2p-peer"
 
@@ -115,3 +116,19 @@ func AOValidAt(bh *types.BlockHeight) ActionOption {
 		return []string{"--validat", sBH}
 	}
 }
+
+// AOLane provides the `--lane=<id>`, `--nonce=<nonce>` and
+// `--merge=<lane:nonce,...>` options to actions
+func AOLane(lane *types.VoucherLane) ActionOption {
+	merges := make([]string, len(lane.Merges))
+	for i, m := range lane.Merges {
+		merges[i] = fmt.Sprintf("%d:%d", m.Lane, m.Nonce)
+	}
+	return func() []string {
+		out := []string{"--lane", fmt.Sprintf("%d", lane.ID), "--nonce", fmt.Sprintf("%d", lane.Nonce)}
+		if len(merges) > 0 {
+			out = append(out, "--merge", strings.Join(merges, ","))
+		}
+		return out
+	}
+}
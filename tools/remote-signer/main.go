@@ -0,0 +1,150 @@
+// remote-signer is a reference implementation of the remote signer protocol
+// used by the wallet's remote backend. It holds keys in memory, either read
+// from a file written by `go-filecoin wallet export --enc=json` or freshly
+// generated, and serves signing requests carrying its token over HTTP on a
+// loopback address or over a unix socket only its user can access.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	logging "github.com/ipfs/go-log"
+
+	"github.com/filecoin-project/go-filecoin/types"
+	"github.com/filecoin-project/go-filecoin/wallet"
+)
+
+var log = logging.Logger("remote-signer")
+
+func init() {
+	// Info level
+	logging.SetAllLoggers(4)
+}
+
+// keyFile is the format of `go-filecoin wallet export --enc=json`.
+type keyFile struct {
+	KeyInfo []*types.KeyInfo
+}
+
+func main() {
+	listen := flag.String("listen", "unix:///tmp/filecoin-signer.sock", "address to serve on, a loopback host:port or a unix socket path prefixed by unix://")
+	keys := flag.String("keys", "", "file of keys exported by go-filecoin wallet export --enc=json")
+	generate := flag.Int("new", 0, "number of new keys to generate")
+	tokenFile := flag.String("token-file", "", "file holding the token requests must carry (required)")
+	flag.Parse()
+
+	if err := run(*listen, *keys, *generate, *tokenFile); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err) // nolint: errcheck
+		os.Exit(1)
+	}
+}
+
+func run(listen, keys string, generate int, tokenFile string) error {
+	if tokenFile == "" {
+		return fmt.Errorf("a token file is required")
+	}
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return fmt.Errorf("token file %s is empty", tokenFile)
+	}
+
+	backend, err := wallet.NewDSBackend(dss.MutexWrap(ds.NewMapDatastore()))
+	if err != nil {
+		return err
+	}
+
+	if keys != "" {
+		content, err := ioutil.ReadFile(keys)
+		if err != nil {
+			return err
+		}
+		var kf keyFile
+		if err := json.Unmarshal(content, &kf); err != nil {
+			return fmt.Errorf("failed to parse keys file %s: %s", keys, err)
+		}
+		for _, ki := range kf.KeyInfo {
+			if err := backend.ImportKey(ki); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i < generate; i++ {
+		if _, err := backend.NewAddress(); err != nil {
+			return err
+		}
+	}
+
+	for _, addr := range backend.Addresses() {
+		fmt.Println(addr.String())
+	}
+
+	listener, err := newListener(listen)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("serving %d keys on %s", len(backend.Addresses()), listen)
+	return http.Serve(listener, wallet.NewRemoteSignerHandler(backend, token))
+}
+
+// newListener listens on a unix socket only the current user can access, or
+// on a loopback TCP address.
+func newListener(listen string) (net.Listener, error) {
+	if strings.HasPrefix(listen, "unix://") {
+		socket := strings.TrimPrefix(listen, "unix://")
+		// remove a socket left behind by an earlier run
+		_ = os.Remove(socket)
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socket, 0600); err != nil {
+			listener.Close() // nolint: errcheck
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("refusing to listen on %s, only loopback addresses and unix sockets are supported", listen)
+	}
+	return net.Listen("tcp", listen)
+}
+
+// isLoopback returns true if host only resolves to loopback addresses.
+func isLoopback(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	if host != "localhost" {
+		return false
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
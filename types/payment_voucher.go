@@ -11,6 +11,8 @@ import (
 func init() {
 	cbor.RegisterCborType(Predicate{})
 	cbor.RegisterCborType(PaymentVoucher{})
+	cbor.RegisterCborType(VoucherLane{})
+	cbor.RegisterCborType(Merge{})
 }
 
 // Predicate is an optional message that is sent to another actor and must return true for the voucher to be valid.
@@ -26,6 +28,41 @@ type Predicate struct {
 	Params []interface{} `json:"params"`
 }
 
+// VoucherLane places a voucher in one of the independent lanes of its payment
+// channel. Voucher amounts are cumulative within a lane, and each lane tracks
+// its own redeemed amount, so one channel can pay a target for several
+// independent purposes. The zero value is lane 0, which is what vouchers
+// without lanes use.
+type VoucherLane struct {
+	// ID identifies the lane within the channel.
+	ID uint64 `json:"id"`
+
+	// Nonce orders the vouchers of the lane. A voucher can not be redeemed
+	// once a voucher with a higher nonce has been redeemed on the lane.
+	Nonce uint64 `json:"nonce"`
+
+	// Merges are other lanes of the channel settled by the voucher. Its amount
+	// covers the amounts already redeemed on those lanes, which start over from
+	// zero once the voucher is redeemed.
+	Merges []Merge `json:"merges"`
+}
+
+// Merge identifies a lane settled by a voucher of another lane, along with
+// the highest nonce of the lane's vouchers that the settlement covers.
+type Merge struct {
+	// Lane is the id of the settled lane.
+	Lane uint64 `json:"lane"`
+
+	// Nonce is the highest nonce of the settled lane covered by the merge.
+	Nonce uint64 `json:"nonce"`
+}
+
+// IsZero returns true if the lane is lane 0 with no nonce or merges, as for
+// vouchers created without lanes.
+func (l *VoucherLane) IsZero() bool {
+	return l == nil || (l.ID == 0 && l.Nonce == 0 && len(l.Merges) == 0)
+}
+
 // PaymentVoucher is a voucher for a payment channel that can be transferred off-chain but guarantees a future payment.
 type PaymentVoucher struct {
 	// Channel is the id of this voucher's payment channel.
@@ -46,6 +83,9 @@ type PaymentVoucher struct {
 	// Condition defines a optional message that will be called and must return true before this voucher can be redeemed.
 	Condition *Predicate `json:"condition"`
 
+	// Lane is the lane of the channel through which this voucher pays.
+	Lane VoucherLane `json:"lane"`
+
 	// Signature is the signature of all the data in this voucher.
 	Signature Signature `json:"signature"`
 }
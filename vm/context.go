@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/actor/builtin/account"
 	"github.com/filecoin-project/go-filecoin/address"
 	"github.com/filecoin-project/go-filecoin/exec"
+	"github.com/filecoin-project/go-filecoin/proofs"
 	"github.com/filecoin-project/go-filecoin/sampling"
 	"github.com/filecoin-project/go-filecoin/state"
 	"github.com/filecoin-project/go-filecoin/types"
@@ -64,8 +65,11 @@ func NewVMContext(params NewContextParams) *Context {
 var _ exec.VMContext = (*Context)(nil)
 
 // Storage returns an implementation of the storage module for this context.
+// Reads and writes through it are charged to the message.
 func (ctx *Context) Storage() exec.Storage {
-	return ctx.storageMap.NewStorage(ctx.message.To, ctx.to)
+	storage := ctx.storageMap.NewStorage(ctx.message.To, ctx.to)
+	storage.gasTracker = ctx.gasTracker
+	return storage
 }
 
 // Message retrieves the message associated with this context.
@@ -138,6 +142,10 @@ func (ctx *Context) IsFromAccountActor() bool {
 func (ctx *Context) Send(to address.Address, method string, value types.AttoFIL, params []interface{}) ([][]byte, uint8, error) {
 	deps := ctx.deps
 
+	if err := ctx.Charge(DefaultGasPrices.Send); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	// the message sender is the `to` actor, so this is what we set as `from` in the new message
 	from := ctx.Message().To
 	fromActor := ctx.to
@@ -210,6 +218,10 @@ func computeActorAddress(creator address.Address, nonce uint64) (address.Address
 // CreateNewActor creates and initializes an actor at the given address.
 // If the address is occupied by a non-empty actor, this method will fail.
 func (ctx *Context) CreateNewActor(addr address.Address, code cid.Cid, initializerData interface{}) error {
+	if err := ctx.Charge(DefaultGasPrices.CreateActor); err != nil {
+		return errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
 	// Check existing address. If nothing there, create empty actor.
 	newActor, err := ctx.state.GetOrCreateActor(context.TODO(), addr, func() (*actor.Actor, error) {
 		return &actor.Actor{}, nil
@@ -243,6 +255,46 @@ func (ctx *Context) CreateNewActor(addr address.Address, code cid.Cid, initializ
 	return nil
 }
 
+// VerifySignature returns whether sig is a valid signature of data by signer.
+// An error is returned when the message runs out of gas for the verification.
+func (ctx *Context) VerifySignature(data []byte, signer address.Address, sig types.Signature) (bool, error) {
+	if err := ctx.Charge(DefaultGasPrices.VerifySignature); err != nil {
+		return false, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return types.IsValidSignature(data, signer, sig), nil
+}
+
+// Verifier returns a proof verifier that charges each verification to the
+// message.
+func (ctx *Context) Verifier() proofs.Verifier {
+	return &gasChargingVerifier{ctx: ctx, verifier: ctx.deps.Verifier}
+}
+
+// gasChargingVerifier charges the gas price of each proof verification before
+// delegating it.
+type gasChargingVerifier struct {
+	ctx      *Context
+	verifier proofs.Verifier
+}
+
+var _ proofs.Verifier = (*gasChargingVerifier)(nil)
+
+// VerifyPoST charges for and verifies a proof-of-spacetime.
+func (v *gasChargingVerifier) VerifyPoST(req proofs.VerifyPoStRequest) (proofs.VerifyPoSTResponse, error) {
+	if err := v.ctx.Charge(DefaultGasPrices.VerifyPoSt); err != nil {
+		return proofs.VerifyPoSTResponse{}, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return v.verifier.VerifyPoST(req)
+}
+
+// VerifySeal charges for and verifies a seal proof.
+func (v *gasChargingVerifier) VerifySeal(req proofs.VerifySealRequest) (proofs.VerifySealResponse, error) {
+	if err := v.ctx.Charge(DefaultGasPrices.VerifySeal); err != nil {
+		return proofs.VerifySealResponse{}, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+	return v.verifier.VerifySeal(req)
+}
+
 // SampleChainRandomness samples randomness from a block's ancestors at the
 // given height.
 func (ctx *Context) SampleChainRandomness(sampleHeight *types.BlockHeight) ([]byte, error) {
@@ -257,6 +309,7 @@ func makeDeps(st *state.CachedTree) *deps {
 		EncodeValues: abi.EncodeValues,
 		Send:         Send,
 		ToValues:     abi.ToValues,
+		Verifier:     &proofs.RustVerifier{},
 	}
 	if st != nil {
 		deps.GetOrCreateActor = st.GetOrCreateActor
@@ -269,4 +322,5 @@ type deps struct {
 	GetOrCreateActor func(context.Context, address.Address, func() (*actor.Actor, error)) (*actor.Actor, error)
 	Send             func(context.Context, *Context) ([][]byte, uint8, error)
 	ToValues         func([]interface{}) ([]*abi.Value, error)
+	Verifier         proofs.Verifier
 }
@@ -55,7 +55,7 @@ func TestVMContextStorage(t *testing.T) {
 		Message:     msg,
 		State:       cstate,
 		StorageMap:  vms,
-		GasTracker:  NewGasTracker(),
+		GasTracker:  newTestGasTracker(),
 		BlockHeight: types.NewBlockHeight(0),
 	}
 	vmCtx := NewVMContext(vmCtxParams)
@@ -98,7 +98,7 @@ func TestVMContextSendFailures(t *testing.T) {
 		Message:     newMsg(),
 		State:       tree,
 		StorageMap:  vms,
-		GasTracker:  NewGasTracker(),
+		GasTracker:  newTestGasTracker(),
 		BlockHeight: types.NewBlockHeight(0),
 	}
 
@@ -367,3 +367,94 @@ func TestVMContextRand(t *testing.T) {
 		assert.Equal(t, []byte(strconv.Itoa(0)), r)
 	})
 }
+
+func TestVMContextChargesGas(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+
+	newCtx := func(t *testing.T, gasTracker *GasTracker) *Context {
+		cst := hamt.NewCborStore()
+		st := state.NewEmptyStateTree(cst)
+		toActor, err := account.NewActor(types.ZeroAttoFIL)
+		require.NoError(t, err)
+		toAddr := addrGetter()
+		require.NoError(t, st.SetActor(ctx, toAddr, toActor))
+
+		return NewVMContext(NewContextParams{
+			From:        actor.NewActor(cid.Undef, types.ZeroAttoFIL),
+			To:          toActor,
+			Message:     types.NewMessage(addrGetter(), toAddr, 0, types.ZeroAttoFIL, "hello", nil),
+			State:       state.NewCachedStateTree(st),
+			StorageMap:  NewStorageMap(blockstore.NewBlockstore(datastore.NewMapDatastore())),
+			GasTracker:  gasTracker,
+			BlockHeight: types.NewBlockHeight(0),
+		})
+	}
+
+	t.Run("storage reads and writes by size", func(t *testing.T) {
+		small := "hello"
+		large := string(make([]byte, 4096))
+		for _, memory := range []string{small, large} {
+			vmCtx := newCtx(t, newTestGasTracker())
+			chunk, err := cbor.DumpObject(memory)
+			require.NoError(t, err)
+			putGas := storageGas(DefaultGasPrices.StoragePutPerKiB, len(chunk))
+			getGas := storageGas(DefaultGasPrices.StorageGetPerKiB, len(chunk))
+
+			require.NoError(t, vmCtx.WriteStorage(memory))
+			assert.Equal(t, putGas, vmCtx.GasUnits())
+
+			_, err = vmCtx.ReadStorage()
+			require.NoError(t, err)
+			assert.Equal(t, putGas+getGas, vmCtx.GasUnits())
+		}
+
+		assert.Equal(t, types.NewGasUnits(1), storageGas(DefaultGasPrices.StorageGetPerKiB, 1))
+		assert.True(t, storageGas(DefaultGasPrices.StoragePutPerKiB, 4096) > storageGas(DefaultGasPrices.StoragePutPerKiB, 8))
+	})
+
+	t.Run("sends", func(t *testing.T) {
+		vmCtx := newCtx(t, newTestGasTracker())
+		vmCtx.deps.Send = func(ctx context.Context, vmCtx *Context) ([][]byte, uint8, error) {
+			return nil, 0, nil
+		}
+
+		_, _, err := vmCtx.Send(addrGetter(), "", types.ZeroAttoFIL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultGasPrices.Send, vmCtx.GasUnits())
+	})
+
+	t.Run("signature verification", func(t *testing.T) {
+		vmCtx := newCtx(t, newTestGasTracker())
+
+		valid, err := vmCtx.VerifySignature([]byte("data"), addrGetter(), types.Signature("not a signature"))
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Equal(t, DefaultGasPrices.VerifySignature, vmCtx.GasUnits())
+	})
+
+	t.Run("reverts when the message runs out of gas", func(t *testing.T) {
+		vmCtx := newCtx(t, NewGasTracker())
+
+		err := vmCtx.WriteStorage("hello")
+		assert.Error(t, err)
+		assert.True(t, errors.ShouldRevert(err))
+		assert.True(t, vmCtx.gasTracker.OutOfGas())
+
+		_, code, err := vmCtx.Send(addrGetter(), "", types.ZeroAttoFIL, nil)
+		assert.Equal(t, exec.ErrInsufficientGas, int(code))
+		assert.True(t, errors.ShouldRevert(err))
+
+		_, err = vmCtx.VerifySignature([]byte("data"), addrGetter(), nil)
+		assert.True(t, errors.ShouldRevert(err))
+	})
+}
+
+// newTestGasTracker returns a gas tracker with enough gas for any message.
+func newTestGasTracker() *GasTracker {
+	gasTracker := NewGasTracker()
+	gasTracker.MsgGasLimit = types.BlockGasLimit
+	return gasTracker
+}
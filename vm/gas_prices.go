@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+// GasPrices is the amount of gas the VM charges for each operation it performs
+// on behalf of an actor. Actors may still charge for their own work with
+// Context.Charge on top of these.
+type GasPrices struct {
+	// StorageGetPerKiB is charged for reading actor storage, in proportion to
+	// the size of each chunk read.
+	StorageGetPerKiB types.GasUnits
+	// StoragePutPerKiB is charged for writing actor storage, in proportion to
+	// the size of each chunk written.
+	StoragePutPerKiB types.GasUnits
+	// Send is charged for each message an actor sends to another actor.
+	Send types.GasUnits
+	// CreateActor is charged for creating and initializing a new actor.
+	CreateActor types.GasUnits
+	// VerifySignature is charged for each signature an actor verifies.
+	VerifySignature types.GasUnits
+	// VerifySeal is charged for each seal proof an actor verifies.
+	VerifySeal types.GasUnits
+	// VerifyPoSt is charged for each proof-of-spacetime an actor verifies.
+	VerifyPoSt types.GasUnits
+}
+
+// DefaultGasPrices is the gas price table charged by the VM.
+// TODO these are placeholders until the prices are measured against real
+// workloads.
+var DefaultGasPrices = GasPrices{
+	StorageGetPerKiB: types.NewGasUnits(20),
+	StoragePutPerKiB: types.NewGasUnits(40),
+	Send:             types.NewGasUnits(10),
+	CreateActor:      types.NewGasUnits(50),
+	VerifySignature:  types.NewGasUnits(20),
+	VerifySeal:       types.NewGasUnits(50),
+	VerifyPoSt:       types.NewGasUnits(50),
+}
+
+// storageGas returns the gas charged for reading or writing size bytes of
+// actor storage at pricePerKiB, rounded up to a whole gas unit.
+func storageGas(pricePerKiB types.GasUnits, size int) types.GasUnits {
+	return (pricePerKiB*types.GasUnits(size) + 1023) / 1024
+}
@@ -10,6 +10,7 @@ type GasTracker struct {
 	MsgGasLimit          types.GasUnits
 	gasConsumedByBlock   types.GasUnits
 	gasConsumedByMessage types.GasUnits
+	outOfGas             bool
 }
 
 // NewGasTracker initializes a new empty gas tracker
@@ -25,6 +26,7 @@ func NewGasTracker() *GasTracker {
 func (gasTracker *GasTracker) ResetForNewMessage(message types.MeteredMessage) {
 	gasTracker.MsgGasLimit = message.GasLimit
 	gasTracker.gasConsumedByMessage = types.NewGasUnits(0)
+	gasTracker.outOfGas = false
 }
 
 // Charge will add the gas charge to the current method gas context.
@@ -32,6 +34,7 @@ func (gasTracker *GasTracker) Charge(cost types.GasUnits) error {
 	if gasTracker.gasConsumedByMessage+cost > gasTracker.MsgGasLimit {
 		gasTracker.gasConsumedByMessage = gasTracker.MsgGasLimit
 		gasTracker.gasConsumedByBlock += gasTracker.MsgGasLimit
+		gasTracker.outOfGas = true
 		return errors.NewRevertError("gas cost exceeds gas limit")
 	}
 
@@ -40,6 +43,12 @@ func (gasTracker *GasTracker) Charge(cost types.GasUnits) error {
 	return nil
 }
 
+// OutOfGas returns whether the current message was charged more gas than its
+// limit.
+func (gasTracker *GasTracker) OutOfGas() bool {
+	return gasTracker.outOfGas
+}
+
 // GasAboveBlockLimit will return true if the MsgGasLimit of the current message is greater than the block gas limit.
 func (gasTracker *GasTracker) GasAboveBlockLimit() bool {
 	return gasTracker.MsgGasLimit > types.BlockGasLimit
@@ -85,10 +85,13 @@ func (s *storageMap) Flush() error {
 }
 
 // Storage is a place to hold chunks that are created while processing a block.
+// When it has a gas tracker, reads and writes are charged to it by the size of
+// the chunks read and written.
 type Storage struct {
 	actor      *actor.Actor
 	chunks     map[cid.Cid]ipld.Node
 	blockstore blockstore.Blockstore
+	gasTracker *GasTracker
 }
 
 var _ exec.Storage = (*Storage)(nil)
@@ -118,6 +121,10 @@ func (s Storage) Put(v interface{}) (cid.Cid, error) {
 		return cid.Undef, exec.Errors[exec.ErrDecode]
 	}
 
+	if err := s.charge(storageGas(DefaultGasPrices.StoragePutPerKiB, len(nd.RawData()))); err != nil {
+		return cid.Undef, err
+	}
+
 	c := nd.Cid()
 	s.chunks[c] = nd
 
@@ -127,20 +134,24 @@ func (s Storage) Put(v interface{}) (cid.Cid, error) {
 // Get retrieves a chunk from either temporary storage or its backing store.
 // If the chunk is not found in storage, a vm.ErrNotFound error is returned.
 func (s Storage) Get(cid cid.Cid) ([]byte, error) {
-	n, ok := s.chunks[cid]
-	if ok {
-		return n.RawData(), nil
+	var data []byte
+	if n, ok := s.chunks[cid]; ok {
+		data = n.RawData()
+	} else {
+		blk, err := s.blockstore.Get(cid)
+		if err != nil {
+			if err == blockstore.ErrNotFound {
+				return []byte{}, ErrNotFound
+			}
+			return []byte{}, err
+		}
+		data = blk.RawData()
 	}
 
-	blk, err := s.blockstore.Get(cid)
-	if err != nil {
-		if err == blockstore.ErrNotFound {
-			return []byte{}, ErrNotFound
-		}
+	if err := s.charge(storageGas(DefaultGasPrices.StorageGetPerKiB, len(data))); err != nil {
 		return []byte{}, err
 	}
-
-	return blk.RawData(), nil
+	return data, nil
 }
 
 // Commit updates the head of the current actor to the given cid.
@@ -164,6 +175,14 @@ func (s Storage) Commit(newCid cid.Cid, oldCid cid.Cid) error {
 	return nil
 }
 
+// charge charges cost to the gas tracker, if any.
+func (s Storage) charge(cost types.GasUnits) error {
+	if s.gasTracker == nil {
+		return nil
+	}
+	return s.gasTracker.Charge(cost)
+}
+
 // Head return the current head of the actor's memory
 func (s Storage) Head() cid.Cid {
 	return s.actor.Head
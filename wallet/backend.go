@@ -34,3 +34,24 @@ type Importer interface {
 	// into the backend
 	ImportKey(ki *types.KeyInfo) error
 }
+
+// PublicKeyer is a specialization of a wallet backend that can return the
+// public key of an address without exposing its private key. Remote and
+// hardware wallets do this, since they cannot return key infos.
+type PublicKeyer interface {
+	// PublicKey returns the public key of the given address.
+	PublicKey(addr address.Address) ([]byte, error)
+}
+
+// backendPublicKey returns the public key of addr stored in backend.
+func backendPublicKey(backend Backend, addr address.Address) ([]byte, error) {
+	if pker, ok := backend.(PublicKeyer); ok {
+		return pker.PublicKey(addr)
+	}
+
+	info, err := backend.GetKeyInfo(addr)
+	if err != nil {
+		return nil, err
+	}
+	return info.PublicKey(), nil
+}
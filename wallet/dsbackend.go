@@ -1,6 +1,8 @@
 package wallet
 
 import (
+	"bytes"
+	"encoding/binary"
 	"reflect"
 	"strings"
 	"sync"
@@ -21,6 +23,15 @@ const (
 	SECP256K1 = "secp256k1"
 )
 
+// hdPrefix is the datastore prefix of the keys storing the seed of a
+// hierarchical deterministic wallet and the index of its next address.
+const hdPrefix = "/hd/"
+
+var (
+	hdSeedKey      = ds.NewKey(hdPrefix + "seed")
+	hdNextIndexKey = ds.NewKey(hdPrefix + "next")
+)
+
 // DSBackendType is the reflect type of the DSBackend.
 var DSBackendType = reflect.TypeOf(&DSBackend{})
 
@@ -33,6 +44,9 @@ type DSBackend struct {
 
 	// TODO: proper cache
 	cache map[address.Address]struct{}
+
+	// hdLk serializes derivations of new addresses from the wallet seed.
+	hdLk sync.Mutex
 }
 
 var _ Backend = (*DSBackend)(nil)
@@ -53,6 +67,9 @@ func NewDSBackend(ds repo.Datastore) (*DSBackend, error) {
 
 	cache := make(map[address.Address]struct{})
 	for _, el := range list {
+		if strings.HasPrefix(el.Key, hdPrefix) {
+			continue
+		}
 		parsedAddr, err := address.NewFromString(strings.Trim(el.Key, "/"))
 		if err != nil {
 			return nil, errors.Wrapf(err, "trying to restore invalid address: %s", el.Key)
@@ -93,9 +110,22 @@ func (backend *DSBackend) HasAddress(addr address.Address) bool {
 	return ok
 }
 
-// NewAddress creates a new address and stores it.
+// NewAddress creates a new address and stores it. If the backend has a seed,
+// the address is derived from the seed at the next unused index, otherwise
+// it is random.
 // Safe for concurrent access.
 func (backend *DSBackend) NewAddress() (address.Address, error) {
+	backend.hdLk.Lock()
+	defer backend.hdLk.Unlock()
+
+	seed, err := backend.seed()
+	if err != nil {
+		return address.Undef, err
+	}
+	if seed != nil {
+		return backend.deriveNextAddress(seed)
+	}
+
 	prv, err := crypto.GenerateKey()
 	if err != nil {
 		return address.Undef, err
@@ -114,6 +144,99 @@ func (backend *DSBackend) NewAddress() (address.Address, error) {
 	return ki.Address()
 }
 
+// SetSeed makes the backend derive new addresses from seed, starting at
+// index next. When the backend already derives its addresses from seed, it
+// keeps deriving them after the last one it derived if that is past next, so
+// that derived addresses are not handed out again. Keys stored earlier are
+// kept.
+// Safe for concurrent access.
+func (backend *DSBackend) SetSeed(seed []byte, next uint32) error {
+	backend.hdLk.Lock()
+	defer backend.hdLk.Unlock()
+
+	current, err := backend.seed()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, seed) {
+		index, err := backend.nextIndex()
+		if err != nil {
+			return err
+		}
+		if index > next {
+			next = index
+		}
+	}
+
+	if err := backend.ds.Put(hdSeedKey, seed); err != nil {
+		return errors.Wrap(err, "failed to store wallet seed")
+	}
+	return backend.setNextIndex(next)
+}
+
+// HasSeed returns true if the backend derives new addresses from a seed.
+func (backend *DSBackend) HasSeed() bool {
+	seed, err := backend.seed()
+	return err == nil && seed != nil
+}
+
+func (backend *DSBackend) seed() ([]byte, error) {
+	seed, err := backend.ds.Get(hdSeedKey)
+	if err == ds.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch wallet seed")
+	}
+	return seed, nil
+}
+
+func (backend *DSBackend) deriveNextAddress(seed []byte) (address.Address, error) {
+	index, err := backend.nextIndex()
+	if err != nil {
+		return address.Undef, err
+	}
+
+	ki, err := DeriveKeyInfo(seed, index)
+	for err == crypto.ErrInvalidChildKey {
+		index++
+		ki, err = DeriveKeyInfo(seed, index)
+	}
+	if err != nil {
+		return address.Undef, err
+	}
+
+	if err := backend.putKeyInfo(ki); err != nil {
+		return address.Undef, err
+	}
+	if err := backend.setNextIndex(index + 1); err != nil {
+		return address.Undef, err
+	}
+
+	return ki.Address()
+}
+
+// nextIndex returns the index of the next address derived from the seed.
+func (backend *DSBackend) nextIndex() (uint32, error) {
+	indexBytes, err := backend.ds.Get(hdNextIndexKey)
+	if err == ds.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to fetch next wallet index")
+	}
+	return binary.BigEndian.Uint32(indexBytes), nil
+}
+
+func (backend *DSBackend) setNextIndex(index uint32) error {
+	indexBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(indexBytes, index)
+	if err := backend.ds.Put(hdNextIndexKey, indexBytes); err != nil {
+		return errors.Wrap(err, "failed to store next wallet index")
+	}
+	return nil
+}
+
 func (backend *DSBackend) putKeyInfo(ki *types.KeyInfo) error {
 	a, err := ki.Address()
 	if err != nil {
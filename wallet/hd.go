@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+
+	"github.com/filecoin-project/go-filecoin/crypto"
+	"github.com/filecoin-project/go-filecoin/types"
+)
+
+const (
+	// hdCoinType is the SLIP-44 coin type of filecoin.
+	hdCoinType = 461
+
+	// mnemonicEntropyBits is the entropy of generated mnemonics, which
+	// makes them 24 words long.
+	mnemonicEntropyBits = 256
+
+	// RestoreGapLimit is the number of consecutive unused addresses after
+	// which restoring a wallet stops looking for used ones.
+	RestoreGapLimit = 20
+)
+
+// ErrInvalidMnemonic is returned for mnemonics that are not valid BIP39
+// mnemonics.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic generates a new random BIP39 mnemonic, from which the seed of
+// a hierarchical deterministic wallet is derived.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropyBits)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate entropy")
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic returns the wallet seed of a BIP39 mnemonic.
+func SeedFromMnemonic(mnemonic string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidMnemonic, err.Error())
+	}
+	return seed, nil
+}
+
+// DeriveKeyInfo derives the key at index of the wallet with the given seed,
+// on the BIP44 path m/44'/461'/0'/0/index. It returns
+// crypto.ErrInvalidChildKey for the rare indices without a valid key.
+func DeriveKeyInfo(seed []byte, index uint32) (*types.KeyInfo, error) {
+	path := []uint32{
+		44 + crypto.HardenedKeyStart,
+		hdCoinType + crypto.HardenedKeyStart,
+		crypto.HardenedKeyStart,
+		0,
+		index,
+	}
+	key, err := crypto.DeriveKey(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return &types.KeyInfo{
+		PrivateKey: key.Key,
+		Curve:      SECP256K1,
+	}, nil
+}
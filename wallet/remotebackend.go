@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/types"
+	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
+)
+
+var log = logging.Logger("wallet")
+
+// RemoteBackendType is the reflect type of the RemoteBackend.
+var RemoteBackendType = reflect.TypeOf(&RemoteBackend{})
+
+// remoteSignerTimeout bounds each request to a remote signer.
+const remoteSignerTimeout = 30 * time.Second
+
+// remoteAddressesTTL is how long the addresses listed by a remote signer are
+// cached before they are listed again. Keys added to the signer are seen by
+// the node within this time.
+const remoteAddressesTTL = time.Minute
+
+// remoteAddressesRetryInterval is how long the backend waits before listing
+// the addresses of a signer again after listing them failed.
+var remoteAddressesRetryInterval = 10 * time.Second
+
+// RemoteBackend is a wallet backend whose keys are held by a remote signer,
+// e.g. an isolated process or a bridge to an HSM. The signer is reached over
+// HTTP or a unix socket and speaks the JSON-RPC protocol implemented by
+// NewRemoteSignerHandler. Private keys never leave the signer, so the backend
+// cannot import or export keys.
+type RemoteBackend struct {
+	// nextID is first so it is aligned for atomic access.
+	nextID uint64
+
+	endpoint string
+	token    string
+	url      string
+	client   *http.Client
+
+	// addrsLk guards addrs, the addresses last listed by the signer,
+	// addrsRefreshed, when listing them last succeeded or failed, addrsErr,
+	// why it failed, and refreshing, whether they are being listed.
+	addrsLk        sync.Mutex
+	addrs          []address.Address
+	addrsRefreshed time.Time
+	addrsErr       error
+	refreshing     bool
+}
+
+var _ Backend = (*RemoteBackend)(nil)
+var _ PublicKeyer = (*RemoteBackend)(nil)
+
+// NewRemoteBackend constructs a backend using the remote signer at endpoint,
+// which is either an http(s) URL or a unix socket path prefixed by unix://,
+// and authenticating with token. A signer that cannot be reached yet only
+// logs a warning; its addresses are listed once it can be.
+func NewRemoteBackend(endpoint, token string) (*RemoteBackend, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid remote signer endpoint %s", endpoint)
+	}
+
+	backend := &RemoteBackend{
+		endpoint: endpoint,
+		token:    token,
+		client:   &http.Client{Timeout: remoteSignerTimeout},
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		backend.url = endpoint
+		if u.Path == "" {
+			backend.url += RemoteSignerPath
+		}
+	case "unix":
+		socket := u.Path
+		if u.Host != "" {
+			socket = u.Host + u.Path
+		}
+		backend.url = "http://unix" + RemoteSignerPath
+		backend.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported remote signer endpoint %s, expected http, https or unix", endpoint)
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("remote signer %s has no token", endpoint)
+	}
+
+	backend.refreshing = true
+	backend.refreshAddresses()
+
+	return backend, nil
+}
+
+// Addresses returns the addresses the remote signer holds keys for, as last
+// listed. It never waits for the signer: once the addresses are older than
+// remoteAddressesTTL, or remoteAddressesRetryInterval after listing them
+// failed, they are listed again in the background.
+func (backend *RemoteBackend) Addresses() []address.Address {
+	backend.addrsLk.Lock()
+	defer backend.addrsLk.Unlock()
+
+	interval := remoteAddressesTTL
+	if backend.addrsErr != nil {
+		interval = remoteAddressesRetryInterval
+	}
+	if !backend.refreshing && time.Since(backend.addrsRefreshed) >= interval {
+		backend.refreshing = true
+		go backend.refreshAddresses()
+	}
+	return backend.addrs
+}
+
+// HasAddress checks if the remote signer holds the key for the passed in
+// address.
+func (backend *RemoteBackend) HasAddress(addr address.Address) bool {
+	for _, a := range backend.Addresses() {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// SignBytes asks the remote signer to sign `data` with the key of `addr`.
+func (backend *RemoteBackend) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
+	var sig []byte
+	if err := backend.call(RemoteSignerMethodSign, &sig, addr.String(), data); err != nil {
+		return nil, errors.Wrapf(err, "remote signer failed to sign for %s", addr)
+	}
+	return sig, nil
+}
+
+// Verify cryptographically verifies that 'sig' is the signed hash of 'data' with
+// the public key `pk`.
+func (backend *RemoteBackend) Verify(data, pk []byte, sig types.Signature) bool {
+	valid, err := wutil.Verify(pk, data, sig)
+	return err == nil && valid
+}
+
+// GetKeyInfo always fails, since the private keys of a remote signer cannot
+// be retrieved.
+func (backend *RemoteBackend) GetKeyInfo(addr address.Address) (*types.KeyInfo, error) {
+	return nil, errors.New("remote signer does not export private keys")
+}
+
+// PublicKey returns the public key of `addr` held by the remote signer.
+func (backend *RemoteBackend) PublicKey(addr address.Address) ([]byte, error) {
+	var pk []byte
+	if err := backend.call(RemoteSignerMethodPublicKey, &pk, addr.String()); err != nil {
+		return nil, errors.Wrapf(err, "remote signer failed to return public key for %s", addr)
+	}
+	return pk, nil
+}
+
+// refreshAddresses lists the addresses of the signer and caches them. A
+// failure is recorded, so that listing them is retried only after
+// remoteAddressesRetryInterval. backend.refreshing must be set by the caller.
+func (backend *RemoteBackend) refreshAddresses() {
+	addrs, err := backend.listAddresses()
+	if err != nil {
+		log.Warningf("failed to list addresses of remote signer %s: %s", backend.endpoint, err)
+	}
+
+	backend.addrsLk.Lock()
+	defer backend.addrsLk.Unlock()
+	backend.refreshing = false
+	backend.addrsRefreshed = time.Now()
+	backend.addrsErr = err
+	if err == nil {
+		backend.addrs = addrs
+	}
+}
+
+// listAddresses requests the addresses of the signer.
+func (backend *RemoteBackend) listAddresses() ([]address.Address, error) {
+	var encoded []string
+	if err := backend.call(RemoteSignerMethodAddresses, &encoded); err != nil {
+		return nil, err
+	}
+
+	addrs := make([]address.Address, len(encoded))
+	for i, s := range encoded {
+		addr, err := address.NewFromString(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "remote signer returned invalid address %s", s)
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
+
+// call sends a JSON-RPC request for method to the remote signer and decodes
+// the result into out.
+func (backend *RemoteBackend) call(method string, out interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	id := atomic.AddUint64(&backend.nextID, 1)
+	body, err := json.Marshal(&remoteSignerRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  encodedParams,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, backend.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", remoteSignerAuthScheme+backend.token)
+
+	resp, err := backend.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote signer responded %s", resp.Status)
+	}
+
+	var response remoteSignerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return errors.Wrap(err, "failed to decode remote signer response")
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if response.ID != id {
+		return fmt.Errorf("remote signer responded to request %d, expected %d", response.ID, id)
+	}
+	return json.Unmarshal(response.Result, out)
+}
@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+)
+
+func TestRemoteBackendRefresh(t *testing.T) {
+	tf.UnitTest(t)
+
+	defer func(interval time.Duration) { remoteAddressesRetryInterval = interval }(remoteAddressesRetryInterval)
+	remoteAddressesRetryInterval = 10 * time.Millisecond
+
+	newSigner := func(t *testing.T) (http.Handler, address.Address) {
+		signerBackend, err := NewDSBackend(datastore.NewMapDatastore())
+		require.NoError(t, err)
+		addr, err := signerBackend.NewAddress()
+		require.NoError(t, err)
+		return NewRemoteSignerHandler(signerBackend, "secret"), addr
+	}
+
+	t.Run("lists the addresses of signers reachable later", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		endpoint := "http://" + listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		backend, err := NewRemoteBackend(endpoint, "secret")
+		require.NoError(t, err)
+		assert.Empty(t, backend.Addresses())
+
+		listener, err = net.Listen("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		handler, addr := newSigner(t)
+		server := &http.Server{Handler: handler}
+		go server.Serve(listener) // nolint: errcheck
+		defer server.Close()      // nolint: errcheck
+
+		var addrs []address.Address
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if addrs = backend.Addresses(); len(addrs) > 0 {
+				break
+			}
+		}
+		assert.Equal(t, []address.Address{addr}, addrs)
+	})
+
+	t.Run("does not wait for a signer that does not respond", func(t *testing.T) {
+		handler, addr := newSigner(t)
+		var hang atomic.Value
+		hang.Store(false)
+		var requests int32
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hang.Load().(bool) {
+				atomic.AddInt32(&requests, 1)
+				<-release
+			}
+			handler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+		defer close(release)
+
+		backend, err := NewRemoteBackend(server.URL, "secret")
+		require.NoError(t, err)
+		require.Equal(t, []address.Address{addr}, backend.Addresses())
+
+		// expire the listed addresses
+		hang.Store(true)
+		backend.addrsLk.Lock()
+		backend.addrsRefreshed = time.Time{}
+		backend.addrsLk.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 10; i++ {
+				assert.Equal(t, []address.Address{addr}, backend.Addresses())
+				assert.True(t, backend.HasAddress(addr))
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("looking up addresses waited for the signer")
+		}
+
+		// the addresses are listed by a single request in the background
+		for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&requests) == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		backend.Addresses()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+}
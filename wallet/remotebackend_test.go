@@ -0,0 +1,157 @@
+package wallet_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/address"
+	tf "github.com/filecoin-project/go-filecoin/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/wallet"
+)
+
+func newRemoteSignerTestBackend(t *testing.T) (*wallet.DSBackend, address.Address) {
+	signerBackend, err := wallet.NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(t, err)
+	addr, err := signerBackend.NewAddress()
+	require.NoError(t, err)
+	return signerBackend, addr
+}
+
+const testSignerToken = "secret"
+
+func TestRemoteBackend(t *testing.T) {
+	tf.UnitTest(t)
+
+	signerBackend, addr := newRemoteSignerTestBackend(t)
+	server := httptest.NewServer(wallet.NewRemoteSignerHandler(signerBackend, testSignerToken))
+	defer server.Close()
+
+	backend, err := wallet.NewRemoteBackend(server.URL, testSignerToken)
+	require.NoError(t, err)
+
+	t.Run("lists the signer's addresses", func(t *testing.T) {
+		assert.Equal(t, []address.Address{addr}, backend.Addresses())
+		assert.True(t, backend.HasAddress(addr))
+		assert.False(t, backend.HasAddress(address.NewForTestGetter()()))
+	})
+
+	t.Run("caches the signer's addresses", func(t *testing.T) {
+		_, err := signerBackend.NewAddress()
+		require.NoError(t, err)
+
+		assert.Equal(t, []address.Address{addr}, backend.Addresses())
+		assert.True(t, backend.HasAddress(addr))
+	})
+
+	t.Run("refuses requests with a wrong token", func(t *testing.T) {
+		other, err := wallet.NewRemoteBackend(server.URL, "wrong")
+		require.NoError(t, err)
+
+		_, err = other.SignBytes([]byte("data"), addr)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "401")
+		assert.Empty(t, other.Addresses())
+	})
+
+	t.Run("signs with the signer's key", func(t *testing.T) {
+		data := []byte("data to sign")
+		sig, err := backend.SignBytes(data, addr)
+		require.NoError(t, err)
+
+		expected, err := signerBackend.SignBytes(data, addr)
+		require.NoError(t, err)
+		assert.Equal(t, expected, sig)
+
+		pk, err := backend.PublicKey(addr)
+		require.NoError(t, err)
+		assert.True(t, backend.Verify(data, pk, sig))
+	})
+
+	t.Run("returns the signer's public keys", func(t *testing.T) {
+		ki, err := signerBackend.GetKeyInfo(addr)
+		require.NoError(t, err)
+
+		pk, err := backend.PublicKey(addr)
+		require.NoError(t, err)
+		assert.Equal(t, ki.PublicKey(), pk)
+	})
+
+	t.Run("does not export keys", func(t *testing.T) {
+		_, err := backend.GetKeyInfo(addr)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails to sign for unknown addresses", func(t *testing.T) {
+		_, err := backend.SignBytes([]byte("data"), address.NewForTestGetter()())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), wallet.ErrUnknownAddress.Error())
+	})
+
+	t.Run("serves a wallet alongside the datastore backend", func(t *testing.T) {
+		localBackend, err := wallet.NewDSBackend(datastore.NewMapDatastore())
+		require.NoError(t, err)
+		localAddr, err := localBackend.NewAddress()
+		require.NoError(t, err)
+
+		w := wallet.New(localBackend, backend)
+		assert.Len(t, w.Backends(wallet.RemoteBackendType), 1)
+		assert.ElementsMatch(t, []address.Address{addr, localAddr}, w.Addresses())
+
+		pk, err := w.GetPubKeyForAddress(addr)
+		require.NoError(t, err)
+		found, err := w.GetAddressForPubKey(pk)
+		require.NoError(t, err)
+		assert.Equal(t, addr, found)
+
+		data := []byte("data to sign")
+		sig, err := w.SignBytes(data, addr)
+		require.NoError(t, err)
+		valid, err := w.Verify(data, pk, sig)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+}
+
+func TestRemoteBackendUnixSocket(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "remote-signer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	socket := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	signerBackend, addr := newRemoteSignerTestBackend(t)
+	server := &http.Server{Handler: wallet.NewRemoteSignerHandler(signerBackend, testSignerToken)}
+	go server.Serve(listener) // nolint: errcheck
+	defer server.Close()      // nolint: errcheck
+
+	backend, err := wallet.NewRemoteBackend("unix://"+socket, testSignerToken)
+	require.NoError(t, err)
+	assert.Equal(t, []address.Address{addr}, backend.Addresses())
+}
+
+func TestNewRemoteBackend(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("fails for unsupported endpoints", func(t *testing.T) {
+		_, err := wallet.NewRemoteBackend("ftp://127.0.0.1/signer", testSignerToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails without a token", func(t *testing.T) {
+		_, err := wallet.NewRemoteBackend("http://127.0.0.1/signer", "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/filecoin-project/go-filecoin/address"
+)
+
+// The remote signer protocol is JSON-RPC 2.0 over HTTP POST requests to
+// RemoteSignerPath. Requests carry the signer's token in an Authorization
+// header of the form "Bearer <token>". Byte strings are base64 encoded, as
+// encoding/json does, and addresses are in their string form.
+const (
+	// RemoteSignerPath is the path remote signers serve requests on.
+	RemoteSignerPath = "/rpc/v0"
+
+	// RemoteSignerMethodAddresses takes no params and returns the addresses
+	// the signer holds keys for.
+	RemoteSignerMethodAddresses = "Signer.Addresses"
+
+	// RemoteSignerMethodPublicKey takes an address and returns its public key.
+	RemoteSignerMethodPublicKey = "Signer.PublicKey"
+
+	// RemoteSignerMethodSign takes an address and data and returns the
+	// signature of the data by the address' key.
+	RemoteSignerMethodSign = "Signer.Sign"
+)
+
+// remoteSignerAuthScheme prefixes the token in the Authorization header.
+const remoteSignerAuthScheme = "Bearer "
+
+// Remote signer error codes, following JSON-RPC 2.0.
+const (
+	remoteSignerErrParse          = -32700
+	remoteSignerErrInvalidRequest = -32600
+	remoteSignerErrMethodNotFound = -32601
+	remoteSignerErrInvalidParams  = -32602
+	remoteSignerErrInternal       = -32603
+)
+
+type remoteSignerRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type remoteSignerResponse struct {
+	JSONRPC string             `json:"jsonrpc"`
+	ID      uint64             `json:"id"`
+	Result  json.RawMessage    `json:"result,omitempty"`
+	Error   *RemoteSignerError `json:"error,omitempty"`
+}
+
+// RemoteSignerError is an error returned by a remote signer.
+type RemoteSignerError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RemoteSignerError) Error() string {
+	return fmt.Sprintf("remote signer error %d: %s", e.Code, e.Message)
+}
+
+// NewRemoteSignerHandler returns an http handler serving the remote signer
+// protocol with the keys of backend to requests carrying token. It is the
+// reference implementation of the protocol used by RemoteBackend. All
+// requests are refused if token is empty.
+func NewRemoteSignerHandler(backend Backend, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RemoteSignerPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "remote signer requests must be POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validRemoteSignerToken(r, token) {
+			http.Error(w, "invalid remote signer token", http.StatusUnauthorized)
+			return
+		}
+
+		var req remoteSignerRequest
+		resp := &remoteSignerResponse{JSONRPC: "2.0"}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp.Error = &RemoteSignerError{Code: remoteSignerErrParse, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = serveRemoteSignerRequest(backend, &req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Warningf("failed to write remote signer response: %s", err)
+		}
+	})
+	return mux
+}
+
+func validRemoteSignerToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	expected := []byte(remoteSignerAuthScheme + token)
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) == 1
+}
+
+func serveRemoteSignerRequest(backend Backend, req *remoteSignerRequest) (json.RawMessage, *RemoteSignerError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &RemoteSignerError{Code: remoteSignerErrInvalidRequest, Message: "unsupported jsonrpc version"}
+	}
+
+	var params []json.RawMessage
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: err.Error()}
+		}
+	}
+
+	var result interface{}
+	switch req.Method {
+	case RemoteSignerMethodAddresses:
+		addrs := backend.Addresses()
+		encoded := make([]string, len(addrs))
+		for i, addr := range addrs {
+			encoded[i] = addr.String()
+		}
+		result = encoded
+	case RemoteSignerMethodPublicKey:
+		if len(params) != 1 {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: "expected an address"}
+		}
+		addr, rerr := decodeRemoteSignerAddress(backend, params[0])
+		if rerr != nil {
+			return nil, rerr
+		}
+		pk, err := backendPublicKey(backend, addr)
+		if err != nil {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInternal, Message: err.Error()}
+		}
+		result = pk
+	case RemoteSignerMethodSign:
+		if len(params) != 2 {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: "expected an address and data"}
+		}
+		addr, rerr := decodeRemoteSignerAddress(backend, params[0])
+		if rerr != nil {
+			return nil, rerr
+		}
+		var data []byte
+		if err := json.Unmarshal(params[1], &data); err != nil {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: err.Error()}
+		}
+		sig, err := backend.SignBytes(data, addr)
+		if err != nil {
+			return nil, &RemoteSignerError{Code: remoteSignerErrInternal, Message: err.Error()}
+		}
+		result = []byte(sig)
+	default:
+		return nil, &RemoteSignerError{Code: remoteSignerErrMethodNotFound, Message: fmt.Sprintf("unknown method %s", req.Method)}
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, &RemoteSignerError{Code: remoteSignerErrInternal, Message: err.Error()}
+	}
+	return encoded, nil
+}
+
+func decodeRemoteSignerAddress(backend Backend, param json.RawMessage) (address.Address, *RemoteSignerError) {
+	var s string
+	if err := json.Unmarshal(param, &s); err != nil {
+		return address.Undef, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: err.Error()}
+	}
+	addr, err := address.NewFromString(s)
+	if err != nil {
+		return address.Undef, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: err.Error()}
+	}
+	if !backend.HasAddress(addr) {
+		return address.Undef, &RemoteSignerError{Code: remoteSignerErrInvalidParams, Message: ErrUnknownAddress.Error()}
+	}
+	return addr, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/address"
+	"github.com/filecoin-project/go-filecoin/crypto"
 	"github.com/filecoin-project/go-filecoin/types"
 	wutil "github.com/filecoin-project/go-filecoin/wallet/util"
 )
@@ -52,14 +53,9 @@ func (w *Wallet) HasAddress(a address.Address) bool {
 // in address.
 // Safe for concurrent access.
 func (w *Wallet) Find(addr address.Address) (Backend, error) {
-	w.lk.Lock()
-	defer w.lk.Unlock()
-
-	for _, backends := range w.backends {
-		for _, backend := range backends {
-			if backend.HasAddress(addr) {
-				return backend, nil
-			}
+	for _, backend := range w.allBackends() {
+		if backend.HasAddress(addr) {
+			return backend, nil
 		}
 	}
 
@@ -70,14 +66,9 @@ func (w *Wallet) Find(addr address.Address) (Backend, error) {
 // Safe for concurrent access.
 // Always sorted in the same order.
 func (w *Wallet) Addresses() []address.Address {
-	w.lk.Lock()
-	defer w.lk.Unlock()
-
 	var out []address.Address
-	for _, backends := range w.backends {
-		for _, backend := range backends {
-			out = append(out, backend.Addresses()...)
-		}
+	for _, backend := range w.allBackends() {
+		out = append(out, backend.Addresses()...)
 	}
 	sort.Slice(out, func(i, j int) bool {
 		return bytes.Compare(out[i].Bytes(), out[j].Bytes()) < 0
@@ -86,6 +77,19 @@ func (w *Wallet) Addresses() []address.Address {
 	return out
 }
 
+// allBackends returns all backends. The backends are queried without holding
+// w.lk, so a slow backend does not block the others.
+func (w *Wallet) allBackends() []Backend {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	var out []Backend
+	for _, backends := range w.backends {
+		out = append(out, backends...)
+	}
+	return out
+}
+
 // Backends returns backends by their kind.
 func (w *Wallet) Backends(kind reflect.Type) []Backend {
 	w.lk.Lock()
@@ -152,12 +156,12 @@ func NewAddress(w *Wallet) (address.Address, error) {
 // GetPubKeyForAddress returns the public key in the keystore associated with
 // the given address.
 func (w *Wallet) GetPubKeyForAddress(addr address.Address) ([]byte, error) {
-	info, err := w.keyInfoForAddr(addr)
+	backend, err := w.Find(addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return info.PublicKey(), nil
+	return backendPublicKey(backend, addr)
 }
 
 // NewKeyInfo creates a new KeyInfo struct in the wallet backend and returns it
@@ -210,6 +214,64 @@ func (w *Wallet) Import(kinfos []*types.KeyInfo) ([]address.Address, error) {
 	return out, nil
 }
 
+// Restore makes the wallet derive new addresses from seed and imports the
+// addresses derived from seed that used reports as used, returning them.
+// Addresses are scanned in order until RestoreGapLimit consecutive ones are
+// unused, and new addresses are derived after the last used one.
+func (w *Wallet) Restore(seed []byte, used func(address.Address) (bool, error)) ([]address.Address, error) {
+	dsb := w.Backends(DSBackendType)
+	if len(dsb) != 1 {
+		return nil, fmt.Errorf("expected exactly one datastore wallet backend")
+	}
+	backend := dsb[0].(*DSBackend)
+
+	var found []*types.KeyInfo
+	next := uint32(0)
+	for index, gap := uint32(0), 0; gap < RestoreGapLimit; index++ {
+		ki, err := DeriveKeyInfo(seed, index)
+		if err == crypto.ErrInvalidChildKey {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		addr, err := ki.Address()
+		if err != nil {
+			return nil, err
+		}
+		isUsed, err := used(addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check whether %s is used", addr)
+		}
+		if !isUsed {
+			gap++
+			continue
+		}
+
+		gap = 0
+		next = index + 1
+		found = append(found, ki)
+	}
+
+	var out []address.Address
+	for _, ki := range found {
+		if err := backend.ImportKey(ki); err != nil {
+			return nil, err
+		}
+		addr, err := ki.Address()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, addr)
+	}
+
+	if err := backend.SetSeed(seed, next); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Export returns the KeyInfos for the given wallet addresses
 func (w *Wallet) Export(addrs []address.Address) ([]*types.KeyInfo, error) {
 	out := make([]*types.KeyInfo, len(addrs))
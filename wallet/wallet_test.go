@@ -224,3 +224,104 @@ func TestWallet_CreateTicket(t *testing.T) {
 		assert.Equal(t, types.Signature(nil), ticket)
 	})
 }
+
+func TestWalletHD(t *testing.T) {
+	tf.UnitTest(t)
+
+	mnemonic, err := wallet.NewMnemonic()
+	require.NoError(t, err)
+	seed, err := wallet.SeedFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	newAddresses := func() []address.Address {
+		ds := datastore.NewMapDatastore()
+		backend, err := wallet.NewDSBackend(ds)
+		require.NoError(t, err)
+		require.NoError(t, backend.SetSeed(seed, 0))
+
+		// the seed is not mistaken for a key when the backend is reopened
+		backend, err = wallet.NewDSBackend(ds)
+		require.NoError(t, err)
+		assert.True(t, backend.HasSeed())
+
+		var addrs []address.Address
+		for i := 0; i < 3; i++ {
+			addr, err := backend.NewAddress()
+			require.NoError(t, err)
+			addrs = append(addrs, addr)
+		}
+		assert.Len(t, backend.Addresses(), 3)
+		return addrs
+	}
+
+	t.Run("derives the same addresses from the same seed", func(t *testing.T) {
+		addrs := newAddresses()
+		assert.Equal(t, addrs, newAddresses())
+
+		for i, addr := range addrs {
+			ki, err := wallet.DeriveKeyInfo(seed, uint32(i))
+			require.NoError(t, err)
+			expected, err := ki.Address()
+			require.NoError(t, err)
+			assert.Equal(t, expected, addr)
+		}
+	})
+
+	t.Run("rejects invalid mnemonics", func(t *testing.T) {
+		_, err := wallet.SeedFromMnemonic("not a valid mnemonic")
+		assert.Error(t, err)
+	})
+}
+
+func TestWalletRestore(t *testing.T) {
+	tf.UnitTest(t)
+
+	mnemonic, err := wallet.NewMnemonic()
+	require.NoError(t, err)
+	seed, err := wallet.SeedFromMnemonic(mnemonic)
+	require.NoError(t, err)
+
+	derived := func(index uint32) address.Address {
+		ki, err := wallet.DeriveKeyInfo(seed, index)
+		require.NoError(t, err)
+		addr, err := ki.Address()
+		require.NoError(t, err)
+		return addr
+	}
+
+	// addresses 0 and 5 are used, and address 30 is past the gap limit
+	used := map[address.Address]bool{
+		derived(0):  true,
+		derived(5):  true,
+		derived(30): true,
+	}
+
+	backend, err := wallet.NewDSBackend(datastore.NewMapDatastore())
+	require.NoError(t, err)
+	existing, err := backend.NewAddress()
+	require.NoError(t, err)
+	w := wallet.New(backend)
+
+	restored, err := w.Restore(seed, func(addr address.Address) (bool, error) {
+		return used[addr], nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []address.Address{derived(0), derived(5)}, restored)
+	assert.True(t, w.HasAddress(existing))
+	assert.True(t, w.HasAddress(derived(5)))
+	assert.False(t, w.HasAddress(derived(30)))
+
+	// new addresses follow the last restored one
+	addr, err := wallet.NewAddress(w)
+	require.NoError(t, err)
+	assert.Equal(t, derived(6), addr)
+
+	// restoring again does not hand out derived addresses again
+	_, err = w.Restore(seed, func(addr address.Address) (bool, error) {
+		return used[addr], nil
+	})
+	require.NoError(t, err)
+	addr, err = wallet.NewAddress(w)
+	require.NoError(t, err)
+	assert.Equal(t, derived(7), addr)
+}